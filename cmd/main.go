@@ -1,29 +1,189 @@
 package main
 
 import (
-	"flag"
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"winding-tree-server/internal/apiserver"
-)
 
-var (
-	configPath string
+	"github.com/urfave/cli"
 )
 
-func init() {
-	flag.StringVar(&configPath, "config-path", "config/server.toml", "path to config file")
-}
+// devSessionKey signs sessions in development, where nothing needs protecting
+const devSessionKey = "development-session-key-do-not-use-in-production"
 
 func main() {
-	flag.Parse()
+	app := cli.NewApp()
+	app.Name = "winding-tree-server"
+	app.Usage = "Winding Tree supplier API"
+	app.HideVersion = true
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "config-path",
+			Value: "config/server.toml",
+			Usage: "path to config file (TOML, YAML or JSON)",
+		},
+		cli.BoolFlag{
+			Name:  "dev",
+			Usage: "use a local SQLite database (dev.db) instead of Postgres",
+		},
+	}
+	app.Action = serve
+	app.Commands = []cli.Command{
+		{
+			Name:   "serve",
+			Usage:  "run the API server (the default)",
+			Action: serve,
+		},
+		{
+			Name:   "worker",
+			Usage:  "run job workers without serving the API",
+			Action: work,
+		},
+		{
+			Name:            "migrate",
+			Usage:           "apply or revert database migrations",
+			UsageText:       "migrate up | down [n] | status",
+			SkipFlagParsing: true,
+			Action:          withConfig(apiserver.Migrate),
+		},
+		{
+			Name:            "seed",
+			Usage:           "fill the database with demo data",
+			UsageText:       "seed [-seed n] [-suppliers n] [-flights n] [-days n]",
+			SkipFlagParsing: true,
+			Action:          withConfig(apiserver.Seed),
+		},
+		{
+			Name:            "create-admin",
+			Usage:           "create an admin user, or make an existing user admin",
+			UsageText:       "create-admin -email <email> [-password <password>]",
+			SkipFlagParsing: true,
+			Action:          withConfig(apiserver.CreateAdmin),
+		},
+		{
+			Name:            "purge",
+			Usage:           "apply the data retention policy once",
+			UsageText:       "purge [-dry-run]",
+			SkipFlagParsing: true,
+			Action:          withConfig(apiserver.Purge),
+		},
+		{
+			Name:            "export",
+			Usage:           "write a logical export of the database or of a user",
+			UsageText:       "export [-user <public id>] [-o file]",
+			SkipFlagParsing: true,
+			// The export itself may go to stdout
+			Action: func(c *cli.Context) error {
+				config, err := loadConfig(c)
+				if err != nil {
+					return err
+				}
 
-	config := apiserver.NewConfig()
-	// _, err := toml.DecodeFile(configPath, config)
-	// if err != nil {
-	// 	log.Fatal(err)
-	// }
+				return apiserver.Export(config, c.Args(), os.Stderr)
+			},
+		},
+		{
+			Name:  "config",
+			Usage: "inspect the config",
+			Subcommands: []cli.Command{
+				{
+					Name:  "validate",
+					Usage: "check that the server can start with the config",
+					Action: func(c *cli.Context) error {
+						config, err := loadConfig(c)
+						if err != nil {
+							return err
+						}
 
-	if err := apiserver.Start(config); err != nil {
+						if err := config.Validate(); err != nil {
+							return fmt.Errorf("invalid config: %v", err)
+						}
+
+						fmt.Println("config is valid")
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:  "routes",
+			Usage: "list the HTTP routes",
+			Action: func(c *cli.Context) error {
+				return apiserver.Routes(os.Stdout)
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// serve runs the server
+func serve(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	return apiserver.Start(config, func() (*apiserver.Config, error) {
+		return loadConfig(c)
+	})
+}
+
+// work runs job workers
+func work(c *cli.Context) error {
+	config, err := loadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	return apiserver.Work(config, func() (*apiserver.Config, error) {
+		return loadConfig(c)
+	})
+}
+
+// withConfig runs a subcommand implemented by the apiserver package, which
+// parses its own arguments
+func withConfig(run func(config *apiserver.Config, args []string, w io.Writer) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		config, err := loadConfig(c)
+		if err != nil {
+			return err
+		}
+
+		return run(config, c.Args(), os.Stdout)
+	}
+}
+
+// loadConfig layers the config file and the environment over the defaults
+func loadConfig(c *cli.Context) (*apiserver.Config, error) {
+	config := apiserver.NewConfig()
+	// The default config file is optional, so the server can be configured
+	// through the environment alone
+	if err := config.ReadFile(c.GlobalString("config-path")); err != nil && !(os.IsNotExist(err) && !c.GlobalIsSet("config-path")) {
+		return nil, err
+	}
+
+	if err := config.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := config.ResolveSecrets(context.Background()); err != nil {
+		return nil, err
+	}
+
+	if c.GlobalBool("dev") {
+		config.UseDevDatabase()
+		// Development runs without certificates or a configured session key
+		config.PlainHTTP = true
+		if config.SessionKey == "" {
+			config.SessionKey = devSessionKey
+		}
+	}
+
+	return config, nil
+}
@@ -4,21 +4,30 @@ go 1.12
 
 require (
 	github.com/BurntSushi/toml v0.3.1
+	github.com/andybalholm/brotli v1.0.0
 	github.com/bahadylbekov/winding-tree-server v0.0.0-20191018202311-3382abf100f5
+	github.com/getsentry/sentry-go v0.4.0
 	github.com/gin-contrib/cors v1.3.0
 	github.com/gin-contrib/sessions v0.0.1
 	github.com/gin-gonic/gin v1.4.0
 	github.com/go-ozzo/ozzo-validation v3.6.0+incompatible
+	github.com/go-redis/redis v6.15.6+incompatible
+	github.com/golang-migrate/migrate/v4 v4.7.0
 	github.com/google/uuid v1.1.1
 	github.com/gorilla/securecookie v1.1.1
 	github.com/gorilla/sessions v1.1.3
 	github.com/jmoiron/sqlx v1.2.0
 	github.com/lib/pq v1.2.0
+	github.com/mattn/go-sqlite3 v1.14.7
+	github.com/prometheus/client_golang v1.3.0
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/objx v0.2.0 // indirect
 	github.com/stretchr/testify v1.4.0
+	github.com/urfave/cli v1.22.2
 	golang.org/x/crypto v0.0.0-20190927123631-a832865fa7ad
+	golang.org/x/net v0.0.0-20190827160401-ba9fcec4b297
 	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e // indirect
 	golang.org/x/tools v0.0.0-20190929041059-e7abfedfabcf // indirect
 	gopkg.in/go-playground/assert.v1 v1.2.1
+	gopkg.in/yaml.v2 v2.2.4
 )
@@ -0,0 +1,92 @@
+package apiserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/sqlstore"
+)
+
+var (
+	errCreateAdminUsage = errors.New("usage: create-admin -email <email> [-password <password>]")
+)
+
+// CreateAdmin runs the create-admin subcommand, which creates an admin user,
+// or grants admin rights to the user with the email if there is one. Without
+// -password a random one is generated and printed.
+func CreateAdmin(config *Config, args []string, w io.Writer) error {
+	var email, password string
+
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&email, "email", "", "email of the admin")
+	flags.StringVar(&password, "password", "", "password of a new admin")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 || email == "" {
+		return errCreateAdminUsage
+	}
+
+	db, err := newDB(config.DatabaseDriver, config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := sqlstore.CheckSchema(db); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	s := sqlstore.New(db)
+
+	u, err := s.User().FindByEmail(ctx, email)
+	switch err {
+	case nil:
+		if password != "" {
+			return fmt.Errorf("%s already exists, its password is left unchanged", email)
+		}
+		fmt.Fprintf(w, "granting admin rights to %s (%s)\n", u.Email, u.PublicID)
+
+	case store.ErrRecordNotFound:
+		generated := password == ""
+		if generated {
+			if password, err = randomPassword(); err != nil {
+				return err
+			}
+		}
+
+		u = &model.User{Email: email, Password: password}
+		if err := s.User().Create(ctx, u); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "created %s (%s)\n", u.Email, u.PublicID)
+		if generated {
+			fmt.Fprintf(w, "password: %s\n", password)
+		}
+
+	default:
+		return err
+	}
+
+	// Admin rights can't be granted through the store
+	_, err = db.ExecContext(ctx, "UPDATE users SET is_admin = true WHERE id = $1", u.ID)
+
+	return err
+}
+
+// randomPassword fits the 30 characters users may have
+func randomPassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -1,38 +1,573 @@
 package apiserver
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"winding-tree-server/internal/chainevents"
+	"winding-tree-server/internal/envelope"
+	"winding-tree-server/internal/ethereum"
+	"winding-tree-server/internal/events"
+	"winding-tree-server/internal/jobqueue"
+	"winding-tree-server/internal/mailer"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/orgid"
+	"winding-tree-server/internal/outbox"
+	"winding-tree-server/internal/ratelimit"
+	"winding-tree-server/internal/requestid"
+	"winding-tree-server/internal/retention"
+	"winding-tree-server/internal/scheduler"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/cachestore"
+	"winding-tree-server/internal/store/metricstore"
+	"winding-tree-server/internal/store/retrystore"
 	"winding-tree-server/internal/store/sqlstore"
+	"winding-tree-server/internal/tracing"
+	"winding-tree-server/internal/vault"
 
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
 )
 
-// Start ...
-func Start(config *Config) error {
-	db, err := newDB(config.DatabaseURL)
+const (
+	dbCheckTimeout = 10 * time.Second
+
+	// memoryCacheURL as cache_url or rate_limit_url keeps the cache or the
+	// rate limits in process instead of in Redis
+	memoryCacheURL = "memory://"
+)
+
+var (
+	errInvalidMinLifDeposit = errors.New("min_lif_deposit must be an integer amount in wei")
+	errUnknownMailer        = errors.New("mailer must be smtp or sendgrid")
+)
+
+// dbSystems names the database drivers as OpenTelemetry does
+var dbSystems = map[string]string{
+	"postgres": "postgresql",
+	"sqlite3":  "sqlite",
+}
+
+// Start runs the server until it is stopped by a signal. On SIGHUP the
+// config is loaded again with load, and settings that can change at runtime
+// are applied.
+func Start(config *Config, load ConfigLoader) error {
+	return start(config, load, true)
+}
+
+// Work runs job workers, and the other background jobs, without serving
+// HTTP, for deployments that keep slow jobs off the API instances. It stops
+// like Start.
+func Work(config *Config, load ConfigLoader) error {
+	return start(config, load, false)
+}
+
+// start runs the server, serving HTTP with serveHTTP
+func start(config *Config, load ConfigLoader, serveHTTP bool) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	hub, err := newSentryHub(config)
+	if err != nil {
+		return err
+	}
+
+	if hub != nil {
+		defer hub.Flush(sentryFlushTimeout)
+	}
+
+	logger, logFile, err := newLogger(config)
+	if err != nil {
+		return err
+	}
+
+	if logFile != nil {
+		defer logFile.Close()
+	}
+
+	if hub != nil {
+		logger.AddHook(&sentryHook{hub: hub})
+	}
+
+	db, err := newDB(config.DatabaseDriver, config.DatabaseURL)
 	if err != nil {
 		return err
 	}
 
 	defer db.Close()
-	store := sqlstore.New(db)
+
+	configurePool(db, config)
+
+	if config.AutoMigrate {
+		if err := sqlstore.MigrateUp(db); err != nil {
+			return err
+		}
+	}
+
+	if err := sqlstore.CheckSchema(db); err != nil {
+		return err
+	}
+
+	replicas := make([]*sqlx.DB, 0, len(config.DatabaseReplicaURLs))
+	for _, url := range config.DatabaseReplicaURLs {
+		// Replicas are opened without a ping so one being down doesn't block
+		// startup; the health check keeps it out of rotation until it's back
+		replica, err := sqlx.Open(config.DatabaseDriver, url)
+		if err != nil {
+			return err
+		}
+
+		defer replica.Close()
+
+		configurePool(replica, config)
+		replicas = append(replicas, replica)
+	}
+
+	sqlStore := sqlstore.New(db, replicas...)
+	if len(config.EncryptionKeys) > 0 {
+		sealer, err := newSealer(config)
+		if err != nil {
+			return err
+		}
+
+		sqlStore.EncryptDocuments(sealer)
+	}
+
+	// Metrics sit below the retries so that each attempt is counted
+	metrics, err := metricstore.NewMetrics(prometheus.DefaultRegisterer, logger, config.StoreSlowThreshold.Duration)
+	if err != nil {
+		return err
+	}
+
+	var tracer *tracing.Tracer
+	var exporter *tracing.OTLPExporter
+	// Outgoing requests made while serving one carry its ID
+	http.DefaultTransport = requestid.NewTransport(http.DefaultTransport)
+	if config.TracingEndpoint != "" {
+		exporter = tracing.NewOTLPExporter(config.TracingEndpoint, config.TracingServiceName, logger)
+		tracer = tracing.NewTracer(exporter, config.TracingSampleRatio)
+		// Outgoing requests of the Ethereum, webhook, SendGrid and Vault
+		// clients go through the default transport
+		http.DefaultTransport = tracing.NewTransport(http.DefaultTransport, tracer)
+	}
+
+	instrumented := metricstore.New(sqlStore, metrics)
+	instrumented.Trace(tracer, dbSystems[config.DatabaseDriver])
+
+	var store store.Store = retrystore.New(
+		instrumented,
+		retrystore.Policy{
+			Attempts:  config.ReadRetryAttempts,
+			BaseDelay: config.ReadRetryBaseDelay.Duration,
+			MaxDelay:  config.ReadRetryMaxDelay.Duration,
+			Retryable: sqlstore.IsRetryableRead,
+		},
+		retrystore.Policy{
+			Attempts:  config.WriteRetryAttempts,
+			BaseDelay: config.WriteRetryBaseDelay.Duration,
+			MaxDelay:  config.WriteRetryMaxDelay.Duration,
+			Retryable: sqlstore.IsTransient,
+		},
+	)
+	var cached *cachestore.Store
+	var redisCache *cachestore.RedisCache
+	if config.CacheURL != "" {
+		var cache cachestore.Cache
+		if config.CacheURL == memoryCacheURL {
+			cache = cachestore.NewMemoryCache()
+		} else {
+			redisCache, err = cachestore.NewRedisCache(config.CacheURL)
+			if err != nil {
+				return err
+			}
+
+			defer redisCache.Close()
+			cache = redisCache
+		}
+
+		cached = cachestore.New(store, cache, config.CacheTTL.Duration)
+		store = cached
+	}
+
 	sessionStore := cookie.NewStore([]byte(config.SessionKey))
 	s := NewServer(store, sessionStore)
+	s.logger = logger
+	s.tracer = tracer
+	s.sentry = hub
+
+	// Background jobs are stopped and waited for before the database and
+	// cache connections close
+	ctx, cancel := context.WithCancel(context.Background())
+	background := &jobs{ctx: ctx}
+	defer func() {
+		cancel()
+		if !background.Wait(config.ShutdownTimeout.Duration) {
+			s.logger.Warnf("background jobs still running after %s", config.ShutdownTimeout.Duration)
+		}
+	}()
+
+	if exporter != nil {
+		background.Go(exporter.Run)
+	}
 
-	return http.ListenAndServe(config.BindAddress, s)
+	if len(config.vaultSecrets) > 0 {
+		client := vault.NewClient(config.VaultAddress, config.VaultToken)
+		for _, secret := range config.vaultSecrets {
+			secret := secret
+			background.Go(func(ctx context.Context) {
+				client.KeepRenewed(ctx, secret, s.logger)
+			})
+		}
+	}
+
+	// Writes by other instances and tools reach the cache through Postgres
+	// notifications; SQLite has none, but dev mode runs alone
+	if cached != nil && config.DatabaseDriver == "postgres" {
+		background.Go(func(ctx context.Context) {
+			listenInvalidations(ctx, config.DatabaseURL, cached, logger)
+		})
+	}
+
+	s.addReadinessCheck("database", sqlStore.Ping)
+	s.addReadinessCheck("migrations", func(ctx context.Context) error {
+		return sqlstore.CheckSchema(db)
+	})
+	if redisCache != nil {
+		s.addOptionalCheck("cache", redisCache.Ping)
+	}
+	s.export = sqlStore.Export
+	s.loadConfig = load
+	if err := s.applyConfig(config); err != nil {
+		return err
+	}
+	s.metricsToken = config.MetricsToken
+	s.maxBodySize = config.MaxBodySize
+	s.maxUploadSize = config.MaxUploadSize
+	if config.RateLimitURL != "" {
+		limiter, err := newRateLimiter(config.RateLimitURL)
+		if err != nil {
+			return err
+		}
+
+		if closer, ok := limiter.(io.Closer); ok {
+			defer closer.Close()
+		}
+
+		s.rateLimiter = limiter
+	}
+	s.trustedProxies, err = parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	s.compression = newCompression(config.Compression, config.CompressionBrotli, config.CompressionMinSize, config.CompressionTypes, config.CompressionExcludedRoutes)
+	if err := s.metrics.register(prometheus.DefaultRegisterer); err != nil {
+		return err
+	}
+
+	if err := registerPoolMetrics(prometheus.DefaultRegisterer, "primary", db); err != nil {
+		return err
+	}
+	for i, replica := range replicas {
+		if err := registerPoolMetrics(prometheus.DefaultRegisterer, fmt.Sprintf("replica%d", i), replica); err != nil {
+			return err
+		}
+	}
+
+	minLifDeposit, ok := new(big.Int).SetString(config.MinLifDeposit, 10)
+	if !ok {
+		return errInvalidMinLifDeposit
+	}
+	s.minLifDeposit = minLifDeposit
+
+	background.Go(func(ctx context.Context) {
+		sqlStore.MonitorReplicas(ctx, config.DatabaseReplicaCheckInterval.Duration)
+	})
+
+	if config.ConnStatsInterval.Duration > 0 {
+		background.Go(func(ctx context.Context) {
+			logPoolStats(ctx, logger.WithField("db", "primary"), db, config.ConnStatsInterval.Duration)
+		})
+		for i, replica := range replicas {
+			entry, replica := logger.WithField("db", fmt.Sprintf("replica%d", i)), replica
+			background.Go(func(ctx context.Context) {
+				logPoolStats(ctx, entry, replica, config.ConnStatsInterval.Duration)
+			})
+		}
+	}
+
+	s.jobQueue = jobqueue.New(store, logger, config.jobQueueConfig())
+	if config.Mailer != "" {
+		m, err := newMailer(config)
+		if err != nil {
+			return err
+		}
+
+		s.jobQueue.Handle(jobSendMail, sendMail(m))
+		if s.mailTemplates, err = newMailTemplates(); err != nil {
+			return err
+		}
+	}
+
+	// Dedicated workers run at least one, whatever the API instances run
+	workers := config.JobWorkers
+	if !serveHTTP && workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		background.Go(s.jobQueue.Run)
+	}
+
+	if config.OutboxWebhookURL != "" {
+		publisher := outbox.NewWebhookPublisher(config.OutboxWebhookURL, config.OutboxWebhookSecret)
+		relay := outbox.NewRelay(store, publisher, logger, config.outboxRelayConfig())
+		background.Go(relay.Run)
+	}
+
+	// Notification connections outlive the HTTP server's shutdown and end
+	// when the hub stops
+	if serveHTTP && config.EventsPollInterval.Duration > 0 {
+		s.events = events.NewHub(store, logger, config.EventsPollInterval.Duration, config.EventsGapTimeout.Duration)
+		background.Go(s.events.Run)
+	}
+
+	schedules, err := config.schedules()
+	if err != nil {
+		return err
+	}
+
+	if len(schedules) > 0 {
+		s.scheduler = scheduler.New(store, logger, config.ScheduleLease.Duration)
+		if spec, ok := schedules[taskRetentionPurge]; ok {
+			purger := retention.NewPurger(store, config.retentionPolicy(), logger, config.RetentionDryRun)
+			if err := s.scheduler.Add(taskRetentionPurge, spec, purger.Purge); err != nil {
+				return err
+			}
+		}
+		background.Go(s.scheduler.Run)
+	}
+
+	if config.EthereumRPCURL != "" {
+		if config.PublicURL == "" {
+			logger.Warn("public_url isn't set, so no organization is linked to a supplier")
+		}
+
+		client := ethereum.NewClient(config.EthereumRPCURL)
+		s.addOptionalCheck("ethereum", func(ctx context.Context) error {
+			_, err := client.BlockNumber(ctx)
+			return err
+		})
+
+		syncer := orgid.NewSyncer(
+			client,
+			store,
+			logger,
+			orgid.Config{
+				ORGiDAddress:      config.OrgIDAddress,
+				LifDepositAddress: config.LifDepositAddress,
+				Directories:       config.OrgIDDirectories,
+				Interval:          config.OrgIDSyncInterval.Duration,
+				PublicURL:         config.PublicURL,
+			},
+		)
+		background.Go(syncer.Run)
+
+		addresses := append([]string{}, config.ContractAddresses...)
+		if config.OrgIDAddress != "" {
+			addresses = append(addresses, config.OrgIDAddress)
+		}
+
+		if len(addresses) > 0 {
+			listener := chainevents.NewListener(
+				client,
+				store,
+				logger,
+				chainevents.Config{
+					Addresses:      addresses,
+					Confirmations:  config.Confirmations,
+					StartBlock:     config.ContractEventsStartBlock,
+					PollInterval:   config.ContractEventsPollInterval.Duration,
+					MaxAttempts:    config.ContractEventsMaxAttempts,
+					RetryBaseDelay: config.ContractEventsRetryBaseDelay.Duration,
+					RetryMaxDelay:  config.ContractEventsRetryMaxDelay.Duration,
+				},
+			)
+			registerContractEventHandlers(listener, syncer)
+			background.Go(listener.Run)
+		}
+	}
+
+	reloads := make(chan os.Signal, 1)
+	signal.Notify(reloads, syscall.SIGHUP)
+	defer signal.Stop(reloads)
+	background.Go(func(ctx context.Context) {
+		s.reloadOnSignal(ctx, reloads)
+	})
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	if !serveHTTP {
+		logger.Infof("running %d job workers", workers)
+		sig := <-signals
+		logger.Infof("received %s, shutting down", sig)
+		return nil
+	}
+
+	srv := s.httpServer(config.BindAddress)
+	if err := configureHTTP2(srv, config); err != nil {
+		return err
+	}
+
+	listen := srv.ListenAndServe
+	switch {
+	case config.PlainHTTP:
+	case len(config.AutocertDomains) > 0:
+		certManager := newCertManager(config)
+		srv.TLSConfig.GetCertificate = certManager.GetCertificate
+		listen = func() error {
+			return srv.ListenAndServeTLS("", "")
+		}
+
+		// Answers HTTP-01 challenges and redirects everything else to HTTPS
+		redirects := &http.Server{
+			Addr:         config.AutocertHTTPAddress,
+			Handler:      certManager.HTTPHandler(nil),
+			ReadTimeout:  s.ReadTimeout,
+			WriteTimeout: s.WriteTimeout,
+		}
+		background.Go(func(ctx context.Context) {
+			serveRedirects(ctx, redirects, s.logger)
+		})
+	default:
+		listen = func() error {
+			return srv.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		}
+	}
+
+	return serve(srv, listen, signals, config.ShutdownTimeout.Duration, s.logger)
 }
 
-// newDB ...
-func newDB(databaseURL string) (*sqlx.DB, error) {
-	db, err := sqlx.Connect("postgres", databaseURL)
+// registerContractEventHandlers ...
+func registerContractEventHandlers(l *chainevents.Listener, syncer *orgid.Syncer) {
+	resync := func(ctx context.Context, e *model.ContractEvent) error {
+		syncer.Trigger()
+		return nil
+	}
+
+	l.Handle("OrganizationCreated(bytes32,address)", resync)
+	l.Handle("OrgJsonUriChanged(bytes32,string,string)", resync)
+	l.Handle("OrgJsonHashChanged(bytes32,bytes32,bytes32)", resync)
+	l.Handle("OrganizationToggled(bytes32,bool,bool)", resync)
+}
+
+// newSealer ...
+func newSealer(config *Config) (*envelope.Sealer, error) {
+	keys, err := envelope.ParseKeys(config.EncryptionKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	keyring, err := envelope.NewKeyring(config.EncryptionKeyID, keys)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
+	return envelope.NewSealer(keyring), nil
+}
+
+// newMailer ...
+func newMailer(config *Config) (mailer.Mailer, error) {
+	switch config.Mailer {
+	case "smtp":
+		return mailer.NewSMTPMailer(config.SMTPAddress, config.SMTPUsername, config.SMTPPassword, config.MailFrom)
+	case "sendgrid":
+		return mailer.NewSendGridMailer(config.SendGridAPIKey, config.MailFrom), nil
+	default:
+		return nil, errUnknownMailer
+	}
+}
+
+// newRateLimiter ...
+func newRateLimiter(url string) (ratelimit.Limiter, error) {
+	if url == memoryCacheURL {
+		return ratelimit.NewMemoryLimiter(), nil
+	}
+
+	limiter, err := ratelimit.NewRedisLimiter(url)
+	if err != nil {
+		return nil, fmt.Errorf("rate_limit_url: %v", err)
+	}
+
+	return limiter, nil
+}
+
+// newDB connects and checks the database server can run the schema
+func newDB(driverName string, databaseURL string) (*sqlx.DB, error) {
+	db, err := sqlx.Connect(driverName, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the %s database: %v", driverName, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbCheckTimeout)
+	defer cancel()
+
+	if err := sqlstore.CheckRequirements(ctx, db); err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return db, nil
 }
+
+// listenInvalidations keeps the cache in sync with writes made elsewhere
+func listenInvalidations(ctx context.Context, databaseURL string, cache *cachestore.Store, logger *logrus.Logger) {
+	if err := sqlstore.ListenInvalidations(ctx, databaseURL, func(ctx context.Context, table string, id int) {
+		if err := cache.Invalidate(ctx, table, id); err != nil {
+			logger.Warnf("cache invalidation of %s %d failed: %v", table, id, err)
+		}
+	}); err != nil {
+		logger.Errorf("cache invalidation listener stopped: %v", err)
+	}
+}
+
+// configurePool ...
+func configurePool(db *sqlx.DB, config *Config) {
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime.Duration)
+}
+
+// logPoolStats periodically logs connection pool usage so exhaustion shows up before timeouts do
+func logPoolStats(ctx context.Context, logger *logrus.Entry, db *sqlx.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := db.Stats()
+			logger.WithFields(logrus.Fields{
+				"open":                stats.OpenConnections,
+				"in_use":              stats.InUse,
+				"idle":                stats.Idle,
+				"wait_count":          stats.WaitCount,
+				"wait_duration":       stats.WaitDuration,
+				"max_idle_closed":     stats.MaxIdleClosed,
+				"max_lifetime_closed": stats.MaxLifetimeClosed,
+			}).Info("database pool stats")
+		}
+	}
+}
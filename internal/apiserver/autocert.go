@@ -0,0 +1,34 @@
+package apiserver
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// newCertManager obtains and renews certificates for the configured domains
+func newCertManager(config *Config) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(config.AutocertDomains...),
+		Cache:      autocert.DirCache(config.AutocertCacheDir),
+		Email:      config.AutocertEmail,
+	}
+}
+
+// serveRedirects runs srv until ctx is done
+func serveRedirects(ctx context.Context, srv *http.Server, logger *logrus.Logger) {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		logger.Errorf("redirect listener stopped: %v", err)
+	case <-ctx.Done():
+		srv.Close()
+	}
+}
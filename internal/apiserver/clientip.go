@@ -0,0 +1,127 @@
+package apiserver
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trustedProxies are the networks of the load balancers and proxies in
+// front of the server, whose forwarding headers are believed
+type trustedProxies []*net.IPNet
+
+// parseTrustedProxies parses CIDRs, such as 10.0.0.0/8, and single addresses
+func parseTrustedProxies(proxies []string) (trustedProxies, error) {
+	var nets trustedProxies
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, errors.New(proxy + " must be an IP address or CIDR")
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.New(proxy + " must be an IP address or CIDR")
+		}
+		nets = append(nets, n)
+	}
+
+	return nets, nil
+}
+
+// contains ...
+func (p trustedProxies) contains(ip net.IP) bool {
+	for _, n := range p {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP is the address of the client of r. Forwarding headers are only
+// read from trusted proxies: X-Forwarded-For is walked from the right, each
+// proxy appending the address it got the request from, to the first
+// address that isn't a trusted proxy. X-Real-IP is used without it.
+func (p trustedProxies) clientIP(r *http.Request) string {
+	host, remote := remoteIP(r)
+	if remote == nil || !p.contains(remote) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range r.Header["X-Forwarded-For"] {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	if len(hops) == 0 {
+		if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+			return ip.String()
+		}
+		return host
+	}
+
+	// A malformed hop was made up by the client, which is then the last
+	// valid hop, appended by a trusted proxy
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+
+		client = ip
+		if !p.contains(ip) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+// trusts reports whether r comes from a trusted proxy
+func (p trustedProxies) trusts(r *http.Request) bool {
+	_, remote := remoteIP(r)
+	return remote != nil && p.contains(remote)
+}
+
+// remoteIP is the host of the peer address of r, and its IP if it is one
+func remoteIP(r *http.Request) (string, net.IP) {
+	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return host, net.ParseIP(host)
+}
+
+// resolveClientIP finds the client address of the request once, for the
+// logs, rate limits and error reports
+func (s *server) resolveClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("ctxKeyClientIP", s.trustedProxies.clientIP(c.Request))
+		c.Next()
+	}
+}
+
+// clientIP ...
+func clientIP(c *gin.Context) string {
+	if ip, ok := c.Get("ctxKeyClientIP"); ok {
+		return ip.(string)
+	}
+
+	return c.ClientIP()
+}
@@ -0,0 +1,234 @@
+package apiserver
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultCompressionMinSize = 1024
+
+// defaultCompressionTypes are the text formats the API serves
+var defaultCompressionTypes = []string{"application/json", "application/x-ndjson", "text/plain", "text/html"}
+
+// encoder is a gzip or brotli writer, reused across responses
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders pool the encoders by content coding
+var encoders = map[string]*sync.Pool{
+	"gzip": {
+		New: func() interface{} {
+			return gzip.NewWriter(nil)
+		},
+	},
+	"br": {
+		New: func() interface{} {
+			return brotli.NewWriter(nil)
+		},
+	},
+}
+
+// compression settings, set from the config by Start
+type compression struct {
+	enabled bool
+	// brotli, when enabled, is used over gzip for clients accepting both
+	brotli bool
+	// minSize is the response size from which responses are compressed
+	minSize int
+	// types are media types, without parameters, that are compressed
+	types map[string]bool
+	// excludedRoutes are route patterns, such as /metrics, never compressed
+	excludedRoutes map[string]bool
+}
+
+// newCompression ...
+func newCompression(enabled, brotli bool, minSize int, types, excludedRoutes []string) *compression {
+	c := &compression{
+		enabled:        enabled,
+		brotli:         brotli,
+		minSize:        minSize,
+		types:          map[string]bool{},
+		excludedRoutes: map[string]bool{},
+	}
+	for _, t := range types {
+		c.types[strings.ToLower(t)] = true
+	}
+	for _, r := range excludedRoutes {
+		c.excludedRoutes[r] = true
+	}
+
+	return c
+}
+
+// compress compresses responses of the configured types once they reach
+// the minimum size, for clients accepting gzip or brotli. Smaller responses
+// are buffered until they end and sent as they are.
+func (s *server) compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.compression.enabled || s.compression.excludedRoutes[s.routePattern(c)] {
+			c.Next()
+			return
+		}
+
+		coding := s.compression.negotiate(c.Request)
+		if coding == "" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, compression: s.compression, coding: coding}
+		c.Writer = w
+		defer func() {
+			w.Close()
+			c.Writer = w.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// negotiate picks the content coding of the response, or none
+func (c *compression) negotiate(r *http.Request) string {
+	accepted := map[string]bool{}
+	for _, coding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(coding, ";")
+		refused := len(parts) > 1 && strings.Replace(parts[1], " ", "", -1) == "q=0"
+		accepted[strings.TrimSpace(parts[0])] = !refused
+	}
+
+	switch {
+	case c.brotli && accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	default:
+		return ""
+	}
+}
+
+// compressWriter holds back the start of a response until it knows
+// whether to compress it
+type compressWriter struct {
+	gin.ResponseWriter
+	compression *compression
+	coding      string
+	buf         []byte
+	decided     bool
+	// enc is set once the response is being compressed
+	enc encoder
+}
+
+// Write ...
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf = append(w.buf, data...)
+	if len(w.buf) >= w.compression.minSize {
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(data), nil
+}
+
+// WriteString ...
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written ...
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush sends what was written so far, deciding on compression with what
+// is known, as streamed responses flush before they end
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Close ends the response
+func (w *compressWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return err
+		}
+	}
+
+	if w.enc == nil {
+		return nil
+	}
+
+	err := w.enc.Close()
+	w.enc.Reset(nil)
+	encoders[w.coding].Put(w.enc)
+	w.enc = nil
+
+	return err
+}
+
+// decide compresses the response if it is large enough and of a
+// compressible type, then sends the buffered start
+func (w *compressWriter) decide() error {
+	w.decided = true
+	buf := w.buf
+	w.buf = nil
+
+	if len(buf) >= w.compression.minSize && w.compressible() {
+		h := w.Header()
+		h.Set("Content-Encoding", w.coding)
+		h.Add("Vary", "Accept-Encoding")
+		h.Del("Content-Length")
+
+		w.enc = encoders[w.coding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+		_, err := w.enc.Write(buf)
+		return err
+	}
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	_, err := w.ResponseWriter.Write(buf)
+
+	return err
+}
+
+// compressible ...
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return w.compression.types[mediaType]
+}
@@ -1,17 +1,586 @@
 package apiserver
 
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"winding-tree-server/internal/jobqueue"
+	"winding-tree-server/internal/outbox"
+	"winding-tree-server/internal/retention"
+	"winding-tree-server/internal/vault"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// envPrefix starts the names of environment variables overriding the config
+const envPrefix = "WT_"
+
+// devDatabaseURL waits on locks instead of failing, as SQLite allows one writer at a time
+const devDatabaseURL = "file:dev.db?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL"
+
 // Config ...
 type Config struct {
-	BindAddress string `toml:"bind_address"`
-	LogLevel    string `toml:"log_level"`
-	DatabaseURL string `toml:"database_url"`
-	SessionKey  string `toml:"session_key"`
+	BindAddress       string   `toml:"bind_address"`
+	LogLevel          string   `toml:"log_level"`
+	DatabaseDriver    string   `toml:"database_driver"`
+	DatabaseURL       string   `toml:"database_url"`
+	AutoMigrate       bool     `toml:"auto_migrate"`
+	SessionKey        string   `toml:"session_key"`
+	EthereumRPCURL    string   `toml:"ethereum_rpc_url"`
+	OrgIDAddress      string   `toml:"orgid_address"`
+	OrgIDDirectories  []string `toml:"orgid_directories"`
+	OrgIDSyncInterval Duration `toml:"orgid_sync_interval"`
+	// PublicURL is where clients reach this server, such as
+	// https://api.example.com. An organization whose on-chain ORG.JSON URI
+	// is a supplier's ORG.JSON URL under it is linked to that supplier;
+	// without it none is.
+	PublicURL         string `toml:"public_url"`
+	LifDepositAddress string `toml:"lif_deposit_address"`
+	MinLifDeposit     string `toml:"min_lif_deposit"`
+	// ContractAddresses are watched for events in addition to the ORGiD contract
+	ContractAddresses          []string `toml:"contract_addresses"`
+	Confirmations              uint64   `toml:"confirmations"`
+	ContractEventsStartBlock   uint64   `toml:"contract_events_start_block"`
+	ContractEventsPollInterval Duration `toml:"contract_events_poll_interval"`
+	// A contract event is tried ContractEventsMaxAttempts times, with delays
+	// doubling from ContractEventsRetryBaseDelay up to
+	// ContractEventsRetryMaxDelay, before it has failed for good.
+	ContractEventsMaxAttempts    int      `toml:"contract_events_max_attempts"`
+	ContractEventsRetryBaseDelay Duration `toml:"contract_events_retry_base_delay"`
+	ContractEventsRetryMaxDelay  Duration `toml:"contract_events_retry_max_delay"`
+	// Mailer is "smtp", "sendgrid" or empty to disable email
+	Mailer         string `toml:"mailer"`
+	MailFrom       string `toml:"mail_from"`
+	SMTPAddress    string `toml:"smtp_address"`
+	SMTPUsername   string `toml:"smtp_username"`
+	SMTPPassword   string `toml:"smtp_password"`
+	SendGridAPIKey string `toml:"sendgrid_api_key"`
+	// CacheURL is a redis:// URL, or memory:// to cache in process; when
+	// empty the store is not cached
+	CacheURL string   `toml:"cache_url"`
+	CacheTTL Duration `toml:"cache_ttl"`
+	// DatabaseReplicaURLs serve reads that tolerate replication lag, such as search
+	DatabaseReplicaURLs          []string `toml:"database_replica_urls"`
+	DatabaseReplicaCheckInterval Duration `toml:"database_replica_check_interval"`
+	// Connection pool settings apply to the primary and to each replica
+	MaxOpenConns    int      `toml:"max_open_conns"`
+	MaxIdleConns    int      `toml:"max_idle_conns"`
+	ConnMaxLifetime Duration `toml:"conn_max_lifetime"`
+	// ConnStatsInterval is how often pool stats are logged; zero disables them
+	ConnStatsInterval Duration `toml:"conn_stats_interval"`
+	// OutboxWebhookURL receives domain events; when empty they stay in the outbox
+	OutboxWebhookURL    string   `toml:"outbox_webhook_url"`
+	OutboxWebhookSecret string   `toml:"outbox_webhook_secret"`
+	OutboxPollInterval  Duration `toml:"outbox_poll_interval"`
+	// An outbox event is tried OutboxMaxAttempts times, with delays doubling
+	// from OutboxRetryBaseDelay up to OutboxRetryMaxDelay, before it has
+	// failed for good and no longer holds back the events after it.
+	OutboxMaxAttempts    int      `toml:"outbox_max_attempts"`
+	OutboxRetryBaseDelay Duration `toml:"outbox_retry_base_delay"`
+	OutboxRetryMaxDelay  Duration `toml:"outbox_retry_max_delay"`
+	// Reads are retried on transient errors and lost connections, writes only
+	// when the database reports they were rolled back; 1 attempt disables retries
+	ReadRetryAttempts   int      `toml:"read_retry_attempts"`
+	ReadRetryBaseDelay  Duration `toml:"read_retry_base_delay"`
+	ReadRetryMaxDelay   Duration `toml:"read_retry_max_delay"`
+	WriteRetryAttempts  int      `toml:"write_retry_attempts"`
+	WriteRetryBaseDelay Duration `toml:"write_retry_base_delay"`
+	WriteRetryMaxDelay  Duration `toml:"write_retry_max_delay"`
+	// EncryptionKeys are "id:base64 key" AES-256 keys. Onboarding documents
+	// are encrypted with the key EncryptionKeyID; the others are kept to read
+	// documents written before a rotation. Empty stores documents in plaintext.
+	EncryptionKeyID string   `toml:"encryption_key_id"`
+	EncryptionKeys  []string `toml:"encryption_keys"`
+	// StoreSlowThreshold logs store calls taking at least this long; zero
+	// disables the log. Call counts and latencies are always exported.
+	StoreSlowThreshold Duration `toml:"store_slow_threshold"`
+	// Retention periods count from publication, recording, completion and
+	// deletion; zero keeps the data forever. Deleted users are anonymized,
+	// not removed, and dead jobs are kept. With RetentionDryRun the job only
+	// logs what it would purge.
+	OutboxRetention      Duration `toml:"outbox_retention"`
+	ChangeRetention      Duration `toml:"change_retention"`
+	JobRetention         Duration `toml:"job_retention"`
+	DeletedUserRetention Duration `toml:"deleted_user_retention"`
+	RetentionInterval    Duration `toml:"retention_interval"`
+	RetentionDryRun      bool     `toml:"retention_dry_run"`
+	// ShutdownTimeout bounds how long a stopping server waits for in-flight
+	// requests, and then again for background jobs
+	ShutdownTimeout Duration `toml:"shutdown_timeout"`
+	// TLSCertFile and TLSKeyFile are PEM files served over HTTPS. PlainHTTP
+	// serves HTTP instead, for development or behind a TLS terminating proxy.
+	TLSCertFile string `toml:"tls_cert_file"`
+	TLSKeyFile  string `toml:"tls_key_file"`
+	PlainHTTP   bool   `toml:"plain_http"`
+	// AutocertDomains get certificates from Let's Encrypt instead, cached in
+	// AutocertCacheDir. AutocertHTTPAddress must be reachable on port 80 of
+	// the domains for the HTTP-01 challenge, and redirects other requests.
+	AutocertDomains     []string `toml:"autocert_domains"`
+	AutocertEmail       string   `toml:"autocert_email"`
+	AutocertCacheDir    string   `toml:"autocert_cache_dir"`
+	AutocertHTTPAddress string   `toml:"autocert_http_address"`
+	// HTTP2 is negotiated over TLS; with PlainHTTP, H2C also serves it
+	// unencrypted to clients that ask for it, such as load balancers
+	HTTP2 bool `toml:"http2"`
+	H2C   bool `toml:"h2c"`
+	// Any value may be a "vault:path#key" reference to a secret in the Vault
+	// at VaultAddress, such as vault:secret/data/winding-tree#session_key
+	VaultAddress string `toml:"vault_address"`
+	VaultToken   string `toml:"vault_token"`
+	// MetricsToken, when set, must be sent as a bearer token to read /metrics
+	MetricsToken string `toml:"metrics_token"`
+	// TracingEndpoint is the base URL of an OpenTelemetry collector receiving
+	// OTLP over HTTP, such as http://localhost:4318; empty disables tracing.
+	// TracingSampleRatio of the traces started here are recorded.
+	TracingEndpoint    string  `toml:"tracing_endpoint"`
+	TracingServiceName string  `toml:"tracing_service_name"`
+	TracingSampleRatio float64 `toml:"tracing_sample_ratio"`
+	// Pprof serves runtime profiles under /debug/pprof to admins. It can be
+	// switched on by a config reload when needed.
+	Pprof bool `toml:"pprof"`
+	// SentryDSN, when set, reports panics, 5xx responses and error logs to
+	// Sentry, tagged with SentryEnvironment
+	SentryDSN         string `toml:"sentry_dsn"`
+	SentryEnvironment string `toml:"sentry_environment"`
+	// LogFormat is "text" or "json"; LogOutput is "stderr", "stdout" or the
+	// path of a file to append to. A reload applies log_level and log_format.
+	LogFormat string `toml:"log_format"`
+	LogOutput string `toml:"log_output"`
+	// MaxBodySize limits request bodies, in bytes, and MaxUploadSize those of
+	// document uploads; zero disables the limit
+	MaxBodySize   int64 `toml:"max_body_size"`
+	MaxUploadSize int64 `toml:"max_upload_size"`
+	// Compression gzips responses of CompressionTypes from CompressionMinSize
+	// bytes, except on CompressionExcludedRoutes, given as patterns like
+	// /private/export. With CompressionBrotli, clients accepting brotli get it.
+	Compression               bool     `toml:"compression"`
+	CompressionBrotli         bool     `toml:"compression_brotli"`
+	CompressionMinSize        int      `toml:"compression_min_size"`
+	CompressionTypes          []string `toml:"compression_types"`
+	CompressionExcludedRoutes []string `toml:"compression_excluded_routes"`
+	// RateLimitURL is a redis:// URL for instances to share limits, or
+	// memory:// to limit each instance on its own; empty disables limits.
+	// Limits are requests per RateLimitWindow: per client IP before login,
+	// per user after, and for flight search on top. Zero disables one.
+	RateLimitURL           string   `toml:"rate_limit_url"`
+	RateLimitWindow        Duration `toml:"rate_limit_window"`
+	RateLimitAnonymous     int      `toml:"rate_limit_anonymous"`
+	RateLimitAuthenticated int      `toml:"rate_limit_authenticated"`
+	RateLimitSearch        int      `toml:"rate_limit_search"`
+	// CORSAllowedOrigins may send cross-origin requests, with the methods
+	// and headers allowed and cookies with CORSAllowCredentials. An origin
+	// such as https://*.example.com allows the subdomains of example.com,
+	// and "*" any origin, without credentials; none disables CORS.
+	CORSAllowedOrigins   []string `toml:"cors_allowed_origins"`
+	CORSAllowedMethods   []string `toml:"cors_allowed_methods"`
+	CORSAllowedHeaders   []string `toml:"cors_allowed_headers"`
+	CORSAllowCredentials bool     `toml:"cors_allow_credentials"`
+	CORSMaxAge           Duration `toml:"cors_max_age"`
+	// TrustedProxies are the CIDRs or addresses of the load balancers and
+	// services in front of the server, whose X-Forwarded-For and X-Real-IP
+	// headers give the client address and whose X-Request-ID or traceparent
+	// is kept as the request ID; requests from elsewhere are taken as they come
+	TrustedProxies []string `toml:"trusted_proxies"`
+	// Maintenance answers 503 with MaintenanceMessage to all but health
+	// checks, metrics, logins and admins, telling clients to come back after
+	// MaintenanceRetryAfter. Admins can switch it at /admin/maintenance
+	// until the config is reloaded.
+	Maintenance           bool     `toml:"maintenance"`
+	MaintenanceMessage    string   `toml:"maintenance_message"`
+	MaintenanceRetryAfter Duration `toml:"maintenance_retry_after"`
+	// FeatureFlags roll features out: "name" turns one on for everyone,
+	// "name:25%" for a quarter of the users and "name:user:ID" for a user,
+	// by public ID. Admins can change them at /admin/features until the
+	// config is reloaded.
+	FeatureFlags []string `toml:"feature_flags"`
+	// JobWorkers run jobs, such as mails, in each instance; zero leaves them
+	// to instances started with the worker command, which run at least one.
+	// A job holds its worker for up to JobLease, and is tried JobMaxAttempts
+	// times, with delays doubling from JobRetryBaseDelay up to
+	// JobRetryMaxDelay, before it is dead and waits for an admin to retry it.
+	JobWorkers        int      `toml:"job_workers"`
+	JobPollInterval   Duration `toml:"job_poll_interval"`
+	JobLease          Duration `toml:"job_lease"`
+	JobMaxAttempts    int      `toml:"job_max_attempts"`
+	JobRetryBaseDelay Duration `toml:"job_retry_base_delay"`
+	JobRetryMaxDelay  Duration `toml:"job_retry_max_delay"`
+	// Schedules set when recurring tasks run, as "task=spec", such as
+	// "retention.purge=0 3 * * *". Specs are cron specs of minute, hour, day
+	// of month, month and day of week, in UTC, descriptors such as @daily or
+	// "@every 30m". The retention purge otherwise runs every
+	// retention_interval. Each run is taken by one instance, which holds the
+	// task for up to ScheduleLease; the runs due meanwhile are skipped.
+	Schedules     []string `toml:"schedules"`
+	ScheduleLease Duration `toml:"schedule_lease"`
+	// EventsPollInterval is how often the outbox is polled for the events
+	// pushed to the clients of /private/notifications; zero disables them
+	EventsPollInterval Duration `toml:"events_poll_interval"`
+	// EventsGapTimeout is how long the events after a missing outbox id
+	// wait for the write of that id to commit, before it is taken for a
+	// rolled back one
+	EventsGapTimeout Duration `toml:"events_gap_timeout"`
+
+	// vaultSecrets were read by ResolveSecrets; some have leases to renew
+	vaultSecrets []*vault.Secret
+}
+
+// Duration is a time.Duration that decodes from strings like "5m"
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText ...
+func (d *Duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+
+	return err
 }
 
 // NewConfig ...
 func NewConfig() *Config {
 	return &Config{
-		BindAddress: ":8000",
-		LogLevel:    "debug",
+		BindAddress:                  ":8000",
+		LogLevel:                     "debug",
+		LogFormat:                    "text",
+		LogOutput:                    "stderr",
+		MaxBodySize:                  defaultMaxBodySize,
+		MaxUploadSize:                defaultMaxUploadSize,
+		Compression:                  true,
+		CompressionMinSize:           defaultCompressionMinSize,
+		CompressionTypes:             append([]string{}, defaultCompressionTypes...),
+		RateLimitWindow:              Duration{time.Minute},
+		RateLimitAnonymous:           120,
+		RateLimitAuthenticated:       600,
+		RateLimitSearch:              60,
+		CORSAllowedOrigins:           append([]string{}, defaultCORSOrigins...),
+		CORSAllowedMethods:           append([]string{}, defaultCORSMethods...),
+		CORSAllowedHeaders:           append([]string{}, defaultCORSHeaders...),
+		CORSMaxAge:                   Duration{defaultCORSMaxAge},
+		MaintenanceRetryAfter:        Duration{defaultMaintenanceRetryAfter},
+		JobWorkers:                   2,
+		JobPollInterval:              Duration{time.Second},
+		JobLease:                     Duration{5 * time.Minute},
+		JobMaxAttempts:               10,
+		JobRetryBaseDelay:            Duration{10 * time.Second},
+		JobRetryMaxDelay:             Duration{time.Hour},
+		ScheduleLease:                Duration{time.Hour},
+		EventsPollInterval:           Duration{time.Second},
+		EventsGapTimeout:             Duration{10 * time.Second},
+		DatabaseDriver:               "postgres",
+		CacheTTL:                     Duration{time.Minute},
+		DatabaseReplicaCheckInterval: Duration{10 * time.Second},
+		MaxOpenConns:                 20,
+		MaxIdleConns:                 10,
+		ConnMaxLifetime:              Duration{30 * time.Minute},
+		ConnStatsInterval:            Duration{time.Minute},
+		OutboxPollInterval:           Duration{5 * time.Second},
+		OutboxMaxAttempts:            10,
+		OutboxRetryBaseDelay:         Duration{10 * time.Second},
+		OutboxRetryMaxDelay:          Duration{time.Hour},
+		ReadRetryAttempts:            3,
+		ReadRetryBaseDelay:           Duration{20 * time.Millisecond},
+		ReadRetryMaxDelay:            Duration{500 * time.Millisecond},
+		WriteRetryAttempts:           3,
+		WriteRetryBaseDelay:          Duration{50 * time.Millisecond},
+		WriteRetryMaxDelay:           Duration{time.Second},
+		OrgIDSyncInterval:            Duration{10 * time.Minute},
+		MinLifDeposit:                "0",
+		Confirmations:                12,
+		ContractEventsPollInterval:   Duration{15 * time.Second},
+		ContractEventsMaxAttempts:    10,
+		ContractEventsRetryBaseDelay: Duration{30 * time.Second},
+		ContractEventsRetryMaxDelay:  Duration{time.Hour},
+		StoreSlowThreshold:           Duration{250 * time.Millisecond},
+		OutboxRetention:              Duration{30 * 24 * time.Hour},
+		JobRetention:                 Duration{7 * 24 * time.Hour},
+		RetentionInterval:            Duration{time.Hour},
+		ShutdownTimeout:              Duration{30 * time.Second},
+		AutocertCacheDir:             "autocert",
+		AutocertHTTPAddress:          ":80",
+		HTTP2:                        true,
+		TracingServiceName:           "winding-tree-server",
+		TracingSampleRatio:           1,
+	}
+}
+
+// retentionPolicy ...
+func (c *Config) retentionPolicy() retention.Policy {
+	return retention.Policy{
+		OutboxEvents: c.OutboxRetention.Duration,
+		Changes:      c.ChangeRetention.Duration,
+		DeletedUsers: c.DeletedUserRetention.Duration,
+		Jobs:         c.JobRetention.Duration,
+	}
+}
+
+// jobQueueConfig ...
+func (c *Config) jobQueueConfig() jobqueue.Config {
+	return jobqueue.Config{
+		MaxAttempts:    c.JobMaxAttempts,
+		PollInterval:   c.JobPollInterval.Duration,
+		Lease:          c.JobLease.Duration,
+		RetryBaseDelay: c.JobRetryBaseDelay.Duration,
+		RetryMaxDelay:  c.JobRetryMaxDelay.Duration,
+	}
+}
+
+// outboxRelayConfig ...
+func (c *Config) outboxRelayConfig() outbox.Config {
+	return outbox.Config{
+		PollInterval:   c.OutboxPollInterval.Duration,
+		MaxAttempts:    c.OutboxMaxAttempts,
+		RetryBaseDelay: c.OutboxRetryBaseDelay.Duration,
+		RetryMaxDelay:  c.OutboxRetryMaxDelay.Duration,
+	}
+}
+
+// UseDevDatabase switches to a local SQLite file, migrated on start, so the
+// server runs without provisioning Postgres
+func (c *Config) UseDevDatabase() {
+	c.DatabaseDriver = "sqlite3"
+	c.DatabaseURL = devDatabaseURL
+	c.DatabaseReplicaURLs = nil
+	c.AutoMigrate = true
+}
+
+// ReadFile decodes the file at path over the current values. Files ending
+// in .yaml, .yml or .json use the same keys as TOML files. Unknown keys are
+// reported, as they are most likely misspelled.
+func (c *Config) ReadFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		// JSON is a subset of YAML
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+
+		return c.setValues(path, values)
+	}
+
+	md, err := toml.DecodeFile(path, c)
+	if err != nil {
+		return err
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("%s: unknown keys %v", path, undecoded)
 	}
+
+	return nil
+}
+
+// ApplyEnv overrides fields with environment variables named after their
+// TOML keys, like WT_DATABASE_URL for database_url. Lists are comma separated.
+func (c *Config) ApplyEnv(lookup func(key string) (string, bool)) error {
+	for key, f := range c.fields() {
+		name := envPrefix + strings.ToUpper(key)
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		if err := setField(f, value); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ResolveSecrets replaces vault:path#key references with the secrets they
+// point to. Keys of the same path come from a single read, so dynamic
+// credentials stay consistent.
+func (c *Config) ResolveSecrets(ctx context.Context) error {
+	var client *vault.Client
+	secrets := map[string]*vault.Secret{}
+	resolve := func(key, ref string) (string, error) {
+		if !strings.HasPrefix(ref, vault.RefPrefix) {
+			return ref, nil
+		}
+
+		if c.VaultAddress == "" {
+			return "", fmt.Errorf("%s: vault_address is required for vault references", key)
+		}
+
+		if client == nil {
+			client = vault.NewClient(c.VaultAddress, c.VaultToken)
+		}
+
+		path, name, err := vault.ParseRef(ref)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", key, err)
+		}
+
+		secret, ok := secrets[path]
+		if !ok {
+			if secret, err = client.Read(ctx, path); err != nil {
+				return "", fmt.Errorf("%s: %v", key, err)
+			}
+			secrets[path] = secret
+			c.vaultSecrets = append(c.vaultSecrets, secret)
+		}
+
+		value, ok := secret.Data[name]
+		if !ok {
+			return "", fmt.Errorf("%s: %v", key, vault.ErrKeyNotFound)
+		}
+
+		return fmt.Sprint(value), nil
+	}
+
+	for key, f := range c.fields() {
+		switch v := f.Interface().(type) {
+		case string:
+			value, err := resolve(key, v)
+			if err != nil {
+				return err
+			}
+			f.SetString(value)
+
+		case []string:
+			for i, item := range v {
+				value, err := resolve(key, item)
+				if err != nil {
+					return err
+				}
+				v[i] = value
+			}
+		}
+	}
+
+	return nil
+}
+
+// fields maps TOML keys to the fields of c
+func (c *Config) fields() map[string]reflect.Value {
+	v := reflect.ValueOf(c).Elem()
+	fields := make(map[string]reflect.Value, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if key := v.Type().Field(i).Tag.Get("toml"); key != "" {
+			fields[key] = v.Field(i)
+		}
+	}
+
+	return fields
+}
+
+// setValues sets fields from decoded YAML or JSON values
+func (c *Config) setValues(path string, values map[string]interface{}) error {
+	fields := c.fields()
+	unknown := []string{}
+	for key, value := range values {
+		f, ok := fields[key]
+		if !ok {
+			unknown = append(unknown, key)
+			continue
+		}
+
+		var err error
+		switch value := value.(type) {
+		case nil:
+			f.Set(reflect.Zero(f.Type()))
+		case []interface{}:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+			err = setList(f, items)
+		case map[interface{}]interface{}:
+			err = fmt.Errorf("unexpected table")
+		default:
+			err = setField(f, fmt.Sprint(value))
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", path, key, err)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%s: unknown keys %v", path, unknown)
+	}
+
+	return nil
+}
+
+// setField parses value into the config field f
+func setField(f reflect.Value, value string) error {
+	if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+
+	case reflect.Uint, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+
+	case reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+
+		return setList(f, items)
+
+	default:
+		return fmt.Errorf("unsupported type %s", f.Type())
+	}
+
+	return nil
+}
+
+// setList sets the list field f
+func setList(f reflect.Value, items []string) error {
+	if f.Kind() != reflect.Slice || f.Type().Elem().Kind() != reflect.String {
+		return fmt.Errorf("unexpected list")
+	}
+
+	f.Set(reflect.ValueOf(items))
+
+	return nil
 }
@@ -0,0 +1,529 @@
+package apiserver
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"winding-tree-server/internal/store/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_ReadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+		isValid bool
+	}{
+		{
+			name:    "toml",
+			file:    "server.toml",
+			content: "bind_address = \":9000\"\ncache_ttl = \"5m\"\norgid_directories = [\"0x1\"]\n",
+			isValid: true,
+		},
+		{
+			name:    "yaml",
+			file:    "server.yaml",
+			content: "bind_address: \":9000\"\ncache_ttl: 5m\norgid_directories:\n  - \"0x1\"\nmax_open_conns: 20\n",
+			isValid: true,
+		},
+		{
+			name:    "json",
+			file:    "server.json",
+			content: `{"bind_address": ":9000", "cache_ttl": "5m", "orgid_directories": ["0x1"], "auto_migrate": false}`,
+			isValid: true,
+		},
+		{
+			name:    "unknown key",
+			file:    "server.toml",
+			content: "bind_adress = \":9000\"\n",
+			isValid: false,
+		},
+		{
+			name:    "unknown yaml key",
+			file:    "server.yml",
+			content: "bind_adress: \":9000\"\n",
+			isValid: false,
+		},
+		{
+			name:    "invalid duration",
+			file:    "server.toml",
+			content: "cache_ttl = \"5 minutes\"\n",
+			isValid: false,
+		},
+		{
+			name:    "list expected",
+			file:    "server.json",
+			content: `{"max_open_conns": [1]}`,
+			isValid: false,
+		},
+		{
+			name:    "invalid json",
+			file:    "server.json",
+			content: `{"bind_address": `,
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.file)
+			assert.NoError(t, ioutil.WriteFile(path, []byte(tc.content), 0600))
+
+			config := NewConfig()
+			err := config.ReadFile(path)
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, ":9000", config.BindAddress)
+			assert.Equal(t, 5*time.Minute, config.CacheTTL.Duration)
+			assert.Equal(t, []string{"0x1"}, config.OrgIDDirectories)
+			assert.Equal(t, 20, config.MaxOpenConns, "defaults are kept")
+		})
+	}
+}
+
+func TestConfig_ApplyEnv(t *testing.T) {
+	testCases := []struct {
+		name    string
+		env     map[string]string
+		isValid bool
+	}{
+		{
+			name: "valid",
+			env: map[string]string{
+				"WT_DATABASE_URL":          "postgres://localhost/wt",
+				"WT_AUTO_MIGRATE":          "true",
+				"WT_MAX_OPEN_CONNS":        "5",
+				"WT_CONFIRMATIONS":         "3",
+				"WT_CACHE_TTL":             "5m",
+				"WT_TRACING_SAMPLE_RATIO":  "0.25",
+				"WT_DATABASE_REPLICA_URLS": "postgres://r1/wt, postgres://r2/wt",
+				"WT_CONTRACT_ADDRESSES":    "",
+				"DATABASE_URL":             "ignored",
+				"WT_UNKNOWN_KEYS_ARE_FINE": "1",
+			},
+			isValid: true,
+		},
+		{
+			name:    "invalid bool",
+			env:     map[string]string{"WT_AUTO_MIGRATE": "maybe"},
+			isValid: false,
+		},
+		{
+			name:    "invalid int",
+			env:     map[string]string{"WT_MAX_OPEN_CONNS": "many"},
+			isValid: false,
+		},
+		{
+			name:    "invalid duration",
+			env:     map[string]string{"WT_CACHE_TTL": "5"},
+			isValid: false,
+		},
+		{
+			name:    "invalid float",
+			env:     map[string]string{"WT_TRACING_SAMPLE_RATIO": "half"},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := NewConfig()
+			config.ContractAddresses = []string{"0x1"}
+			err := config.ApplyEnv(func(key string) (string, bool) {
+				value, ok := tc.env[key]
+				return value, ok
+			})
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "postgres://localhost/wt", config.DatabaseURL)
+			assert.True(t, config.AutoMigrate)
+			assert.Equal(t, 5, config.MaxOpenConns)
+			assert.Equal(t, uint64(3), config.Confirmations)
+			assert.Equal(t, 5*time.Minute, config.CacheTTL.Duration)
+			assert.Equal(t, 0.25, config.TracingSampleRatio)
+			assert.Equal(t, []string{"postgres://r1/wt", "postgres://r2/wt"}, config.DatabaseReplicaURLs)
+			assert.Empty(t, config.ContractAddresses)
+			assert.Equal(t, ":8000", config.BindAddress, "defaults are kept")
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	validConfig := func() *Config {
+		config := NewConfig()
+		config.DatabaseURL = "postgres://localhost/wt"
+		config.SessionKey = "6f1c0a7e9b2d4c3f8a5e1d7b0c9f2a4e"
+		config.PlainHTTP = true
+		return config
+	}
+
+	testCases := []struct {
+		name   string
+		config func() *Config
+		errors []string
+	}{
+		{
+			name:   "valid",
+			config: validConfig,
+		},
+		{
+			name: "all problems are reported",
+			config: func() *Config {
+				config := validConfig()
+				config.DatabaseURL = ""
+				config.SessionKey = "secret"
+				config.BindAddress = "8000"
+				return config
+			},
+			errors: []string{"database_url", "session_key", "bind_address"},
+		},
+		{
+			name: "predictable session key",
+			config: func() *Config {
+				config := validConfig()
+				config.SessionKey = "secretsecretsecretsecretsecretsecret"
+				return config
+			},
+			errors: []string{"session_key"},
+		},
+		{
+			name: "tls files",
+			config: func() *Config {
+				config := validConfig()
+				config.PlainHTTP = false
+				config.TLSCertFile = "missing.pem"
+				return config
+			},
+			errors: []string{"tls_cert_file: cannot be read", "tls_key_file: cannot be blank"},
+		},
+		{
+			name: "autocert needs no tls files",
+			config: func() *Config {
+				config := validConfig()
+				config.PlainHTTP = false
+				config.AutocertDomains = []string{"api.example.org"}
+				return config
+			},
+		},
+		{
+			name: "mailer",
+			config: func() *Config {
+				config := validConfig()
+				config.Mailer = "smtp"
+				return config
+			},
+			errors: []string{"mail_from", "smtp_address"},
+		},
+		{
+			name: "encryption keys",
+			config: func() *Config {
+				config := validConfig()
+				config.EncryptionKeys = []string{"k1:short"}
+				return config
+			},
+			errors: []string{"encryption_key_id", "encryption_keys"},
+		},
+		{
+			name: "tracing",
+			config: func() *Config {
+				config := validConfig()
+				config.TracingEndpoint = "http://localhost:4318"
+				config.TracingServiceName = ""
+				config.TracingSampleRatio = 1.5
+				return config
+			},
+			errors: []string{"tracing_service_name", "tracing_sample_ratio"},
+		},
+		{
+			name: "feature flags",
+			config: func() *Config {
+				config := validConfig()
+				config.FeatureFlags = []string{"search_v2:10%", "export_v2:all"}
+				return config
+			},
+			errors: []string{"feature_flags"},
+		},
+		{
+			name: "trusted proxies",
+			config: func() *Config {
+				config := validConfig()
+				config.TrustedProxies = []string{"10.0.0.0/8", "192.168.1.1", "proxy.internal"}
+				return config
+			},
+			errors: []string{"trusted_proxies: proxy.internal must be an IP address or CIDR"},
+		},
+		{
+			name: "cors origins",
+			config: func() *Config {
+				config := validConfig()
+				config.CORSAllowedOrigins = []string{"https://*.example.com", "example.com"}
+				return config
+			},
+			errors: []string{"cors_allowed_origins: example.com must be an origin"},
+		},
+		{
+			name: "cors any origin with credentials",
+			config: func() *Config {
+				config := validConfig()
+				config.CORSAllowedOrigins = []string{"*"}
+				config.CORSAllowCredentials = true
+				return config
+			},
+			errors: []string{"cors_allowed_origins"},
+		},
+		{
+			name: "contract events",
+			config: func() *Config {
+				config := validConfig()
+				config.ContractEventsMaxAttempts = 0
+				config.ContractEventsRetryMaxDelay = Duration{}
+				return config
+			},
+			errors: []string{"contract_events_max_attempts", "contract_events_retry_max_delay: must be a positive duration"},
+		},
+		{
+			name: "outbox retries",
+			config: func() *Config {
+				config := validConfig()
+				config.OutboxMaxAttempts = 0
+				config.OutboxRetryBaseDelay = Duration{}
+				return config
+			},
+			errors: []string{"outbox_max_attempts", "outbox_retry_base_delay: must be a positive duration"},
+		},
+		{
+			name: "rate limit window",
+			config: func() *Config {
+				config := validConfig()
+				config.RateLimitURL = "memory://"
+				config.RateLimitWindow = Duration{}
+				return config
+			},
+			errors: []string{"rate_limit_window: must be a positive duration"},
+		},
+		{
+			name: "job queue",
+			config: func() *Config {
+				config := validConfig()
+				config.JobWorkers = -1
+				config.JobLease = Duration{}
+				config.JobMaxAttempts = 0
+				return config
+			},
+			errors: []string{"job_workers", "job_lease: must be a positive duration", "job_max_attempts"},
+		},
+		{
+			name: "schedules",
+			config: func() *Config {
+				config := validConfig()
+				config.Schedules = []string{"retention.purge=0 3 * * *", "reports.send=@daily"}
+				return config
+			},
+			errors: []string{"schedules: reports.send is not a scheduled task"},
+		},
+		{
+			name: "schedule spec",
+			config: func() *Config {
+				config := validConfig()
+				config.Schedules = []string{"retention.purge=0 25 * * *"}
+				config.ScheduleLease = Duration{}
+				return config
+			},
+			errors: []string{"schedules: retention.purge: hour", "schedule_lease"},
+		},
+		{
+			name: "events poll interval",
+			config: func() *Config {
+				config := validConfig()
+				config.EventsPollInterval = Duration{time.Millisecond}
+				config.EventsGapTimeout = Duration{}
+				return config
+			},
+			errors: []string{"events_poll_interval: must be at least 100ms", "events_gap_timeout"},
+		},
+		{
+			name: "misc",
+			config: func() *Config {
+				config := validConfig()
+				config.LogLevel = "verbose"
+				config.LogFormat = "xml"
+				config.DatabaseDriver = "mysql"
+				config.MinLifDeposit = "1e18"
+				config.PublicURL = "api.example.com"
+				return config
+			},
+			errors: []string{"log_level", "log_format", "database_driver", "min_lif_deposit", "public_url"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config().Validate()
+			if len(tc.errors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			if assert.Error(t, err) {
+				for _, e := range tc.errors {
+					assert.Contains(t, err.Error(), e)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_ResolveSecrets(t *testing.T) {
+	reads := 0
+	vaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reads++
+		switch r.URL.Path {
+		case "/v1/secret/data/wt":
+			w.Write([]byte(`{"data": {"data": {"session_key": "s3cret", "key": "k1:a2V5"}, "metadata": {}}}`))
+		case "/v1/database/creds/wt":
+			w.Write([]byte(`{"lease_id": "l1", "lease_duration": 60, "renewable": true, "data": {"url": "postgres://wt-1@db/wt"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vaultServer.Close()
+
+	testCases := []struct {
+		name    string
+		config  func(config *Config)
+		isValid bool
+	}{
+		{
+			name: "valid",
+			config: func(config *Config) {
+				config.VaultAddress = vaultServer.URL
+				config.SessionKey = "vault:secret/data/wt#session_key"
+				config.EncryptionKeys = []string{"vault:secret/data/wt#key"}
+				config.DatabaseURL = "vault:database/creds/wt#url"
+			},
+			isValid: true,
+		},
+		{
+			name: "vault address missing",
+			config: func(config *Config) {
+				config.SessionKey = "vault:secret/data/wt#session_key"
+			},
+			isValid: false,
+		},
+		{
+			name: "key missing",
+			config: func(config *Config) {
+				config.VaultAddress = vaultServer.URL
+				config.SessionKey = "vault:secret/data/wt#password"
+			},
+			isValid: false,
+		},
+		{
+			name: "secret missing",
+			config: func(config *Config) {
+				config.VaultAddress = vaultServer.URL
+				config.SessionKey = "vault:secret/data/missing#session_key"
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reads = 0
+			config := NewConfig()
+			tc.config(config)
+			err := config.ResolveSecrets(context.Background())
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "s3cret", config.SessionKey)
+			assert.Equal(t, []string{"k1:a2V5"}, config.EncryptionKeys)
+			assert.Equal(t, "postgres://wt-1@db/wt", config.DatabaseURL)
+			assert.Equal(t, 2, reads, "one read per path")
+			assert.Len(t, config.vaultSecrets, 2)
+		})
+	}
+}
+
+func TestCreateAdmin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	config := NewConfig()
+	config.DatabaseDriver = "sqlite3"
+	config.DatabaseURL = "file:" + filepath.Join(dir, "test.db") + "?_foreign_keys=on"
+	if !assert.NoError(t, Migrate(config, []string{"up"}, ioutil.Discard)) {
+		return
+	}
+
+	var out bytes.Buffer
+	assert.Equal(t, errCreateAdminUsage, CreateAdmin(config, nil, &out))
+	assert.NoError(t, CreateAdmin(config, []string{"-email", "admin@example.org"}, &out))
+	assert.Contains(t, out.String(), "password: ")
+	assert.Error(t, CreateAdmin(config, []string{"-email", "admin@example.org", "-password", "secret"}, &out), "existing password is kept")
+	assert.NoError(t, CreateAdmin(config, []string{"-email", "admin@example.org"}, &out), "promoting again is harmless")
+
+	db, err := newDB(config.DatabaseDriver, config.DatabaseURL)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	u, err := sqlstore.New(db).User().FindByEmail(context.Background(), "admin@example.org")
+	assert.NoError(t, err)
+	assert.True(t, u.IsAdmin)
+}
+
+func TestRoutes(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, Routes(&out))
+	assert.Contains(t, out.String(), "POST    /admin/config/reload\n")
+	assert.Contains(t, out.String(), "GET     /private/whoami\n")
+}
+
+func TestConfig_Schedules(t *testing.T) {
+	config := NewConfig()
+	schedules, err := config.schedules()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"retention.purge": "@every 1h0m0s"}, schedules)
+
+	config.Schedules = []string{"retention.purge = 0 3 * * *"}
+	schedules, err = config.schedules()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"retention.purge": "0 3 * * *"}, schedules)
+
+	config.Schedules = nil
+	config.RetentionInterval = Duration{}
+	schedules, err = config.schedules()
+	assert.NoError(t, err)
+	assert.Empty(t, schedules)
+}
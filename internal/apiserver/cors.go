@@ -0,0 +1,188 @@
+package apiserver
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultCORSMaxAge = 12 * time.Hour
+
+var (
+	defaultCORSOrigins = []string{"http://moonshard.io", "http://equityone.org"}
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}
+	defaultCORSHeaders = []string{"Origin", "Content-Length", "Content-Type"}
+
+	errCORSCredentials = errors.New("any origin (*) can't be allowed with credentials")
+)
+
+// originPattern is an allowed origin; with wildcard, any subdomain of host
+type originPattern struct {
+	scheme   string
+	host     string
+	port     string
+	wildcard bool
+}
+
+// parseOrigin parses an origin such as https://example.com, or
+// https://*.example.com for its subdomains
+func parseOrigin(origin string) (originPattern, error) {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" || (u.Path != "" && u.Path != "/") {
+		return originPattern{}, errors.New("must be an origin such as https://example.com")
+	}
+
+	p := originPattern{scheme: u.Scheme, host: u.Hostname(), port: u.Port()}
+	if strings.HasPrefix(p.host, "*.") {
+		p.wildcard = true
+		p.host = p.host[2:]
+	}
+	if p.host == "" || strings.Contains(p.host, "*") {
+		return originPattern{}, errors.New("may only have a wildcard as its first label, such as https://*.example.com")
+	}
+
+	return p, nil
+}
+
+// matches ...
+func (p originPattern) matches(origin originPattern) bool {
+	if origin.wildcard || origin.scheme != p.scheme || origin.port != p.port {
+		return false
+	}
+
+	if p.wildcard {
+		return strings.HasSuffix(origin.host, "."+p.host)
+	}
+
+	return origin.host == p.host
+}
+
+// allowedOrigins are the origins of cross-origin requests: any of them, or
+// those matching patterns
+type allowedOrigins struct {
+	any      bool
+	patterns []originPattern
+}
+
+// parseAllowedOrigins parses origins, which may be "*" for any origin or
+// have wildcard subdomains
+func parseAllowedOrigins(origins []string) (allowedOrigins, error) {
+	var a allowedOrigins
+	for _, origin := range origins {
+		if origin == "*" {
+			a.any = true
+			continue
+		}
+
+		p, err := parseOrigin(origin)
+		if err != nil {
+			return allowedOrigins{}, err
+		}
+		a.patterns = append(a.patterns, p)
+	}
+
+	return a, nil
+}
+
+// allows reports whether origin matches a pattern; "*" isn't considered
+func (a allowedOrigins) allows(origin string) bool {
+	o, err := parseOrigin(origin)
+	if err != nil {
+		return false
+	}
+
+	for _, p := range a.patterns {
+		if p.matches(o) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newCORS allows cross-origin requests from origins, which may be "*" for
+// any origin or have wildcard subdomains. It returns nil without origins.
+func newCORS(origins, methods, headers []string, credentials bool, maxAge time.Duration) (gin.HandlerFunc, error) {
+	if len(origins) == 0 {
+		return nil, nil
+	}
+
+	allowed, err := parseAllowedOrigins(origins)
+	if err != nil {
+		return nil, err
+	}
+
+	config := cors.Config{
+		AllowMethods:     methods,
+		AllowHeaders:     headers,
+		AllowCredentials: credentials,
+		MaxAge:           maxAge,
+	}
+
+	if allowed.any {
+		if credentials {
+			return nil, errCORSCredentials
+		}
+
+		config.AllowAllOrigins = true
+		return cors.New(config), nil
+	}
+
+	config.AllowOriginFunc = allowed.allows
+
+	return cors.New(config), nil
+}
+
+// corsPolicy is replaced as a whole when the config is reloaded
+type corsPolicy struct {
+	// handler is nil when cross-origin requests aren't allowed
+	handler gin.HandlerFunc
+	// origins may open WebSockets too
+	origins allowedOrigins
+}
+
+// setCORS allows cross-origin requests as newCORS does, in place of the
+// current policy
+func (s *server) setCORS(origins, methods, headers []string, credentials bool, maxAge time.Duration) error {
+	handler, err := newCORS(origins, methods, headers, credentials, maxAge)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := parseAllowedOrigins(origins)
+	if err != nil {
+		return err
+	}
+
+	s.cors.Store(&corsPolicy{handler: handler, origins: allowed})
+
+	return nil
+}
+
+// corsPolicy ...
+func (s *server) corsPolicy() *corsPolicy {
+	policy, ok := s.cors.Load().(*corsPolicy)
+	if !ok {
+		return &corsPolicy{}
+	}
+
+	return policy
+}
+
+// allowCORS answers preflight requests and sets the CORS headers of
+// allowed origins, as configured by Start and config reloads
+func (s *server) allowCORS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handler := s.corsPolicy().handler
+		if handler == nil {
+			c.Next()
+			return
+		}
+
+		handler(c)
+	}
+}
@@ -0,0 +1,216 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+	"winding-tree-server/internal/events"
+	"winding-tree-server/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventStreamHeartbeatInterval keeps idle streams open through proxies
+	eventStreamHeartbeatInterval = 15 * time.Second
+	// eventStreamRetry is how long, in milliseconds, clients wait before
+	// reconnecting
+	eventStreamRetry = 2000
+	// eventStreamReplayLimit bounds the events looked through to resume a
+	// stream; clients further behind are told to reset
+	eventStreamReplayLimit = 1000
+
+	// eventStreamReset tells clients to reload what they show, as the
+	// events they missed can't be replayed
+	eventStreamReset = "reset"
+)
+
+// handleEventsStream streams the events of the user as server-sent events,
+// for clients that can't use WebSockets, as handleNotifications selects them.
+// Each event has the outbox event as data and its id, so that clients
+// reconnecting with Last-Event-ID get the events they missed first.
+func (s *server) handleEventsStream(c *gin.Context) {
+	if s.events == nil {
+		respondWithError(c, http.StatusServiceUnavailable, errEventsUnavailable)
+		return
+	}
+
+	u := c.Value("ctxKeyUser").(*model.User)
+	filter := eventsFilter(c, u)
+
+	// Subscribing first leaves no gap with the replay; events in both are
+	// sent once
+	sub := s.events.Subscribe(filter)
+	defer sub.Close()
+
+	lastID, _ := strconv.Atoi(c.GetHeader("Last-Event-ID"))
+	var missed []*events.Event
+	var reset bool
+	if lastID > 0 {
+		var err error
+		missed, err = s.events.Replay(c.Request.Context(), filter, lastID, eventStreamReplayLimit)
+		if err == events.ErrReplayTooLong {
+			reset, err = true, nil
+			lastID = s.events.LastID()
+		}
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+			return
+		}
+	}
+
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// Proxies such as nginx would otherwise buffer the stream
+	h.Set("X-Accel-Buffering", "no")
+
+	stream, err := s.openEventStream(c)
+	if err != nil {
+		s.requestLogger(c).Errorf("event stream not opened: %v", err)
+		return
+	}
+	defer stream.close()
+
+	err = stream.write(fmt.Sprintf("retry: %d\n\n", eventStreamRetry))
+	if err == nil && reset {
+		err = stream.send(lastID, eventStreamReset, []byte("{}"))
+	}
+	for _, e := range missed {
+		if err != nil {
+			break
+		}
+		err = stream.sendEvent(e)
+		lastID = e.ID
+	}
+
+	heartbeat := time.NewTicker(eventStreamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for err == nil {
+		select {
+		case <-stream.closed:
+			return
+		case <-stream.end:
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				// The server is stopping, or the client fell behind; it
+				// reconnects and resumes
+				return
+			}
+
+			if e.ID > lastID {
+				err = stream.sendEvent(e)
+				lastID = e.ID
+			}
+		case <-heartbeat.C:
+			err = stream.write(": heartbeat\n\n")
+		}
+	}
+
+	s.requestLogger(c).Debugf("event stream closed: %v", err)
+}
+
+// eventStream writes server-sent events to a client
+type eventStream struct {
+	w     io.Writer
+	flush func() error
+	close func()
+	// closed is closed when the client goes away
+	closed <-chan struct{}
+	// end fires when the stream must end for the client to reconnect; it is
+	// nil when the stream may last
+	end <-chan time.Time
+}
+
+// openEventStream starts the response of an event stream. The server's
+// write timeout would end it, so HTTP/1 connections are taken over, while
+// other streams end before the timeout and clients reconnect.
+func (s *server) openEventStream(c *gin.Context) (*eventStream, error) {
+	c.Status(http.StatusOK)
+
+	if c.Request.ProtoMajor != 1 {
+		stream := &eventStream{
+			w: c.Writer,
+			flush: func() error {
+				c.Writer.Flush()
+				return nil
+			},
+			close:  func() {},
+			closed: c.Request.Context().Done(),
+		}
+		if s.WriteTimeout > 0 {
+			stream.end = time.After(s.WriteTimeout * 9 / 10)
+		}
+
+		return stream, stream.flush()
+	}
+
+	conn, rw, err := c.Writer.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	// Clients send nothing; reading notices when they close
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, conn)
+		close(closed)
+	}()
+
+	stream := &eventStream{
+		w: rw,
+		flush: func() error {
+			conn.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+			return rw.Flush()
+		},
+		close: func() {
+			conn.Close()
+		},
+		closed: closed,
+	}
+
+	// The body ends with the connection, which isn't reused
+	h := c.Writer.Header()
+	h.Set("Connection", "close")
+	rw.WriteString("HTTP/1.1 200 OK\r\n")
+	h.Write(rw)
+	rw.WriteString("\r\n")
+
+	return stream, stream.flush()
+}
+
+// write sends raw lines of the stream
+func (s *eventStream) write(lines string) error {
+	if _, err := io.WriteString(s.w, lines); err != nil {
+		return err
+	}
+
+	return s.flush()
+}
+
+// send sends an event; data must be on a single line
+func (s *eventStream) send(id int, event string, data []byte) error {
+	lines := fmt.Sprintf("id: %d\n", id)
+	if event != "" {
+		lines += "event: " + event + "\n"
+	}
+
+	return s.write(lines + "data: " + string(data) + "\n\n")
+}
+
+// sendEvent sends e as an unnamed event, which EventSource's onmessage gets
+func (s *eventStream) sendEvent(e *events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	return s.send(e.ID, "", data)
+}
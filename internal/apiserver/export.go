@@ -0,0 +1,127 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store/sqlstore"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errExportUsage = errors.New("usage: export [-user <public id>] [-o file]")
+
+	errExportUnavailable = "export unavailable"
+)
+
+// exportFunc writes a logical export of the given users, or of the whole
+// database when userIDs is nil; see sqlstore.Store.Export
+type exportFunc func(ctx context.Context, w io.Writer, userIDs []int, progress sqlstore.ExportProgress) error
+
+// handleExport lets suppliers download all of their data
+func (s *server) handleExport(c *gin.Context) {
+	s.streamExport(c, c.Value("ctxKeyUser").(*model.User))
+}
+
+// handleAdminUsersExport downloads the data of any user
+func (s *server) handleAdminUsersExport(c *gin.Context) {
+	u, ok := s.userParam(c)
+	if !ok {
+		return
+	}
+
+	s.streamExport(c, u)
+}
+
+func (s *server) streamExport(c *gin.Context, u *model.User) {
+	if s.export == nil {
+		respondWithError(c, http.StatusServiceUnavailable, errExportUnavailable)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	attachment(c, fmt.Sprintf("export-%s.jsonl", u.PublicID))
+	c.Status(http.StatusOK)
+
+	// Once rows were streamed the status can't change; a truncated file
+	// has no trailing newline on its last line
+	if err := s.export(c.Request.Context(), c.Writer, []int{u.ID}, nil); err != nil {
+		s.requestLogger(c).Errorf("export of user %s failed: %v", u.PublicID, err)
+	}
+}
+
+// Export runs the export subcommand, writing the whole database or one
+// user's data to a file or stdout and reporting progress to w
+func Export(config *Config, args []string, w io.Writer) error {
+	var publicID, path string
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.StringVar(&publicID, "user", "", "export only this user's data")
+	flags.StringVar(&path, "o", "", "write to this file instead of stdout")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return errExportUsage
+	}
+
+	db, err := newDB(config.DatabaseDriver, config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := sqlstore.CheckSchema(db); err != nil {
+		return err
+	}
+
+	s := sqlstore.New(db)
+	if len(config.EncryptionKeys) > 0 {
+		sealer, err := newSealer(config)
+		if err != nil {
+			return err
+		}
+
+		s.EncryptDocuments(sealer)
+	}
+
+	ctx := context.Background()
+
+	var userIDs []int
+	if publicID != "" {
+		u, err := s.User().FindByPublicID(ctx, publicID)
+		if err != nil {
+			return fmt.Errorf("user %s: %v", publicID, err)
+		}
+
+		userIDs = []int{u.ID}
+	}
+
+	out := io.Writer(os.Stdout)
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		out = f
+	}
+
+	if err := s.Export(ctx, out, userIDs, func(table string, rows int) {
+		fmt.Fprintf(w, "%s: %d rows\n", table, rows)
+	}); err != nil {
+		return err
+	}
+
+	if f, ok := out.(*os.File); ok && f != os.Stdout {
+		return f.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package apiserver
+
+import (
+	"net/http"
+	"winding-tree-server/internal/features"
+	"winding-tree-server/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// featureSubject is who a rollout is decided for: the user once the
+// request is authenticated, the client address before
+func featureSubject(c *gin.Context) string {
+	if u, ok := c.Value("ctxKeyUser").(*model.User); ok {
+		return u.PublicID
+	}
+
+	return "ip:" + clientIP(c)
+}
+
+// featureEnabled reports whether a feature being rolled out is on for the
+// request, for handlers to pick their behaviour
+func (s *server) featureEnabled(c *gin.Context, name string) bool {
+	return s.features.Enabled(name, featureSubject(c))
+}
+
+// handleFeaturesGet lists the features that are on for the current user,
+// for dashboards to show them
+func (s *server) handleFeaturesGet(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"features": s.features.EnabledFor(featureSubject(c)),
+	})
+}
+
+// handleAdminFeaturesList ...
+func (s *server) handleAdminFeaturesList(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"features": s.features.All(),
+	})
+}
+
+// handleAdminFeatureSet replaces the rollout of a feature until the config
+// is reloaded
+func (s *server) handleAdminFeatureSet(c *gin.Context) {
+	flag := features.Flag{}
+	if err := c.ShouldBindJSON(&flag); err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	if err := s.features.Set(c.Param("name"), flag); err != nil {
+		respondWithError(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	s.requestLogger(c).Infof("feature %s set to %+v", c.Param("name"), flag)
+
+	c.JSON(http.StatusOK, flag)
+}
+
+// handleAdminFeatureDelete turns a feature off for everyone until the
+// config is reloaded
+func (s *server) handleAdminFeatureDelete(c *gin.Context) {
+	s.features.Delete(c.Param("name"))
+	s.requestLogger(c).Infof("feature %s deleted", c.Param("name"))
+
+	c.Status(http.StatusNoContent)
+}
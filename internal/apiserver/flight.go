@@ -0,0 +1,253 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFaresPerUpsert ...
+const maxFaresPerUpsert = 10000
+
+var errTooManyFares = fmt.Errorf("at most %d fares can be upserted at once", maxFaresPerUpsert)
+
+// handleFlightsCreate adds a flight with its segments and fares for the current supplier
+func (s *server) handleFlightsCreate(c *gin.Context) {
+	u := c.Value("ctxKeyUser").(*model.User)
+
+	f := &model.Flight{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	f.UserID = u.ID
+	if err := f.Validate(); err != nil {
+		respondWithError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	if err := s.tenantStore(c).Flight().Create(c.Request.Context(), f); err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	s.metrics.flights.Inc()
+	c.JSON(http.StatusCreated, f)
+}
+
+// handleFlightsList returns the current supplier's flights
+func (s *server) handleFlightsList(c *gin.Context) {
+	opts, err := listOptions(c)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	flights, total, err := s.tenantStore(c).Flight().List(c.Request.Context(), opts)
+	if err == store.ErrInvalidListOptions {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flights": flights,
+		"total":   total,
+	})
+}
+
+// handleFaresCreate adds a fare to one of the current supplier's flights
+func (s *server) handleFaresCreate(c *gin.Context) {
+	flightID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	tenant := s.tenantStore(c)
+	f, err := tenant.Flight().Find(c.Request.Context(), flightID)
+	if err == store.ErrRecordNotFound {
+		respondWithError(c, http.StatusNotFound, errNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	fare := &model.Fare{}
+	if err := c.ShouldBindJSON(fare); err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	fare.FlightID = f.ID
+	if err := fare.Validate(); err != nil {
+		respondWithError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	if err := tenant.Fare().Create(c.Request.Context(), fare); err != nil {
+		if err == store.ErrRecordExists {
+			respondWithError(c, http.StatusConflict, err.Error())
+			return
+		}
+
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, fare)
+}
+
+// handleFaresUpsert creates or updates fares across the current supplier's
+// flights in one request, for inventory imports and channel syncs
+func (s *server) handleFaresUpsert(c *gin.Context) {
+	fares := []*model.Fare{}
+	if err := c.ShouldBindJSON(&fares); err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	if len(fares) > maxFaresPerUpsert {
+		respondWithError(c, http.StatusUnprocessableEntity, errTooManyFares.Error())
+		return
+	}
+
+	tenant := s.tenantStore(c)
+	owned := map[int]bool{}
+	for i, fare := range fares {
+		if err := fare.Validate(); err != nil {
+			respondWithError(c, http.StatusUnprocessableEntity, gin.H{strconv.Itoa(i): err})
+			return
+		}
+
+		if owned[fare.FlightID] {
+			continue
+		}
+
+		_, err := tenant.Flight().Find(c.Request.Context(), fare.FlightID)
+		if err == store.ErrRecordNotFound {
+			respondWithError(c, http.StatusUnprocessableEntity, gin.H{strconv.Itoa(i): gin.H{"flight_id": errNotFound}})
+			return
+		}
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+			return
+		}
+
+		owned[fare.FlightID] = true
+	}
+
+	if err := tenant.Fare().Upsert(c.Request.Context(), fares); err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"fares": fares,
+	})
+}
+
+// handleFlightsSearch ...
+func (s *server) handleFlightsSearch(c *gin.Context) {
+	search := &model.FlightSearch{}
+	if err := c.ShouldBindQuery(search); err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	if search.Passengers == 0 {
+		search.Passengers = 1
+	}
+
+	if err := search.Validate(); err != nil {
+		respondWithError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	offers, err := s.store.Fare().Search(c.Request.Context(), search)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	ids := make([]int, len(offers))
+	for i, o := range offers {
+		ids[i] = o.Flight.UserID
+	}
+
+	publicIDs, err := s.publicIDs(c.Request.Context(), ids)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	results := make([]*publicFlightOffer, len(offers))
+	for i, o := range offers {
+		results[i] = newPublicFlightOffer(o, publicIDs[o.Flight.UserID])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"offers": results,
+	})
+}
+
+// publicFlightOffer is a flight offer as anyone searching sees it, without
+// the sequential ids of the flight, its segments, its fare and its supplier,
+// which is known by its public id
+type publicFlightOffer struct {
+	Flight struct {
+		Segments []*publicFlightSegment `json:"segments"`
+	} `json:"flight"`
+	Fare struct {
+		Cabin          string `json:"cabin"`
+		Amount         int64  `json:"amount"`
+		Currency       string `json:"currency"`
+		SeatsAvailable int    `json:"seats_available"`
+	} `json:"fare"`
+	SupplierID string `json:"supplier_id"`
+}
+
+// publicFlightSegment ...
+type publicFlightSegment struct {
+	Position    int       `json:"position"`
+	Carrier     string    `json:"carrier"`
+	Number      string    `json:"number"`
+	Origin      string    `json:"origin"`
+	Destination string    `json:"destination"`
+	DepartureAt time.Time `json:"departure_at"`
+	ArrivalAt   time.Time `json:"arrival_at"`
+}
+
+// newPublicFlightOffer ...
+func newPublicFlightOffer(o *model.FlightOffer, supplierID string) *publicFlightOffer {
+	p := &publicFlightOffer{SupplierID: supplierID}
+	p.Flight.Segments = make([]*publicFlightSegment, len(o.Flight.Segments))
+	for i, seg := range o.Flight.Segments {
+		p.Flight.Segments[i] = &publicFlightSegment{
+			Position:    seg.Position,
+			Carrier:     seg.Carrier,
+			Number:      seg.Number,
+			Origin:      seg.Origin,
+			Destination: seg.Destination,
+			DepartureAt: seg.DepartureAt,
+			ArrivalAt:   seg.ArrivalAt,
+		}
+	}
+	p.Fare.Cabin = o.Fare.Cabin
+	p.Fare.Amount = o.Fare.Amount
+	p.Fare.Currency = o.Fare.Currency
+	p.Fare.SeatsAvailable = o.Fare.SeatsAvailable
+
+	return p
+}
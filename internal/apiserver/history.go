@@ -0,0 +1,51 @@
+package apiserver
+
+import (
+	"net/http"
+	"strconv"
+	"winding-tree-server/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// historyEntities maps the :entity path segment to the entities with change history
+var historyEntities = map[string]string{
+	"users":      model.EntityUser,
+	"fares":      model.EntityFare,
+	"onboarding": model.EntityOnboarding,
+}
+
+// handleAdminHistoryGet returns an entity's change timeline, oldest first
+func (s *server) handleAdminHistoryGet(c *gin.Context) {
+	entity, ok := historyEntities[c.Param("entity")]
+	if !ok {
+		respondWithError(c, http.StatusNotFound, errNotFound)
+		return
+	}
+
+	// Users and onboardings are addressed by the user's public id
+	var id int
+	if entity == model.EntityFare {
+		var err error
+		if id, err = strconv.Atoi(c.Param("id")); err != nil {
+			respondWithError(c, http.StatusBadRequest, errBadRequest)
+			return
+		}
+	} else {
+		u, ok := s.userParam(c)
+		if !ok {
+			return
+		}
+		id = u.ID
+	}
+
+	changes, err := s.store.Change().FindByEntity(c.Request.Context(), entity, id)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changes": changes,
+	})
+}
@@ -0,0 +1,30 @@
+package apiserver
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// configureHTTP2 enables HTTP/2 on srv over TLS, or with h2c over plain HTTP
+// for load balancers that speak HTTP/2 to their backends
+func configureHTTP2(srv *http.Server, config *Config) error {
+	if !config.HTTP2 {
+		// A non-nil map keeps net/http from enabling HTTP/2 by itself
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		return nil
+	}
+
+	h2 := &http2.Server{IdleTimeout: srv.IdleTimeout}
+	if config.PlainHTTP {
+		if config.H2C {
+			srv.Handler = h2c.NewHandler(srv.Handler, h2)
+		}
+
+		return nil
+	}
+
+	return http2.ConfigureServer(srv, h2)
+}
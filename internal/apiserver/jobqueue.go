@@ -0,0 +1,143 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"winding-tree-server/internal/jobqueue"
+	"winding-tree-server/internal/mailer"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Job kinds
+const (
+	jobSendMail = "mail.send"
+
+	errJobNotRetryable = "only pending and dead jobs can be retried"
+)
+
+// sendMail is the handler of jobSendMail jobs, whose payload is a message
+func sendMail(m mailer.Mailer) jobqueue.Handler {
+	return func(ctx context.Context, j *model.Job) error {
+		msg := &mailer.Message{}
+		if err := json.Unmarshal(j.Payload, msg); err != nil {
+			return err
+		}
+
+		return m.Send(msg)
+	}
+}
+
+// handleAdminJobsList lists jobs, newest first, filtered by ?kind= and ?state=
+func (s *server) handleAdminJobsList(c *gin.Context) {
+	opts, err := listOptions(c, "kind", "state")
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if opts.Sort == "" {
+		opts.Sort = "-id"
+	}
+
+	jobs, total, err := s.store.Job().List(c.Request.Context(), opts)
+	if err == store.ErrInvalidListOptions {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":  jobs,
+		"total": total,
+	})
+}
+
+// handleAdminJobsGet ...
+func (s *server) handleAdminJobsGet(c *gin.Context) {
+	j, ok := s.jobParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, j)
+}
+
+// handleAdminJobsRetry runs a pending or dead job again, with all its
+// attempts, now or at the run_at given in the body
+func (s *server) handleAdminJobsRetry(c *gin.Context) {
+	var req struct {
+		RunAt *time.Time `json:"run_at"`
+	}
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondWithError(c, http.StatusBadRequest, errBadRequest)
+			return
+		}
+	}
+
+	j, ok := s.jobParam(c)
+	if !ok {
+		return
+	}
+
+	if !j.Retryable() {
+		respondWithError(c, http.StatusConflict, errJobNotRetryable)
+		return
+	}
+
+	runAt := time.Now()
+	if req.RunAt != nil {
+		runAt = *req.RunAt
+	}
+
+	err := s.store.Job().Reschedule(c.Request.Context(), j.ID, runAt)
+	if err == store.ErrConflict {
+		respondWithError(c, http.StatusConflict, errJobNotRetryable)
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	s.requestLogger(c).WithField("job_id", j.ID).Warnf("%s job rescheduled for %s", j.Kind, runAt.Format(time.RFC3339))
+
+	j, ok = s.jobParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, j)
+}
+
+// jobParam resolves the :id path parameter, a job id. When it returns false
+// it has already responded.
+func (s *server) jobParam(c *gin.Context) (*model.Job, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return nil, false
+	}
+
+	j, err := s.store.Job().Find(c.Request.Context(), id)
+	if err == store.ErrRecordNotFound {
+		respondWithError(c, http.StatusNotFound, errNotFound)
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return nil, false
+	}
+
+	return j, true
+}
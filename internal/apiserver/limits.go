@@ -0,0 +1,65 @@
+package apiserver
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMaxBodySize = 1 << 20
+	// defaultMaxUploadSize leaves room for the multipart encoding of a document
+	defaultMaxUploadSize = maxDocumentSize + 1<<20
+
+	errBodyTooLarge = "request body too large"
+)
+
+// uploadRoutes accept bodies up to the upload limit
+var uploadRoutes = map[string]bool{
+	"POST /private/onboarding/documents": true,
+}
+
+// limitBody rejects requests with bodies over the limit of their route
+// before handlers read them. A zero limit disables the check.
+func (s *server) limitBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := s.maxBodySize
+		if uploadRoutes[c.Request.Method+" "+s.routePattern(c)] {
+			limit = s.maxUploadSize
+		}
+
+		if limit <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		// The server stops reading at the declared length, so only bodies
+		// of unknown length, which are chunked, need to be read to be checked
+		tooLarge := c.Request.ContentLength > limit
+		if c.Request.ContentLength < 0 {
+			body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, limit+1))
+			if err != nil {
+				respondWithError(c, http.StatusBadRequest, errBadRequest)
+				return
+			}
+
+			tooLarge = int64(len(body)) > limit
+			c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		if tooLarge {
+			// The rest of the body isn't worth reading to keep the connection
+			c.Header("Connection", "close")
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":     errBodyTooLarge,
+				"max_bytes": limit,
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package apiserver
+
+import (
+	"strconv"
+	"winding-tree-server/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listOptions reads ?limit=, ?offset= and ?sort= from the query, along with
+// exact-match filters for each of the given query parameters that is present.
+// Values are checked by the repository, which returns store.ErrInvalidListOptions.
+func listOptions(c *gin.Context, filters ...string) (*store.ListOptions, error) {
+	opts := &store.ListOptions{
+		Limit:   store.DefaultLimit,
+		Sort:    c.Query("sort"),
+		Filters: map[string]string{},
+	}
+
+	if v, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, store.ErrInvalidListOptions
+		}
+
+		opts.Limit = limit
+	}
+
+	if v, ok := c.GetQuery("offset"); ok {
+		offset, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, store.ErrInvalidListOptions
+		}
+
+		opts.Offset = offset
+	}
+
+	for _, f := range filters {
+		if v, ok := c.GetQuery(f); ok {
+			opts.Filters[f] = v
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,58 @@
+package apiserver
+
+import (
+	"io"
+	"os"
+	"winding-tree-server/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// newLogger is the logger shared by the server and its background jobs. The
+// returned file, if any, must be closed once nothing logs anymore.
+func newLogger(config *Config) (*logrus.Logger, io.Closer, error) {
+	logger := logrus.New()
+	logger.SetFormatter(logFormatter(config.LogFormat))
+	if level, err := logrus.ParseLevel(config.LogLevel); err == nil {
+		logger.SetLevel(level)
+	}
+
+	switch config.LogOutput {
+	case "", "stderr":
+		return logger, nil, nil
+	case "stdout":
+		logger.SetOutput(os.Stdout)
+		return logger, nil, nil
+	}
+
+	f, err := os.OpenFile(config.LogOutput, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.SetOutput(f)
+
+	return logger, f, nil
+}
+
+// logFormatter ...
+func logFormatter(format string) logrus.Formatter {
+	if format == "json" {
+		return &logrus.JSONFormatter{}
+	}
+
+	return &logrus.TextFormatter{}
+}
+
+// requestLogger logs with the request ID and, once the request is
+// authenticated, the user's public ID
+func (s *server) requestLogger(c *gin.Context) *logrus.Entry {
+	fields := logrus.Fields{
+		"request_id": c.Value("ctxKeyRequestID"),
+	}
+	if u, ok := c.Value("ctxKeyUser").(*model.User); ok {
+		fields["user_id"] = u.PublicID
+	}
+
+	return s.logger.WithFields(fields)
+}
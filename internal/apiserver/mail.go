@@ -0,0 +1,58 @@
+package apiserver
+
+import (
+	"context"
+	"winding-tree-server/internal/mailer"
+)
+
+// Mails sent to users
+const (
+	mailOnboardingApproved = "onboarding.approved"
+	mailOnboardingRejected = "onboarding.rejected"
+)
+
+// mailSources are the subject, text and html bodies of each mail
+var mailSources = map[string][3]string{
+	mailOnboardingApproved: {
+		"Your onboarding was approved",
+		"Your onboarding was approved. Your organization and flights can now be listed.\n",
+		"<p>Your onboarding was approved. Your organization and flights can now be listed.</p>",
+	},
+	mailOnboardingRejected: {
+		"Your onboarding was rejected",
+		"Your onboarding was rejected: {{.Reason}}\n\nYou can update it and submit it again.\n",
+		"<p>Your onboarding was rejected: {{.Reason}}</p><p>You can update it and submit it again.</p>",
+	},
+}
+
+// newMailTemplates parses mailSources
+func newMailTemplates() (map[string]*mailer.Template, error) {
+	templates := make(map[string]*mailer.Template, len(mailSources))
+	for name, src := range mailSources {
+		t, err := mailer.NewTemplate(name, src[0], src[1], src[2])
+		if err != nil {
+			return nil, err
+		}
+
+		templates[name] = t
+	}
+
+	return templates, nil
+}
+
+// sendMailTo renders the named mail with data and queues it for delivery as
+// a jobSendMail job, which is retried if delivery fails. It does nothing when
+// mail is disabled.
+func (s *server) sendMailTo(ctx context.Context, to, name string, data interface{}) error {
+	if s.mailTemplates == nil {
+		return nil
+	}
+
+	msg, err := s.mailTemplates[name].Render(to, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.jobQueue.Enqueue(ctx, jobSendMail, msg)
+	return err
+}
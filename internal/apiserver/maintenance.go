@@ -0,0 +1,123 @@
+package apiserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	defaultMaintenanceRetryAfter = 5 * time.Minute
+
+	errMaintenance = "service under maintenance"
+)
+
+// maintenanceRoutes stay available during maintenance, for load balancers,
+// monitoring and admins to log in. Admin and profiling routes also do.
+var maintenanceRoutes = map[string]bool{
+	"GET /healthz":   true,
+	"GET /readyz":    true,
+	"GET /metrics":   true,
+	"POST /sessions": true,
+}
+
+// maintenanceMode is replaced as a whole when it changes
+type maintenanceMode struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+	// RetryAfter is the time, in seconds, clients are told to wait
+	RetryAfter int        `json:"retry_after,omitempty"`
+	Since      *time.Time `json:"since,omitempty"`
+}
+
+// setMaintenance switches maintenance mode on or off
+func (s *server) setMaintenance(enabled bool, message string, retryAfter time.Duration) {
+	mode := &maintenanceMode{
+		Enabled:    enabled,
+		Message:    message,
+		RetryAfter: int(retryAfter / time.Second),
+	}
+	if enabled {
+		now := time.Now()
+		if current := s.maintenanceMode(); current.Enabled {
+			now = *current.Since
+		}
+		mode.Since = &now
+	}
+
+	s.maintenance.Store(mode)
+}
+
+// maintenanceMode ...
+func (s *server) maintenanceMode() *maintenanceMode {
+	mode, ok := s.maintenance.Load().(*maintenanceMode)
+	if !ok {
+		return &maintenanceMode{}
+	}
+
+	return mode
+}
+
+// checkMaintenance answers 503 during maintenance, except on the routes
+// that stay available
+func (s *server) checkMaintenance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mode := s.maintenanceMode()
+		if !mode.Enabled {
+			c.Next()
+			return
+		}
+
+		route := s.routePattern(c)
+		if maintenanceRoutes[c.Request.Method+" "+route] || strings.HasPrefix(route, "/admin/") || strings.HasPrefix(route, "/debug/pprof/") {
+			c.Next()
+			return
+		}
+
+		body := gin.H{"error": errMaintenance}
+		if mode.Message != "" {
+			body["message"] = mode.Message
+		}
+		if mode.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(mode.RetryAfter))
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, body)
+	}
+}
+
+// handleAdminMaintenanceGet ...
+func (s *server) handleAdminMaintenanceGet(c *gin.Context) {
+	c.JSON(http.StatusOK, s.maintenanceMode())
+}
+
+// handleAdminMaintenanceSet switches maintenance mode until it is switched
+// again or the config is reloaded
+func (s *server) handleAdminMaintenanceSet(c *gin.Context) {
+	var req struct {
+		Enabled    bool   `json:"enabled"`
+		Message    string `json:"message"`
+		RetryAfter int    `json:"retry_after"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	if err := validation.ValidateStruct(&req,
+		validation.Field(&req.Message, validation.Length(0, 500)),
+		validation.Field(&req.RetryAfter, validation.Min(0)),
+	); err != nil {
+		respondWithError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	s.setMaintenance(req.Enabled, req.Message, time.Duration(req.RetryAfter)*time.Second)
+	s.requestLogger(c).Warnf("maintenance mode set to %t", req.Enabled)
+
+	c.JSON(http.StatusOK, s.maintenanceMode())
+}
@@ -0,0 +1,158 @@
+package apiserver
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests for paths without a route, which would
+// otherwise create a series per path
+const unmatchedRoute = "unmatched"
+
+// metrics are created with the server and registered by Start, as a
+// registry accepts each metric only once
+type metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	signups  prometheus.Counter
+	logins   *prometheus.CounterVec
+	flights  prometheus.Counter
+	submits  prometheus.Counter
+}
+
+// newMetrics ...
+func newMetrics() *metrics {
+	return &metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route and status code.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		signups: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "signups_total",
+			Help: "Users who signed up.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "logins_total",
+			Help: "Login attempts by result, success or failure.",
+		}, []string{"result"}),
+		flights: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "flights_created_total",
+			Help: "Flights created by suppliers.",
+		}),
+		submits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "onboarding_submissions_total",
+			Help: "Onboardings submitted for review.",
+		}),
+	}
+}
+
+// register ...
+func (m *metrics) register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{m.requests, m.duration, m.signups, m.logins, m.flights, m.submits} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// registerPoolMetrics exports the connection pool stats of db, labeled with name
+func registerPoolMetrics(reg prometheus.Registerer, name string, db *sqlx.DB) error {
+	labels := prometheus.Labels{"db": name}
+	gauge := func(metric, help string, value func() float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: metric, Help: help, ConstLabels: labels}, value)
+	}
+	counter := func(metric, help string, value func() float64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: metric, Help: help, ConstLabels: labels}, value)
+	}
+
+	for _, c := range []prometheus.Collector{
+		gauge("db_open_connections", "Open connections, in use or idle.", func() float64 {
+			return float64(db.Stats().OpenConnections)
+		}),
+		gauge("db_in_use_connections", "Connections in use.", func() float64 {
+			return float64(db.Stats().InUse)
+		}),
+		gauge("db_idle_connections", "Idle connections.", func() float64 {
+			return float64(db.Stats().Idle)
+		}),
+		counter("db_wait_count_total", "Times a connection had to be waited for.", func() float64 {
+			return float64(db.Stats().WaitCount)
+		}),
+		counter("db_wait_duration_seconds_total", "Time spent waiting for connections.", func() float64 {
+			return db.Stats().WaitDuration.Seconds()
+		}),
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// instrument counts requests and observes their latency by route
+func (s *server) instrument() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := s.routePattern(c)
+		s.metrics.requests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		s.metrics.duration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// routePattern turns the request path back into the pattern it matched,
+// as gin doesn't keep it
+func (s *server) routePattern(c *gin.Context) string {
+	path := c.Request.URL.Path
+	if len(c.Params) > 0 {
+		segments := strings.Split(path, "/")
+		for _, p := range c.Params {
+			for i, segment := range segments {
+				if segment == p.Value {
+					segments[i] = ":" + p.Key
+					break
+				}
+			}
+		}
+		path = strings.Join(segments, "/")
+	}
+
+	if !s.routes[c.Request.Method+" "+path] {
+		return unmatchedRoute
+	}
+
+	return path
+}
+
+// handleMetrics serves Prometheus metrics, to holders of the metrics token
+// when one is configured
+func (s *server) handleMetrics(c *gin.Context) {
+	if s.metricsToken != "" {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.metricsToken)) != 1 {
+			c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", "metrics"))
+			respondWithError(c, http.StatusUnauthorized, errNotAuthenticated)
+			return
+		}
+	}
+
+	promhttp.Handler().ServeHTTP(c.Writer, c.Request)
+}
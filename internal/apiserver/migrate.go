@@ -0,0 +1,61 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"winding-tree-server/internal/store/sqlstore"
+)
+
+var (
+	errMigrateUsage = errors.New("usage: migrate up | down [N] | status")
+)
+
+// Migrate runs the migrate subcommand: up, down [N] (default 1) or status
+func Migrate(config *Config, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	db, err := newDB(config.DatabaseDriver, config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "up":
+		return sqlstore.MigrateUp(db)
+
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return errMigrateUsage
+			}
+		}
+
+		return sqlstore.MigrateDown(db, n)
+
+	case "status":
+		version, dirty, migrations, err := sqlstore.MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "version: %d, dirty: %t\n", version, dirty)
+		for _, m := range migrations {
+			state := "pending"
+			if m.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, state, m.Name)
+		}
+
+		return nil
+
+	default:
+		return errMigrateUsage
+	}
+}
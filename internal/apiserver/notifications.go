@@ -0,0 +1,149 @@
+package apiserver
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"winding-tree-server/internal/events"
+	"winding-tree-server/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// notificationsPingInterval keeps idle connections open through proxies
+	notificationsPingInterval = 30 * time.Second
+	// eventsWriteTimeout bounds each message pushed to a client, WebSocket
+	// or event stream, so that one that stopped reading is dropped
+	eventsWriteTimeout = 10 * time.Second
+
+	errEventsUnavailable = "events unavailable"
+	errWebSocketRequired = "websocket upgrade required"
+)
+
+// handleNotifications upgrades to a WebSocket pushing the events of the
+// user, such as onboarding reviews and created flights, as they happen;
+// admins get those of all users. ?topics= limits them to a comma-separated
+// list of topics, such as onboarding.* or flight.created. Events aren't
+// replayed: clients reconnecting after a drop reload what they show.
+func (s *server) handleNotifications(c *gin.Context) {
+	if s.events == nil {
+		respondWithError(c, http.StatusServiceUnavailable, errEventsUnavailable)
+		return
+	}
+
+	if c.Request.ProtoMajor != 1 || !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		c.Header("Upgrade", "websocket")
+		respondWithError(c, http.StatusUpgradeRequired, errWebSocketRequired)
+		return
+	}
+
+	if !s.allowsWebSocketOrigin(c.Request) {
+		respondWithError(c, http.StatusForbidden, errForbidden)
+		return
+	}
+
+	u := c.Value("ctxKeyUser").(*model.User)
+	sub := s.events.Subscribe(eventsFilter(c, u))
+	defer sub.Close()
+
+	logger := s.requestLogger(c)
+	c.Status(http.StatusSwitchingProtocols)
+
+	server := websocket.Server{
+		// The origin was checked above, before the connection was hijacked
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			pushNotifications(ws, sub, logger)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// eventsFilter selects the events of u, or of all users for admins, with
+// the topics of ?topics=
+func eventsFilter(c *gin.Context, u *model.User) events.Filter {
+	f := events.Filter{SupplierID: u.PublicID}
+	if u.IsAdmin {
+		f.SupplierID = ""
+	}
+
+	for _, topic := range strings.Split(c.Query("topics"), ",") {
+		if topic = strings.TrimSpace(topic); topic != "" {
+			f.Topics = append(f.Topics, topic)
+		}
+	}
+
+	return f
+}
+
+// pushNotifications writes the events of sub to ws as JSON messages until
+// either ends. Only this goroutine writes messages and pings.
+func pushNotifications(ws *websocket.Conn, sub *events.Subscription, logger *logrus.Entry) {
+	defer ws.Close()
+
+	// The server's read and write timeouts are meant for requests, not for
+	// connections that stay open
+	ws.SetDeadline(time.Time{})
+
+	// Clients send nothing but control frames; reading answers their pings
+	// and notices when they close
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, ws)
+		close(closed)
+	}()
+
+	ping := time.NewTicker(notificationsPingInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case <-closed:
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				// The server is stopping, or the client fell behind
+				return
+			}
+
+			ws.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+			err = websocket.JSON.Send(ws, e)
+		case <-ping.C:
+			ws.SetWriteDeadline(time.Now().Add(eventsWriteTimeout))
+			ws.PayloadType = websocket.PingFrame
+			_, err = ws.Write(nil)
+			ws.PayloadType = websocket.TextFrame
+		}
+
+		if err != nil {
+			logger.Debugf("notifications connection closed: %v", err)
+			return
+		}
+	}
+}
+
+// allowsWebSocketOrigin reports whether a WebSocket handshake may come from
+// the Origin of r. Browsers send the session cookie with handshakes from any
+// site, so only the API's own host and the CORS origins, but not "*", are
+// allowed. Clients other than browsers send no Origin.
+func (s *server) allowsWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return s.corsPolicy().origins.allows(origin)
+}
@@ -0,0 +1,399 @@
+package apiserver
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxDocumentSize = 10 << 20
+
+// adminOnboarding adds the public id admin routes address the supplier by,
+// and that of the reviewer
+type adminOnboarding struct {
+	*model.Onboarding
+	SupplierID string `json:"supplier_id"`
+	ReviewerID string `json:"reviewer_id,omitempty"`
+}
+
+// adminOnboardings ...
+func (s *server) adminOnboardings(ctx context.Context, onboardings ...*model.Onboarding) ([]*adminOnboarding, error) {
+	ids := []int{}
+	for _, o := range onboardings {
+		ids = append(ids, o.UserID)
+		if o.ReviewerID != nil {
+			ids = append(ids, *o.ReviewerID)
+		}
+	}
+
+	publicIDs, err := s.publicIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]*adminOnboarding, len(onboardings))
+	for i, o := range onboardings {
+		views[i] = &adminOnboarding{Onboarding: o, SupplierID: publicIDs[o.UserID]}
+		if o.ReviewerID != nil {
+			views[i].ReviewerID = publicIDs[*o.ReviewerID]
+		}
+	}
+
+	return views, nil
+}
+
+// handleOnboardingGet returns the current user's onboarding state and documents
+func (s *server) handleOnboardingGet(c *gin.Context) {
+	u := c.Value("ctxKeyUser").(*model.User)
+
+	o, err := s.findOnboarding(c.Request.Context(), u.ID)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	documents, err := s.tenantStore(c).Onboarding().FindDocuments(c.Request.Context(), u.ID)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"onboarding": o,
+		"documents":  documents,
+	})
+}
+
+// handleOnboardingDocumentsCreate accepts a multipart upload with "kind" and "file" fields
+func (s *server) handleOnboardingDocumentsCreate(c *gin.Context) {
+	u := c.Value("ctxKeyUser").(*model.User)
+
+	o, err := s.findOnboarding(c.Request.Context(), u.ID)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	if !o.CanUpload() {
+		respondWithError(c, http.StatusConflict, model.ErrInvalidTransition.Error())
+		return
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	if fh.Size > maxDocumentSize {
+		respondWithError(c, http.StatusRequestEntityTooLarge, errDocumentTooLarge)
+		return
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+	defer f.Close()
+
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	d := &model.OnboardingDocument{
+		UserID:      u.ID,
+		Kind:        c.PostForm("kind"),
+		FileName:    fh.Filename,
+		ContentType: fh.Header.Get("Content-Type"),
+		Content:     content,
+	}
+	if err := d.Validate(); err != nil {
+		respondWithError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	if err := s.tenantStore(c).Onboarding().CreateDocument(c.Request.Context(), d); err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	if err := s.tenantStore(c).Onboarding().Save(c.Request.Context(), o); err != nil {
+		s.respondWithSaveError(c, o.UserID, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, d)
+}
+
+// handleOnboardingSubmit puts the current user into the review queue
+func (s *server) handleOnboardingSubmit(c *gin.Context) {
+	u := c.Value("ctxKeyUser").(*model.User)
+
+	o, err := s.findOnboarding(c.Request.Context(), u.ID)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	documents, err := s.tenantStore(c).Onboarding().FindDocuments(c.Request.Context(), u.ID)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	if err := o.Submit(len(documents)); err != nil {
+		respondWithError(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	if err := s.tenantStore(c).Onboarding().Save(c.Request.Context(), o); err != nil {
+		s.respondWithSaveError(c, o.UserID, err)
+		return
+	}
+
+	s.metrics.submits.Inc()
+	c.JSON(http.StatusOK, o)
+}
+
+// handleAdminOnboardingList returns the review queue, or onboardings in ?state=
+func (s *server) handleAdminOnboardingList(c *gin.Context) {
+	opts, err := listOptions(c, "state")
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, ok := opts.Filters["state"]; !ok {
+		opts.Filters["state"] = model.OnboardingSubmitted
+	}
+
+	if opts.Sort == "" {
+		opts.Sort = "submitted_at"
+	}
+
+	onboardings, total, err := s.store.Onboarding().List(c.Request.Context(), opts)
+	if err == store.ErrInvalidListOptions {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	queue, err := s.adminOnboardings(c.Request.Context(), onboardings...)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"onboardings": queue,
+		"total":       total,
+	})
+}
+
+// handleAdminOnboardingGet ...
+func (s *server) handleAdminOnboardingGet(c *gin.Context) {
+	u, ok := s.userParam(c)
+	if !ok {
+		return
+	}
+	userID := u.ID
+
+	o, err := s.store.Onboarding().Find(c.Request.Context(), userID)
+	if err == store.ErrRecordNotFound {
+		respondWithError(c, http.StatusNotFound, errNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	documents, err := s.store.Onboarding().FindDocuments(c.Request.Context(), userID)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	views, err := s.adminOnboardings(c.Request.Context(), o)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.Header("ETag", onboardingETag(o))
+	c.JSON(http.StatusOK, gin.H{
+		"onboarding": views[0],
+		"documents":  documents,
+	})
+}
+
+// handleAdminOnboardingDocumentGet downloads an uploaded document
+func (s *server) handleAdminOnboardingDocumentGet(c *gin.Context) {
+	u, ok := s.userParam(c)
+	if !ok {
+		return
+	}
+	userID := u.ID
+
+	documentID, err := strconv.Atoi(c.Param("document_id"))
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	d, err := s.store.Onboarding().FindDocument(c.Request.Context(), userID, documentID)
+	if err == store.ErrRecordNotFound {
+		respondWithError(c, http.StatusNotFound, errNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	attachment(c, d.FileName)
+	c.Data(http.StatusOK, d.ContentType, d.Content)
+}
+
+// handleAdminOnboardingReview approves or, with a {"reason": ...} body, rejects a submission.
+// An If-Match header carrying the ETag the reviewer looked at guards against
+// overwriting another reviewer's decision.
+func (s *server) handleAdminOnboardingReview(approve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin := c.Value("ctxKeyUser").(*model.User)
+
+		u, ok := s.userParam(c)
+		if !ok {
+			return
+		}
+		userID := u.ID
+
+		o, err := s.store.Onboarding().Find(c.Request.Context(), userID)
+		if err == store.ErrRecordNotFound {
+			respondWithError(c, http.StatusNotFound, errNotFound)
+			return
+		}
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+			return
+		}
+
+		etag := onboardingETag(o)
+		c.Header("ETag", etag)
+		if !matchesETag(c.GetHeader("If-Match"), etag) {
+			s.respondWithSaveError(c, o.UserID, store.ErrConflict)
+			return
+		}
+
+		req := &struct {
+			Reason string `json:"reason"`
+		}{}
+		mail := mailOnboardingApproved
+		if approve {
+			err = o.Approve(admin.ID)
+		} else {
+			if err := c.ShouldBindJSON(req); err != nil {
+				respondWithError(c, http.StatusBadRequest, errBadRequest)
+				return
+			}
+			err = o.Reject(admin.ID, req.Reason)
+			mail = mailOnboardingRejected
+		}
+
+		if err == model.ErrInvalidTransition {
+			respondWithError(c, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			respondWithError(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		if err := s.store.Onboarding().Save(c.Request.Context(), o); err != nil {
+			s.respondWithSaveError(c, o.UserID, err)
+			return
+		}
+
+		// Deleted suppliers aren't mailed, and the review stands even if the
+		// supplier can't be told about it
+		if u.DeletedAt == nil {
+			if err := s.sendMailTo(c.Request.Context(), u.Email, mail, req); err != nil {
+				s.requestLogger(c).WithError(err).Error("onboarding review mail not queued")
+			}
+		}
+
+		views, err := s.adminOnboardings(c.Request.Context(), o)
+		if err != nil {
+			respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+			return
+		}
+
+		c.Header("ETag", onboardingETag(o))
+		c.JSON(http.StatusOK, views[0])
+	}
+}
+
+// onboardingETag is the entity tag of an onboarding's version
+func onboardingETag(o *model.Onboarding) string {
+	return strconv.Quote(strconv.Itoa(o.Version))
+}
+
+// matchesETag reports whether an If-Match header lets a request change the
+// resource tagged etag: without the header, with "*", or with etag among
+// its comma-separated tags
+func matchesETag(ifMatch string, etag string) bool {
+	if strings.TrimSpace(ifMatch) == "" {
+		return true
+	}
+
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findOnboarding returns the user's onboarding, a new draft if they haven't started
+func (s *server) findOnboarding(ctx context.Context, userID int) (*model.Onboarding, error) {
+	o, err := s.store.Onboarding().Find(ctx, userID)
+	if err == store.ErrRecordNotFound {
+		return model.NewOnboarding(userID), nil
+	}
+
+	return o, err
+}
+
+// respondWithSaveError reports a failed Save, including the current onboarding
+// when it was changed concurrently so the client can reconcile
+func (s *server) respondWithSaveError(c *gin.Context, userID int, err error) {
+	if err != store.ErrConflict {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	current, err := s.store.Onboarding().Find(c.Request.Context(), userID)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.Header("ETag", onboardingETag(current))
+	c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+		"error":      store.ErrConflict.Error(),
+		"onboarding": current,
+	})
+}
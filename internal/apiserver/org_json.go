@@ -0,0 +1,79 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleOrgJSONCreate uploads a new version of the user's ORG.JSON
+func (s *server) handleOrgJSONCreate(c *gin.Context) {
+	u := c.Value("ctxKeyUser").(*model.User)
+
+	body, err := c.GetRawData()
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return
+	}
+
+	o := &model.OrgJSON{
+		UserID:   u.ID,
+		Document: body,
+	}
+	if err := o.Validate(); err != nil {
+		respondWithError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	if err := s.tenantStore(c).OrgJSON().Create(c.Request.Context(), o); err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"version": o.Version,
+		"hash":    o.Hash,
+		"uri":     fmt.Sprintf("/suppliers/%s/org.json", u.PublicID),
+	})
+}
+
+// handleOrgJSONGet serves the latest or a specific version of a supplier's ORG.JSON
+func (s *server) handleOrgJSONGet(c *gin.Context) {
+	u, ok := s.supplierParam(c)
+	if !ok {
+		return
+	}
+	userID := u.ID
+
+	var o *model.OrgJSON
+	var err error
+	if v := c.Param("version"); v != "" {
+		version, convErr := strconv.Atoi(v)
+		if convErr != nil {
+			respondWithError(c, http.StatusBadRequest, errBadRequest)
+			return
+		}
+		o, err = s.store.OrgJSON().FindByVersion(c.Request.Context(), userID, version)
+	} else {
+		o, err = s.store.OrgJSON().FindLatest(c.Request.Context(), userID)
+	}
+
+	if err == store.ErrRecordNotFound {
+		respondWithError(c, http.StatusNotFound, errNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.Header("X-OrgJSON-Version", strconv.Itoa(o.Version))
+	c.Header("X-OrgJSON-Hash", o.Hash)
+	// Uploaded by suppliers and served publicly, so never sniffed as HTML
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, "application/json", o.Document)
+}
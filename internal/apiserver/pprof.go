@@ -0,0 +1,50 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setPprof enables or disables the profiling endpoints
+func (s *server) setPprof(enabled bool) {
+	var value int32
+	if enabled {
+		value = 1
+	}
+
+	atomic.StoreInt32(&s.pprof, value)
+}
+
+// requirePprof answers as if there were no route while profiling is disabled
+func (s *server) requirePprof() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if atomic.LoadInt32(&s.pprof) == 0 {
+			respondWithError(c, http.StatusNotFound, errNotFound)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// handlePprof serves the net/http/pprof handlers. CPU profiles and
+// execution traces can't run longer than the write timeout, so ask for
+// less than its default 30 seconds, as in /debug/pprof/profile?seconds=5.
+func (s *server) handlePprof(c *gin.Context) {
+	switch c.Param("profile") {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		// The index serves the named profiles too, such as heap and goroutine
+		pprof.Index(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,52 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+	"winding-tree-server/internal/retention"
+	"winding-tree-server/internal/store/sqlstore"
+)
+
+var (
+	errPurgeUsage = errors.New("usage: purge [-dry-run]")
+)
+
+// Purge runs the purge subcommand, applying the configured retention policy
+// once and reporting what was (or with -dry-run would be) purged
+func Purge(config *Config, args []string, w io.Writer) error {
+	dryRun := config.RetentionDryRun
+
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.BoolVar(&dryRun, "dry-run", dryRun, "only count what would be purged")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		return errPurgeUsage
+	}
+
+	db, err := newDB(config.DatabaseDriver, config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := sqlstore.CheckSchema(db); err != nil {
+		return err
+	}
+
+	report, err := retention.Purge(context.Background(), sqlstore.New(db), config.retentionPolicy(), time.Now(), dryRun)
+	if err != nil {
+		return err
+	}
+
+	if report.DryRun {
+		fmt.Fprint(w, "dry run, nothing was changed\n")
+	}
+	fmt.Fprintf(w, "outbox events: %d, changes: %d, anonymized users: %d\n", report.OutboxEvents, report.Changes, report.AnonymizedUsers)
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package apiserver
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"winding-tree-server/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rate limit buckets, counted apart
+const (
+	rateLimitAPI    = "api"
+	rateLimitSearch = "search"
+
+	errRateLimited = "too many requests"
+)
+
+// rateLimits are the requests allowed per window; zero disables a limit
+type rateLimits struct {
+	window time.Duration
+	// anonymous requests are counted per client IP
+	anonymous int
+	// authenticated requests are counted per user
+	authenticated int
+	// search is the limit of the search bucket, for anyone
+	search int
+}
+
+// setRateLimits replaces the limits, for the requests that follow
+func (s *server) setRateLimits(limits rateLimits) {
+	s.rateLimits.Store(&limits)
+}
+
+// currentRateLimits ...
+func (s *server) currentRateLimits() *rateLimits {
+	limits, ok := s.rateLimits.Load().(*rateLimits)
+	if !ok {
+		return &rateLimits{}
+	}
+
+	return limits
+}
+
+// rateLimit answers 429 to clients over the limit of bucket, which counts
+// per user once the request is authenticated and per client IP before.
+// Requests are let through if the limiter fails.
+func (s *server) rateLimit(bucket string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.rateLimiter == nil {
+			c.Next()
+			return
+		}
+
+		limits := s.currentRateLimits()
+		key, limit := "ip:"+clientIP(c), limits.anonymous
+		if u, ok := c.Value("ctxKeyUser").(*model.User); ok {
+			key, limit = "user:"+u.PublicID, limits.authenticated
+		}
+		if bucket == rateLimitSearch {
+			limit = limits.search
+		}
+
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		result, err := s.rateLimiter.Allow(c.Request.Context(), "ratelimit:"+bucket+":"+key, limit, limits.window)
+		if err != nil {
+			s.requestLogger(c).Warnf("rate limiter failed, request allowed: %v", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+			respondWithError(c, http.StatusTooManyRequests, errRateLimited)
+			return
+		}
+
+		c.Next()
+	}
+}
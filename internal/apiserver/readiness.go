@@ -0,0 +1,95 @@
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds each check so /readyz answers even when a dependency hangs
+const readinessTimeout = 2 * time.Second
+
+// readinessCheck reports whether a dependency can serve requests
+type readinessCheck func(context.Context) error
+
+// addReadinessCheck registers a dependency reported by /readyz
+func (s *server) addReadinessCheck(name string, check readinessCheck) {
+	if s.readinessChecks == nil {
+		s.readinessChecks = map[string]readinessCheck{}
+	}
+
+	s.readinessChecks[name] = check
+}
+
+// addOptionalCheck registers a dependency the server can do without for a
+// while, such as a cache; when it fails /readyz reports degraded but ready
+func (s *server) addOptionalCheck(name string, check readinessCheck) {
+	s.addReadinessCheck(name, check)
+
+	if s.optionalChecks == nil {
+		s.optionalChecks = map[string]bool{}
+	}
+
+	s.optionalChecks[name] = true
+}
+
+// handleHealthz answers as long as the process can serve requests at all, so
+// orchestrators only restart instances that are stuck
+func (s *server) handleHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// handleReadyz runs every readiness check and answers 503 if any fails, so
+// load balancers stop routing to an instance that can't reach its database
+func (s *server) handleReadyz(c *gin.Context) {
+	names := make([]string, 0, len(s.readinessChecks))
+	for name := range s.readinessChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	code := http.StatusOK
+	degraded := false
+	checks := gin.H{}
+	for _, name := range names {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		start := time.Now()
+		err := s.readinessChecks[name](ctx)
+		latency := time.Since(start)
+		cancel()
+
+		result := gin.H{
+			"status":     "ok",
+			"latency_ms": float64(latency) / float64(time.Millisecond),
+		}
+		switch {
+		case err == nil:
+		case s.optionalChecks[name]:
+			degraded = true
+			result["status"] = "degraded"
+			result["error"] = err.Error()
+		default:
+			code = http.StatusServiceUnavailable
+			result["status"] = "unavailable"
+			result["error"] = err.Error()
+		}
+
+		checks[name] = result
+	}
+
+	status := "ok"
+	switch {
+	case code != http.StatusOK:
+		status = "unavailable"
+	case degraded:
+		status = "degraded"
+	}
+
+	c.JSON(code, gin.H{
+		"status": status,
+		"checks": checks,
+	})
+}
@@ -0,0 +1,70 @@
+package apiserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// recoverPanics answers 500 with the usual error body when a handler
+// panics, and logs the panic with its stack and reports it to Sentry.
+// Panics writing to connections the client closed are only logged, as
+// there is no one left to answer.
+func (s *server) recoverPanics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+
+			// net/http aborts the response silently on this one
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			if isConnectionClosed(err) {
+				s.requestLogger(c).Warnf("connection closed by the client: %v", err)
+				c.Abort()
+				return
+			}
+
+			s.requestLogger(c).WithFields(logrus.Fields{
+				"panic": fmt.Sprint(err),
+				"stack": string(debug.Stack()),
+			}).Error("panic recovered")
+			reportPanic(c, err)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		}()
+
+		c.Next()
+	}
+}
+
+// isConnectionClosed ...
+func isConnectionClosed(err interface{}) bool {
+	ne, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+
+	se, ok := ne.Err.(*os.SyscallError)
+	if !ok {
+		return false
+	}
+
+	message := strings.ToLower(se.Error())
+
+	return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+}
@@ -0,0 +1,107 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"winding-tree-server/internal/features"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errReloadUnavailable = errors.New("config reload unavailable")
+)
+
+// ConfigLoader reads the config again from where it was first read
+type ConfigLoader func() (*Config, error)
+
+// applyConfig applies the settings that can change while the server runs.
+// The config must be valid; if it still can't be applied, nothing is.
+func (s *server) applyConfig(config *Config) error {
+	if err := s.setCORS(config.CORSAllowedOrigins, config.CORSAllowedMethods, config.CORSAllowedHeaders, config.CORSAllowCredentials, config.CORSMaxAge.Duration); err != nil {
+		return errors.New("cors: " + err.Error())
+	}
+
+	if level, err := logrus.ParseLevel(config.LogLevel); err == nil {
+		s.logger.SetLevel(level)
+	}
+	s.logger.SetFormatter(logFormatter(config.LogFormat))
+	s.setPprof(config.Pprof)
+	s.setMaintenance(config.Maintenance, config.MaintenanceMessage, config.MaintenanceRetryAfter.Duration)
+	s.setRateLimits(rateLimits{
+		window:        config.RateLimitWindow.Duration,
+		anonymous:     config.RateLimitAnonymous,
+		authenticated: config.RateLimitAuthenticated,
+		search:        config.RateLimitSearch,
+	})
+	if flags, err := features.Parse(config.FeatureFlags); err == nil {
+		s.features.Replace(flags)
+	}
+
+	return nil
+}
+
+// reloadConfig loads the config again and applies what can change at
+// runtime; other changes need a restart
+func (s *server) reloadConfig() error {
+	if s.loadConfig == nil {
+		return errReloadUnavailable
+	}
+
+	config, err := s.loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// The whole config is checked, not just what is applied, so that a
+	// config that passes a reload also passes the next start
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	// The limiter itself only changes on restart
+	if s.rateLimiter != nil {
+		if err := isPositiveDuration(config.RateLimitWindow); err != nil {
+			return errors.New("rate_limit_window: " + err.Error())
+		}
+	}
+
+	if err := s.applyConfig(config); err != nil {
+		return err
+	}
+	s.logger.Infof("config reloaded, log level %s", config.LogLevel)
+
+	return nil
+}
+
+// reloadOnSignal reloads the config whenever a signal arrives, until ctx is done
+func (s *server) reloadOnSignal(ctx context.Context, signals <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := s.reloadConfig(); err != nil {
+				s.logger.Errorf("config reload failed, keeping the current settings: %v", err)
+			}
+		}
+	}
+}
+
+// handleAdminConfigReload ...
+func (s *server) handleAdminConfigReload(c *gin.Context) {
+	if err := s.reloadConfig(); err != nil {
+		if err == errReloadUnavailable {
+			respondWithError(c, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		respondWithError(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
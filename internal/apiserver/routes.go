@@ -0,0 +1,33 @@
+package apiserver
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+)
+
+// Routes lists the routes of the server
+func Routes(w io.Writer) error {
+	// Keeps gin from printing every route as it is registered
+	gin.SetMode(gin.ReleaseMode)
+
+	s := NewServer(nil, cookie.NewStore([]byte("routes")))
+	routes := s.router.Routes()
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, r := range routes {
+		fmt.Fprintf(tw, "%s\t%s\n", r.Method, r.Path)
+	}
+
+	return tw.Flush()
+}
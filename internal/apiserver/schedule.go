@@ -0,0 +1,107 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Scheduled tasks
+const (
+	taskRetentionPurge = "retention.purge"
+)
+
+// scheduledTasks are the tasks schedules can be set for
+var scheduledTasks = map[string]bool{
+	taskRetentionPurge: true,
+}
+
+// parseSchedules parses "task=spec" schedules, by task
+func parseSchedules(specs []string) (map[string]string, error) {
+	schedules := map[string]string{}
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.New(spec + " must be task=spec, such as " + taskRetentionPurge + "=@daily")
+		}
+
+		task := strings.TrimSpace(parts[0])
+		if !scheduledTasks[task] {
+			return nil, errors.New(task + " is not a scheduled task")
+		}
+
+		if _, err := scheduler.Parse(parts[1]); err != nil {
+			return nil, errors.New(task + ": " + err.Error())
+		}
+
+		schedules[task] = strings.TrimSpace(parts[1])
+	}
+
+	return schedules, nil
+}
+
+// schedules are the specs of the tasks to schedule: the retention purge
+// runs every retention interval unless its schedule is set
+func (c *Config) schedules() (map[string]string, error) {
+	schedules, err := parseSchedules(c.Schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := schedules[taskRetentionPurge]; !ok && c.RetentionInterval.Duration > 0 {
+		schedules[taskRetentionPurge] = "@every " + c.RetentionInterval.Duration.String()
+	}
+
+	return schedules, nil
+}
+
+// scheduledTask is a task as scheduled here, with its last run by any
+// instance
+type scheduledTask struct {
+	Name        string     `json:"name"`
+	Spec        string     `json:"spec"`
+	NextRunAt   time.Time  `json:"next_run_at"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// handleAdminSchedulesList lists the scheduled tasks, when they run next
+// and how they last ran
+func (s *server) handleAdminSchedulesList(c *gin.Context) {
+	if s.scheduler == nil {
+		c.JSON(http.StatusOK, gin.H{"schedules": []*scheduledTask{}})
+		return
+	}
+
+	runs, err := s.store.ScheduledTask().List(c.Request.Context())
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	last := map[string]*model.ScheduledTask{}
+	for _, run := range runs {
+		last[run.Name] = run
+	}
+
+	entries := s.scheduler.Entries(time.Now())
+	tasks := make([]*scheduledTask, len(entries))
+	for i, e := range entries {
+		tasks[i] = &scheduledTask{Name: e.Name, Spec: e.Spec, NextRunAt: e.NextRunAt}
+		if run, ok := last[e.Name]; ok {
+			tasks[i].LastRunAt = run.LastRunAt
+			tasks[i].LockedUntil = run.LockedUntil
+			tasks[i].FinishedAt = run.FinishedAt
+			tasks[i].LastError = run.LastError
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedules": tasks})
+}
@@ -0,0 +1,74 @@
+package apiserver
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+	"winding-tree-server/internal/seed"
+	"winding-tree-server/internal/store/sqlstore"
+)
+
+var (
+	errSeedUsage = errors.New("usage: seed [-seed N] [-suppliers N] [-flights N] [-days N]")
+)
+
+// Seed runs the seed subcommand, filling a migrated database with demo data
+func Seed(config *Config, args []string, w io.Writer) error {
+	opts := seed.Options{
+		Start: time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1),
+	}
+
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	flags.Int64Var(&opts.Seed, "seed", 1, "random seed; the same seed produces the same data")
+	flags.IntVar(&opts.Suppliers, "suppliers", 3, "number of approved suppliers")
+	flags.IntVar(&opts.FlightsPerSupplier, "flights", 20, "flights per supplier")
+	flags.IntVar(&opts.Days, "days", 14, "days from tomorrow over which flights depart")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 || opts.Suppliers < 0 || opts.FlightsPerSupplier < 0 || opts.Days < 1 {
+		return errSeedUsage
+	}
+
+	db, err := newDB(config.DatabaseDriver, config.DatabaseURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := sqlstore.CheckSchema(db); err != nil {
+		return err
+	}
+
+	s := sqlstore.New(db)
+	if len(config.EncryptionKeys) > 0 {
+		sealer, err := newSealer(config)
+		if err != nil {
+			return err
+		}
+
+		s.EncryptDocuments(sealer)
+	}
+
+	ctx := context.Background()
+	res, err := seed.Run(ctx, s, opts)
+	if err != nil {
+		return err
+	}
+
+	// Admin rights can't be granted through the store
+	if _, err := db.ExecContext(ctx, "UPDATE users SET is_admin = true WHERE id = $1", res.Reviewer.ID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "admin: %s\n", res.Reviewer.Email)
+	for _, u := range res.Suppliers {
+		fmt.Fprintf(w, "supplier: %s (%s)\n", u.Email, u.PublicID)
+	}
+	fmt.Fprintf(w, "pending review: %s (%s)\n", res.Pending.Email, res.Pending.PublicID)
+	fmt.Fprintf(w, "flights: %d, fares: %d, password of all users: %s\n", res.Flights, res.Fares, seed.Password)
+
+	return nil
+}
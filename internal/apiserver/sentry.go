@@ -0,0 +1,156 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+	"winding-tree-server/internal/model"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// sentryFlushTimeout bounds how long a stopping server waits for events to be sent
+const sentryFlushTimeout = 5 * time.Second
+
+// newSentryHub returns nil when no DSN is configured
+func newSentryHub(config *Config) (*sentry.Hub, error) {
+	if config.SentryDSN == "" {
+		return nil, nil
+	}
+
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              config.SentryDSN,
+		Environment:      config.SentryEnvironment,
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sentry_dsn: %v", err)
+	}
+
+	return sentry.NewHub(client, sentry.NewScope()), nil
+}
+
+// reportErrors reports 5xx responses to Sentry, with the route, request ID
+// and user. Panics are reported by recoverPanics, through the hub it leaves
+// in the request context.
+func (s *server) reportErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.sentry == nil {
+			c.Next()
+			return
+		}
+
+		hub := s.sentry.Clone()
+		route := s.routePattern(c)
+		hub.ConfigureScope(func(scope *sentry.Scope) {
+			scope.SetRequest(sentryRequest(c.Request))
+			scope.SetTransaction(c.Request.Method + " " + route)
+			scope.SetTag("route", route)
+			if id, ok := c.Get("ctxKeyRequestID"); ok {
+				scope.SetTag("request_id", fmt.Sprint(id))
+			}
+		})
+		c.Request = c.Request.WithContext(sentry.SetHubOnContext(c.Request.Context(), hub))
+
+		c.Next()
+
+		if _, panicked := c.Get("ctxKeyPanicReported"); panicked {
+			return
+		}
+
+		if status := c.Writer.Status(); status >= 500 {
+			setSentryUser(hub, c)
+			message := fmt.Sprintf("%s %s responded %d %s", c.Request.Method, route, status, http.StatusText(status))
+			if len(c.Errors) > 0 {
+				message += ": " + c.Errors.String()
+			}
+			hub.CaptureMessage(message)
+		}
+	}
+}
+
+// reportPanic reports a recovered panic, while the stack that led to it is
+// still there to be attached
+func reportPanic(c *gin.Context, err interface{}) {
+	hub := sentry.GetHubFromContext(c.Request.Context())
+	if hub == nil {
+		return
+	}
+
+	setSentryUser(hub, c)
+	hub.RecoverWithContext(c.Request.Context(), err)
+	c.Set("ctxKeyPanicReported", true)
+}
+
+// sentryRequest describes r without its body, query or credentials, which
+// may hold passwords, documents and session cookies
+func sentryRequest(r *http.Request) sentry.Request {
+	headers := map[string]string{}
+	for _, name := range []string{"User-Agent", "Content-Type", "Content-Length", "X-Forwarded-For"} {
+		if value := r.Header.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+
+	return sentry.Request{
+		URL:     r.Host + r.URL.Path,
+		Method:  r.Method,
+		Headers: headers,
+	}
+}
+
+// setSentryUser identifies the client by address and the authenticated
+// user, if any, by public ID
+func setSentryUser(hub *sentry.Hub, c *gin.Context) {
+	user := sentry.User{IPAddress: clientIP(c)}
+	if u, ok := c.Value("ctxKeyUser").(*model.User); ok {
+		user.ID = u.PublicID
+	}
+	hub.Scope().SetUser(user)
+}
+
+// sentryHook reports error logs to Sentry. Request logs, which have the
+// response status, and recovered panics are left out as they are reported
+// with more context by reportErrors and recoverPanics.
+type sentryHook struct {
+	hub *sentry.Hub
+}
+
+// Levels ...
+func (h *sentryHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+// Fire ...
+func (h *sentryHook) Fire(entry *logrus.Entry) error {
+	for _, key := range []string{"status", "panic"} {
+		if _, ok := entry.Data[key]; ok {
+			return nil
+		}
+	}
+
+	event := sentry.NewEvent()
+	event.Message = entry.Message
+	event.Level = sentry.LevelError
+	if entry.Level < logrus.ErrorLevel {
+		event.Level = sentry.LevelFatal
+	}
+
+	for key, value := range entry.Data {
+		if err, ok := value.(error); ok {
+			event.Exception = []sentry.Exception{{
+				Type:  reflect.TypeOf(err).String(),
+				Value: err.Error(),
+			}}
+			value = err.Error()
+		}
+		event.Extra[key] = value
+	}
+
+	h.hub.CaptureEvent(event)
+
+	return nil
+}
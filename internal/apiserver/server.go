@@ -1,13 +1,25 @@
 package apiserver
 
 import (
+	"context"
 	"crypto/tls"
+	"math/big"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
+	"winding-tree-server/internal/events"
+	"winding-tree-server/internal/features"
+	"winding-tree-server/internal/jobqueue"
+	"winding-tree-server/internal/mailer"
 	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/ratelimit"
+	"winding-tree-server/internal/requestid"
+	"winding-tree-server/internal/scheduler"
 	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/tracing"
 
-	"github.com/gin-contrib/cors"
+	"github.com/getsentry/sentry-go"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/sessions"
@@ -25,6 +37,9 @@ var (
 	errInternalServerError      = "internal server error"
 	errNotAuthenticated         = "not authenticated"
 	errBadRequest               = "bad request"
+	errNotFound                 = "not found"
+	errForbidden                = "forbidden"
+	errDocumentTooLarge         = "document too large"
 )
 
 type server struct {
@@ -36,6 +51,51 @@ type server struct {
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
 	TLSConfig    *tls.Config
+	// minLifDeposit is the Lif deposit (in wei) a supplier needs to be listed as verified
+	minLifDeposit *big.Int
+	// jobQueue runs background work, such as mails, stored as jobs
+	jobQueue *jobqueue.Queue
+	// mailTemplates is nil when mail isn't sent
+	mailTemplates map[string]*mailer.Template
+	// scheduler is nil when no recurring tasks are scheduled
+	scheduler *scheduler.Scheduler
+	// events is nil when events aren't pushed to clients
+	events *events.Hub
+	// readinessChecks are run by /readyz, keyed by dependency name
+	readinessChecks map[string]readinessCheck
+	// optionalChecks are the names of readiness checks that may fail
+	optionalChecks map[string]bool
+	// export is nil when the store can't produce exports
+	export exportFunc
+	// loadConfig is nil when the config can't be reloaded
+	loadConfig ConfigLoader
+	metrics    *metrics
+	// metricsToken, when set, must be presented to read /metrics
+	metricsToken string
+	// routes holds the "METHOD pattern" of every route
+	routes map[string]bool
+	// tracer is nil when tracing is disabled
+	tracer *tracing.Tracer
+	// pprof is 1 while the profiling endpoints are enabled
+	pprof int32
+	// sentry is nil when errors aren't reported
+	sentry *sentry.Hub
+	// maxBodySize and maxUploadSize limit request bodies, in bytes
+	maxBodySize   int64
+	maxUploadSize int64
+	compression   *compression
+	// rateLimiter is nil when requests aren't rate limited
+	rateLimiter ratelimit.Limiter
+	// rateLimits holds the current *rateLimits
+	rateLimits atomic.Value
+	// cors holds the current *corsPolicy
+	cors atomic.Value
+	// trustedProxies may forward the client address
+	trustedProxies trustedProxies
+	// maintenance holds the current *maintenanceMode
+	maintenance atomic.Value
+	// features are the rollouts of the features being released
+	features *features.Flags
 }
 
 type ctxKey int8
@@ -65,21 +125,47 @@ func NewServer(store store.Store, sessionStore sessions.Store) *server {
 	}
 
 	s := &server{
-		router:       gin.Default(),
-		logger:       logrus.New(),
-		store:        store,
-		sessionStore: sessionStore,
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
-		TLSConfig:    tlsConfig,
+		router:        gin.New(),
+		logger:        logrus.New(),
+		store:         store,
+		sessionStore:  sessionStore,
+		ReadTimeout:   5 * time.Second,
+		WriteTimeout:  10 * time.Second,
+		IdleTimeout:   120 * time.Second,
+		TLSConfig:     tlsConfig,
+		minLifDeposit: new(big.Int),
+		metrics:       newMetrics(),
+		maxBodySize:   defaultMaxBodySize,
+		maxUploadSize: defaultMaxUploadSize,
+		compression:   newCompression(true, false, defaultCompressionMinSize, defaultCompressionTypes, nil),
+		features:      features.New(nil),
 	}
 
+	// Forwarding headers are only read from trusted proxies, by resolveClientIP
+	s.router.ForwardedByClientIP = false
+	s.setCORS(defaultCORSOrigins, defaultCORSMethods, defaultCORSHeaders, false, defaultCORSMaxAge)
 	s.configureRouter()
 
+	s.routes = map[string]bool{}
+	for _, r := range s.router.Routes() {
+		s.routes[r.Method+" "+r.Path] = true
+	}
+
 	return s
 }
 
+// httpServer serves s on addr with its timeouts and TLS settings
+func (s *server) httpServer(addr string) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      s,
+		ReadTimeout:  s.ReadTimeout,
+		WriteTimeout: s.WriteTimeout,
+		IdleTimeout:  s.IdleTimeout,
+		TLSConfig:    s.TLSConfig,
+	}
+}
+
 // ServeHTTP ...
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.router.ServeHTTP(w, r)
@@ -87,19 +173,84 @@ func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // configureRouter ..
 func (s *server) configureRouter() {
-	config := cors.DefaultConfig()
-	config.AllowOrigins = []string{"http://moonshard.io", "http://equityone.org"}
-
+	// Requests are logged by logRequest rather than gin's logger. Panics are
+	// recovered after the request is identified and before the response is
+	// logged, measured and reported, so that they see a 500.
+	s.router.Use(s.resolveClientIP())
 	s.router.Use(s.SetRequestID())
 	s.router.Use(s.logRequest())
-	s.router.Use(cors.New(config))
-	s.router.POST("/users", s.handleUsersCreate)
-	s.router.POST("/sessions", s.handleSessionsCreate)
+	s.router.Use(s.instrument())
+	s.router.Use(s.trace())
+	s.router.Use(s.reportErrors())
+	s.router.Use(s.recoverPanics())
+	s.router.Use(s.limitBody())
+	s.router.Use(s.compress())
+	s.router.Use(s.allowCORS())
+	s.router.Use(s.checkMaintenance())
+	s.router.GET("/healthz", s.handleHealthz)
+	s.router.GET("/readyz", s.handleReadyz)
+	s.router.GET("/metrics", s.handleMetrics)
+
+	public := s.router.Group("/")
+	public.Use(s.rateLimit(rateLimitAPI))
+	{
+		public.POST("/users", s.handleUsersCreate)
+		public.POST("/sessions", s.handleSessionsCreate)
+		public.GET("/suppliers/:id", s.handleSupplierGet)
+		public.GET("/suppliers/:id/org.json", s.handleOrgJSONGet)
+		public.GET("/suppliers/:id/org.json/:version", s.handleOrgJSONGet)
+		public.GET("/search/flights", s.rateLimit(rateLimitSearch), s.handleFlightsSearch)
+	}
 
 	private := s.router.Group("/private")
-	private.Use(s.AuthenticationUser())
+	private.Use(s.AuthenticationUser(), s.rateLimit(rateLimitAPI))
 	{
 		private.GET("/whoami", s.getMyUserInfo)
+		private.GET("/features", s.handleFeaturesGet)
+		private.POST("/org.json", s.handleOrgJSONCreate)
+		private.GET("/flights", s.handleFlightsList)
+		private.POST("/flights", s.handleFlightsCreate)
+		private.POST("/flights/:id/fares", s.handleFaresCreate)
+		private.PUT("/fares", s.handleFaresUpsert)
+		private.GET("/onboarding", s.handleOnboardingGet)
+		private.POST("/onboarding/documents", s.handleOnboardingDocumentsCreate)
+		private.POST("/onboarding/submit", s.handleOnboardingSubmit)
+		private.GET("/export", s.handleExport)
+		private.GET("/notifications", s.handleNotifications)
+		private.GET("/events/stream", s.handleEventsStream)
+	}
+
+	admin := s.router.Group("/admin")
+	admin.Use(s.AuthenticationUser(), s.AuthorizeAdmin())
+	{
+		admin.GET("/users", s.handleAdminUsersList)
+		admin.DELETE("/users/:id", s.handleAdminUsersDelete)
+		admin.POST("/users/:id/restore", s.handleAdminUsersRestore)
+		admin.GET("/users/:id/export", s.handleAdminUsersExport)
+		admin.GET("/onboarding", s.handleAdminOnboardingList)
+		admin.GET("/onboarding/:id", s.handleAdminOnboardingGet)
+		admin.GET("/onboarding/:id/documents/:document_id", s.handleAdminOnboardingDocumentGet)
+		admin.POST("/onboarding/:id/approve", s.handleAdminOnboardingReview(true))
+		admin.POST("/onboarding/:id/reject", s.handleAdminOnboardingReview(false))
+		admin.GET("/history/:entity/:id", s.handleAdminHistoryGet)
+		admin.POST("/config/reload", s.handleAdminConfigReload)
+		admin.GET("/maintenance", s.handleAdminMaintenanceGet)
+		admin.PUT("/maintenance", s.handleAdminMaintenanceSet)
+		admin.GET("/features", s.handleAdminFeaturesList)
+		admin.PUT("/features/:name", s.handleAdminFeatureSet)
+		admin.DELETE("/features/:name", s.handleAdminFeatureDelete)
+		admin.GET("/jobs", s.handleAdminJobsList)
+		admin.GET("/jobs/:id", s.handleAdminJobsGet)
+		admin.POST("/jobs/:id/retry", s.handleAdminJobsRetry)
+		admin.GET("/schedules", s.handleAdminSchedulesList)
+	}
+
+	debug := s.router.Group("/debug/pprof")
+	debug.Use(s.requirePprof(), s.AuthenticationUser(), s.AuthorizeAdmin())
+	{
+		debug.GET("/", s.handlePprof)
+		debug.GET("/:profile", s.handlePprof)
+		debug.POST("/:profile", s.handlePprof)
 	}
 
 }
@@ -113,13 +264,14 @@ func (s *server) AuthenticationUser() gin.HandlerFunc {
 			return
 		}
 
-		id, ok := session.Values["user_id"]
+		// Sessions from before users had public ids hold an int and must log in again
+		id, ok := session.Values["user_id"].(string)
 		if !ok {
 			respondWithError(c, http.StatusUnauthorized, errNotAuthenticated)
 			return
 		}
 
-		u, err := s.store.User().Find(id.(int))
+		u, err := s.store.User().FindByPublicID(c.Request.Context(), id)
 		if err != nil {
 			respondWithError(c, http.StatusUnauthorized, errNotAuthenticated)
 			return
@@ -129,16 +281,111 @@ func (s *server) AuthenticationUser() gin.HandlerFunc {
 	}
 }
 
+// AuthorizeAdmin must run after AuthenticationUser
+func (s *server) AuthorizeAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u := c.Value("ctxKeyUser").(*model.User)
+		if !u.IsAdmin {
+			respondWithError(c, http.StatusForbidden, errForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// tenantStore returns the store scoped to the authenticated supplier, so
+// private handlers can't reach other suppliers' records. It must run after
+// AuthenticationUser.
+func (s *server) tenantStore(c *gin.Context) store.Store {
+	u := c.Value("ctxKeyUser").(*model.User)
+	return s.store.ForTenant(u.ID)
+}
+
+// userParam resolves the :id path parameter, a user's public id. Deleted
+// users are found too, so admins can still act on them. When it returns
+// false it has already responded.
+func (s *server) userParam(c *gin.Context) (*model.User, bool) {
+	publicID := c.Param("id")
+	if _, err := uuid.Parse(publicID); err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return nil, false
+	}
+
+	users, _, err := s.store.User().List(c.Request.Context(), &store.ListOptions{
+		Limit:   1,
+		Filters: map[string]string{"public_id": publicID},
+	})
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return nil, false
+	}
+
+	if len(users) == 0 {
+		respondWithError(c, http.StatusNotFound, errNotFound)
+		return nil, false
+	}
+
+	return users[0], true
+}
+
+// supplierParam resolves the :id path parameter of public routes, a
+// supplier's public id; deleted users aren't found. When it returns false it
+// has already responded.
+func (s *server) supplierParam(c *gin.Context) (*model.User, bool) {
+	publicID := c.Param("id")
+	if _, err := uuid.Parse(publicID); err != nil {
+		respondWithError(c, http.StatusBadRequest, errBadRequest)
+		return nil, false
+	}
+
+	u, err := s.store.User().FindByPublicID(c.Request.Context(), publicID)
+	if err == store.ErrRecordNotFound {
+		respondWithError(c, http.StatusNotFound, errNotFound)
+		return nil, false
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return nil, false
+	}
+
+	return u, true
+}
+
+// publicIDs maps internal user ids to the public ids responses refer to
+// users by; deleted users are left out
+func (s *server) publicIDs(ctx context.Context, ids []int) (map[int]string, error) {
+	publicIDs := make(map[int]string, len(ids))
+	for _, id := range ids {
+		if _, ok := publicIDs[id]; ok {
+			continue
+		}
+
+		u, err := s.store.User().Find(ctx, id)
+		if err == store.ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		publicIDs[id] = u.PublicID
+	}
+
+	return publicIDs, nil
+}
+
 // handleUsersCreate ...
 func (s *server) handleUsersCreate(c *gin.Context) {
 	var u *model.User
 	c.BindJSON(&u)
 
-	if err := s.store.User().Create(u); err != nil {
+	if err := s.store.User().Create(c.Request.Context(), u); err != nil {
 		respondWithError(c, http.StatusBadRequest, errBadRequest)
 		return
 	}
 
+	s.metrics.signups.Inc()
 	u.Sanitize()
 	c.JSON(http.StatusOK, gin.H{
 		"email":              u.Email,
@@ -151,8 +398,9 @@ func (s *server) handleSessionsCreate(c *gin.Context) {
 	var req *model.User
 	c.BindJSON(&req)
 
-	u, err := s.store.User().FindByEmail(req.Email)
+	u, err := s.store.User().FindByEmail(c.Request.Context(), req.Email)
 	if err != nil || !u.ComparePasswords(req.Password) {
+		s.metrics.logins.WithLabelValues("failure").Inc()
 		respondWithError(c, http.StatusUnauthorized, errIncorrectEmailOrPassword)
 		return
 	}
@@ -163,23 +411,95 @@ func (s *server) handleSessionsCreate(c *gin.Context) {
 		return
 	}
 
-	session.Values["user_id"] = u.ID
+	session.Values["user_id"] = u.PublicID
 	if err := s.sessionStore.Save(c.Request, c.Writer, session); err != nil {
 		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
 		return
 	}
+
+	s.metrics.logins.WithLabelValues("success").Inc()
+}
+
+// handleAdminUsersList lists users, or searches them by email with ?q=
+func (s *server) handleAdminUsersList(c *gin.Context) {
+	opts, err := listOptions(c, "email", "is_admin", "deleted")
+	if err != nil {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var users []*model.User
+	var total int
+	if q := c.Query("q"); q != "" {
+		users, total, err = s.store.User().Search(c.Request.Context(), q, opts)
+	} else {
+		users, total, err = s.store.User().List(c.Request.Context(), opts)
+	}
+	if err == store.ErrInvalidListOptions {
+		respondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	for _, u := range users {
+		u.Sanitize()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+		"total": total,
+	})
+}
+
+// handleAdminUsersDelete deactivates a user; their sessions stop authenticating
+func (s *server) handleAdminUsersDelete(c *gin.Context) {
+	s.updateUserDeleted(c, s.store.User().Delete)
+}
+
+// handleAdminUsersRestore reactivates a deleted user
+func (s *server) handleAdminUsersRestore(c *gin.Context) {
+	s.updateUserDeleted(c, s.store.User().Restore)
+}
+
+func (s *server) updateUserDeleted(c *gin.Context, update func(context.Context, int) error) {
+	u, ok := s.userParam(c)
+	if !ok {
+		return
+	}
+
+	if err := update(c.Request.Context(), u.ID); err != nil {
+		if err == store.ErrRecordNotFound {
+			respondWithError(c, http.StatusNotFound, errNotFound)
+			return
+		}
+
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 func (s *server) logRequest() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		logger := s.logger.WithFields(logrus.Fields{
+		fields := logrus.Fields{
 			"remote_addr": c.Request.RemoteAddr,
-			"request_id":  c.Value("ctxKeyRequestID"),
-		})
-		logger.Infof("started %s %s", c.Request.Method, c.Request.RequestURI)
+			"client_ip":   clientIP(c),
+			"method":      c.Request.Method,
+			"path":        c.Request.URL.Path,
+		}
+		s.requestLogger(c).WithFields(fields).Infof("started %s %s", c.Request.Method, c.Request.RequestURI)
 		start := time.Now()
 		c.Next()
 
+		elapsed := time.Since(start)
+		fields["status"] = c.Writer.Status()
+		fields["duration"] = elapsed.Seconds()
+		logger := s.requestLogger(c).WithFields(fields)
+
 		var level logrus.Level
 		switch {
 		case c.Writer.Status() >= 500:
@@ -194,25 +514,58 @@ func (s *server) logRequest() gin.HandlerFunc {
 			"completed with %d %s in %v",
 			c.Writer.Status(),
 			http.StatusText(c.Writer.Status()),
-			time.Now().Sub(start),
+			elapsed,
 		)
 	}
 }
 
+// SetRequestID identifies the request in its logs, response and outgoing
+// calls, keeping the ID given by a trusted proxy or service
 func (s *server) SetRequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id := uuid.New().String()
-		c.Header("X-Request-ID", id)
+		id := s.inboundRequestID(c.Request)
+		if id == "" {
+			id = uuid.New().String()
+		}
+
+		c.Header(requestid.Header, id)
 		c.Set("ctxKeyRequestID", id)
+		c.Request = c.Request.WithContext(requestid.NewContext(c.Request.Context(), id))
 		c.Next()
 	}
 }
 
+// inboundRequestID is the valid X-Request-ID of a request from a trusted
+// proxy, or else the ID of the trace it continues, if any
+func (s *server) inboundRequestID(r *http.Request) string {
+	if !s.trustedProxies.trusts(r) {
+		return ""
+	}
+
+	if id := r.Header.Get(requestid.Header); requestid.Valid(id) {
+		return id
+	}
+
+	if parent, ok := tracing.Extract(r.Header); ok {
+		return parent.TraceID.String()
+	}
+
+	return ""
+}
+
 // handleUsersCreate ...
 func (s *server) getMyUserInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, c.Value("ctxKeyUser").(*model.User))
 }
 
+// attachment makes browsers download the response as filename instead of
+// rendering it, and keeps them from guessing a type other than the one sent,
+// since uploaded content could otherwise run as a page of this site
+func attachment(c *gin.Context, filename string) {
+	c.Header("Content-Disposition", "attachment; filename="+strconv.Quote(filename))
+	c.Header("X-Content-Type-Options", "nosniff")
+}
+
 // respondWithError ...
 func respondWithError(c *gin.Context, code int, message interface{}) {
 	c.AbortWithStatusJSON(code, gin.H{"error": message})
@@ -0,0 +1,2395 @@
+package apiserver
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"winding-tree-server/internal/events"
+	"winding-tree-server/internal/jobqueue"
+	"winding-tree-server/internal/mailer"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/ratelimit"
+	"winding-tree-server/internal/requestid"
+	"winding-tree-server/internal/scheduler"
+	"winding-tree-server/internal/store/sqlstore"
+	"winding-tree-server/internal/store/teststore"
+	"winding-tree-server/internal/tracing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/websocket"
+)
+
+func TestServer_AuthenticationUser(t *testing.T) {
+	store := teststore.New()
+	u := model.TestUser(t)
+	store.User().Create(context.Background(), u)
+
+	testCases := []struct {
+		name         string
+		cookieValue  map[interface{}]interface{}
+		expectedCode int
+	}{
+		{
+			name: "authenticated",
+			cookieValue: map[interface{}]interface{}{
+				"user_id": u.PublicID,
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not authenticated",
+			cookieValue:  nil,
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/private/whoami", nil)
+			cookieStr, _ := sc.Encode(sessionName, tc.cookieValue)
+			req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_HandleUsersCreate(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+
+	testCases := []struct {
+		name         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name: "valid",
+			payload: map[string]string{
+				"email":    "user@example.test",
+				"password": "password",
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "invalid params",
+			payload: map[string]string{
+				"email": "invalid",
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			b := &bytes.Buffer{}
+			json.NewEncoder(b).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPost, "/users", b)
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_HandleSessionsCreate(t *testing.T) {
+	u := model.TestUser(t)
+	store := teststore.New()
+	store.User().Create(context.Background(), u)
+	s := NewServer(store, sessions.NewCookieStore([]byte("secret")))
+
+	testCases := []struct {
+		name         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name: "valid",
+			payload: map[string]string{
+				"email":    u.Email,
+				"password": u.Password,
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "invalid email",
+			payload: map[string]string{
+				"email":    "invalid",
+				"password": u.Password,
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "invalid password",
+			payload: map[string]string{
+				"email":    u.Email,
+				"password": "invalid",
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			b := &bytes.Buffer{}
+			json.NewEncoder(b).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPost, "/sessions", b)
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_HandleFlightsSearch(t *testing.T) {
+	store := teststore.New()
+	u := model.TestUser(t)
+	store.User().Create(context.Background(), u)
+	f := model.TestFlight(t)
+	f.UserID = u.ID
+	store.Flight().Create(context.Background(), f)
+	s := NewServer(store, sessions.NewCookieStore([]byte("secret")))
+
+	search := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/search/flights?origin=ZRH&destination=KBP&date=2019-12-20", nil)
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	res := struct {
+		Offers []map[string]interface{} `json:"offers"`
+	}{}
+
+	rec := search()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Len(t, res.Offers, 0, "unapproved suppliers are hidden")
+
+	o := model.NewOnboarding(u.ID)
+	o.Submit(1)
+	o.Approve(u.ID)
+	store.Onboarding().Save(context.Background(), o)
+
+	rec = search()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.NoError(t, json.Unmarshal([]byte(body), &res))
+	if assert.Len(t, res.Offers, 1) {
+		assert.Equal(t, u.PublicID, res.Offers[0]["supplier_id"])
+	}
+	for _, id := range []string{`"id"`, `"user_id"`, `"flight_id"`} {
+		assert.NotContains(t, body, id, "internal ids aren't public")
+	}
+
+	rec = httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/search/flights?origin=ZRH", nil)
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	store.User().Delete(context.Background(), u.ID)
+	rec = search()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Len(t, res.Offers, 0, "deleted suppliers are hidden")
+}
+
+func TestServer_HandleOrgJSONGet(t *testing.T) {
+	store := teststore.New()
+	u := model.TestUser(t)
+	store.User().Create(context.Background(), u)
+	deleted := model.TestUser(t)
+	deleted.Email = "deleted@example.org"
+	store.User().Create(context.Background(), deleted)
+	for _, user := range []*model.User{u, deleted} {
+		o := model.TestOrgJSON(t)
+		o.UserID = user.ID
+		store.OrgJSON().Create(context.Background(), o)
+	}
+	store.User().Delete(context.Background(), deleted.ID)
+	s := NewServer(store, sessions.NewCookieStore([]byte("secret")))
+
+	testCases := []struct {
+		name         string
+		path         string
+		expectedCode int
+	}{
+		{
+			name:         "latest",
+			path:         "/suppliers/" + u.PublicID + "/org.json",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "version",
+			path:         "/suppliers/" + u.PublicID + "/org.json/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "missing version",
+			path:         "/suppliers/" + u.PublicID + "/org.json/2",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "deleted supplier",
+			path:         "/suppliers/" + deleted.PublicID + "/org.json",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid id",
+			path:         "/suppliers/1/org.json",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.expectedCode == http.StatusOK {
+				assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+			}
+		})
+	}
+}
+
+func TestServer_HandleAdminOnboardingDocumentGet(t *testing.T) {
+	ctx := context.Background()
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(ctx, admin)
+	supplier := model.TestUser(t)
+	supplier.Email = "supplier@example.org"
+	store.User().Create(ctx, supplier)
+	// Only the declared type is checked, not the content
+	d := &model.OnboardingDocument{
+		UserID:      supplier.ID,
+		Kind:        model.DocumentOther,
+		FileName:    "license.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("<script>alert(1)</script>"),
+	}
+	assert.NoError(t, store.Onboarding().CreateDocument(ctx, d))
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/admin/onboarding/%s/documents/%d", supplier.PublicID, d.ID), nil)
+	cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": admin.PublicID})
+	req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+	s.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename="license.pdf"`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+}
+
+func TestServer_InternalIDsHidden(t *testing.T) {
+	ctx := context.Background()
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(ctx, admin)
+	supplier := model.TestUser(t)
+	supplier.Email = "supplier@example.org"
+	store.User().Create(ctx, supplier)
+
+	o := model.NewOnboarding(supplier.ID)
+	o.Submit(1)
+	o.Approve(admin.ID)
+	store.Onboarding().Save(ctx, o)
+	f := model.TestFlight(t)
+	f.UserID = supplier.ID
+	store.Flight().Create(ctx, f)
+	orgJSON := model.TestOrgJSON(t)
+	orgJSON.UserID = supplier.ID
+	store.OrgJSON().Create(ctx, orgJSON)
+	store.OrgID().Save(ctx, &model.OrgID{ID: "0x01", UserID: &supplier.ID, LifDeposit: "0"})
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	testCases := []struct {
+		name string
+		path string
+		user *model.User
+	}{
+		{
+			name: "supplier",
+			path: "/suppliers/" + supplier.PublicID,
+		},
+		{
+			name: "org.json",
+			path: "/suppliers/" + supplier.PublicID + "/org.json",
+		},
+		{
+			name: "flights",
+			path: "/private/flights",
+			user: supplier,
+		},
+		{
+			name: "onboarding",
+			path: "/private/onboarding",
+			user: supplier,
+		},
+		{
+			name: "admin onboarding queue",
+			path: "/admin/onboarding?state=approved",
+			user: admin,
+		},
+		{
+			name: "admin onboarding",
+			path: "/admin/onboarding/" + supplier.PublicID,
+			user: admin,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.user != nil {
+				cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": tc.user.PublicID})
+				req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+			}
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.NotContains(t, rec.Body.String(), `"user_id"`)
+			assert.NotContains(t, rec.Body.String(), `"reviewer_id":1`)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/admin/onboarding/"+supplier.PublicID, nil)
+	cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": admin.PublicID})
+	req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+	s.ServeHTTP(rec, req)
+	res := struct {
+		Onboarding map[string]interface{} `json:"onboarding"`
+	}{}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+	assert.Equal(t, supplier.PublicID, res.Onboarding["supplier_id"])
+	assert.Equal(t, admin.PublicID, res.Onboarding["reviewer_id"], "admins see the reviewer by public id")
+}
+
+func TestServer_HandleFaresUpsert(t *testing.T) {
+	store := teststore.New()
+	u := model.TestUser(t)
+	store.User().Create(context.Background(), u)
+	other := model.TestUser(t)
+	other.Email = "other@example.org"
+	store.User().Create(context.Background(), other)
+	f := model.TestFlight(t)
+	f.UserID = u.ID
+	store.Flight().Create(context.Background(), f)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	testCases := []struct {
+		name         string
+		userID       string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:   "valid",
+			userID: u.PublicID,
+			payload: []map[string]interface{}{
+				{"flight_id": f.ID, "cabin": "economy", "amount": 20000, "currency": "EUR", "seats_available": 4},
+				{"flight_id": f.ID, "cabin": "business", "amount": 90000, "currency": "EUR", "seats_available": 2},
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:   "invalid fare",
+			userID: u.PublicID,
+			payload: []map[string]interface{}{
+				{"flight_id": f.ID, "cabin": "cargo", "amount": 20000, "currency": "EUR"},
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "someone else's flight",
+			userID: other.PublicID,
+			payload: []map[string]interface{}{
+				{"flight_id": f.ID, "cabin": "economy", "amount": 1, "currency": "EUR"},
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "not a list",
+			userID:       u.PublicID,
+			payload:      map[string]interface{}{"cabin": "economy"},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			b := &bytes.Buffer{}
+			json.NewEncoder(b).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPut, "/private/fares", b)
+			cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": tc.userID})
+			req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	found, _ := store.Flight().Find(context.Background(), f.ID)
+	if assert.Len(t, found.Fares, 2) {
+		assert.Equal(t, int64(20000), found.Fares[0].Amount)
+		assert.Equal(t, 4, found.Fares[0].SeatsAvailable)
+	}
+}
+
+func TestServer_HandleAdminUsersList(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+	u := model.TestUser(t)
+	u.Email = "supplier@example.org"
+	store.User().Create(context.Background(), u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	testCases := []struct {
+		name          string
+		userID        string
+		query         string
+		expectedCode  int
+		expectedTotal int
+	}{
+		{
+			name:          "all",
+			userID:        admin.PublicID,
+			expectedCode:  http.StatusOK,
+			expectedTotal: 2,
+		},
+		{
+			name:          "filtered",
+			userID:        admin.PublicID,
+			query:         "?is_admin=false&sort=-email",
+			expectedCode:  http.StatusOK,
+			expectedTotal: 1,
+		},
+		{
+			name:          "search",
+			userID:        admin.PublicID,
+			query:         "?q=supplier",
+			expectedCode:  http.StatusOK,
+			expectedTotal: 1,
+		},
+		{
+			name:         "invalid limit",
+			userID:       admin.PublicID,
+			query:        "?limit=1000",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid sort",
+			userID:       admin.PublicID,
+			query:        "?sort=password",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "not admin",
+			userID:       u.PublicID,
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/admin/users"+tc.query, nil)
+			cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": tc.userID})
+			req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+
+			if tc.expectedCode == http.StatusOK {
+				res := struct {
+					Total int `json:"total"`
+				}{}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&res))
+				assert.Equal(t, tc.expectedTotal, res.Total)
+			}
+		})
+	}
+}
+
+func TestServer_HandleAdminUsersDelete(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+	u := model.TestUser(t)
+	u.Email = "supplier@example.org"
+	store.User().Create(context.Background(), u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	request := func(method, path string, userID string) int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": userID})
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	path := fmt.Sprintf("/admin/users/%s", u.PublicID)
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, path, admin.PublicID))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodDelete, path, admin.PublicID))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/private/whoami", u.PublicID))
+
+	assert.Equal(t, http.StatusNoContent, request(http.MethodPost, path+"/restore", admin.PublicID))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, path+"/restore", admin.PublicID))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/private/whoami", u.PublicID))
+}
+
+func TestServer_HandleAdminOnboardingReview(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+	supplier := model.TestUser(t)
+	supplier.Email = "supplier@example.org"
+	store.User().Create(context.Background(), supplier)
+	o := model.NewOnboarding(supplier.ID)
+	o.Submit(1)
+	store.Onboarding().Save(context.Background(), o)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	testCases := []struct {
+		name            string
+		ifMatch         string
+		expectedCode    int
+		expectedState   string
+		expectedVersion int
+	}{
+		{
+			name:            "stale version",
+			ifMatch:         `"2"`,
+			expectedCode:    http.StatusConflict,
+			expectedState:   model.OnboardingSubmitted,
+			expectedVersion: 1,
+		},
+		{
+			name:            "bare version",
+			ifMatch:         "1",
+			expectedCode:    http.StatusConflict,
+			expectedState:   model.OnboardingSubmitted,
+			expectedVersion: 1,
+		},
+		{
+			name:            "current version",
+			ifMatch:         `"3", "1"`,
+			expectedCode:    http.StatusOK,
+			expectedState:   model.OnboardingApproved,
+			expectedVersion: 2,
+		},
+		{
+			name:            "already reviewed",
+			expectedCode:    http.StatusConflict,
+			expectedState:   model.OnboardingApproved,
+			expectedVersion: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/admin/onboarding/"+supplier.PublicID+"/approve", nil)
+			cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": admin.PublicID})
+			req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+
+			current, err := store.Onboarding().Find(context.Background(), supplier.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedState, current.State)
+			assert.Equal(t, tc.expectedVersion, current.Version)
+			assert.Equal(t, fmt.Sprintf(`"%d"`, tc.expectedVersion), rec.Header().Get("ETag"))
+		})
+	}
+
+	// Any existing onboarding matches "*"
+	other := model.TestUser(t)
+	other.Email = "other@example.org"
+	store.User().Create(context.Background(), other)
+	o = model.NewOnboarding(other.ID)
+	o.Submit(1)
+	store.Onboarding().Save(context.Background(), o)
+
+	request := func(method, path, ifMatch string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": admin.PublicID})
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/admin/onboarding/"+other.PublicID, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"1"`, rec.Header().Get("ETag"))
+
+	rec = request(http.MethodPost, "/admin/onboarding/"+other.PublicID+"/approve", "*")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+}
+
+func TestServer_OnboardingReviewMail(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+	supplier := model.TestUser(t)
+	supplier.Email = "supplier@example.org"
+	store.User().Create(context.Background(), supplier)
+	o := model.NewOnboarding(supplier.ID)
+	o.Submit(1)
+	store.Onboarding().Save(context.Background(), o)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	s.jobQueue = jobqueue.New(store, s.logger, jobqueue.Config{MaxAttempts: 1})
+	templates, err := newMailTemplates()
+	assert.NoError(t, err)
+	s.mailTemplates = templates
+	sc := securecookie.New(secretKey, nil)
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(
+		http.MethodPost,
+		"/admin/onboarding/"+supplier.PublicID+"/reject",
+		strings.NewReader(`{"reason":"missing <b>license</b>"}`),
+	)
+	req.Header.Set("Content-Type", "application/json")
+	cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": admin.PublicID})
+	req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	j, err := store.Job().Find(context.Background(), 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, jobSendMail, j.Kind)
+	msg := &mailer.Message{}
+	assert.NoError(t, json.Unmarshal(j.Payload, msg))
+	assert.Equal(t, supplier.Email, msg.To)
+	assert.Equal(t, "Your onboarding was rejected", msg.Subject)
+	assert.Contains(t, msg.Text, "rejected: missing <b>license</b>")
+	assert.Contains(t, msg.HTML, "rejected: missing &lt;b&gt;license&lt;/b&gt;")
+}
+
+func TestServer_HandleAdminHistoryGet(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+	u := model.TestUser(t)
+	u.Email = "supplier@example.org"
+	store.User().Create(context.Background(), u)
+	store.User().Delete(context.Background(), u.ID)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	testCases := []struct {
+		name         string
+		path         string
+		expectedCode int
+		expected     []string
+	}{
+		{
+			name:         "user",
+			path:         fmt.Sprintf("/admin/history/users/%s", u.PublicID),
+			expectedCode: http.StatusOK,
+			expected:     []string{model.ChangeCreate, model.ChangeDelete},
+		},
+		{
+			name:         "no changes",
+			path:         "/admin/history/fares/1",
+			expectedCode: http.StatusOK,
+			expected:     []string{},
+		},
+		{
+			name:         "unknown entity",
+			path:         "/admin/history/hotels/1",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid id",
+			path:         "/admin/history/users/abc",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": admin.PublicID})
+			req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+
+			if tc.expectedCode != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Changes []*model.Change `json:"changes"`
+			}
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+
+			actions := []string{}
+			for _, c := range body.Changes {
+				actions = append(actions, c.Action)
+				assert.NotContains(t, string(c.After), "password")
+			}
+			assert.Equal(t, tc.expected, actions)
+		})
+	}
+}
+
+func TestServer_HandleReadyz(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+
+	readyz := func() (int, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		s.ServeHTTP(rec, req)
+
+		body := map[string]interface{}{}
+		json.NewDecoder(rec.Body).Decode(&body)
+		return rec.Code, body
+	}
+
+	code, body := readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body["status"])
+
+	var dbErr error
+	s.addReadinessCheck("database", func(ctx context.Context) error {
+		return dbErr
+	})
+
+	code, body = readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body["checks"], "database")
+
+	dbErr = errors.New("connection refused")
+	code, body = readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", body["status"])
+	assert.Equal(t, "connection refused", body["checks"].(map[string]interface{})["database"].(map[string]interface{})["error"])
+
+	dbErr = nil
+	cacheErr := errors.New("i/o timeout")
+	s.addOptionalCheck("cache", func(ctx context.Context) error {
+		return cacheErr
+	})
+
+	code, body = readyz()
+	assert.Equal(t, http.StatusOK, code, "optional dependencies don't fail readiness")
+	assert.Equal(t, "degraded", body["status"])
+	assert.Equal(t, "degraded", body["checks"].(map[string]interface{})["cache"].(map[string]interface{})["status"])
+
+	cacheErr = nil
+	code, body = readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body["status"])
+}
+
+func TestServer_HandleHealthz(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+	s.addReadinessCheck("database", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "dependencies don't matter")
+}
+
+func TestServer_HandleExport(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+	u := model.TestUser(t)
+	u.Email = "supplier@example.org"
+	store.User().Create(context.Background(), u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	request := func(path string, userID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": userID})
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, request("/private/export", u.PublicID).Code)
+
+	s.export = func(ctx context.Context, w io.Writer, userIDs []int, progress sqlstore.ExportProgress) error {
+		_, err := fmt.Fprintf(w, "%v\n", userIDs)
+		return err
+	}
+
+	rec := request("/private/export", u.PublicID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Disposition"), u.PublicID)
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, fmt.Sprintf("[%d]\n", u.ID), rec.Body.String())
+
+	path := fmt.Sprintf("/admin/users/%s/export", u.PublicID)
+	assert.Equal(t, http.StatusForbidden, request(path, u.PublicID).Code)
+	rec = request(path, admin.PublicID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, fmt.Sprintf("[%d]\n", u.ID), rec.Body.String())
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	started := make(chan struct{})
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	}
+
+	signals := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(srv, func() error { return srv.Serve(l) }, signals, time.Second, logrus.New())
+	}()
+
+	responses := make(chan int, 1)
+	go func() {
+		res, err := http.Get("http://" + l.Addr().String())
+		if assert.NoError(t, err) {
+			res.Body.Close()
+			responses <- res.StatusCode
+		}
+	}()
+
+	<-started
+	signals <- os.Interrupt
+	assert.NoError(t, <-served)
+	assert.Equal(t, http.StatusNoContent, <-responses, "in-flight request completes")
+
+	_, err = http.Get("http://" + l.Addr().String())
+	assert.Error(t, err, "no new connections")
+}
+
+func TestJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	background := &jobs{ctx: ctx}
+
+	stopped := false
+	background.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		stopped = true
+	})
+	background.Go(func(ctx context.Context) {})
+
+	assert.False(t, background.Wait(10*time.Millisecond))
+	cancel()
+	assert.True(t, background.Wait(time.Second))
+	assert.True(t, stopped)
+}
+
+func TestServer_HTTPServer(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+
+	srv := s.httpServer(":8443")
+	assert.Equal(t, ":8443", srv.Addr)
+	assert.Equal(t, s.TLSConfig, srv.TLSConfig)
+	assert.Equal(t, s.ReadTimeout, srv.ReadTimeout)
+	assert.Equal(t, s.WriteTimeout, srv.WriteTimeout)
+	assert.Equal(t, s.IdleTimeout, srv.IdleTimeout)
+}
+
+func TestStart_InvalidConfig(t *testing.T) {
+	config := NewConfig()
+	err := Start(config, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "tls_cert_file: cannot be blank")
+	}
+
+	config.TLSCertFile = "cert.pem"
+	err = Start(config, nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "tls_key_file: cannot be blank", "key missing")
+	}
+}
+
+func TestNewCertManager(t *testing.T) {
+	config := NewConfig()
+	config.AutocertDomains = []string{"api.example.org"}
+	config.AutocertCacheDir = t.Name()
+	m := newCertManager(config)
+
+	assert.NoError(t, m.HostPolicy(context.Background(), "api.example.org"))
+	assert.Error(t, m.HostPolicy(context.Background(), "example.org"))
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "http://api.example.org/suppliers?page=2", nil)
+	m.HTTPHandler(nil).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://api.example.org/suppliers?page=2", rec.Header().Get("Location"))
+}
+
+func TestConfigureHTTP2(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+
+	config := NewConfig()
+	srv := s.httpServer(":8443")
+	assert.NoError(t, configureHTTP2(srv, config))
+	assert.Contains(t, srv.TLSConfig.NextProtos, "h2")
+
+	config.HTTP2 = false
+	srv = &http.Server{}
+	assert.NoError(t, configureHTTP2(srv, config))
+	assert.NotNil(t, srv.TLSNextProto, "disabled")
+
+	config.HTTP2 = true
+	config.PlainHTTP = true
+	config.H2C = true
+	srv = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})}
+	assert.NoError(t, configureHTTP2(srv, config))
+
+	ts := httptest.NewServer(srv.Handler)
+	defer ts.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	res, err := client.Get(ts.URL)
+	if assert.NoError(t, err) {
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		assert.Equal(t, "HTTP/2.0", string(body))
+	}
+}
+
+func TestServer_HandleAdminConfigReload(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+	assert.NoError(t, s.applyConfig(NewConfig()))
+
+	reload := func() int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/admin/config/reload", nil)
+		cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": admin.PublicID})
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, reload())
+	assert.Equal(t, logrus.DebugLevel, s.logger.GetLevel())
+
+	logLevel := "warn"
+	origins := []string{"https://app.example.org"}
+	anonymousLimit, window := 1, time.Minute
+	jobAttempts := 10
+	s.loadConfig = func() (*Config, error) {
+		config := NewConfig()
+		config.DatabaseURL = "postgres://localhost/wt"
+		config.SessionKey = "6f1c0a7e9b2d4c3f8a5e1d7b0c9f2a4e"
+		config.PlainHTTP = true
+		config.LogLevel = logLevel
+		config.CORSAllowedOrigins = origins
+		config.RateLimitAnonymous = anonymousLimit
+		config.RateLimitWindow = Duration{window}
+		config.JobMaxAttempts = jobAttempts
+		return config, nil
+	}
+	assert.Equal(t, http.StatusNoContent, reload())
+	assert.Equal(t, logrus.WarnLevel, s.logger.GetLevel())
+
+	preflight := func(origin string) string {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodOptions, "/healthz", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		s.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+	webSocketOrigin := func(origin string) bool {
+		req, _ := http.NewRequest(http.MethodGet, "/private/notifications", nil)
+		req.Header.Set("Origin", origin)
+		return s.allowsWebSocketOrigin(req)
+	}
+	assert.Equal(t, "https://app.example.org", preflight("https://app.example.org"))
+	assert.Empty(t, preflight("http://moonshard.io"))
+	assert.True(t, webSocketOrigin("https://app.example.org"))
+	assert.False(t, webSocketOrigin("http://moonshard.io"))
+
+	logLevel = "loud"
+	assert.Equal(t, http.StatusUnprocessableEntity, reload())
+	assert.Equal(t, logrus.WarnLevel, s.logger.GetLevel(), "kept on invalid config")
+
+	logLevel = "info"
+	origins = []string{"https://*.example.org", "app.example.com"}
+	assert.Equal(t, http.StatusUnprocessableEntity, reload())
+	assert.Equal(t, logrus.WarnLevel, s.logger.GetLevel(), "kept on invalid config")
+	assert.Equal(t, "https://app.example.org", preflight("https://app.example.org"), "kept on invalid config")
+
+	origins = []string{"https://*.example.com"}
+	assert.Equal(t, http.StatusNoContent, reload())
+
+	s.rateLimiter = ratelimit.NewMemoryLimiter()
+	limited := func(client string) bool {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/suppliers/missing", nil)
+		req.RemoteAddr = client + ":1000"
+		s.ServeHTTP(rec, req)
+		return rec.Code == http.StatusTooManyRequests
+	}
+	assert.False(t, limited("10.0.0.1"))
+	assert.True(t, limited("10.0.0.1"))
+
+	anonymousLimit = 2
+	assert.Equal(t, http.StatusNoContent, reload())
+	assert.False(t, limited("10.0.0.2"))
+	assert.False(t, limited("10.0.0.2"))
+	assert.True(t, limited("10.0.0.2"))
+	assert.Equal(t, time.Minute, s.currentRateLimits().window)
+
+	window = 0
+	assert.Equal(t, http.StatusUnprocessableEntity, reload())
+	assert.Equal(t, time.Minute, s.currentRateLimits().window, "kept on invalid config")
+	window = time.Hour
+	assert.Equal(t, http.StatusNoContent, reload())
+	assert.Equal(t, time.Hour, s.currentRateLimits().window)
+	assert.Equal(t, "https://app.example.com", preflight("https://app.example.com"))
+	assert.Empty(t, preflight("https://app.example.org"))
+	assert.True(t, webSocketOrigin("https://app.example.com"))
+	assert.False(t, webSocketOrigin("https://app.example.org"))
+
+	// Settings that need a restart are checked too
+	jobAttempts = 0
+	logLevel = "error"
+	assert.Equal(t, http.StatusUnprocessableEntity, reload())
+	assert.Equal(t, logrus.InfoLevel, s.logger.GetLevel(), "kept on invalid config")
+	jobAttempts = 10
+
+	invalid := NewConfig()
+	invalid.LogLevel = "error"
+	invalid.CORSAllowedOrigins = []string{"app.example.com"}
+	assert.Error(t, s.applyConfig(invalid))
+	assert.Equal(t, logrus.InfoLevel, s.logger.GetLevel(), "nothing is applied")
+	assert.Equal(t, "https://app.example.com", preflight("https://app.example.com"))
+
+	signals := make(chan os.Signal, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.reloadOnSignal(ctx, signals)
+		close(done)
+	}()
+
+	logLevel = "error"
+	signals <- syscall.SIGHUP
+	for i := 0; i < 100 && s.logger.GetLevel() != logrus.ErrorLevel; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, logrus.ErrorLevel, s.logger.GetLevel())
+
+	cancel()
+	<-done
+}
+
+func TestServer_Metrics(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+	reg := prometheus.NewRegistry()
+	assert.NoError(t, s.metrics.register(reg))
+
+	request := func(method, path, token string) int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(`{"email": "user@example.org", "password": "password"}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	request(http.MethodPost, "/users", "")
+	request(http.MethodPost, "/sessions", "")
+	request(http.MethodGet, "/suppliers/2b5e1c7a-0d1e-4c4e-9a8b-2f6b0c1d2e3f", "")
+	request(http.MethodGet, "/no/such/path", "")
+
+	count := func(name string, labels map[string]string) float64 {
+		families, err := reg.Gather()
+		assert.NoError(t, err)
+		for _, f := range families {
+			if f.GetName() != name {
+				continue
+			}
+		metrics:
+			for _, m := range f.GetMetric() {
+				for _, l := range m.GetLabel() {
+					if labels[l.GetName()] != l.GetValue() {
+						continue metrics
+					}
+				}
+				return m.GetCounter().GetValue()
+			}
+		}
+		return 0
+	}
+
+	assert.Equal(t, 1.0, count("http_requests_total", map[string]string{"method": "GET", "route": "/suppliers/:id", "status": "404"}))
+	assert.Equal(t, 1.0, count("http_requests_total", map[string]string{"method": "GET", "route": "unmatched", "status": "404"}))
+	assert.Equal(t, 1.0, count("signups_total", nil))
+	assert.Equal(t, 1.0, count("logins_total", map[string]string{"result": "success"}))
+
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/metrics", ""))
+	s.metricsToken = "token"
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/metrics", ""))
+	assert.Equal(t, http.StatusUnauthorized, request(http.MethodGet, "/metrics", "guess"))
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/metrics", "token"))
+}
+
+type spanRecorder []*tracing.Span
+
+func (r *spanRecorder) Export(s *tracing.Span) {
+	*r = append(*r, s)
+}
+
+func TestServer_Trace(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+	spans := &spanRecorder{}
+	s.tracer = tracing.NewTracer(spans, 1)
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/suppliers/2b5e1c7a-0d1e-4c4e-9a8b-2f6b0c1d2e3f", nil)
+	req.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	s.ServeHTTP(rec, req)
+
+	if assert.Len(t, *spans, 1) {
+		span := (*spans)[0]
+		assert.Equal(t, "GET /suppliers/:id", span.Name)
+		assert.Equal(t, tracing.KindServer, span.Kind)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.Context.TraceID.String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent.String())
+		assert.Equal(t, rec.Code, span.Attributes["http.status_code"])
+		assert.Equal(t, rec.Header().Get("X-Request-ID"), span.Attributes["request_id"])
+	}
+}
+
+func TestServer_Pprof(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+	u := model.TestUser(t)
+	u.Email = "supplier@example.org"
+	store.User().Create(context.Background(), u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	request := func(path string, user *model.User) int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		if user != nil {
+			cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": user.PublicID})
+			req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		}
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, request("/debug/pprof/", admin), "disabled by default")
+
+	config := NewConfig()
+	config.Pprof = true
+	assert.NoError(t, s.applyConfig(config))
+
+	testCases := []struct {
+		name         string
+		path         string
+		user         *model.User
+		expectedCode int
+	}{
+		{
+			name:         "index",
+			path:         "/debug/pprof/",
+			user:         admin,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "named profile",
+			path:         "/debug/pprof/heap",
+			user:         admin,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "cmdline",
+			path:         "/debug/pprof/cmdline",
+			user:         admin,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not admin",
+			path:         "/debug/pprof/heap",
+			user:         u,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "not authenticated",
+			path:         "/debug/pprof/heap",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedCode, request(tc.path, tc.user))
+		})
+	}
+}
+
+// sentryRecorder is a Sentry transport keeping events
+type sentryRecorder struct {
+	events []*sentry.Event
+}
+
+func (r *sentryRecorder) Configure(sentry.ClientOptions)   {}
+func (r *sentryRecorder) Flush(timeout time.Duration) bool { return true }
+func (r *sentryRecorder) SendEvent(event *sentry.Event) {
+	r.events = append(r.events, event)
+}
+
+func TestServer_ReportErrors(t *testing.T) {
+	store := teststore.New()
+	u := model.TestUser(t)
+	store.User().Create(context.Background(), u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	events := &sentryRecorder{}
+	client, err := sentry.NewClient(sentry.ClientOptions{Transport: events})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.sentry = sentry.NewHub(client, sentry.NewScope())
+	s.logger.AddHook(&sentryHook{hub: s.sentry})
+
+	s.router.GET("/private/panic", s.AuthenticationUser(), func(c *gin.Context) {
+		panic("boom")
+	})
+	s.router.GET("/private/fail", s.AuthenticationUser(), func(c *gin.Context) {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+	})
+	s.routes["GET /private/panic"] = true
+	s.routes["GET /private/fail"] = true
+
+	request := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": u.PublicID})
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("/private/panic")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	rec = request("/private/fail")
+	request("/suppliers/2b5e1c7a-0d1e-4c4e-9a8b-2f6b0c1d2e3f")
+	s.logger.WithError(errors.New("disk full")).Error("backup failed")
+
+	if assert.Len(t, events.events, 3, "the 404 and the request logs aren't reported") {
+		panicked, failed, logged := events.events[0], events.events[1], events.events[2]
+		assert.Equal(t, "boom", panicked.Message)
+		assert.Equal(t, u.PublicID, panicked.User.ID)
+		assert.Equal(t, "/private/panic", panicked.Tags["route"])
+
+		assert.Contains(t, failed.Message, "GET /private/fail responded 500")
+		assert.Equal(t, rec.Header().Get("X-Request-ID"), failed.Tags["request_id"])
+		assert.Empty(t, failed.Request.Cookies)
+		assert.NotContains(t, failed.Request.Headers, "Cookie")
+
+		assert.Equal(t, "backup failed", logged.Message)
+		assert.Equal(t, "disk full", logged.Extra[logrus.ErrorKey])
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := NewConfig()
+	config.LogLevel = "warn"
+	config.LogFormat = "json"
+	config.LogOutput = dir + "/server.log"
+	logger, f, err := newLogger(config)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	logger.Info("skipped")
+	logger.WithField("request_id", "1").Warn("logged")
+	assert.NoError(t, f.Close())
+
+	data, err := ioutil.ReadFile(config.LogOutput)
+	assert.NoError(t, err)
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(data, &entry), "one JSON line")
+	assert.Equal(t, "logged", entry["msg"])
+	assert.Equal(t, "1", entry["request_id"])
+
+	config.LogOutput = dir + "/missing/server.log"
+	_, _, err = newLogger(config)
+	assert.Error(t, err)
+}
+
+func TestServer_LogRequest(t *testing.T) {
+	store := teststore.New()
+	u := model.TestUser(t)
+	store.User().Create(context.Background(), u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+	logger, hook := test.NewNullLogger()
+	s.logger = logger
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/private/whoami", nil)
+	cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": u.PublicID})
+	req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+	s.ServeHTTP(rec, req)
+
+	if assert.Len(t, hook.Entries, 2) {
+		started, completed := hook.Entries[0], hook.Entries[1]
+		assert.Equal(t, rec.Header().Get("X-Request-ID"), started.Data["request_id"])
+		assert.NotContains(t, started.Data, "user_id", "not authenticated yet")
+		assert.Equal(t, rec.Header().Get("X-Request-ID"), completed.Data["request_id"])
+		assert.Equal(t, u.PublicID, completed.Data["user_id"])
+		assert.Equal(t, http.StatusOK, completed.Data["status"])
+		assert.Equal(t, "/private/whoami", completed.Data["path"])
+	}
+}
+
+func TestServer_RecoverPanics(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+	logger, hook := test.NewNullLogger()
+	s.logger = logger
+
+	s.router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	s.routes["GET /panic"] = true
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/panic", nil)
+	s.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"error":"`+errInternalServerError+`"}`, rec.Body.String())
+
+	if assert.Len(t, hook.Entries, 3) {
+		recovered, completed := hook.Entries[1], hook.Entries[2]
+		assert.Equal(t, logrus.ErrorLevel, recovered.Level)
+		assert.Equal(t, "boom", recovered.Data["panic"])
+		assert.Contains(t, recovered.Data["stack"], "TestServer_RecoverPanics")
+		assert.Equal(t, rec.Header().Get("X-Request-ID"), recovered.Data["request_id"])
+		assert.Equal(t, http.StatusInternalServerError, completed.Data["status"])
+	}
+}
+
+func TestServer_LimitBody(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+	s.maxBodySize = 1024
+	s.maxUploadSize = 4096
+
+	testCases := []struct {
+		name         string
+		path         string
+		size         int
+		chunked      bool
+		expectedCode int
+	}{
+		{
+			name:         "within limit",
+			path:         "/users",
+			size:         512,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "declared too large",
+			path:         "/users",
+			size:         2048,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "chunked within limit",
+			path:         "/users",
+			size:         512,
+			chunked:      true,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "chunked too large",
+			path:         "/users",
+			size:         2048,
+			chunked:      true,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "upload within upload limit",
+			path:         "/private/onboarding/documents",
+			size:         2048,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "upload too large",
+			path:         "/private/onboarding/documents",
+			size:         8192,
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			body := bytes.Repeat([]byte("x"), tc.size)
+			req, _ := http.NewRequest(http.MethodPost, tc.path, bytes.NewReader(body))
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+
+			if tc.expectedCode == http.StatusRequestEntityTooLarge {
+				resp := map[string]interface{}{}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+				assert.Equal(t, errBodyTooLarge, resp["error"])
+				assert.NotZero(t, resp["max_bytes"])
+			}
+		})
+	}
+}
+
+func TestServer_Compress(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+	s.compression = newCompression(true, true, 100, []string{"application/json"}, []string{"/excluded"})
+
+	large := strings.Repeat("winding tree ", 50)
+	s.router.GET("/large", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"text": large})
+	})
+	s.router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"text": "small"})
+	})
+	s.router.GET("/binary", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/octet-stream", []byte(large))
+	})
+	s.router.GET("/excluded", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"text": large})
+	})
+	for _, path := range []string{"/large", "/small", "/binary", "/excluded"} {
+		s.routes["GET "+path] = true
+	}
+
+	testCases := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		coding         string
+	}{
+		{
+			name:           "gzip",
+			path:           "/large",
+			acceptEncoding: "gzip, deflate",
+			coding:         "gzip",
+		},
+		{
+			name:           "brotli preferred",
+			path:           "/large",
+			acceptEncoding: "gzip, br",
+			coding:         "br",
+		},
+		{
+			name:           "refused",
+			path:           "/large",
+			acceptEncoding: "gzip;q=0, br;q=0",
+		},
+		{
+			name:           "small json",
+			path:           "/small",
+			acceptEncoding: "gzip",
+		},
+		{
+			name:           "other content type",
+			path:           "/binary",
+			acceptEncoding: "gzip",
+		},
+		{
+			name:           "excluded route",
+			path:           "/excluded",
+			acceptEncoding: "gzip",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			s.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			assert.Equal(t, tc.coding, rec.Header().Get("Content-Encoding"))
+			body := io.Reader(rec.Body)
+			switch tc.coding {
+			case "gzip":
+				gz, err := gzip.NewReader(rec.Body)
+				if !assert.NoError(t, err) {
+					return
+				}
+				body = gz
+			case "br":
+				body = brotli.NewReader(rec.Body)
+			}
+
+			data, err := ioutil.ReadAll(body)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, data)
+			if tc.path != "/binary" {
+				assert.True(t, json.Valid(data))
+			}
+		})
+	}
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	store := teststore.New()
+	u := model.TestUser(t)
+	store.User().Create(context.Background(), u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+	s.rateLimiter = ratelimit.NewMemoryLimiter()
+	s.setRateLimits(rateLimits{window: time.Minute, anonymous: 2, authenticated: 3, search: 1})
+
+	request := func(path, remoteAddr string, user *model.User) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		if user != nil {
+			cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": user.PublicID})
+			req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		}
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	supplier := "/suppliers/2b5e1c7a-0d1e-4c4e-9a8b-2f6b0c1d2e3f"
+	assert.Equal(t, "1", request(supplier, "10.0.0.1:1000", nil).Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, http.StatusNotFound, request(supplier, "10.0.0.1:1001", nil).Code)
+	rec := request(supplier, "10.0.0.1:1002", nil)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusNotFound, request(supplier, "10.0.0.2:1000", nil).Code, "other clients aren't limited")
+	assert.Equal(t, http.StatusOK, request("/healthz", "10.0.0.1:1003", nil).Code, "health checks aren't limited")
+
+	// Flight search has a bucket of its own on top
+	assert.NotEqual(t, http.StatusTooManyRequests, request("/search/flights", "10.0.0.3:1000", nil).Code)
+	assert.Equal(t, http.StatusTooManyRequests, request("/search/flights", "10.0.0.3:1001", nil).Code)
+
+	// Users are counted apart from their IP
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, request("/private/whoami", "10.0.0.1:2000", u).Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, request("/private/whoami", "10.0.0.4:2000", u).Code)
+}
+
+func TestServer_CORS(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+	err := s.setCORS(
+		[]string{"https://app.example.org", "https://*.example.com"},
+		defaultCORSMethods, defaultCORSHeaders, true, time.Hour,
+	)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		method       string
+		origin       string
+		expectedCode int
+		allowed      bool
+	}{
+		{
+			name:         "same origin",
+			method:       http.MethodGet,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "exact origin",
+			method:       http.MethodGet,
+			origin:       "https://app.example.org",
+			expectedCode: http.StatusOK,
+			allowed:      true,
+		},
+		{
+			name:         "subdomain",
+			method:       http.MethodGet,
+			origin:       "https://a.b.example.com",
+			expectedCode: http.StatusOK,
+			allowed:      true,
+		},
+		{
+			name:         "preflight",
+			method:       http.MethodOptions,
+			origin:       "https://www.example.com",
+			expectedCode: http.StatusNoContent,
+			allowed:      true,
+		},
+		{
+			name:         "wildcard parent domain",
+			method:       http.MethodGet,
+			origin:       "https://example.com",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "lookalike domain",
+			method:       http.MethodGet,
+			origin:       "https://evilexample.com",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "other scheme",
+			method:       http.MethodGet,
+			origin:       "http://app.example.org",
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, "/healthz", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			}
+			s.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.allowed {
+				assert.Equal(t, tc.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+			} else {
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+			}
+		})
+	}
+}
+
+func TestServer_ClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "2001:db8::1"})
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		expectedIP   string
+	}{
+		{
+			name:       "direct",
+			remoteAddr: "203.0.113.7:4000",
+			expectedIP: "203.0.113.7",
+		},
+		{
+			name:         "untrusted forwarding headers",
+			remoteAddr:   "203.0.113.7:4000",
+			forwardedFor: []string{"198.51.100.1"},
+			realIP:       "198.51.100.2",
+			expectedIP:   "203.0.113.7",
+		},
+		{
+			name:         "trusted proxy",
+			remoteAddr:   "10.0.0.2:4000",
+			forwardedFor: []string{"198.51.100.1"},
+			expectedIP:   "198.51.100.1",
+		},
+		{
+			name:         "spoofed hops left of the client",
+			remoteAddr:   "10.0.0.2:4000",
+			forwardedFor: []string{"1.1.1.1, 198.51.100.1", "10.0.0.3"},
+			expectedIP:   "198.51.100.1",
+		},
+		{
+			name:         "malformed hop",
+			remoteAddr:   "10.0.0.2:4000",
+			forwardedFor: []string{"unknown, 10.0.0.3"},
+			expectedIP:   "10.0.0.3",
+		},
+		{
+			name:       "real ip",
+			remoteAddr: "[2001:db8::1]:4000",
+			realIP:     "198.51.100.2",
+			expectedIP: "198.51.100.2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for _, hops := range tc.forwardedFor {
+				req.Header.Add("X-Forwarded-For", hops)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+
+			assert.Equal(t, tc.expectedIP, proxies.clientIP(req))
+		})
+	}
+
+	// Rate limits count the client, not the proxy it came through
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+	s.trustedProxies = proxies
+	s.rateLimiter = ratelimit.NewMemoryLimiter()
+	s.setRateLimits(rateLimits{window: time.Minute, anonymous: 1})
+
+	request := func(client string) int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/suppliers/missing", nil)
+		req.RemoteAddr = "10.0.0.2:4000"
+		req.Header.Set("X-Forwarded-For", client)
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.NotEqual(t, http.StatusTooManyRequests, request("198.51.100.1"))
+	assert.NotEqual(t, http.StatusTooManyRequests, request("198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, request("198.51.100.1"))
+}
+
+func TestServer_SetRequestID(t *testing.T) {
+	s := NewServer(teststore.New(), sessions.NewCookieStore([]byte("secret")))
+	s.trustedProxies, _ = parseTrustedProxies([]string{"10.0.0.0/8"})
+
+	var contextID string
+	s.router.GET("/id", func(c *gin.Context) {
+		contextID, _ = requestid.FromContext(c.Request.Context())
+	})
+
+	testCases := []struct {
+		name        string
+		remoteAddr  string
+		requestID   string
+		traceparent string
+		expectedID  string
+	}{
+		{
+			name:       "trusted",
+			remoteAddr: "10.0.0.2:4000",
+			requestID:  "upstream-42",
+			expectedID: "upstream-42",
+		},
+		{
+			name:       "untrusted",
+			remoteAddr: "203.0.113.7:4000",
+			requestID:  "upstream-42",
+		},
+		{
+			name:       "invalid",
+			remoteAddr: "10.0.0.2:4000",
+			requestID:  "upstream 42",
+		},
+		{
+			name:        "trace id",
+			remoteAddr:  "10.0.0.2:4000",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectedID:  "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/id", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.requestID != "" {
+				req.Header.Set("X-Request-ID", tc.requestID)
+			}
+			if tc.traceparent != "" {
+				req.Header.Set("traceparent", tc.traceparent)
+			}
+			s.ServeHTTP(rec, req)
+
+			id := rec.Header().Get("X-Request-ID")
+			if tc.expectedID != "" {
+				assert.Equal(t, tc.expectedID, id)
+			} else {
+				assert.NotEqual(t, tc.requestID, id)
+				_, err := uuid.Parse(id)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, id, contextID)
+		})
+	}
+}
+
+func TestServer_Maintenance(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+	u := model.TestUser(t)
+	u.Email = "supplier@example.org"
+	store.User().Create(context.Background(), u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	request := func(method, path string, body interface{}, user *model.User) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		if body != nil {
+			json.NewEncoder(b).Encode(body)
+		}
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, b)
+		if user != nil {
+			cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": user.PublicID})
+			req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		}
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	config := NewConfig()
+	config.Maintenance = true
+	config.MaintenanceMessage = "database upgrade"
+	assert.NoError(t, s.applyConfig(config))
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		user         *model.User
+		expectedCode int
+	}{
+		{
+			name:         "public route",
+			method:       http.MethodGet,
+			path:         "/search/flights",
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "private route",
+			method:       http.MethodGet,
+			path:         "/private/whoami",
+			user:         u,
+			expectedCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "health check",
+			method:       http.MethodGet,
+			path:         "/healthz",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "admin",
+			method:       http.MethodGet,
+			path:         "/admin/maintenance",
+			user:         admin,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "admin route, not admin",
+			method:       http.MethodGet,
+			path:         "/admin/maintenance",
+			user:         u,
+			expectedCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := request(tc.method, tc.path, nil, tc.user)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.expectedCode == http.StatusServiceUnavailable {
+				assert.Equal(t, "300", rec.Header().Get("Retry-After"))
+				assert.JSONEq(t, `{"error":"service under maintenance","message":"database upgrade"}`, rec.Body.String())
+			}
+		})
+	}
+
+	rec := request(http.MethodPut, "/admin/maintenance", map[string]interface{}{"enabled": false}, admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"enabled":false}`, rec.Body.String())
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/private/whoami", nil, u).Code)
+
+	rec = request(http.MethodPut, "/admin/maintenance", map[string]interface{}{"enabled": true, "retry_after": 60}, admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = request(http.MethodGet, "/private/whoami", nil, u)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+}
+
+func TestServer_Features(t *testing.T) {
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(context.Background(), admin)
+	u := model.TestUser(t)
+	u.Email = "supplier@example.org"
+	store.User().Create(context.Background(), u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	config := NewConfig()
+	config.FeatureFlags = []string{"export_v2", "search_v2:0%", "search_v2:user:" + u.PublicID, "fare_rules:off"}
+	assert.NoError(t, s.applyConfig(config))
+
+	request := func(method, path string, body interface{}, user *model.User) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		if body != nil {
+			json.NewEncoder(b).Encode(body)
+		}
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, b)
+		if user != nil {
+			cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": user.PublicID})
+			req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		}
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/private/features", nil, u)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"features":["export_v2","search_v2"]}`, rec.Body.String())
+	rec = request(http.MethodGet, "/private/features", nil, admin)
+	assert.JSONEq(t, `{"features":["export_v2"]}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPut, "/admin/features/fare_rules", map[string]interface{}{"enabled": true}, u).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, request(http.MethodPut, "/admin/features/fare_rules", map[string]interface{}{"percentage": 120}, admin).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPut, "/admin/features/fare_rules", map[string]interface{}{"percentage": 100}, admin).Code)
+	assert.Equal(t, http.StatusNoContent, request(http.MethodDelete, "/admin/features/export_v2", nil, admin).Code)
+
+	rec = request(http.MethodGet, "/private/features", nil, admin)
+	assert.JSONEq(t, `{"features":["fare_rules"]}`, rec.Body.String())
+
+	rec = request(http.MethodGet, "/admin/features", nil, admin)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"search_v2":{"enabled":false,"percentage":0,"users":["`+u.PublicID+`"]}`)
+
+	assert.NoError(t, s.applyConfig(config))
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+	c.Set("ctxKeyUser", u)
+	assert.True(t, s.featureEnabled(c, "export_v2"), "reset by a reload")
+	assert.True(t, s.featureEnabled(c, "search_v2"))
+	assert.False(t, s.featureEnabled(c, "fare_rules"))
+}
+
+func TestServer_HandleAdminJobs(t *testing.T) {
+	ctx := context.Background()
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(ctx, admin)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	// A dead job, a done one and a pending one
+	for _, kind := range []string{"mail.send", "mail.send", "webhook.send"} {
+		j, _ := model.NewJob(kind, nil, 1, time.Now())
+		store.Job().Create(ctx, j)
+	}
+	store.Job().Claim(ctx, []string{"mail.send"}, 2, time.Minute)
+	store.Job().Fail(ctx, 1, 1, "unavailable", nil)
+	store.Job().Complete(ctx, 2, 1)
+
+	request := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		b := &bytes.Buffer{}
+		if body != nil {
+			json.NewEncoder(b).Encode(body)
+		}
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, b)
+		cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": admin.PublicID})
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodGet, "/admin/jobs?kind=mail.send", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Jobs  []*model.Job `json:"jobs"`
+		Total int          `json:"total"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if assert.Equal(t, 2, list.Total) {
+		assert.Equal(t, 2, list.Jobs[0].ID, "newest first")
+	}
+
+	rec = request(http.MethodGet, "/admin/jobs/1", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"last_error":"unavailable"`)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "/admin/jobs/9", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "/admin/jobs/first", nil).Code)
+
+	testCases := []struct {
+		name         string
+		id           int
+		body         interface{}
+		expectedCode int
+	}{
+		{
+			name:         "dead",
+			id:           1,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "done",
+			id:           2,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "pending, later",
+			id:           3,
+			body:         map[string]string{"run_at": time.Now().Add(time.Hour).Format(time.RFC3339)},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid run_at",
+			id:           3,
+			body:         map[string]string{"run_at": "tomorrow"},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := request(http.MethodPost, fmt.Sprintf("/admin/jobs/%d/retry", tc.id), tc.body)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	j, _ := store.Job().Find(ctx, 1)
+	assert.Equal(t, model.JobPending, j.State)
+	assert.Equal(t, 0, j.Attempts)
+	j, _ = store.Job().Find(ctx, 3)
+	assert.True(t, j.RunAt.After(time.Now().Add(50*time.Minute)))
+}
+
+func TestServer_HandleAdminSchedulesList(t *testing.T) {
+	ctx := context.Background()
+	store := teststore.New()
+	admin := model.TestUser(t)
+	admin.IsAdmin = true
+	store.User().Create(ctx, admin)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+
+	request := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/admin/schedules", nil)
+		cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": admin.PublicID})
+		req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"schedules":[]}`, rec.Body.String())
+
+	logger, _ := test.NewNullLogger()
+	s.scheduler = scheduler.New(store, logger, time.Minute)
+	s.scheduler.Add(taskRetentionPurge, "@daily", func(ctx context.Context) error {
+		return errors.New("database unavailable")
+	})
+	s.scheduler.Trigger(ctx, taskRetentionPurge, time.Now())
+
+	rec = request()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Schedules []*scheduledTask `json:"schedules"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if assert.Len(t, list.Schedules, 1) {
+		task := list.Schedules[0]
+		assert.Equal(t, taskRetentionPurge, task.Name)
+		assert.Equal(t, "@daily", task.Spec)
+		assert.Equal(t, time.Now().UTC().Truncate(24*time.Hour).Add(24*time.Hour), task.NextRunAt.UTC())
+		assert.Equal(t, "database unavailable", task.LastError)
+		assert.NotNil(t, task.LastRunAt)
+	}
+}
+
+func TestServer_HandleNotifications(t *testing.T) {
+	ctx := context.Background()
+	store := teststore.New()
+	u := model.TestUser(t)
+	store.User().Create(ctx, u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+	cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": u.PublicID})
+	cookie := fmt.Sprintf("%s=%s", sessionName, cookieStr)
+
+	request := func(origin string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/private/notifications", nil)
+		req.Header.Set("Cookie", cookie)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		s.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, request("").Code, "without a hub")
+
+	logger, _ := test.NewNullLogger()
+	s.events = events.NewHub(store, logger, time.Minute, time.Minute)
+	assert.NoError(t, s.events.Poll(ctx))
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/private/notifications", nil)
+	req.Header.Set("Cookie", cookie)
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUpgradeRequired, rec.Code)
+
+	assert.Equal(t, http.StatusForbidden, request("https://example.org").Code, "other sites can't use the session")
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// A dashboard served from an allowed CORS origin
+	config, _ := websocket.NewConfig("ws"+strings.TrimPrefix(ts.URL, "http")+"/private/notifications?topics=onboarding.*", "http://moonshard.io")
+	config.Header.Set("Cookie", cookie)
+	ws, err := websocket.DialConfig(config)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ws.Close()
+
+	// Only the events of the user are pushed
+	for _, userID := range []int{u.ID + 1, u.ID} {
+		o := model.NewOnboarding(userID)
+		o.Submit(1)
+		store.Onboarding().Save(ctx, o)
+	}
+	store.Flight().Create(ctx, model.TestFlight(t))
+	assert.NoError(t, s.events.Poll(ctx))
+
+	var e struct {
+		Topic   string                 `json:"topic"`
+		Payload map[string]interface{} `json:"payload"`
+	}
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if assert.NoError(t, websocket.JSON.Receive(ws, &e)) {
+		assert.Equal(t, "onboarding.submitted", e.Topic)
+		assert.Equal(t, u.PublicID, e.Payload["supplier_id"])
+		assert.NotContains(t, e.Payload, "user_id", "internal ids aren't pushed")
+	}
+
+	ws.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	assert.Error(t, websocket.JSON.Receive(ws, &e), "flights aren't in the topics")
+}
+
+func TestServer_HandleEventsStream(t *testing.T) {
+	ctx := context.Background()
+	store := teststore.New()
+	u := model.TestUser(t)
+	store.User().Create(ctx, u)
+
+	secretKey := []byte("secret")
+	s := NewServer(store, sessions.NewCookieStore(secretKey))
+	sc := securecookie.New(secretKey, nil)
+	cookieStr, _ := sc.Encode(sessionName, map[interface{}]interface{}{"user_id": u.PublicID})
+	cookie := fmt.Sprintf("%s=%s", sessionName, cookieStr)
+
+	newRequest := func(url string) *http.Request {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Cookie", cookie)
+		// The stream resumes after the first event
+		req.Header.Set("Last-Event-ID", "1")
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, newRequest("/private/events/stream"))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "without a hub")
+
+	logger, _ := test.NewNullLogger()
+	s.events = events.NewHub(store, logger, time.Minute, time.Minute)
+	assert.NoError(t, s.events.Poll(ctx))
+
+	// The user's onboarding is submitted, then another user's, then the
+	// user's is approved
+	o := model.NewOnboarding(u.ID)
+	o.Submit(1)
+	store.Onboarding().Save(ctx, o)
+	other := model.NewOnboarding(u.ID + 1)
+	other.Submit(1)
+	store.Onboarding().Save(ctx, other)
+	o.Approve(2)
+	store.Onboarding().Save(ctx, o)
+	assert.NoError(t, s.events.Poll(ctx))
+
+	// Streams that aren't HTTP/1 end before the write timeout
+	s.WriteTimeout = 100 * time.Millisecond
+	rec = httptest.NewRecorder()
+	req := newRequest("/private/events/stream")
+	req.ProtoMajor = 2
+	s.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rec.Body.String(), "retry: 2000\n\nid: 3\ndata: "), rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `"topic":"onboarding.approved"`)
+	assert.NotContains(t, rec.Body.String(), "id: 2\n")
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := http.DefaultClient.Do(newRequest(ts.URL + "/private/events/stream?topics=flight.created,onboarding.*"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "id: ") {
+				lines <- scanner.Text()
+			}
+		}
+		close(lines)
+	}()
+
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(time.Second):
+			return ""
+		}
+	}
+
+	// The missed event, then the new ones
+	assert.Equal(t, "id: 3", next())
+	store.Flight().Create(ctx, model.TestFlight(t))
+	assert.NoError(t, s.events.Poll(ctx))
+	assert.Equal(t, "id: 4", next())
+}
@@ -0,0 +1,72 @@
+package apiserver
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// serve runs listen, which serves srv, until it fails or a signal arrives.
+// It then stops accepting connections and waits up to timeout for in-flight
+// requests to complete.
+func serve(srv *http.Server, listen func() error, signals <-chan os.Signal, timeout time.Duration, logger *logrus.Logger) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- listen()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-signals:
+		logger.Infof("received %s, shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	if err := <-errc; err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// jobs runs the background jobs of the server so they can be waited for
+type jobs struct {
+	ctx context.Context
+	wg  sync.WaitGroup
+}
+
+// Go runs job in the background until the jobs' context is done
+func (j *jobs) Go(job func(ctx context.Context)) {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		job(j.ctx)
+	}()
+}
+
+// Wait waits up to timeout for the jobs to return and reports whether they did
+func (j *jobs) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		j.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,47 @@
+package apiserver
+
+import (
+	"net/http"
+	"winding-tree-server/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleSupplierGet returns an approved supplier's public profile with its on-chain organizations.
+// A supplier is verified when one of them is active and holds the minimum Lif deposit.
+func (s *server) handleSupplierGet(c *gin.Context) {
+	u, ok := s.supplierParam(c)
+	if !ok {
+		return
+	}
+	id := u.ID
+
+	o, err := s.store.Onboarding().Find(c.Request.Context(), id)
+	if err == store.ErrRecordNotFound || (err == nil && !o.IsApproved()) {
+		respondWithError(c, http.StatusNotFound, errNotFound)
+		return
+	}
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	orgs, err := s.store.OrgID().FindByUser(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, errInternalServerError)
+		return
+	}
+
+	verified := false
+	for _, o := range orgs {
+		if o.IsActive && o.HasDeposit(s.minLifDeposit) {
+			verified = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       u.PublicID,
+		"verified": verified,
+		"orgids":   orgs,
+	})
+}
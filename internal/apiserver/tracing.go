@@ -0,0 +1,44 @@
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"winding-tree-server/internal/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// trace records a span per request, continuing the trace of the caller when
+// it sent a traceparent header
+func (s *server) trace() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.tracer == nil {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		if parent, ok := tracing.Extract(c.Request.Header); ok {
+			ctx = tracing.ContextWithSpanContext(ctx, parent)
+		}
+
+		route := s.routePattern(c)
+		ctx, span := s.tracer.Start(ctx, c.Request.Method+" "+route, tracing.KindServer)
+		span.SetAttribute("http.method", c.Request.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.target", c.Request.URL.Path)
+		if id, ok := c.Get("ctxKeyRequestID"); ok {
+			span.SetAttribute("request_id", id)
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttribute("http.status_code", status)
+		if status >= 500 {
+			span.SetError(errors.New(http.StatusText(status)))
+		}
+		span.End()
+	}
+}
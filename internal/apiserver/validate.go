@@ -0,0 +1,279 @@
+package apiserver
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+	"winding-tree-server/internal/features"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// minSessionKeyLength is the size of a key for HMAC-SHA256, which signs sessions
+	minSessionKeyLength = 32
+	// minSessionKeyBytes distinct bytes rule out keys like "secretsecret..."
+	minSessionKeyBytes = 12
+)
+
+// Validate checks that the server can start with c, reporting all problems
+// at once by their config keys
+func (c *Config) Validate() error {
+	tlsFiles := !c.PlainHTTP && len(c.AutocertDomains) == 0
+
+	err := validation.ValidateStruct(c,
+		validation.Field(&c.BindAddress, validation.Required, validation.By(isHostPort)),
+		validation.Field(&c.LogLevel, validation.By(isLogLevel)),
+		validation.Field(&c.LogFormat, validation.In("text", "json")),
+		validation.Field(&c.DatabaseDriver, validation.Required, validation.In("postgres", "sqlite3")),
+		validation.Field(&c.DatabaseURL, validation.Required),
+		validation.Field(&c.SessionKey, validation.Required, validation.By(isSessionKey)),
+		validation.Field(&c.MinLifDeposit, validation.By(isInteger)),
+		validation.Field(&c.Mailer, validation.In("smtp", "sendgrid")),
+		validation.Field(&c.MailFrom, validation.By(requiredIf(c.Mailer != ""))),
+		validation.Field(&c.SMTPAddress, validation.By(requiredIf(c.Mailer == "smtp")), validation.By(isHostPort)),
+		validation.Field(&c.SendGridAPIKey, validation.By(requiredIf(c.Mailer == "sendgrid"))),
+		validation.Field(&c.MaxOpenConns, validation.Min(0)),
+		validation.Field(&c.MaxIdleConns, validation.Min(0)),
+		validation.Field(&c.MaxBodySize, validation.Min(int64(0))),
+		validation.Field(&c.MaxUploadSize, validation.Min(int64(0))),
+		validation.Field(&c.CompressionMinSize, validation.Min(0)),
+		validation.Field(&c.FeatureFlags, validation.By(areFeatureFlags)),
+		validation.Field(&c.PublicURL, validation.By(isPublicURL)),
+		validation.Field(&c.TrustedProxies, validation.By(areTrustedProxies)),
+		validation.Field(&c.CORSAllowedOrigins, validation.By(c.areCORSOrigins)),
+		validation.Field(&c.ContractEventsMaxAttempts, validation.Required, validation.Min(1)),
+		validation.Field(&c.ContractEventsRetryBaseDelay, validation.By(isPositiveDuration)),
+		validation.Field(&c.ContractEventsRetryMaxDelay, validation.By(isPositiveDuration)),
+		validation.Field(&c.OutboxMaxAttempts, validation.Required, validation.Min(1)),
+		validation.Field(&c.OutboxRetryBaseDelay, validation.By(isPositiveDuration)),
+		validation.Field(&c.OutboxRetryMaxDelay, validation.By(isPositiveDuration)),
+		validation.Field(&c.JobWorkers, validation.Min(0)),
+		validation.Field(&c.JobPollInterval, validation.By(isPositiveDuration)),
+		validation.Field(&c.JobLease, validation.By(isPositiveDuration)),
+		validation.Field(&c.JobMaxAttempts, validation.Required, validation.Min(1)),
+		validation.Field(&c.JobRetryBaseDelay, validation.By(isPositiveDuration)),
+		validation.Field(&c.JobRetryMaxDelay, validation.By(isPositiveDuration)),
+		validation.Field(&c.Schedules, validation.By(areSchedules)),
+		validation.Field(&c.ScheduleLease, validation.By(isPositiveDuration)),
+		validation.Field(&c.RetentionInterval, validation.By(isRetentionInterval)),
+		validation.Field(&c.EventsPollInterval, validation.By(isEventsPollInterval)),
+		validation.Field(&c.EventsGapTimeout, validation.By(isPositiveDuration)),
+		validation.Field(&c.RateLimitWindow, validation.By(positiveDurationIf(c.RateLimitURL != ""))),
+		validation.Field(&c.EncryptionKeyID, validation.By(requiredIf(len(c.EncryptionKeys) > 0))),
+		validation.Field(&c.EncryptionKeys, validation.By(c.areEncryptionKeys)),
+		validation.Field(&c.TLSCertFile, validation.By(requiredIf(tlsFiles)), validation.By(isReadableFile)),
+		validation.Field(&c.TLSKeyFile, validation.By(requiredIf(tlsFiles)), validation.By(isReadableFile)),
+		validation.Field(&c.TracingServiceName, validation.By(requiredIf(c.TracingEndpoint != ""))),
+		validation.Field(&c.TracingSampleRatio, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&c.AutocertHTTPAddress, validation.By(requiredIf(len(c.AutocertDomains) > 0)), validation.By(isHostPort)),
+	)
+
+	errs, ok := err.(validation.Errors)
+	if !ok {
+		return err
+	}
+
+	// Report the keys of the config file rather than Go field names
+	keys := validation.Errors{}
+	t := reflect.TypeOf(c).Elem()
+	for name, err := range errs {
+		if f, ok := t.FieldByName(name); ok {
+			name = f.Tag.Get("toml")
+		}
+		keys[name] = err
+	}
+
+	return keys
+}
+
+func requiredIf(condition bool) validation.RuleFunc {
+	return func(value interface{}) error {
+		if condition {
+			return validation.Validate(value, validation.Required)
+		}
+		return nil
+	}
+}
+
+func positiveDurationIf(condition bool) validation.RuleFunc {
+	return func(value interface{}) error {
+		if condition {
+			return isPositiveDuration(value)
+		}
+		return nil
+	}
+}
+
+func isHostPort(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+
+	_, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return errors.New("must be a host:port address")
+	}
+
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return errors.New("must have a numeric port")
+	}
+
+	return nil
+}
+
+func isPositiveDuration(value interface{}) error {
+	if d, _ := value.(Duration); d.Duration <= 0 {
+		return errors.New("must be a positive duration")
+	}
+
+	return nil
+}
+
+func isLogLevel(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+
+	if _, err := logrus.ParseLevel(s); err != nil {
+		return errors.New("must be a log level like info or debug")
+	}
+
+	return nil
+}
+
+func isSessionKey(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+
+	if len(s) < minSessionKeyLength {
+		return fmt.Errorf("must be at least %d bytes", minSessionKeyLength)
+	}
+
+	distinct := map[byte]bool{}
+	for i := 0; i < len(s); i++ {
+		distinct[s[i]] = true
+	}
+
+	if len(distinct) < minSessionKeyBytes {
+		return errors.New("is too predictable, use a random key")
+	}
+
+	return nil
+}
+
+func isInteger(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+
+	if _, ok := new(big.Int).SetString(s, 10); !ok {
+		return errors.New("must be an integer")
+	}
+
+	return nil
+}
+
+func isReadableFile(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+
+	f, err := os.Open(s)
+	if err != nil {
+		return errors.New("cannot be read")
+	}
+
+	return f.Close()
+}
+
+func (c *Config) areEncryptionKeys(value interface{}) error {
+	if len(c.EncryptionKeys) == 0 {
+		return nil
+	}
+
+	_, err := newSealer(c)
+
+	return err
+}
+
+func (c *Config) areCORSOrigins(value interface{}) error {
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			if c.CORSAllowCredentials {
+				return errCORSCredentials
+			}
+			continue
+		}
+
+		if _, err := parseOrigin(origin); err != nil {
+			return fmt.Errorf("%s %v", origin, err)
+		}
+	}
+
+	return nil
+}
+
+func areTrustedProxies(value interface{}) error {
+	proxies, _ := value.([]string)
+	_, err := parseTrustedProxies(proxies)
+
+	return err
+}
+
+func areFeatureFlags(value interface{}) error {
+	specs, _ := value.([]string)
+	_, err := features.Parse(specs)
+
+	return err
+}
+
+func areSchedules(value interface{}) error {
+	specs, _ := value.([]string)
+	_, err := parseSchedules(specs)
+
+	return err
+}
+
+func isRetentionInterval(value interface{}) error {
+	if d, _ := value.(Duration); d.Duration != 0 && d.Duration < time.Second {
+		return errors.New("must be at least 1s, or zero to disable the purge")
+	}
+
+	return nil
+}
+
+func isEventsPollInterval(value interface{}) error {
+	if d, _ := value.(Duration); d.Duration != 0 && d.Duration < 100*time.Millisecond {
+		return errors.New("must be at least 100ms, or zero to disable the events")
+	}
+
+	return nil
+}
+
+func isPublicURL(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("must be an http(s) URL such as https://api.example.com")
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+// Package backoff computes the delays between retries of failed work, which
+// double with each attempt up to a limit.
+package backoff
+
+import (
+	"math"
+	"time"
+)
+
+// Delay is the delay before the attempt after attempt, starting at base and
+// doubling with each attempt up to max; max of 0 or less doesn't limit it
+func Delay(base, max time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && (max <= 0 || delay < max) && delay <= math.MaxInt64/2; i++ {
+		delay *= 2
+	}
+
+	if max > 0 && delay > max {
+		delay = max
+	}
+
+	return delay
+}
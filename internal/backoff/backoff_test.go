@@ -0,0 +1,40 @@
+package backoff
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	testCases := []struct {
+		name   string
+		max    time.Duration
+		delays []time.Duration
+	}{
+		{
+			name:   "limited",
+			max:    5 * time.Minute,
+			delays: []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute},
+		},
+		{
+			name:   "unlimited",
+			delays: []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for attempt, delay := range tc.delays {
+				assert.Equal(t, delay, Delay(time.Minute, tc.max, attempt+1))
+			}
+		})
+	}
+}
+
+func TestDelay_Overflow(t *testing.T) {
+	assert.True(t, Delay(time.Minute, 0, 100) > 0)
+	assert.True(t, Delay(time.Minute, math.MaxInt64, 100) > 0)
+}
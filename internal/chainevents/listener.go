@@ -0,0 +1,214 @@
+package chainevents
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/backoff"
+	"winding-tree-server/internal/ethereum"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	cursorName    = "contract_events"
+	maxBlockRange = 1000
+	dispatchBatch = 100
+)
+
+// Client is the part of the Ethereum client the listener needs
+type Client interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	Logs(ctx context.Context, addresses []string, fromBlock, toBlock uint64) ([]*ethereum.Log, error)
+}
+
+// Handler processes a persisted event. The handlers of a topic run in the
+// order they were registered; an error stops there and the event is retried
+// from the failed handler on. Handlers may still see the same event more
+// than once and must be idempotent.
+type Handler func(ctx context.Context, e *model.ContractEvent) error
+
+// Config ...
+type Config struct {
+	Addresses     []string
+	Confirmations uint64
+	StartBlock    uint64
+	PollInterval  time.Duration
+	// MaxAttempts of an event, after which it has failed and is no longer
+	// dispatched
+	MaxAttempts int
+	// RetryBaseDelay doubles after each failed attempt, up to RetryMaxDelay
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// Listener polls contract logs that are Confirmations blocks deep, stores
+// each one exactly once and dispatches it to the handlers registered for its topic
+type Listener struct {
+	client   Client
+	store    store.Store
+	logger   *logrus.Logger
+	config   Config
+	handlers map[string][]Handler
+}
+
+// NewListener ...
+func NewListener(client Client, store store.Store, logger *logrus.Logger, config Config) *Listener {
+	return &Listener{
+		client:   client,
+		store:    store,
+		logger:   logger,
+		config:   config,
+		handlers: make(map[string][]Handler),
+	}
+}
+
+// Handle registers a handler for an event signature such as "OrganizationCreated(bytes32,address)".
+// Handlers must be registered before Run.
+func (l *Listener) Handle(signature string, h Handler) {
+	topic := ethereum.EventTopic(signature)
+	l.handlers[topic] = append(l.handlers[topic], h)
+}
+
+// Run polls until ctx is done
+func (l *Listener) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := l.Poll(ctx); err != nil {
+			l.logger.Errorf("contract events poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches newly confirmed logs and dispatches all unprocessed events
+func (l *Listener) Poll(ctx context.Context) error {
+	if err := l.fetch(ctx); err != nil {
+		return err
+	}
+
+	return l.dispatch(ctx)
+}
+
+// fetch ...
+func (l *Listener) fetch(ctx context.Context) error {
+	head, err := l.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+
+	if head < l.config.Confirmations {
+		return nil
+	}
+	confirmed := head - l.config.Confirmations
+
+	cursor, err := l.store.ContractEvent().Cursor(ctx, cursorName)
+	if err != nil {
+		return err
+	}
+
+	from := cursor + 1
+	if from < l.config.StartBlock {
+		from = l.config.StartBlock
+	}
+
+	for from <= confirmed {
+		to := from + maxBlockRange - 1
+		if to > confirmed {
+			to = confirmed
+		}
+
+		logs, err := l.client.Logs(ctx, l.config.Addresses, from, to)
+		if err != nil {
+			return err
+		}
+
+		for _, lg := range logs {
+			if len(lg.Topics) == 0 {
+				continue
+			}
+
+			err := l.store.ContractEvent().Create(ctx, &model.ContractEvent{
+				Address:     lg.Address,
+				Topic:       lg.Topics[0],
+				Topics:      lg.Topics,
+				Data:        lg.Data,
+				BlockNumber: lg.BlockNumber,
+				BlockHash:   lg.BlockHash,
+				TxHash:      lg.TxHash,
+				LogIndex:    lg.LogIndex,
+			})
+			if err != nil && err != store.ErrRecordExists {
+				return err
+			}
+		}
+
+		if err := l.store.ContractEvent().SaveCursor(ctx, cursorName, to); err != nil {
+			return err
+		}
+
+		from = to + 1
+	}
+
+	return nil
+}
+
+// dispatch runs the handlers of the events due. A failed event is retried
+// with exponential backoff, without holding back the events after it, until
+// it is out of attempts.
+func (l *Listener) dispatch(ctx context.Context) error {
+	events, err := l.store.ContractEvent().FindUnprocessed(ctx, dispatchBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range events {
+		handlers := l.handlers[e.Topic]
+		handled := e.Handled
+		var failure error
+		for ; handled < len(handlers); handled++ {
+			if failure = handlers[handled](ctx, e); failure != nil {
+				break
+			}
+		}
+
+		if failure != nil {
+			err = l.fail(ctx, e, handled, failure)
+		} else {
+			err = l.store.ContractEvent().MarkProcessed(ctx, e.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fail ...
+func (l *Listener) fail(ctx context.Context, e *model.ContractEvent, handled int, err error) error {
+	logger := l.logger.WithFields(logrus.Fields{
+		"tx_hash":   e.TxHash,
+		"log_index": e.LogIndex,
+		"attempt":   e.Attempts + 1,
+		"error":     err.Error(),
+	})
+
+	if e.Attempts+1 >= l.config.MaxAttempts {
+		logger.Error("contract event failed")
+		return l.store.ContractEvent().MarkFailed(ctx, e.ID, handled, err.Error(), nil)
+	}
+
+	retryAt := time.Now().Add(backoff.Delay(l.config.RetryBaseDelay, l.config.RetryMaxDelay, e.Attempts+1))
+	logger.WithField("retry_at", retryAt).Warn("contract event handler failed")
+
+	return l.store.ContractEvent().MarkFailed(ctx, e.ID, handled, err.Error(), &retryAt)
+}
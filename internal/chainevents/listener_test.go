@@ -0,0 +1,100 @@
+package chainevents_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"winding-tree-server/internal/chainevents"
+	"winding-tree-server/internal/ethereum"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	failing    = "Failing(bytes32)"
+	succeeding = "Succeeding(bytes32)"
+)
+
+// client serves logs from memory, all in block 1
+type client struct {
+	logs []*ethereum.Log
+}
+
+func (c *client) BlockNumber(ctx context.Context) (uint64, error) {
+	return 1, nil
+}
+
+func (c *client) Logs(ctx context.Context, addresses []string, fromBlock, toBlock uint64) ([]*ethereum.Log, error) {
+	if fromBlock > 1 {
+		return nil, nil
+	}
+
+	return c.logs, nil
+}
+
+func (c *client) add(signature string) {
+	c.logs = append(c.logs, &ethereum.Log{
+		Address:     "0x0000000000000000000000000000000000000001",
+		Topics:      []string{ethereum.EventTopic(signature)},
+		BlockNumber: 1,
+		BlockHash:   "0x01",
+		TxHash:      fmt.Sprintf("0x%x", len(c.logs)),
+		LogIndex:    uint64(len(c.logs)),
+	})
+}
+
+func TestListener_Poll(t *testing.T) {
+	ctx := context.Background()
+	s := teststore.New()
+	logger, _ := test.NewNullLogger()
+
+	// More failing events than a dispatch batch, then one that succeeds
+	c := &client{}
+	for i := 0; i < 150; i++ {
+		c.add(failing)
+	}
+	c.add(succeeding)
+
+	l := chainevents.NewListener(c, s, logger, chainevents.Config{
+		StartBlock:     1,
+		PollInterval:   time.Minute,
+		MaxAttempts:    2,
+		RetryBaseDelay: 50 * time.Millisecond,
+		RetryMaxDelay:  50 * time.Millisecond,
+	})
+
+	calls := map[string]int{}
+	count := func(name string, err error) chainevents.Handler {
+		return func(ctx context.Context, e *model.ContractEvent) error {
+			calls[name]++
+			return err
+		}
+	}
+	l.Handle(failing, count("before", nil))
+	l.Handle(failing, count("failing", errors.New("failed")))
+	l.Handle(succeeding, count("succeeding", nil))
+
+	// Failed events wait for their retry and make room for the others
+	assert.NoError(t, l.Poll(ctx))
+	assert.NoError(t, l.Poll(ctx))
+	assert.Equal(t, 1, calls["succeeding"])
+
+	// Retries resume with the failed handler, until the events are out of
+	// attempts
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, l.Poll(ctx))
+	}
+	assert.Equal(t, 150, calls["before"])
+	assert.Equal(t, 300, calls["failing"])
+	assert.Equal(t, 1, calls["succeeding"])
+
+	events, err := s.ContractEvent().FindUnprocessed(ctx, 1000)
+	assert.NoError(t, err)
+	assert.Empty(t, events, "failed events aren't dispatched again")
+}
@@ -0,0 +1,133 @@
+// Package envelope encrypts values with envelope encryption: each value is
+// sealed with its own random data key, and only that data key is encrypted
+// with a long-lived key held by a KeyWrapper, such as a Keyring loaded from
+// config or a KMS client. Rotating the long-lived key doesn't require
+// re-encrypting the data, only unwrapping keys with the old one.
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// dataKeySize selects AES-256 for data keys
+const dataKeySize = 32
+
+var (
+	// ErrUnknownKey is returned when a value was sealed with a key the wrapper doesn't have
+	ErrUnknownKey = errors.New("envelope: unknown key")
+	// ErrDecrypt is returned when a value or data key fails authentication
+	ErrDecrypt = errors.New("envelope: decryption failed")
+)
+
+// KeyWrapper encrypts and decrypts data keys with a key that never leaves it
+type KeyWrapper interface {
+	// WrapKey encrypts a data key, returning it with the id of the key used
+	WrapKey(ctx context.Context, dataKey []byte) (wrapped []byte, keyID string, err error)
+	// UnwrapKey decrypts a data key wrapped with keyID
+	UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error)
+}
+
+// Sealed is an encrypted value with the wrapped data key needed to open it
+type Sealed struct {
+	KeyID      string
+	Key        []byte
+	Ciphertext []byte
+}
+
+// Sealer seals and opens values
+type Sealer struct {
+	wrapper KeyWrapper
+}
+
+// NewSealer ...
+func NewSealer(wrapper KeyWrapper) *Sealer {
+	return &Sealer{
+		wrapper: wrapper,
+	}
+}
+
+// Seal encrypts plaintext under a new data key. additionalData is
+// authenticated but not stored; the same value must be passed to Open, which
+// binds a ciphertext to e.g. the record owning it.
+func (s *Sealer) Seal(ctx context.Context, plaintext, additionalData []byte) (*Sealed, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := encrypt(dataKey, plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, keyID, err := s.wrapper.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sealed{
+		KeyID:      keyID,
+		Key:        wrapped,
+		Ciphertext: ciphertext,
+	}, nil
+}
+
+// Open decrypts a sealed value
+func (s *Sealer) Open(ctx context.Context, sealed *Sealed, additionalData []byte) ([]byte, error) {
+	dataKey, err := s.wrapper.UnwrapKey(ctx, sealed.Key, sealed.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return decrypt(dataKey, sealed.Ciphertext, additionalData)
+}
+
+// encrypt returns the nonce followed by the AES-GCM ciphertext
+func encrypt(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// decrypt opens the output of encrypt
+func decrypt(key, ciphertext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
+
+// newGCM ...
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,132 @@
+package envelope_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"winding-tree-server/internal/envelope"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKeyring(t *testing.T, primary string, ids ...string) *envelope.Keyring {
+	t.Helper()
+
+	keys := map[string][]byte{}
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, 32)
+	}
+
+	k, err := envelope.NewKeyring(primary, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return k
+}
+
+func TestSealer(t *testing.T) {
+	ctx := context.Background()
+	old := envelope.NewSealer(testKeyring(t, "2019-01", "2019-01"))
+	rotated := envelope.NewSealer(testKeyring(t, "2019-12", "2019-01", "2019-12"))
+
+	sealed, err := old.Seal(ctx, []byte("passport"), []byte("user:1"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "2019-01", sealed.KeyID)
+	assert.NotContains(t, string(sealed.Ciphertext), "passport")
+
+	testCases := []struct {
+		name    string
+		sealer  *envelope.Sealer
+		sealed  func() *envelope.Sealed
+		aad     string
+		wantErr error
+	}{
+		{
+			name:   "same key",
+			sealer: old,
+			sealed: func() *envelope.Sealed { return sealed },
+			aad:    "user:1",
+		},
+		{
+			name:   "retired key",
+			sealer: rotated,
+			sealed: func() *envelope.Sealed { return sealed },
+			aad:    "user:1",
+		},
+		{
+			name:    "other record",
+			sealer:  old,
+			sealed:  func() *envelope.Sealed { return sealed },
+			aad:     "user:2",
+			wantErr: envelope.ErrDecrypt,
+		},
+		{
+			name:   "tampered",
+			sealer: old,
+			sealed: func() *envelope.Sealed {
+				s := *sealed
+				s.Ciphertext = append([]byte{}, sealed.Ciphertext...)
+				s.Ciphertext[len(s.Ciphertext)-1] ^= 1
+				return &s
+			},
+			aad:     "user:1",
+			wantErr: envelope.ErrDecrypt,
+		},
+		{
+			name:   "unknown key",
+			sealer: envelope.NewSealer(testKeyring(t, "2020-01", "2020-01")),
+			sealed: func() *envelope.Sealed {
+				return sealed
+			},
+			aad:     "user:1",
+			wantErr: envelope.ErrUnknownKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plaintext, err := tc.sealer.Open(ctx, tc.sealed(), []byte(tc.aad))
+			if tc.wantErr != nil {
+				assert.Equal(t, tc.wantErr, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "passport", string(plaintext))
+		})
+	}
+
+	resealed, err := rotated.Seal(ctx, []byte("passport"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "2019-12", resealed.KeyID)
+}
+
+func TestParseKeys(t *testing.T) {
+	testCases := []struct {
+		name    string
+		specs   []string
+		isValid bool
+	}{
+		{"valid", []string{"a:" + "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}, true},
+		{"no id", []string{":AAAA"}, false},
+		{"no separator", []string{"AAAA"}, false},
+		{"bad base64", []string{"a:!!"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := envelope.ParseKeys(tc.specs)
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			_, err = envelope.NewKeyring("a", keys)
+			assert.NoError(t, err)
+		})
+	}
+}
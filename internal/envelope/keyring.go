@@ -0,0 +1,77 @@
+package envelope
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errInvalidKeySpec = errors.New(`envelope: keys must be given as "id:base64 key"`)
+
+// Keyring is a KeyWrapper holding AES-256 key encryption keys in memory.
+// New data keys are wrapped with the primary key; retired keys are kept so
+// values sealed before a rotation can still be opened.
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring returns a keyring wrapping with the key primaryID, which must be among keys
+func NewKeyring(primaryID string, keys map[string][]byte) (*Keyring, error) {
+	for id, key := range keys {
+		if len(key) != dataKeySize {
+			return nil, fmt.Errorf("envelope: key %q must be %d bytes", id, dataKeySize)
+		}
+	}
+
+	if _, ok := keys[primaryID]; !ok {
+		return nil, fmt.Errorf("envelope: primary key %q is missing", primaryID)
+	}
+
+	return &Keyring{
+		primary: primaryID,
+		keys:    keys,
+	}, nil
+}
+
+// ParseKeys decodes "id:base64 key" specs, as they appear in config
+func ParseKeys(specs []string) (map[string][]byte, error) {
+	keys := make(map[string][]byte, len(specs))
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errInvalidKeySpec
+		}
+
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, errInvalidKeySpec
+		}
+
+		keys[parts[0]] = key
+	}
+
+	return keys, nil
+}
+
+// WrapKey ...
+func (k *Keyring) WrapKey(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	wrapped, err := encrypt(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return wrapped, k.primary, nil
+}
+
+// UnwrapKey ...
+func (k *Keyring) UnwrapKey(ctx context.Context, wrapped []byte, keyID string) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return decrypt(key, wrapped, []byte(keyID))
+}
@@ -0,0 +1,173 @@
+package ethereum
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const wordSize = 32
+
+var (
+	// ErrShortData ...
+	ErrShortData = errors.New("abi: data too short")
+)
+
+// Selector returns the 4-byte function selector for a signature such as "transfer(address,uint256)"
+func Selector(signature string) []byte {
+	return Keccak256([]byte(signature))[:4]
+}
+
+// EventTopic returns the topic hash of an event signature such as "Transfer(address,address,uint256)"
+func EventTopic(signature string) string {
+	return EncodeHex(Keccak256([]byte(signature)))
+}
+
+// Keccak256 ...
+func Keccak256(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+
+	return h.Sum(nil)
+}
+
+// EncodeCall packs a selector with static 32-byte arguments
+func EncodeCall(signature string, args ...[]byte) []byte {
+	data := Selector(signature)
+	for _, a := range args {
+		data = append(data, a...)
+	}
+
+	return data
+}
+
+// Uint256 encodes an unsigned integer as an ABI word
+func Uint256(v uint64) []byte {
+	return leftPad(new(big.Int).SetUint64(v).Bytes())
+}
+
+// Bytes32 encodes a fixed 32-byte value as an ABI word
+func Bytes32(b [32]byte) []byte {
+	return b[:]
+}
+
+// Address encodes a 0x-prefixed hex address as an ABI word
+func Address(addr string) ([]byte, error) {
+	b, err := DecodeHex(addr)
+	if err != nil || len(b) != 20 {
+		return nil, ErrInvalidHex
+	}
+
+	return leftPad(b), nil
+}
+
+// Word returns the i-th 32-byte word of data
+func Word(data []byte, i int) ([]byte, error) {
+	start := i * wordSize
+	if start+wordSize > len(data) {
+		return nil, ErrShortData
+	}
+
+	return data[start : start+wordSize], nil
+}
+
+// DecodeBool ...
+func DecodeBool(word []byte) bool {
+	return word[wordSize-1] != 0
+}
+
+// DecodeUint ...
+func DecodeUint(word []byte) *big.Int {
+	return new(big.Int).SetBytes(word)
+}
+
+// DecodeAddress returns the 0x-prefixed hex address stored in word
+func DecodeAddress(word []byte) string {
+	return "0x" + hex.EncodeToString(word[wordSize-20:])
+}
+
+// DecodeBytes32 ...
+func DecodeBytes32(word []byte) [32]byte {
+	var b [32]byte
+	copy(b[:], word)
+
+	return b
+}
+
+// DecodeString decodes a dynamic string whose head word is at index i
+func DecodeString(data []byte, i int) (string, error) {
+	b, err := decodeDynamic(data, i)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// DecodeBytes32Array decodes a dynamic bytes32[] whose head word is at index i
+func DecodeBytes32Array(data []byte, i int) ([][32]byte, error) {
+	start, n, err := dynamicHeader(data, i)
+	if err != nil {
+		return nil, err
+	}
+
+	if start+n*wordSize > len(data) {
+		return nil, ErrShortData
+	}
+
+	res := make([][32]byte, n)
+	for j := range res {
+		copy(res[j][:], data[start+j*wordSize:])
+	}
+
+	return res, nil
+}
+
+// decodeDynamic ...
+func decodeDynamic(data []byte, i int) ([]byte, error) {
+	start, n, err := dynamicHeader(data, i)
+	if err != nil {
+		return nil, err
+	}
+
+	if start+n > len(data) {
+		return nil, ErrShortData
+	}
+
+	return data[start : start+n], nil
+}
+
+// dynamicHeader follows the offset at word i and returns the content start and length
+func dynamicHeader(data []byte, i int) (int, int, error) {
+	head, err := Word(data, i)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	offset := DecodeUint(head)
+	if !offset.IsInt64() || offset.Int64() > int64(len(data)) {
+		return 0, 0, ErrShortData
+	}
+
+	lw, err := Word(data[offset.Int64():], 0)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	length := DecodeUint(lw)
+	if !length.IsInt64() || length.Int64() > int64(len(data)) {
+		return 0, 0, ErrShortData
+	}
+
+	return int(offset.Int64()) + wordSize, int(length.Int64()), nil
+}
+
+// leftPad ...
+func leftPad(b []byte) []byte {
+	w := make([]byte, wordSize)
+	copy(w[wordSize-len(b):], b)
+
+	return w
+}
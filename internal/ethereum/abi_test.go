@@ -0,0 +1,63 @@
+package ethereum_test
+
+import (
+	"strings"
+	"testing"
+	"winding-tree-server/internal/ethereum"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelector(t *testing.T) {
+	assert.Equal(t, "0xa9059cbb", ethereum.EncodeHex(ethereum.Selector("transfer(address,uint256)")))
+}
+
+func TestDecodeBytes32Array(t *testing.T) {
+	data := mustDecode(t, strings.Join([]string{
+		"0x",
+		word("20"),
+		word("02"),
+		word("aa"),
+		word("bb"),
+	}, ""))
+
+	res, err := ethereum.DecodeBytes32Array(data, 0)
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assert.Equal(t, byte(0xaa), res[0][31])
+	assert.Equal(t, byte(0xbb), res[1][31])
+
+	_, err = ethereum.DecodeBytes32Array(data[:96], 0)
+	assert.Equal(t, ethereum.ErrShortData, err)
+}
+
+func TestDecodeString(t *testing.T) {
+	data := mustDecode(t, "0x"+word("01")+word("40")+word("03")+"616263"+strings.Repeat("0", 58))
+
+	s, err := ethereum.DecodeString(data, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", s)
+}
+
+func TestAddress(t *testing.T) {
+	w, err := ethereum.Address("0x00000000000000000000000000000000000000ff")
+	assert.NoError(t, err)
+	assert.Equal(t, "0x00000000000000000000000000000000000000ff", ethereum.DecodeAddress(w))
+
+	_, err = ethereum.Address("0xff")
+	assert.Equal(t, ethereum.ErrInvalidHex, err)
+}
+
+// word left-pads a hex value to a 32-byte ABI word
+func word(v string) string {
+	return strings.Repeat("0", 64-len(v)) + v
+}
+
+func mustDecode(t *testing.T, s string) []byte {
+	b, err := ethereum.DecodeHex(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
@@ -0,0 +1,228 @@
+package ethereum
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrInvalidHex ...
+	ErrInvalidHex = errors.New("invalid hex string")
+)
+
+// Client is a minimal Ethereum JSON-RPC client
+type Client struct {
+	url        string
+	httpClient *http.Client
+	nextID     uint64
+}
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error ...
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// NewClient ...
+func NewClient(url string) *Client {
+	return &Client{
+		url: url,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Call executes a read-only contract call against the latest block
+func (c *Client) Call(ctx context.Context, to string, data []byte) ([]byte, error) {
+	var result string
+	if err := c.call(ctx, &result, "eth_call", map[string]string{
+		"to":   to,
+		"data": EncodeHex(data),
+	}, "latest"); err != nil {
+		return nil, err
+	}
+
+	return DecodeHex(result)
+}
+
+// call ...
+func (c *Client) call(ctx context.Context, result interface{}, method string, params ...interface{}) error {
+	body, err := json.Marshal(&rpcRequest{
+		JSONRPC: "2.0",
+		ID:      atomic.AddUint64(&c.nextID, 1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpc: unexpected status %s", res.Status)
+	}
+
+	resp := &rpcResponse{}
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	return json.Unmarshal(resp.Result, result)
+}
+
+// EncodeHex ...
+func EncodeHex(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// DecodeHex ...
+func DecodeHex(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return nil, ErrInvalidHex
+	}
+
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, ErrInvalidHex
+	}
+
+	return b, nil
+}
+
+// Log is a contract event log as returned by eth_getLogs
+type Log struct {
+	Address     string
+	Topics      []string
+	Data        []byte
+	BlockNumber uint64
+	BlockHash   string
+	TxHash      string
+	LogIndex    uint64
+}
+
+type rpcLog struct {
+	Address     string   `json:"address"`
+	Topics      []string `json:"topics"`
+	Data        string   `json:"data"`
+	BlockNumber string   `json:"blockNumber"`
+	BlockHash   string   `json:"blockHash"`
+	TxHash      string   `json:"transactionHash"`
+	LogIndex    string   `json:"logIndex"`
+	Removed     bool     `json:"removed"`
+}
+
+// BlockNumber returns the number of the most recent block
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var result string
+	if err := c.call(ctx, &result, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+
+	return decodeQuantity(result)
+}
+
+// Logs returns the logs emitted by addresses in the inclusive block range
+func (c *Client) Logs(ctx context.Context, addresses []string, fromBlock, toBlock uint64) ([]*Log, error) {
+	var result []*rpcLog
+	if err := c.call(ctx, &result, "eth_getLogs", map[string]interface{}{
+		"address":   addresses,
+		"fromBlock": encodeQuantity(fromBlock),
+		"toBlock":   encodeQuantity(toBlock),
+	}); err != nil {
+		return nil, err
+	}
+
+	logs := make([]*Log, 0, len(result))
+	for _, l := range result {
+		if l.Removed {
+			continue
+		}
+
+		data, err := DecodeHex(l.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		blockNumber, err := decodeQuantity(l.BlockNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		logIndex, err := decodeQuantity(l.LogIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, &Log{
+			Address:     strings.ToLower(l.Address),
+			Topics:      l.Topics,
+			Data:        data,
+			BlockNumber: blockNumber,
+			BlockHash:   l.BlockHash,
+			TxHash:      l.TxHash,
+			LogIndex:    logIndex,
+		})
+	}
+
+	return logs, nil
+}
+
+// encodeQuantity ...
+func encodeQuantity(v uint64) string {
+	return "0x" + strconv.FormatUint(v, 16)
+}
+
+// decodeQuantity ...
+func decodeQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, ErrInvalidHex
+	}
+
+	v, err := strconv.ParseUint(s[2:], 16, 64)
+	if err != nil {
+		return 0, ErrInvalidHex
+	}
+
+	return v, nil
+}
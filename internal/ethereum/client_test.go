@@ -0,0 +1,83 @@
+package ethereum_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"winding-tree-server/internal/ethereum"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_BlockNumber(t *testing.T) {
+	s := testRPCServer(t, map[string]string{
+		"eth_blockNumber": `"0x10"`,
+	})
+	defer s.Close()
+
+	c := ethereum.NewClient(s.URL)
+
+	n, err := c.BlockNumber(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(16), n)
+}
+
+func TestClient_Logs(t *testing.T) {
+	s := testRPCServer(t, map[string]string{
+		"eth_getLogs": `[
+			{"address": "0xABCD", "topics": ["0x01"], "data": "0x", "blockNumber": "0x2", "blockHash": "0xbb", "transactionHash": "0xaa", "logIndex": "0x3", "removed": false},
+			{"address": "0xabcd", "topics": ["0x01"], "data": "0x", "blockNumber": "0x2", "blockHash": "0xbb", "transactionHash": "0xaa", "logIndex": "0x4", "removed": true}
+		]`,
+	})
+	defer s.Close()
+
+	c := ethereum.NewClient(s.URL)
+
+	logs, err := c.Logs(context.Background(), []string{"0xabcd"}, 1, 2)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "0xabcd", logs[0].Address)
+	assert.Equal(t, uint64(2), logs[0].BlockNumber)
+	assert.Equal(t, uint64(3), logs[0].LogIndex)
+}
+
+func TestClient_RPCError(t *testing.T) {
+	s := testRPCServer(t, map[string]string{})
+	defer s.Close()
+
+	c := ethereum.NewClient(s.URL)
+
+	_, err := c.BlockNumber(context.Background())
+	assert.Error(t, err)
+}
+
+// testRPCServer answers JSON-RPC calls with canned results keyed by method
+func testRPCServer(t *testing.T, results map[string]string) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+
+		res, ok := results[req.Method]
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":    req.ID,
+				"error": map[string]interface{}{"code": -32601, "message": "method not found"},
+			})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":     req.ID,
+			"result": json.RawMessage(res),
+		})
+	}))
+
+	return s
+}
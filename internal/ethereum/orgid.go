@@ -0,0 +1,118 @@
+package ethereum
+
+import (
+	"context"
+	"math/big"
+)
+
+// Organization is an organization record as returned by the ORGiD contract
+type Organization struct {
+	OrgID                  string
+	OrgJSONURI             string
+	OrgJSONHash            string
+	ParentEntity           string
+	Owner                  string
+	Director               string
+	IsActive               bool
+	IsDirectorshipAccepted bool
+}
+
+// DirectoryOrganizations returns the ORGiDs registered in a segment directory
+func (c *Client) DirectoryOrganizations(ctx context.Context, directory string) ([]string, error) {
+	// getOrganizations(cursor, count) returns all entries when count is 0
+	data, err := c.Call(ctx, directory, EncodeCall(
+		"getOrganizations(uint256,uint256)",
+		Uint256(0),
+		Uint256(0),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := DecodeBytes32Array(data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, len(ids))
+	for i, id := range ids {
+		res[i] = EncodeHex(id[:])
+	}
+
+	return res, nil
+}
+
+// Organization fetches an organization from the ORGiD contract, nil if it doesn't exist
+func (c *Client) Organization(ctx context.Context, orgidAddress string, orgID string) (*Organization, error) {
+	id, err := decodeBytes32Hex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.Call(ctx, orgidAddress, EncodeCall("getOrganization(bytes32)", Bytes32(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	// (bool exists, bytes32 orgId, string orgJsonUri, bytes32 orgJsonHash, bytes32 parentEntity,
+	//  address owner, address director, bool isActive, bool isDirectorshipAccepted)
+	words := make([][]byte, 9)
+	for i := range words {
+		if words[i], err = Word(data, i); err != nil {
+			return nil, err
+		}
+	}
+
+	if !DecodeBool(words[0]) {
+		return nil, nil
+	}
+
+	uri, err := DecodeString(data, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Organization{
+		OrgID:                  EncodeHex(words[1]),
+		OrgJSONURI:             uri,
+		OrgJSONHash:            EncodeHex(words[3]),
+		ParentEntity:           EncodeHex(words[4]),
+		Owner:                  DecodeAddress(words[5]),
+		Director:               DecodeAddress(words[6]),
+		IsActive:               DecodeBool(words[7]),
+		IsDirectorshipAccepted: DecodeBool(words[8]),
+	}, nil
+}
+
+// decodeBytes32Hex ...
+func decodeBytes32Hex(s string) ([32]byte, error) {
+	var res [32]byte
+	b, err := DecodeHex(s)
+	if err != nil || len(b) != 32 {
+		return res, ErrInvalidHex
+	}
+
+	copy(res[:], b)
+
+	return res, nil
+}
+
+// LifDeposit returns the Lif deposit (in wei) held by the deposit contract for an organization
+func (c *Client) LifDeposit(ctx context.Context, depositAddress string, orgID string) (*big.Int, error) {
+	id, err := decodeBytes32Hex(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.Call(ctx, depositAddress, EncodeCall("balanceOf(bytes32)", Bytes32(id)))
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := Word(data, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecodeUint(w), nil
+}
@@ -0,0 +1,330 @@
+// Package events pushes the domain events of the outbox to the clients
+// connected to this instance, such as supplier dashboards. Each instance
+// tails the outbox on its own, so a client may connect to any of them.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pollBatch = 100
+	// subscriptionBuffer events may wait for a subscriber; one that falls
+	// further behind is dropped
+	subscriptionBuffer = 64
+)
+
+// ErrReplayTooLong ...
+var ErrReplayTooLong = errors.New("too many events to replay")
+
+// Event is an outbox event, with the supplier it concerns
+type Event struct {
+	*model.OutboxEvent
+	// SupplierID is the public id of the supplier, empty for events of no
+	// supplier in particular
+	SupplierID string `json:"-"`
+}
+
+// newEvent finds the supplier of e from the supplier_id of its payload,
+// which onboardings and flights have
+func newEvent(e *model.OutboxEvent) *Event {
+	var payload struct {
+		SupplierID string `json:"supplier_id"`
+	}
+	json.Unmarshal(e.Payload, &payload)
+
+	return &Event{OutboxEvent: e, SupplierID: payload.SupplierID}
+}
+
+// Filter selects the events of a subscriber
+type Filter struct {
+	// SupplierID limits the events to those of a supplier, by public id;
+	// empty selects all of them
+	SupplierID string
+	// Topics, such as flight.created, or onboarding.* for all the
+	// onboarding events, limit the events to theirs; none selects all topics
+	Topics []string
+}
+
+// Matches ...
+func (f Filter) Matches(e *Event) bool {
+	if f.SupplierID != "" && e.SupplierID != f.SupplierID {
+		return false
+	}
+
+	if len(f.Topics) == 0 {
+		return true
+	}
+
+	for _, topic := range f.Topics {
+		if topic == e.Topic || (strings.HasSuffix(topic, ".*") && strings.HasPrefix(e.Topic, topic[:len(topic)-1])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Subscription receives the events matching its filter from when it was
+// made, until it is closed
+type Subscription struct {
+	filter Filter
+	events chan *Event
+	hub    *Hub
+}
+
+// Events is closed when the subscription is, by Close, by the hub stopping
+// or by the subscriber falling behind
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Close ...
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// Hub polls the outbox for new events and fans them out to subscribers
+type Hub struct {
+	store      store.Store
+	logger     *logrus.Logger
+	interval   time.Duration
+	gapTimeout time.Duration
+
+	mu            sync.Mutex
+	subscriptions map[*Subscription]bool
+	// lastID is the last event published; every event before it was
+	// published or given up on
+	lastID  int
+	started bool
+
+	// gap is the first missing id Poll waits for, since gapSince; only Poll
+	// uses them
+	gap      int
+	gapSince time.Time
+}
+
+// NewHub returns a hub polling every interval. Outbox ids are assigned when
+// events are written but show once their transaction commits, so the hub
+// waits up to gapTimeout for a missing id before taking it for a rolled
+// back write.
+func NewHub(store store.Store, logger *logrus.Logger, interval time.Duration, gapTimeout time.Duration) *Hub {
+	return &Hub{
+		store:         store,
+		logger:        logger,
+		interval:      interval,
+		gapTimeout:    gapTimeout,
+		subscriptions: make(map[*Subscription]bool),
+	}
+}
+
+// Subscribe ...
+func (h *Hub) Subscribe(f Filter) *Subscription {
+	s := &Subscription{
+		filter: f,
+		events: make(chan *Event, subscriptionBuffer),
+		hub:    h,
+	}
+
+	h.mu.Lock()
+	h.subscriptions[s] = true
+	h.mu.Unlock()
+
+	return s
+}
+
+// unsubscribe ...
+func (h *Hub) unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscriptions[s] {
+		delete(h.subscriptions, s)
+		close(s.events)
+	}
+}
+
+// Run polls until ctx is done, then closes the subscriptions
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	defer func() {
+		h.mu.Lock()
+		subscriptions := h.subscriptions
+		h.subscriptions = make(map[*Subscription]bool)
+		h.mu.Unlock()
+
+		for s := range subscriptions {
+			close(s.events)
+		}
+	}()
+
+	for {
+		if err := h.Poll(ctx); err != nil {
+			h.logger.Errorf("events poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll publishes the events written since the last poll, in id order. A
+// missing id holds back the events after it until its transaction commits
+// or the gap timeout passes; an event committed later than that is never
+// published. The first poll only skips to the last event, so that the
+// first subscribers don't get a backlog.
+func (h *Hub) Poll(ctx context.Context) error {
+	h.mu.Lock()
+	started := h.started
+	lastID := h.lastID
+	h.mu.Unlock()
+
+	if !started {
+		id, err := h.store.Outbox().LastID(ctx)
+		if err != nil {
+			return err
+		}
+
+		h.mu.Lock()
+		h.lastID = id
+		h.started = true
+		h.mu.Unlock()
+
+		return nil
+	}
+
+	for {
+		events, err := h.store.Outbox().FindAfter(ctx, lastID, pollBatch)
+		if err != nil {
+			return err
+		}
+
+		settled := h.settled(lastID, events)
+		for _, e := range events[:settled] {
+			h.publish(newEvent(e))
+			lastID = e.ID
+		}
+
+		h.mu.Lock()
+		h.lastID = lastID
+		h.mu.Unlock()
+
+		if settled < len(events) || len(events) < pollBatch {
+			return nil
+		}
+	}
+}
+
+// settled returns how many of events, read after id, can be published: all
+// of them unless an id is missing and its timeout hasn't passed
+func (h *Hub) settled(id int, events []*model.OutboxEvent) int {
+	for i, e := range events {
+		if e.ID != id+1 {
+			if h.gap != id+1 {
+				h.gap = id + 1
+				h.gapSince = time.Now()
+			}
+
+			if time.Since(h.gapSince) < h.gapTimeout {
+				return i
+			}
+
+			h.logger.Warnf("outbox ids %d to %d skipped after %s, taken for rolled back writes", id+1, e.ID-1, h.gapTimeout)
+		}
+
+		id = e.ID
+	}
+
+	return len(events)
+}
+
+// LastID is the last event published; every event before it was published
+// or given up on
+func (h *Hub) LastID() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.lastID
+}
+
+// Replay returns the events matching f published after the one with the
+// given id, oldest first, for clients resuming a stream. Later events are
+// left to the subscription of the client, which must be made first, so
+// that an event committed late is either replayed or pushed. Past limit
+// events, matching or not, or before the hub first polled, it gives up with
+// ErrReplayTooLong.
+func (h *Hub) Replay(ctx context.Context, f Filter, id int, limit int) ([]*Event, error) {
+	h.mu.Lock()
+	started := h.started
+	until := h.lastID
+	h.mu.Unlock()
+
+	if !started {
+		return nil, ErrReplayTooLong
+	}
+
+	matched := []*Event{}
+	scanned := 0
+	for id < until {
+		events, err := h.store.Outbox().FindAfter(ctx, id, pollBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range events {
+			if e.ID > until {
+				return matched, nil
+			}
+
+			if scanned++; scanned > limit {
+				return nil, ErrReplayTooLong
+			}
+
+			if event := newEvent(e); f.Matches(event) {
+				matched = append(matched, event)
+			}
+			id = e.ID
+		}
+
+		if len(events) < pollBatch {
+			break
+		}
+	}
+
+	return matched, nil
+}
+
+// publish hands e to the subscribers it matches, dropping those whose
+// buffer is full rather than waiting for them
+func (h *Hub) publish(e *Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscriptions {
+		if !s.filter.Matches(e) {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		default:
+			h.logger.Warn("events subscriber dropped for falling behind")
+			delete(h.subscriptions, s)
+			close(s.events)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package events_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"winding-tree-server/internal/events"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Matches(t *testing.T) {
+	e := &events.Event{OutboxEvent: &model.OutboxEvent{Topic: "onboarding.submitted"}, SupplierID: "a"}
+
+	testCases := []struct {
+		name    string
+		filter  events.Filter
+		matches bool
+	}{
+		{
+			name:    "all",
+			filter:  events.Filter{},
+			matches: true,
+		},
+		{
+			name:    "supplier",
+			filter:  events.Filter{SupplierID: "a"},
+			matches: true,
+		},
+		{
+			name:    "other supplier",
+			filter:  events.Filter{SupplierID: "b"},
+			matches: false,
+		},
+		{
+			name:    "topic",
+			filter:  events.Filter{Topics: []string{"flight.created", "onboarding.submitted"}},
+			matches: true,
+		},
+		{
+			name:    "topic prefix",
+			filter:  events.Filter{SupplierID: "a", Topics: []string{"onboarding.*"}},
+			matches: true,
+		},
+		{
+			name:    "other topic",
+			filter:  events.Filter{Topics: []string{"flight.created", "onboarding"}},
+			matches: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.matches, tc.filter.Matches(e))
+		})
+	}
+}
+
+// testUsers creates n users, whose events the hub routes by public id
+func testUsers(t *testing.T, s *teststore.Store, n int) []*model.User {
+	users := make([]*model.User, n)
+	for i := range users {
+		users[i] = model.TestUser(t)
+		users[i].Email = fmt.Sprintf("user%d@example.org", i)
+		assert.NoError(t, s.User().Create(context.Background(), users[i]))
+	}
+
+	return users
+}
+
+func TestHub_Poll(t *testing.T) {
+	ctx := context.Background()
+	s := teststore.New()
+	logger, _ := test.NewNullLogger()
+	hub := events.NewHub(s, logger, time.Minute, time.Minute)
+	users := testUsers(t, s, 3)
+
+	submit := func(u *model.User) {
+		o := model.NewOnboarding(u.ID)
+		assert.NoError(t, o.Submit(1))
+		assert.NoError(t, s.Onboarding().Save(ctx, o))
+	}
+
+	// Events from before the first subscriber aren't pushed
+	submit(users[2])
+	assert.NoError(t, hub.Poll(ctx))
+
+	user := hub.Subscribe(events.Filter{SupplierID: users[0].PublicID})
+	admin := hub.Subscribe(events.Filter{Topics: []string{"onboarding.*"}})
+	flights := hub.Subscribe(events.Filter{Topics: []string{model.TopicFlightCreated}})
+	defer user.Close()
+	defer admin.Close()
+	defer flights.Close()
+
+	submit(users[0])
+	submit(users[1])
+	assert.NoError(t, hub.Poll(ctx))
+
+	received := func(sub *events.Subscription) []string {
+		suppliers := []string{}
+		for {
+			select {
+			case e := <-sub.Events():
+				suppliers = append(suppliers, e.SupplierID)
+			default:
+				return suppliers
+			}
+		}
+	}
+
+	assert.Equal(t, []string{users[0].PublicID}, received(user))
+	assert.Equal(t, []string{users[0].PublicID, users[1].PublicID}, received(admin))
+	assert.Empty(t, received(flights))
+
+	// Events are pushed once
+	assert.NoError(t, hub.Poll(ctx))
+	assert.Empty(t, received(admin))
+}
+
+// lateStore hides the outbox events in pending, as if their transactions
+// hadn't committed yet
+type lateStore struct {
+	*teststore.Store
+	pending map[int]bool
+}
+
+func (s *lateStore) Outbox() store.OutboxRepository {
+	return &lateOutbox{s.Store.Outbox(), s.pending}
+}
+
+type lateOutbox struct {
+	store.OutboxRepository
+	pending map[int]bool
+}
+
+func (r *lateOutbox) FindAfter(ctx context.Context, id int, limit int) ([]*model.OutboxEvent, error) {
+	all, err := r.OutboxRepository.FindAfter(ctx, id, limit)
+	events := []*model.OutboxEvent{}
+	for _, e := range all {
+		if !r.pending[e.ID] {
+			events = append(events, e)
+		}
+	}
+
+	return events, err
+}
+
+func TestHub_PollLateEvents(t *testing.T) {
+	ctx := context.Background()
+	s := &lateStore{Store: teststore.New(), pending: map[int]bool{}}
+	logger, _ := test.NewNullLogger()
+	hub := events.NewHub(s, logger, time.Minute, 50*time.Millisecond)
+	assert.NoError(t, hub.Poll(ctx))
+
+	sub := hub.Subscribe(events.Filter{})
+	defer sub.Close()
+
+	written := 0
+	write := func(n int) {
+		for i := 0; i < n; i++ {
+			written++
+			o := model.NewOnboarding(written)
+			assert.NoError(t, o.Submit(1))
+			assert.NoError(t, s.Onboarding().Save(ctx, o))
+		}
+	}
+	received := func() []int {
+		ids := []int{}
+		for {
+			select {
+			case e := <-sub.Events():
+				ids = append(ids, e.ID)
+			default:
+				return ids
+			}
+		}
+	}
+
+	// The second event commits after the third was written
+	write(3)
+	s.pending[2] = true
+	assert.NoError(t, hub.Poll(ctx))
+	assert.Equal(t, []int{1}, received(), "events wait for those before them")
+
+	delete(s.pending, 2)
+	assert.NoError(t, hub.Poll(ctx))
+	assert.Equal(t, []int{2, 3}, received())
+
+	// The fifth event never commits
+	write(3)
+	s.pending[5] = true
+	assert.NoError(t, hub.Poll(ctx))
+	assert.Equal(t, []int{4}, received())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, hub.Poll(ctx))
+	assert.Equal(t, []int{6}, received(), "missing events are given up on")
+}
+
+func TestHub_Replay(t *testing.T) {
+	ctx := context.Background()
+	s := teststore.New()
+	logger, _ := test.NewNullLogger()
+	hub := events.NewHub(s, logger, time.Minute, time.Minute)
+	users := testUsers(t, s, 3)
+
+	// The first user's onboarding is submitted, then the second user's,
+	// then the first is approved
+	first := model.NewOnboarding(users[0].ID)
+	assert.NoError(t, first.Submit(1))
+	assert.NoError(t, s.Onboarding().Save(ctx, first))
+	second := model.NewOnboarding(users[1].ID)
+	assert.NoError(t, second.Submit(1))
+	assert.NoError(t, s.Onboarding().Save(ctx, second))
+	assert.NoError(t, first.Approve(users[2].ID))
+	assert.NoError(t, s.Onboarding().Save(ctx, first))
+
+	filter := events.Filter{SupplierID: users[0].PublicID}
+	_, err := hub.Replay(ctx, filter, 1, 10)
+	assert.Equal(t, events.ErrReplayTooLong, err, "the hub hasn't polled yet")
+
+	assert.NoError(t, hub.Poll(ctx))
+	assert.Equal(t, 3, hub.LastID())
+
+	// Events the hub hasn't published yet are left to subscriptions
+	third := model.NewOnboarding(users[0].ID + 10)
+	assert.NoError(t, third.Submit(1))
+	assert.NoError(t, s.Onboarding().Save(ctx, third))
+
+	replayed, err := hub.Replay(ctx, filter, 1, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, replayed, 1) {
+		assert.Equal(t, 3, replayed[0].ID)
+		assert.Equal(t, "onboarding.approved", replayed[0].Topic)
+	}
+
+	_, err = hub.Replay(ctx, filter, 0, 2)
+	assert.Equal(t, events.ErrReplayTooLong, err)
+
+	replayed, err = hub.Replay(ctx, events.Filter{}, 3, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, replayed)
+
+	replayed, err = hub.Replay(ctx, events.Filter{}, 2, 10)
+	assert.NoError(t, err)
+	assert.Len(t, replayed, 1)
+}
+
+func TestHub_SlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	s := teststore.New()
+	logger, _ := test.NewNullLogger()
+	hub := events.NewHub(s, logger, time.Minute, time.Minute)
+	assert.NoError(t, hub.Poll(ctx))
+
+	sub := hub.Subscribe(events.Filter{})
+	for i := 0; i < 100; i++ {
+		o := model.NewOnboarding(i + 1)
+		assert.NoError(t, o.Submit(i+1))
+		assert.NoError(t, s.Onboarding().Save(ctx, o))
+	}
+	assert.NoError(t, hub.Poll(ctx))
+
+	n := 0
+	for range sub.Events() {
+		n++
+	}
+	assert.True(t, n < 100, "the subscriber is dropped once its buffer is full")
+
+	// Closing a dropped subscription is harmless
+	sub.Close()
+}
+
+func TestHub_Run(t *testing.T) {
+	s := teststore.New()
+	logger, _ := test.NewNullLogger()
+	hub := events.NewHub(s, logger, time.Millisecond, time.Minute)
+	sub := hub.Subscribe(events.Filter{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("hub still running")
+	}
+
+	_, open := <-sub.Events()
+	assert.False(t, open, "subscriptions are closed when the hub stops")
+}
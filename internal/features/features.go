@@ -0,0 +1,198 @@
+// Package features decides which users get features that are being rolled
+// out, so that they can be turned on for some suppliers or a share of users
+// before everyone.
+package features
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+	errPercentage = errors.New("percentage must be between 0 and 100")
+)
+
+// Flag is the rollout of a feature. It is on for everyone when Enabled,
+// and otherwise for Percentage of the users and for Users, by public ID.
+type Flag struct {
+	Enabled    bool     `json:"enabled"`
+	Percentage int      `json:"percentage"`
+	Users      []string `json:"users"`
+}
+
+// Validate ...
+func (f Flag) Validate() error {
+	if f.Percentage < 0 || f.Percentage > 100 {
+		return errPercentage
+	}
+
+	return nil
+}
+
+// enabledFor reports whether the flag named name is on for subject. Each
+// subject falls in the same bucket of a flag every time, so the users a
+// feature is on for stay the same as its percentage grows.
+func (f Flag) enabledFor(name, subject string) bool {
+	if f.Enabled {
+		return true
+	}
+
+	if subject == "" {
+		return false
+	}
+
+	for _, u := range f.Users {
+		if u == subject {
+			return true
+		}
+	}
+
+	if f.Percentage <= 0 {
+		return false
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + subject))
+
+	return int(h.Sum32()%100) < f.Percentage
+}
+
+// Parse reads flags written as name, on for everyone, name:NN% for a
+// share of users, or name:user:ID for a user. A flag may be given several
+// times to combine rollouts, as in ["search_v2:10%", "search_v2:user:ID"].
+func Parse(specs []string) (map[string]Flag, error) {
+	flags := map[string]Flag{}
+	for _, spec := range specs {
+		parts := strings.SplitN(strings.TrimSpace(spec), ":", 2)
+		name := parts[0]
+		if !validName.MatchString(name) {
+			return nil, fmt.Errorf("%q: invalid flag name", spec)
+		}
+
+		f := flags[name]
+		switch {
+		case len(parts) == 1 || parts[1] == "on":
+			f.Enabled = true
+		case parts[1] == "off":
+		case strings.HasPrefix(parts[1], "user:") && len(parts[1]) > len("user:"):
+			f.Users = append(f.Users, strings.TrimPrefix(parts[1], "user:"))
+		case strings.HasSuffix(parts[1], "%"):
+			percentage, err := strconv.Atoi(strings.TrimSuffix(parts[1], "%"))
+			if err != nil {
+				return nil, fmt.Errorf("%q: %v", spec, errPercentage)
+			}
+			f.Percentage = percentage
+		default:
+			return nil, fmt.Errorf("%q: expected on, off, NN%% or user:ID", spec)
+		}
+
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("%q: %v", spec, err)
+		}
+		flags[name] = f
+	}
+
+	return flags, nil
+}
+
+// Flags are the current rollouts, safe for concurrent use. Unknown flags
+// are off.
+type Flags struct {
+	mu    sync.RWMutex
+	flags map[string]Flag
+}
+
+// New ...
+func New(flags map[string]Flag) *Flags {
+	f := &Flags{}
+	f.Replace(flags)
+
+	return f
+}
+
+// Enabled reports whether the feature is on for subject, the public ID of
+// a user or, for anonymous requests, any other stable identifier
+func (f *Flags) Enabled(name, subject string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	flag, ok := f.flags[name]
+
+	return ok && flag.enabledFor(name, subject)
+}
+
+// EnabledFor lists, sorted, the features that are on for subject
+func (f *Flags) EnabledFor(subject string) []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	names := []string{}
+	for name, flag := range f.flags {
+		if flag.enabledFor(name, subject) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// All ...
+func (f *Flags) All() map[string]Flag {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	flags := make(map[string]Flag, len(f.flags))
+	for name, flag := range f.flags {
+		flags[name] = flag
+	}
+
+	return flags
+}
+
+// Set replaces the rollout of a feature
+func (f *Flags) Set(name string, flag Flag) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("%q: invalid flag name", name)
+	}
+
+	if err := flag.Validate(); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.flags[name] = flag
+
+	return nil
+}
+
+// Delete turns a feature off for everyone
+func (f *Flags) Delete(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.flags, name)
+}
+
+// Replace replaces all the rollouts, such as when the config is reloaded
+func (f *Flags) Replace(flags map[string]Flag) {
+	copied := make(map[string]Flag, len(flags))
+	for name, flag := range flags {
+		copied[name] = flag
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.flags = copied
+}
@@ -0,0 +1,86 @@
+package features
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		specs    []string
+		expected map[string]Flag
+		isValid  bool
+	}{
+		{
+			name:     "on",
+			specs:    []string{"search_v2", "export:on", "legacy:off"},
+			expected: map[string]Flag{"search_v2": {Enabled: true}, "export": {Enabled: true}, "legacy": {}},
+			isValid:  true,
+		},
+		{
+			name:     "combined rollouts",
+			specs:    []string{"search_v2:25%", "search_v2:user:a", "search_v2:user:b"},
+			expected: map[string]Flag{"search_v2": {Percentage: 25, Users: []string{"a", "b"}}},
+			isValid:  true,
+		},
+		{
+			name:  "invalid name",
+			specs: []string{"Search V2"},
+		},
+		{
+			name:  "percentage over 100",
+			specs: []string{"search_v2:150%"},
+		},
+		{
+			name:  "unknown rule",
+			specs: []string{"search_v2:maybe"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			flags, err := Parse(tc.specs)
+			if tc.isValid {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, flags)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestFlags_Enabled(t *testing.T) {
+	flags := New(map[string]Flag{
+		"everyone": {Enabled: true},
+		"some":     {Users: []string{"a"}},
+		"half":     {Percentage: 50},
+		"none":     {Percentage: 0},
+	})
+
+	assert.True(t, flags.Enabled("everyone", ""))
+	assert.True(t, flags.Enabled("some", "a"))
+	assert.False(t, flags.Enabled("some", "b"))
+	assert.False(t, flags.Enabled("unknown", "a"))
+
+	on := 0
+	for i := 0; i < 1000; i++ {
+		subject := fmt.Sprintf("user-%d", i)
+		assert.False(t, flags.Enabled("none", subject))
+		if flags.Enabled("half", subject) {
+			on++
+			assert.True(t, flags.Enabled("half", subject), "stable for a subject")
+		}
+	}
+	assert.InDelta(t, 500, on, 75)
+
+	assert.Error(t, flags.Set("half", Flag{Percentage: 101}))
+	assert.NoError(t, flags.Set("half", Flag{Percentage: 100}))
+	assert.Equal(t, []string{"everyone", "half"}, flags.EnabledFor("b"))
+
+	flags.Delete("everyone")
+	assert.False(t, flags.Enabled("everyone", ""))
+}
@@ -0,0 +1,193 @@
+// Package jobqueue runs background work, such as sending mail or calling
+// webhooks, from jobs stored in the database, so that it survives restarts
+// and is shared by the instances running workers.
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+	"winding-tree-server/internal/backoff"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrUnknownKind ...
+	ErrUnknownKind = errors.New("no handler for the job kind")
+)
+
+// Handler does the work of a job. An error retries the job until it has no
+// attempts left, so handlers may see the same job more than once and must
+// be idempotent.
+type Handler func(ctx context.Context, j *model.Job) error
+
+// Config ...
+type Config struct {
+	// MaxAttempts of the jobs enqueued, after which they are dead
+	MaxAttempts int
+	// PollInterval is how long an idle worker waits before looking for jobs
+	PollInterval time.Duration
+	// Lease bounds how long a job may run; past it the job is presumed
+	// abandoned and claimed again
+	Lease time.Duration
+	// RetryBaseDelay doubles after each failed attempt, up to RetryMaxDelay
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// Queue enqueues jobs and runs them with the handlers registered for their
+// kind. Handlers must be registered before Run.
+type Queue struct {
+	store    store.Store
+	logger   *logrus.Logger
+	config   Config
+	handlers map[string]Handler
+	kinds    []string
+}
+
+// New ...
+func New(store store.Store, logger *logrus.Logger, config Config) *Queue {
+	return &Queue{
+		store:    store,
+		logger:   logger,
+		config:   config,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle registers the handler of kind, such as "mail.send"
+func (q *Queue) Handle(kind string, h Handler) {
+	if _, ok := q.handlers[kind]; !ok {
+		q.kinds = append(q.kinds, kind)
+	}
+	q.handlers[kind] = h
+}
+
+// Enqueue stores a job of kind to run now with payload, encoded as JSON
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload interface{}) (*model.Job, error) {
+	return q.EnqueueAt(ctx, kind, payload, time.Now())
+}
+
+// EnqueueAt stores a job of kind to run at runAt
+func (q *Queue) EnqueueAt(ctx context.Context, kind string, payload interface{}, runAt time.Time) (*model.Job, error) {
+	j, err := model.NewJob(kind, payload, q.config.MaxAttempts, runAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := q.store.Job().Create(ctx, j); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// Run works through due jobs until ctx is done. It may be started from
+// several goroutines, and instances, to run jobs in parallel.
+func (q *Queue) Run(ctx context.Context) {
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		worked, err := q.Work(ctx)
+		if err != nil {
+			q.logger.Errorf("job queue failed: %v", err)
+		}
+
+		// Jobs are worked one after the other while there are some
+		if worked && err == nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Work runs one due job, if any, and reports whether it found one. A failed
+// job is retried with exponential backoff, or is dead once it is out of
+// attempts.
+func (q *Queue) Work(ctx context.Context) (bool, error) {
+	if len(q.kinds) == 0 {
+		return false, nil
+	}
+
+	claimed, err := q.store.Job().Claim(ctx, q.kinds, 1, q.config.Lease)
+	if err != nil || len(claimed) == 0 {
+		return false, err
+	}
+
+	j := claimed[0]
+	logger := q.logger.WithFields(logrus.Fields{
+		"job_id":   j.ID,
+		"job_kind": j.Kind,
+		"attempt":  j.Attempts,
+	})
+
+	start := time.Now()
+	if err := q.run(ctx, j); err != nil {
+		return true, leaseLost(logger, q.fail(ctx, logger, j, err))
+	}
+
+	logger.WithField("duration", time.Since(start)).Info("job done")
+
+	return true, leaseLost(logger, q.store.Job().Complete(ctx, j.ID, j.Attempts))
+}
+
+// leaseLost reports a job that ran past its lease, whose outcome is dropped
+// as another worker may have claimed it since; other errors are returned
+func leaseLost(logger *logrus.Entry, err error) error {
+	if err != store.ErrLeaseLost {
+		return err
+	}
+
+	logger.Warn("job lease lost, its outcome is dropped")
+
+	return nil
+}
+
+// run calls the handler of j within its lease, turning a panic into an error
+// so that one bad job doesn't stop the worker
+func (q *Queue) run(ctx context.Context, j *model.Job) (err error) {
+	h, ok := q.handlers[j.Kind]
+	if !ok {
+		return ErrUnknownKind
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, q.config.Lease)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			q.logger.WithField("stack", string(debug.Stack())).Errorf("job %d panicked: %v", j.ID, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return h(ctx, j)
+}
+
+// fail ...
+func (q *Queue) fail(ctx context.Context, logger *logrus.Entry, j *model.Job, err error) error {
+	logger = logger.WithField("error", err.Error())
+	if j.Attempts >= j.MaxAttempts {
+		logger.Error("job dead")
+		return q.store.Job().Fail(ctx, j.ID, j.Attempts, err.Error(), nil)
+	}
+
+	retryAt := time.Now().Add(backoff.Delay(q.config.RetryBaseDelay, q.config.RetryMaxDelay, j.Attempts))
+	logger.WithField("retry_at", retryAt).Warn("job failed")
+
+	return q.store.Job().Fail(ctx, j.ID, j.Attempts, err.Error(), &retryAt)
+}
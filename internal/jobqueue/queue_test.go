@@ -0,0 +1,140 @@
+package jobqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueue_Work(t *testing.T) {
+	testCases := []struct {
+		name        string
+		handler     Handler
+		maxAttempts int
+		state       string
+		lastError   string
+		retried     bool
+	}{
+		{
+			name: "done",
+			handler: func(ctx context.Context, j *model.Job) error {
+				return nil
+			},
+			maxAttempts: 3,
+			state:       model.JobDone,
+		},
+		{
+			name: "retried",
+			handler: func(ctx context.Context, j *model.Job) error {
+				return errors.New("unavailable")
+			},
+			maxAttempts: 3,
+			state:       model.JobPending,
+			lastError:   "unavailable",
+			retried:     true,
+		},
+		{
+			name: "dead after the last attempt",
+			handler: func(ctx context.Context, j *model.Job) error {
+				return errors.New("unavailable")
+			},
+			maxAttempts: 1,
+			state:       model.JobDead,
+			lastError:   "unavailable",
+		},
+		{
+			name: "panic",
+			handler: func(ctx context.Context, j *model.Job) error {
+				panic("boom")
+			},
+			maxAttempts: 3,
+			state:       model.JobPending,
+			lastError:   "panic: boom",
+			retried:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := teststore.New()
+			logger, _ := test.NewNullLogger()
+			q := New(s, logger, Config{
+				MaxAttempts:    tc.maxAttempts,
+				Lease:          time.Minute,
+				RetryBaseDelay: time.Minute,
+				RetryMaxDelay:  time.Hour,
+			})
+
+			var payload map[string]string
+			q.Handle("test", func(ctx context.Context, j *model.Job) error {
+				assert.NoError(t, json.Unmarshal(j.Payload, &payload))
+				return tc.handler(ctx, j)
+			})
+
+			j, err := q.Enqueue(ctx, "test", map[string]string{"to": "user@example.org"})
+			assert.NoError(t, err)
+
+			worked, err := q.Work(ctx)
+			assert.NoError(t, err)
+			assert.True(t, worked)
+			assert.Equal(t, map[string]string{"to": "user@example.org"}, payload)
+
+			j, err = s.Job().Find(ctx, j.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.state, j.State)
+			assert.Equal(t, 1, j.Attempts)
+			assert.Equal(t, tc.lastError, j.LastError)
+			if tc.retried {
+				assert.WithinDuration(t, time.Now().Add(time.Minute), j.RunAt, time.Second)
+			}
+
+			// A retried job isn't due yet, and finished ones are never
+			worked, err = q.Work(ctx)
+			assert.NoError(t, err)
+			assert.False(t, worked)
+		})
+	}
+}
+
+func TestQueue_Run(t *testing.T) {
+	s := teststore.New()
+	logger, _ := test.NewNullLogger()
+	q := New(s, logger, Config{
+		MaxAttempts:    1,
+		PollInterval:   time.Millisecond,
+		Lease:          time.Minute,
+		RetryBaseDelay: time.Minute,
+		RetryMaxDelay:  time.Hour,
+	})
+
+	done := make(chan int, 2)
+	q.Handle("test", func(ctx context.Context, j *model.Job) error {
+		done <- j.ID
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	for i := 0; i < 2; i++ {
+		_, err := q.Enqueue(ctx, "test", nil)
+		assert.NoError(t, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("job not run")
+		}
+	}
+}
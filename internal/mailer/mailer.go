@@ -0,0 +1,76 @@
+package mailer
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// Message ...
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers a single message
+type Mailer interface {
+	Send(*Message) error
+}
+
+// Template renders messages from text/template subject and body and an optional html/template body
+type Template struct {
+	Subject *texttemplate.Template
+	Text    *texttemplate.Template
+	HTML    *htmltemplate.Template
+}
+
+// NewTemplate parses the given sources; html may be empty
+func NewTemplate(name, subject, text, html string) (*Template, error) {
+	t := &Template{}
+
+	var err error
+	if t.Subject, err = texttemplate.New(name + ".subject").Parse(subject); err != nil {
+		return nil, err
+	}
+
+	if t.Text, err = texttemplate.New(name + ".text").Parse(text); err != nil {
+		return nil, err
+	}
+
+	if html != "" {
+		if t.HTML, err = htmltemplate.New(name + ".html").Parse(html); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// Render builds a message for to using data
+func (t *Template) Render(to string, data interface{}) (*Message, error) {
+	m := &Message{To: to}
+
+	var buf bytes.Buffer
+	if err := t.Subject.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	m.Subject = buf.String()
+
+	buf.Reset()
+	if err := t.Text.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	m.Text = buf.String()
+
+	if t.HTML != nil {
+		buf.Reset()
+		if err := t.HTML.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		m.HTML = buf.String()
+	}
+
+	return m, nil
+}
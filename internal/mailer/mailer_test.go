@@ -0,0 +1,25 @@
+package mailer_test
+
+import (
+	"testing"
+	"winding-tree-server/internal/mailer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate_Render(t *testing.T) {
+	tpl, err := mailer.NewTemplate(
+		"welcome",
+		"Welcome, {{.Name}}",
+		"Hello {{.Name}}",
+		"<p>Hello {{.Name}}</p>",
+	)
+	assert.NoError(t, err)
+
+	m, err := tpl.Render("user@example.test", struct{ Name string }{"<b>Bob</b>"})
+	assert.NoError(t, err)
+	assert.Equal(t, "user@example.test", m.To)
+	assert.Equal(t, "Welcome, <b>Bob</b>", m.Subject)
+	assert.Equal(t, "Hello <b>Bob</b>", m.Text)
+	assert.Equal(t, "<p>Hello &lt;b&gt;Bob&lt;/b&gt;</p>", m.HTML)
+}
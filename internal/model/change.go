@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entities with change history
+const (
+	EntityUser       = "user"
+	EntityFare       = "fare"
+	EntityOnboarding = "onboarding"
+)
+
+// Change actions
+const (
+	ChangeCreate    = "create"
+	ChangeUpdate    = "update"
+	ChangeDelete    = "delete"
+	ChangeRestore   = "restore"
+	ChangeAnonymize = "anonymize"
+)
+
+// Change is a before/after snapshot of an entity, recorded in the same
+// transaction as the change. Before is null for creates.
+type Change struct {
+	ID        int             `json:"id"`
+	Entity    string          `json:"entity"`
+	EntityID  int             `json:"entity_id"`
+	Action    string          `json:"action"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// NewChange snapshots before and after, either of which may be nil
+func NewChange(entity string, entityID int, action string, before, after interface{}) (*Change, error) {
+	c := &Change{
+		Entity:   entity,
+		EntityID: entityID,
+		Action:   action,
+	}
+
+	var err error
+	if before != nil {
+		if c.Before, err = json.Marshal(before); err != nil {
+			return nil, err
+		}
+	}
+
+	if after != nil {
+		if c.After, err = json.Marshal(after); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// ContractEvent is a confirmed smart contract log persisted by the event listener
+type ContractEvent struct {
+	ID          int      `json:"id"`
+	Address     string   `json:"address"`
+	Topic       string   `json:"topic"`
+	Topics      []string `json:"topics"`
+	Data        []byte   `json:"data"`
+	BlockNumber uint64   `json:"block_number"`
+	BlockHash   string   `json:"block_hash"`
+	TxHash      string   `json:"tx_hash"`
+	LogIndex    uint64   `json:"log_index"`
+	// Handled is the number of the topic's handlers, in the order they run,
+	// that succeeded; a retry resumes with the next one
+	Handled   int    `json:"handled"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	// RetryAt delays the next attempt after a failure
+	RetryAt *time.Time `json:"retry_at,omitempty"`
+	// FailedAt is set once the event is out of attempts; it is no longer
+	// dispatched
+	FailedAt    *time.Time `json:"failed_at,omitempty"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
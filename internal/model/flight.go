@@ -0,0 +1,145 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+)
+
+// Cabin classes
+const (
+	CabinEconomy        = "economy"
+	CabinPremiumEconomy = "premium_economy"
+	CabinBusiness       = "business"
+	CabinFirst          = "first"
+)
+
+var (
+	airportCode = regexp.MustCompile(`^[A-Z]{3}$`)
+	carrierCode = regexp.MustCompile(`^[A-Z0-9]{2}$`)
+	cabins      = []interface{}{CabinEconomy, CabinPremiumEconomy, CabinBusiness, CabinFirst}
+
+	errSegmentsNotConnected = errors.New("segments must connect in order")
+	errDuplicateCabin       = errors.New("only one fare per cabin is allowed")
+)
+
+// Flight is an airline supplier's itinerary of one or more segments
+type Flight struct {
+	ID       int              `json:"id"`
+	UserID   int              `json:"-"`
+	Segments []*FlightSegment `json:"segments"`
+	Fares    []*Fare          `json:"fares,omitempty"`
+}
+
+// FlightSegment is a single leg of a flight, times are UTC
+type FlightSegment struct {
+	ID          int       `json:"id"`
+	FlightID    int       `json:"-"`
+	Position    int       `json:"position"`
+	Carrier     string    `json:"carrier"`
+	Number      string    `json:"number"`
+	Origin      string    `json:"origin"`
+	Destination string    `json:"destination"`
+	DepartureAt time.Time `json:"departure_at"`
+	ArrivalAt   time.Time `json:"arrival_at"`
+}
+
+// Fare is a bookable price for a cabin on a flight, amount in minor currency units
+type Fare struct {
+	ID             int    `json:"id"`
+	FlightID       int    `json:"flight_id"`
+	Cabin          string `json:"cabin"`
+	Amount         int64  `json:"amount"`
+	Currency       string `json:"currency"`
+	SeatsAvailable int    `json:"seats_available"`
+}
+
+// FlightOffer is a search result: a flight together with one of its fares
+type FlightOffer struct {
+	Flight *Flight `json:"flight"`
+	Fare   *Fare   `json:"fare"`
+}
+
+// FlightSearch ...
+type FlightSearch struct {
+	Origin      string `form:"origin"`
+	Destination string `form:"destination"`
+	Date        string `form:"date"`
+	Cabin       string `form:"cabin"`
+	Passengers  int    `form:"passengers"`
+}
+
+// Validate ...
+func (f *Flight) Validate() error {
+	if err := validation.ValidateStruct(
+		f,
+		validation.Field(&f.UserID, validation.Required),
+		validation.Field(&f.Segments, validation.Required),
+		validation.Field(&f.Fares),
+	); err != nil {
+		return err
+	}
+
+	for i := 1; i < len(f.Segments); i++ {
+		prev, next := f.Segments[i-1], f.Segments[i]
+		if prev.Destination != next.Origin || !next.DepartureAt.After(prev.ArrivalAt) {
+			return validation.Errors{"segments": errSegmentsNotConnected}
+		}
+	}
+
+	cabins := map[string]bool{}
+	for _, fare := range f.Fares {
+		if cabins[fare.Cabin] {
+			return validation.Errors{"fares": errDuplicateCabin}
+		}
+		cabins[fare.Cabin] = true
+	}
+
+	return nil
+}
+
+// BeforeCreate numbers the segments in order
+func (f *Flight) BeforeCreate() {
+	for i, s := range f.Segments {
+		s.Position = i
+	}
+}
+
+// Validate ...
+func (s *FlightSegment) Validate() error {
+	return validation.ValidateStruct(
+		s,
+		validation.Field(&s.Carrier, validation.Required, validation.Match(carrierCode)),
+		validation.Field(&s.Number, validation.Required, is.Digit, validation.Length(1, 4)),
+		validation.Field(&s.Origin, validation.Required, validation.Match(airportCode)),
+		validation.Field(&s.Destination, validation.Required, validation.Match(airportCode), validation.NotIn(s.Origin)),
+		validation.Field(&s.DepartureAt, validation.Required),
+		validation.Field(&s.ArrivalAt, validation.Required, validation.Min(s.DepartureAt).Exclusive()),
+	)
+}
+
+// Validate ...
+func (f *Fare) Validate() error {
+	return validation.ValidateStruct(
+		f,
+		validation.Field(&f.Cabin, validation.Required, validation.In(cabins...)),
+		validation.Field(&f.Amount, validation.Required, validation.Min(int64(1))),
+		validation.Field(&f.Currency, validation.Required, is.CurrencyCode),
+		validation.Field(&f.SeatsAvailable, validation.Min(0)),
+	)
+}
+
+// Validate ...
+func (s *FlightSearch) Validate() error {
+	return validation.ValidateStruct(
+		s,
+		validation.Field(&s.Origin, validation.Required, validation.Match(airportCode)),
+		validation.Field(&s.Destination, validation.Required, validation.Match(airportCode)),
+		validation.Field(&s.Date, validation.Required, validation.Date("2006-01-02")),
+		validation.Field(&s.Cabin, validation.In(cabins...)),
+		validation.Field(&s.Passengers, validation.Min(1), validation.Max(9)),
+	)
+}
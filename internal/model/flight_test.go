@@ -0,0 +1,128 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlight_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		f       func() *model.Flight
+		isValid bool
+	}{
+		{
+			name: "valid",
+			f: func() *model.Flight {
+				return model.TestFlight(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "without segments",
+			f: func() *model.Flight {
+				f := model.TestFlight(t)
+				f.Segments = nil
+				return f
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid airport code",
+			f: func() *model.Flight {
+				f := model.TestFlight(t)
+				f.Segments[0].Origin = "zrh"
+				return f
+			},
+			isValid: false,
+		},
+		{
+			name: "arrival before departure",
+			f: func() *model.Flight {
+				f := model.TestFlight(t)
+				f.Segments[0].ArrivalAt = f.Segments[0].DepartureAt.Add(-time.Hour)
+				return f
+			},
+			isValid: false,
+		},
+		{
+			name: "disconnected segments",
+			f: func() *model.Flight {
+				f := model.TestFlight(t)
+				f.Segments[1].Origin = "MUC"
+				return f
+			},
+			isValid: false,
+		},
+		{
+			name: "overlapping segments",
+			f: func() *model.Flight {
+				f := model.TestFlight(t)
+				f.Segments[1].DepartureAt = f.Segments[0].ArrivalAt.Add(-time.Minute)
+				return f
+			},
+			isValid: false,
+		},
+		{
+			name: "unknown cabin",
+			f: func() *model.Flight {
+				f := model.TestFlight(t)
+				f.Fares[0].Cabin = "cargo"
+				return f
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid currency",
+			f: func() *model.Flight {
+				f := model.TestFlight(t)
+				f.Fares[0].Currency = "EURO"
+				return f
+			},
+			isValid: false,
+		},
+		{
+			name: "duplicate cabin",
+			f: func() *model.Flight {
+				f := model.TestFlight(t)
+				fare := *f.Fares[0]
+				f.Fares = append(f.Fares, &fare)
+				return f
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.f().Validate())
+			} else {
+				assert.Error(t, tc.f().Validate())
+			}
+		})
+	}
+}
+
+func TestFlight_BeforeCreate(t *testing.T) {
+	f := model.TestFlight(t)
+	f.BeforeCreate()
+	assert.Equal(t, 0, f.Segments[0].Position)
+	assert.Equal(t, 1, f.Segments[1].Position)
+}
+
+func TestFlightSearch_Validate(t *testing.T) {
+	s := &model.FlightSearch{
+		Origin:      "ZRH",
+		Destination: "KBP",
+		Date:        "2019-12-20",
+		Passengers:  1,
+	}
+	assert.NoError(t, s.Validate())
+
+	s.Date = "20.12.2019"
+	assert.Error(t, s.Validate())
+}
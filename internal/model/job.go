@@ -0,0 +1,60 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job states
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	// JobDead jobs failed their last attempt. They are kept for admins to
+	// inspect and retry.
+	JobDead = "dead"
+)
+
+// JobLeaseExpired is the error of a job dead because the lease of its last
+// attempt ended, such as when its worker crashed
+const JobLeaseExpired = "lease expired on the last attempt"
+
+// Job is background work, such as sending a mail, stored until a worker
+// of the job queue has done it
+type Job struct {
+	ID          int             `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	State       string          `json:"state"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	RunAt       time.Time       `json:"run_at"`
+	// LockedUntil is the end of the lease of the worker running the job;
+	// past it the worker is presumed gone and another one takes the job
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// NewJob ...
+func NewJob(kind string, payload interface{}, maxAttempts int, runAt time.Time) (*Job, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Job{
+		Kind:        kind,
+		Payload:     b,
+		State:       JobPending,
+		MaxAttempts: maxAttempts,
+		RunAt:       runAt,
+	}, nil
+}
+
+// Retryable reports whether the job can be rescheduled, which running and
+// finished jobs can't
+func (j *Job) Retryable() bool {
+	return j.State == JobPending || j.State == JobDead
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Onboarding states
+const (
+	OnboardingDraft     = "draft"
+	OnboardingSubmitted = "submitted"
+	OnboardingApproved  = "approved"
+	OnboardingRejected  = "rejected"
+)
+
+// Onboarding document kinds
+const (
+	DocumentRegistration = "registration"
+	DocumentIdentity     = "identity"
+	DocumentAddress      = "proof_of_address"
+	DocumentOther        = "other"
+)
+
+var (
+	// ErrInvalidTransition ...
+	ErrInvalidTransition = errors.New("invalid onboarding state transition")
+	// ErrNoDocuments ...
+	ErrNoDocuments = errors.New("at least one document is required")
+
+	onboardingTransitions = map[string][]string{
+		OnboardingDraft:     {OnboardingSubmitted},
+		OnboardingSubmitted: {OnboardingApproved, OnboardingRejected},
+		OnboardingRejected:  {OnboardingSubmitted},
+	}
+)
+
+// Onboarding is a supplier's KYC review state
+type Onboarding struct {
+	// UserID and ReviewerID are internal ids, never serialised; views add
+	// the public ids of the users where clients need them
+	UserID          int        `json:"-"`
+	State           string     `json:"state"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	ReviewerID      *int       `json:"-"`
+	SubmittedAt     *time.Time `json:"submitted_at,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	// Version is incremented on every save; zero means not yet stored
+	Version int `json:"version"`
+	// events are the outbox topics of transitions made since the last save
+	events []string
+}
+
+// OnboardingDocument is a file uploaded for KYC review; Content is only loaded for downloads
+type OnboardingDocument struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"-"`
+	Kind        string    `json:"kind"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Content     []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewOnboarding ...
+func NewOnboarding(userID int) *Onboarding {
+	return &Onboarding{
+		UserID: userID,
+		State:  OnboardingDraft,
+	}
+}
+
+// IsApproved ...
+func (o *Onboarding) IsApproved() bool {
+	return o.State == OnboardingApproved
+}
+
+// CanUpload reports whether documents may still be added
+func (o *Onboarding) CanUpload() bool {
+	return o.State == OnboardingDraft || o.State == OnboardingRejected
+}
+
+// Submit moves the onboarding into the review queue
+func (o *Onboarding) Submit(documents int) error {
+	if documents == 0 {
+		return ErrNoDocuments
+	}
+
+	if err := o.transition(OnboardingSubmitted); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	o.SubmittedAt = &now
+	o.RejectionReason = ""
+	o.ReviewerID = nil
+	o.ReviewedAt = nil
+
+	return nil
+}
+
+// Approve ...
+func (o *Onboarding) Approve(reviewerID int) error {
+	return o.review(OnboardingApproved, reviewerID, "")
+}
+
+// Reject requires a reason that is shown to the supplier
+func (o *Onboarding) Reject(reviewerID int, reason string) error {
+	if err := validation.Validate(reason, validation.Required, validation.Length(1, 1000)); err != nil {
+		return validation.Errors{"reason": err}
+	}
+
+	return o.review(OnboardingRejected, reviewerID, reason)
+}
+
+// review ...
+func (o *Onboarding) review(state string, reviewerID int, reason string) error {
+	if err := o.transition(state); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	o.ReviewerID = &reviewerID
+	o.ReviewedAt = &now
+	o.RejectionReason = reason
+
+	return nil
+}
+
+// Events returns the outbox topics of the transitions not yet saved,
+// e.g. "onboarding.approved"
+func (o *Onboarding) Events() []string {
+	return o.events
+}
+
+// ClearEvents is called by stores once the events have been saved
+func (o *Onboarding) ClearEvents() {
+	o.events = nil
+}
+
+// transition ...
+func (o *Onboarding) transition(to string) error {
+	for _, s := range onboardingTransitions[o.State] {
+		if s == to {
+			o.State = to
+			o.events = append(o.events, "onboarding."+to)
+			return nil
+		}
+	}
+
+	return ErrInvalidTransition
+}
+
+// Validate ...
+func (d *OnboardingDocument) Validate() error {
+	return validation.ValidateStruct(
+		d,
+		validation.Field(&d.UserID, validation.Required),
+		validation.Field(&d.Kind, validation.Required, validation.In(DocumentRegistration, DocumentIdentity, DocumentAddress, DocumentOther)),
+		validation.Field(&d.FileName, validation.Required, validation.Length(1, 255)),
+		validation.Field(&d.ContentType, validation.Required, validation.In("application/pdf", "image/jpeg", "image/png")),
+		validation.Field(&d.Content, validation.Required),
+	)
+}
@@ -0,0 +1,47 @@
+package model_test
+
+import (
+	"testing"
+	"winding-tree-server/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnboarding_Workflow(t *testing.T) {
+	o := model.NewOnboarding(1)
+	assert.True(t, o.CanUpload())
+	assert.Equal(t, model.ErrNoDocuments, o.Submit(0))
+	assert.Equal(t, model.ErrInvalidTransition, o.Approve(2))
+
+	assert.NoError(t, o.Submit(1))
+	assert.Equal(t, model.OnboardingSubmitted, o.State)
+	assert.False(t, o.CanUpload())
+
+	assert.Error(t, o.Reject(2, ""))
+	assert.NoError(t, o.Reject(2, "document is not readable"))
+	assert.Equal(t, model.OnboardingRejected, o.State)
+	assert.Equal(t, "document is not readable", o.RejectionReason)
+	assert.True(t, o.CanUpload())
+
+	assert.NoError(t, o.Submit(2))
+	assert.Empty(t, o.RejectionReason)
+	assert.NoError(t, o.Approve(2))
+	assert.True(t, o.IsApproved())
+	assert.Equal(t, 2, *o.ReviewerID)
+
+	assert.Equal(t, model.ErrInvalidTransition, o.Submit(2))
+}
+
+func TestOnboardingDocument_Validate(t *testing.T) {
+	d := &model.OnboardingDocument{
+		UserID:      1,
+		Kind:        model.DocumentIdentity,
+		FileName:    "passport.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("%PDF-1.4"),
+	}
+	assert.NoError(t, d.Validate())
+
+	d.ContentType = "application/x-msdownload"
+	assert.Error(t, d.Validate())
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"golang.org/x/crypto/sha3"
+)
+
+// OrgJSON is a versioned ORG.JSON document uploaded by a supplier
+type OrgJSON struct {
+	ID        int             `json:"id"`
+	UserID    int             `json:"-"`
+	Version   int             `json:"version"`
+	Document  json.RawMessage `json:"document"`
+	Hash      string          `json:"hash"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// orgJSONDocument is the part of the Winding Tree ORG.JSON schema we validate
+type orgJSONDocument struct {
+	DataFormatVersion string              `json:"dataFormatVersion"`
+	UpdatedAt         string              `json:"updatedAt"`
+	LegalEntity       *orgJSONLegalEntity `json:"legalEntity"`
+}
+
+type orgJSONLegalEntity struct {
+	LegalName string          `json:"legalName"`
+	Address   *orgJSONAddress `json:"address"`
+}
+
+type orgJSONAddress struct {
+	Road        string `json:"road"`
+	City        string `json:"city"`
+	CountryCode string `json:"countryCode"`
+}
+
+// Validate ...
+func (d *orgJSONDocument) Validate() error {
+	return validation.ValidateStruct(
+		d,
+		validation.Field(&d.DataFormatVersion, validation.Required),
+		validation.Field(&d.UpdatedAt, validation.Required, validation.Date(time.RFC3339)),
+		validation.Field(&d.LegalEntity, validation.Required),
+	)
+}
+
+// Validate ...
+func (e *orgJSONLegalEntity) Validate() error {
+	return validation.ValidateStruct(
+		e,
+		validation.Field(&e.LegalName, validation.Required),
+		validation.Field(&e.Address, validation.Required),
+	)
+}
+
+// Validate ...
+func (a *orgJSONAddress) Validate() error {
+	return validation.ValidateStruct(
+		a,
+		validation.Field(&a.Road, validation.Required),
+		validation.Field(&a.City, validation.Required),
+		validation.Field(&a.CountryCode, validation.Required, is.CountryCode2),
+	)
+}
+
+// Validate checks the document against the ORG.JSON schema
+func (o *OrgJSON) Validate() error {
+	if err := validation.ValidateStruct(
+		o,
+		validation.Field(&o.UserID, validation.Required),
+		validation.Field(&o.Document, validation.Required),
+	); err != nil {
+		return err
+	}
+
+	d := &orgJSONDocument{}
+	if err := json.Unmarshal(o.Document, d); err != nil {
+		return validation.Errors{"document": err}
+	}
+
+	if err := d.Validate(); err != nil {
+		return validation.Errors{"document": err}
+	}
+
+	return nil
+}
+
+// BeforeCreate ...
+func (o *OrgJSON) BeforeCreate() error {
+	o.Hash = keccak256Hex(o.Document)
+	return nil
+}
+
+// keccak256Hex returns the 0x-prefixed keccak256 hash used for on-chain registration
+func keccak256Hex(b []byte) string {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+
+	return "0x" + hex.EncodeToString(h.Sum(nil))
+}
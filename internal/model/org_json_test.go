@@ -0,0 +1,93 @@
+package model_test
+
+import (
+	"testing"
+	"winding-tree-server/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgJSON_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		o       func() *model.OrgJSON
+		isValid bool
+	}{
+		{
+			name: "valid",
+			o: func() *model.OrgJSON {
+				return model.TestOrgJSON(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "empty document",
+			o: func() *model.OrgJSON {
+				o := model.TestOrgJSON(t)
+				o.Document = nil
+				return o
+			},
+			isValid: false,
+		},
+		{
+			name: "malformed json",
+			o: func() *model.OrgJSON {
+				o := model.TestOrgJSON(t)
+				o.Document = []byte(`{"dataFormatVersion":`)
+				return o
+			},
+			isValid: false,
+		},
+		{
+			name: "missing legal entity",
+			o: func() *model.OrgJSON {
+				o := model.TestOrgJSON(t)
+				o.Document = []byte(`{"dataFormatVersion": "0.2.3", "updatedAt": "2019-11-05T12:00:00Z"}`)
+				return o
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid country code",
+			o: func() *model.OrgJSON {
+				o := model.TestOrgJSON(t)
+				o.Document = []byte(`{
+					"dataFormatVersion": "0.2.3",
+					"updatedAt": "2019-11-05T12:00:00Z",
+					"legalEntity": {
+						"legalName": "Example Hotels Ltd.",
+						"address": {"road": "Main street 1", "city": "Zug", "countryCode": "Switzerland"}
+					}
+				}`)
+				return o
+			},
+			isValid: false,
+		},
+		{
+			name: "without user",
+			o: func() *model.OrgJSON {
+				o := model.TestOrgJSON(t)
+				o.UserID = 0
+				return o
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.o().Validate())
+			} else {
+				assert.Error(t, tc.o().Validate())
+			}
+		})
+	}
+}
+
+func TestOrgJSON_BeforeCreate(t *testing.T) {
+	o := model.TestOrgJSON(t)
+	o.Document = []byte("")
+	assert.NoError(t, o.BeforeCreate())
+	assert.Equal(t, "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470", o.Hash)
+}
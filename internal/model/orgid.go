@@ -0,0 +1,29 @@
+package model
+
+import (
+	"math/big"
+	"time"
+)
+
+// OrgID is an on-chain organization mirrored from the ORGiD directories
+type OrgID struct {
+	ID          string    `json:"id"`
+	Directory   string    `json:"directory"`
+	OrgJSONURI  string    `json:"orgjson_uri"`
+	OrgJSONHash string    `json:"orgjson_hash"`
+	Owner       string    `json:"owner"`
+	IsActive    bool      `json:"is_active"`
+	LifDeposit  string    `json:"lif_deposit"`
+	UserID      *int      `json:"-"`
+	SyncedAt    time.Time `json:"synced_at"`
+}
+
+// HasDeposit reports whether the cached Lif deposit (in wei) is at least min
+func (o *OrgID) HasDeposit(min *big.Int) bool {
+	deposit, ok := new(big.Int).SetString(o.LifDeposit, 10)
+	if !ok {
+		return false
+	}
+
+	return deposit.Cmp(min) >= 0
+}
@@ -0,0 +1,19 @@
+package model_test
+
+import (
+	"math/big"
+	"testing"
+	"winding-tree-server/internal/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgID_HasDeposit(t *testing.T) {
+	o := model.TestOrgID(t)
+	assert.True(t, o.HasDeposit(big.NewInt(0)))
+	assert.True(t, o.HasDeposit(new(big.Int).Exp(big.NewInt(10), big.NewInt(21), nil)))
+	assert.False(t, o.HasDeposit(new(big.Int).Exp(big.NewInt(10), big.NewInt(22), nil)))
+
+	o.LifDeposit = ""
+	assert.False(t, o.HasDeposit(big.NewInt(0)))
+}
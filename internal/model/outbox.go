@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outbox event topics
+const (
+	TopicFlightCreated = "flight.created"
+)
+
+// OutboxEvent is a domain event stored in the same transaction as the change
+// it describes and relayed to subscribers afterwards
+type OutboxEvent struct {
+	ID        int             `json:"id"`
+	Topic     string          `json:"topic"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int             `json:"-"`
+	LastError string          `json:"-"`
+	// RetryAt delays the next delivery after a failure
+	RetryAt *time.Time `json:"-"`
+	// FailedAt is set once the event is out of attempts; it is no longer
+	// relayed
+	FailedAt    *time.Time `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	PublishedAt *time.Time `json:"-"`
+}
+
+// NewOutboxEvent ...
+func NewOutboxEvent(topic string, payload interface{}) (*OutboxEvent, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OutboxEvent{
+		Topic:   topic,
+		Payload: b,
+	}, nil
+}
+
+// FlightPayload is the payload of flight events, which names the supplier
+// by its public id
+type FlightPayload struct {
+	*Flight
+	SupplierID string `json:"supplier_id"`
+}
+
+// OnboardingPayload is the payload of onboarding events, which names the
+// supplier and the reviewer by their public ids
+type OnboardingPayload struct {
+	*Onboarding
+	SupplierID string `json:"supplier_id"`
+	ReviewerID string `json:"reviewer_id,omitempty"`
+}
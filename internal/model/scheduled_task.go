@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// ScheduledTask is the last run of a recurring task, shared by the
+// instances that schedule it
+type ScheduledTask struct {
+	Name      string     `json:"name"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	// LockedUntil is set while an instance runs the task, and bounds how
+	// long it may hold it
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
@@ -1,6 +1,9 @@
 package model
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 // TestUser ...
 func TestUser(t *testing.T) *User {
@@ -9,3 +12,70 @@ func TestUser(t *testing.T) *User {
 		Password: "password",
 	}
 }
+
+// TestOrgJSON ...
+func TestOrgJSON(t *testing.T) *OrgJSON {
+	return &OrgJSON{
+		UserID: 1,
+		Document: []byte(`{
+			"dataFormatVersion": "0.2.3",
+			"updatedAt": "2019-11-05T12:00:00Z",
+			"legalEntity": {
+				"legalName": "Example Hotels Ltd.",
+				"address": {
+					"road": "Main street 1",
+					"city": "Zug",
+					"countryCode": "CH"
+				}
+			}
+		}`),
+	}
+}
+
+// TestOrgID ...
+func TestOrgID(t *testing.T) *OrgID {
+	return &OrgID{
+		ID:          "0x6c1ac1eb1a61b9e6f1b1cc0d66d4a9e2b4ac3b5a67e0bd47a2e0f9ae3c8a4b10",
+		Directory:   "0x0000000000000000000000000000000000000001",
+		OrgJSONURI:  "https://example.test/suppliers/1/org.json",
+		OrgJSONHash: "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		Owner:       "0x0000000000000000000000000000000000000002",
+		IsActive:    true,
+		LifDeposit:  "1000000000000000000000",
+	}
+}
+
+// TestFlight ...
+func TestFlight(t *testing.T) *Flight {
+	departure := time.Date(2019, 12, 20, 8, 0, 0, 0, time.UTC)
+
+	return &Flight{
+		UserID: 1,
+		Segments: []*FlightSegment{
+			{
+				Carrier:     "LX",
+				Number:      "1612",
+				Origin:      "ZRH",
+				Destination: "VIE",
+				DepartureAt: departure,
+				ArrivalAt:   departure.Add(90 * time.Minute),
+			},
+			{
+				Carrier:     "OS",
+				Number:      "651",
+				Origin:      "VIE",
+				Destination: "KBP",
+				DepartureAt: departure.Add(3 * time.Hour),
+				ArrivalAt:   departure.Add(5 * time.Hour),
+			},
+		},
+		Fares: []*Fare{
+			{
+				Cabin:          CabinEconomy,
+				Amount:         25000,
+				Currency:       "EUR",
+				SeatsAvailable: 9,
+			},
+		},
+	}
+}
@@ -1,6 +1,10 @@
 package model
 
 import (
+	"time"
+
+	"github.com/google/uuid"
+
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/go-ozzo/ozzo-validation/is"
 	"golang.org/x/crypto/bcrypt"
@@ -8,10 +12,17 @@ import (
 
 // User structure the same as into database
 type User struct {
-	ID                int    `json:"id"`
-	Email             string `json:"email"`
-	Password          string `json:"password,omitempty"`
-	EncryptedPassword string `json:"-"`
+	// ID is internal; outside the server users are known by PublicID, a UUID
+	// that can't be enumerated by guessing sequential numbers
+	ID                int        `json:"-"`
+	PublicID          string     `json:"id"`
+	Email             string     `json:"email"`
+	Password          string     `json:"password,omitempty"`
+	EncryptedPassword string     `json:"-"`
+	IsAdmin           bool       `json:"is_admin"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
+	// AnonymizedAt is set when the retention policy scrubbed a deleted user
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
 }
 
 // Validate ...
@@ -35,6 +46,10 @@ func (u *User) ComparePasswords(password string) bool {
 
 // BeforeCreate  ...
 func (u *User) BeforeCreate() error {
+	if u.PublicID == "" {
+		u.PublicID = uuid.New().String()
+	}
+
 	if len(u.Password) > 0 {
 		enc, err := encryptString(u.Password)
 
@@ -0,0 +1,181 @@
+package orgid
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+	"winding-tree-server/internal/ethereum"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Client is the part of the Ethereum client the syncer needs
+type Client interface {
+	DirectoryOrganizations(ctx context.Context, directory string) ([]string, error)
+	Organization(ctx context.Context, orgidAddress string, orgID string) (*ethereum.Organization, error)
+	LifDeposit(ctx context.Context, depositAddress string, orgID string) (*big.Int, error)
+}
+
+// Config ...
+type Config struct {
+	ORGiDAddress      string
+	LifDepositAddress string
+	Directories       []string
+	Interval          time.Duration
+	// PublicURL is where this server is reached, such as
+	// https://api.example.com; without it no organization is linked
+	PublicURL string
+}
+
+// Syncer mirrors organizations from ORGiD segment directories into the store
+type Syncer struct {
+	client  Client
+	store   store.Store
+	logger  *logrus.Logger
+	config  Config
+	trigger chan struct{}
+}
+
+// NewSyncer ...
+func NewSyncer(client Client, store store.Store, logger *logrus.Logger, config Config) *Syncer {
+	return &Syncer{
+		client:  client,
+		store:   store,
+		logger:  logger,
+		config:  config,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Run syncs immediately and then on every interval until ctx is done
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(ctx); err != nil {
+			s.logger.Errorf("orgid sync failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+	}
+}
+
+// Trigger requests a sync ahead of the next interval
+func (s *Syncer) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Sync mirrors every organization listed in the configured directories and
+// deletes those a directory no longer lists, which unlinks their suppliers
+func (s *Syncer) Sync(ctx context.Context) error {
+	for _, directory := range s.config.Directories {
+		ids, err := s.client.DirectoryOrganizations(ctx, directory)
+		if err != nil {
+			return err
+		}
+
+		synced := 0
+		for _, id := range ids {
+			org, err := s.client.Organization(ctx, s.config.ORGiDAddress, id)
+			if err != nil {
+				s.logger.Warnf("orgid %s: %v", id, err)
+				continue
+			}
+
+			if org == nil {
+				continue
+			}
+
+			deposit, err := s.lifDeposit(ctx, id)
+			if err != nil {
+				s.logger.Warnf("orgid %s: lif deposit: %v", id, err)
+				continue
+			}
+
+			o := &model.OrgID{
+				ID:          org.OrgID,
+				Directory:   directory,
+				OrgJSONURI:  org.OrgJSONURI,
+				OrgJSONHash: org.OrgJSONHash,
+				Owner:       org.Owner,
+				IsActive:    org.IsActive,
+				LifDeposit:  deposit.String(),
+			}
+			if o.UserID, err = s.supplier(ctx, org.OrgJSONURI); err != nil {
+				return err
+			}
+
+			if err := s.store.OrgID().Save(ctx, o); err != nil {
+				return err
+			}
+
+			synced++
+		}
+
+		removed, err := s.store.OrgID().Prune(ctx, directory, ids)
+		if err != nil {
+			return err
+		}
+
+		s.logger.Infof("orgid directory %s: synced %d of %d organizations, removed %d", directory, synced, len(ids), removed)
+	}
+
+	return nil
+}
+
+// lifDeposit returns zero when no deposit contract is configured
+func (s *Syncer) lifDeposit(ctx context.Context, id string) (*big.Int, error) {
+	if s.config.LifDepositAddress == "" {
+		return new(big.Int), nil
+	}
+
+	return s.client.LifDeposit(ctx, s.config.LifDepositAddress, id)
+}
+
+// supplier returns the id of the supplier whose ORG.JSON on this server uri
+// points at, such as https://api.example.com/suppliers/<public id>/org.json,
+// or nil. Only the owner of an organization can set its URI, so this is how
+// they link it to a supplier; the store checks that the supplier uploaded
+// the document on chain.
+func (s *Syncer) supplier(ctx context.Context, uri string) (*int, error) {
+	if s.config.PublicURL == "" {
+		return nil, nil
+	}
+
+	prefix := strings.TrimRight(s.config.PublicURL, "/") + "/suppliers/"
+	if !strings.HasPrefix(uri, prefix) {
+		return nil, nil
+	}
+
+	// <public id>/org.json, or <public id>/org.json/<version>
+	parts := strings.Split(strings.TrimPrefix(uri, prefix), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "org.json" {
+		return nil, nil
+	}
+	if _, err := uuid.Parse(parts[0]); err != nil {
+		return nil, nil
+	}
+
+	u, err := s.store.User().FindByPublicID(ctx, parts[0])
+	if err == store.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &u.ID, nil
+}
@@ -0,0 +1,167 @@
+package orgid_test
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+	"winding-tree-server/internal/ethereum"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/orgid"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// client serves organizations from memory
+type client struct {
+	orgs map[string]*ethereum.Organization
+}
+
+func (c *client) DirectoryOrganizations(ctx context.Context, directory string) ([]string, error) {
+	ids := []string{}
+	for id := range c.orgs {
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (c *client) Organization(ctx context.Context, orgidAddress string, orgID string) (*ethereum.Organization, error) {
+	return c.orgs[orgID], nil
+}
+
+func (c *client) LifDeposit(ctx context.Context, depositAddress string, orgID string) (*big.Int, error) {
+	return big.NewInt(1000), nil
+}
+
+func TestSyncer_Sync(t *testing.T) {
+	ctx := context.Background()
+	s := teststore.New()
+	owner := model.TestUser(t)
+	s.User().Create(ctx, owner)
+	copycat := model.TestUser(t)
+	copycat.Email = "copycat@example.test"
+	s.User().Create(ctx, copycat)
+
+	// The owner uploads the document, then someone else uploads a copy
+	var hash string
+	for _, u := range []*model.User{owner, copycat} {
+		doc := model.TestOrgJSON(t)
+		doc.UserID = u.ID
+		s.OrgJSON().Create(ctx, doc)
+		hash = doc.Hash
+	}
+
+	testCases := []struct {
+		name      string
+		publicURL string
+		uri       string
+		hash      string
+		linked    bool
+	}{
+		{
+			name:      "owner's org.json",
+			publicURL: "https://api.example.test",
+			uri:       "https://api.example.test/suppliers/" + owner.PublicID + "/org.json",
+			hash:      hash,
+			linked:    true,
+		},
+		{
+			name:      "owner's org.json version",
+			publicURL: "https://api.example.test/",
+			uri:       "https://api.example.test/suppliers/" + owner.PublicID + "/org.json/1",
+			hash:      hash,
+			linked:    true,
+		},
+		{
+			name:      "another document",
+			publicURL: "https://api.example.test",
+			uri:       "https://api.example.test/suppliers/" + owner.PublicID + "/org.json",
+			hash:      "0x01",
+		},
+		{
+			name:      "another server",
+			publicURL: "https://api.example.test",
+			uri:       "https://example.test/suppliers/" + owner.PublicID + "/org.json",
+			hash:      hash,
+		},
+		{
+			name:      "another path",
+			publicURL: "https://api.example.test",
+			uri:       "https://api.example.test/suppliers/" + owner.PublicID + "/documents",
+			hash:      hash,
+		},
+		{
+			name: "no public url",
+			uri:  "https://api.example.test/suppliers/" + owner.PublicID + "/org.json",
+			hash: hash,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &client{orgs: map[string]*ethereum.Organization{
+				"0x01": {OrgID: "0x01", OrgJSONURI: tc.uri, OrgJSONHash: tc.hash, IsActive: true},
+			}}
+			logger, _ := test.NewNullLogger()
+			syncer := orgid.NewSyncer(c, s, logger, orgid.Config{
+				Directories: []string{"0x02"},
+				Interval:    time.Minute,
+				PublicURL:   tc.publicURL,
+			})
+			assert.NoError(t, syncer.Sync(ctx))
+
+			orgs, err := s.OrgID().FindByUser(ctx, owner.ID)
+			assert.NoError(t, err)
+			if tc.linked {
+				assert.Len(t, orgs, 1)
+			} else {
+				assert.Len(t, orgs, 0)
+			}
+
+			orgs, err = s.OrgID().FindByUser(ctx, copycat.ID)
+			assert.NoError(t, err)
+			assert.Len(t, orgs, 0, "uploading a copy never links an organization")
+		})
+	}
+}
+
+func TestSyncer_SyncRemoved(t *testing.T) {
+	ctx := context.Background()
+	s := teststore.New()
+	owner := model.TestUser(t)
+	s.User().Create(ctx, owner)
+	doc := model.TestOrgJSON(t)
+	doc.UserID = owner.ID
+	s.OrgJSON().Create(ctx, doc)
+
+	c := &client{orgs: map[string]*ethereum.Organization{
+		"0x01": {
+			OrgID:       "0x01",
+			OrgJSONURI:  "https://api.example.test/suppliers/" + owner.PublicID + "/org.json",
+			OrgJSONHash: doc.Hash,
+			IsActive:    true,
+		},
+	}}
+	logger, _ := test.NewNullLogger()
+	syncer := orgid.NewSyncer(c, s, logger, orgid.Config{
+		Directories: []string{"0x02"},
+		Interval:    time.Minute,
+		PublicURL:   "https://api.example.test",
+	})
+	assert.NoError(t, syncer.Sync(ctx))
+
+	orgs, err := s.OrgID().FindByUser(ctx, owner.ID)
+	assert.NoError(t, err)
+	assert.Len(t, orgs, 1)
+
+	// The organization leaves the directory
+	delete(c.orgs, "0x01")
+	assert.NoError(t, syncer.Sync(ctx))
+
+	orgs, err = s.OrgID().FindByUser(ctx, owner.ID)
+	assert.NoError(t, err)
+	assert.Len(t, orgs, 0, "the supplier is unlinked")
+}
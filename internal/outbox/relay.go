@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/backoff"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+const relayBatch = 100
+
+// Publisher delivers an event to subscribers, e.g. a webhook or a message broker
+type Publisher interface {
+	Publish(ctx context.Context, e *model.OutboxEvent) error
+}
+
+// Config ...
+type Config struct {
+	PollInterval time.Duration
+	// MaxAttempts of an event, after which it has failed and is no longer
+	// relayed
+	MaxAttempts int
+	// RetryBaseDelay doubles after each failed attempt, up to RetryMaxDelay
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+}
+
+// Relay publishes outbox events in the order they were written. An event is
+// marked published only after the publisher accepted it, so delivery is at
+// least once and subscribers should deduplicate on the event id.
+type Relay struct {
+	store     store.Store
+	publisher Publisher
+	logger    *logrus.Logger
+	config    Config
+}
+
+// NewRelay ...
+func NewRelay(store store.Store, publisher Publisher, logger *logrus.Logger, config Config) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		logger:    logger,
+		config:    config,
+	}
+}
+
+// Run relays until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Relay(ctx); err != nil {
+			r.logger.Errorf("outbox relay failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes unpublished events until none are left. It stops at the
+// first event that can't be published so that later events aren't delivered
+// before it; that event is retried with backoff on later runs. Once it is out
+// of attempts it has failed, is left for admins and no longer holds back the
+// events after it.
+func (r *Relay) Relay(ctx context.Context) error {
+	for {
+		events, err := r.store.Outbox().FindUnpublished(ctx, relayBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			if e.RetryAt != nil && e.RetryAt.After(time.Now()) {
+				return nil
+			}
+
+			if err := r.publisher.Publish(ctx, e); err != nil {
+				retried := e.Attempts+1 < r.config.MaxAttempts
+				if err := r.fail(ctx, e, err); err != nil {
+					return err
+				}
+				if retried {
+					return nil
+				}
+
+				continue
+			}
+
+			if err := r.store.Outbox().MarkPublished(ctx, e.ID); err != nil {
+				return err
+			}
+		}
+
+		if len(events) < relayBatch {
+			return nil
+		}
+	}
+}
+
+// fail records a failed delivery. The event is retried after a backoff, or
+// has failed for good once it is out of attempts.
+func (r *Relay) fail(ctx context.Context, e *model.OutboxEvent, err error) error {
+	logger := r.logger.WithFields(logrus.Fields{
+		"event_id": e.ID,
+		"topic":    e.Topic,
+		"attempt":  e.Attempts + 1,
+		"error":    err.Error(),
+	})
+
+	if e.Attempts+1 >= r.config.MaxAttempts {
+		logger.Error("outbox event failed")
+		return r.store.Outbox().MarkFailed(ctx, e.ID, err.Error(), nil)
+	}
+
+	retryAt := time.Now().Add(backoff.Delay(r.config.RetryBaseDelay, r.config.RetryMaxDelay, e.Attempts+1))
+	logger.WithField("retry_at", retryAt).Warn("outbox event not published")
+
+	return r.store.Outbox().MarkFailed(ctx, e.ID, err.Error(), &retryAt)
+}
@@ -0,0 +1,121 @@
+package outbox_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/outbox"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelay_Relay(t *testing.T) {
+	store := teststore.New()
+	o := model.NewOnboarding(1)
+	o.Submit(1)
+	store.Onboarding().Save(context.Background(), o)
+	o.Approve(2)
+	store.Onboarding().Save(context.Background(), o)
+
+	fail := true
+	topics := []string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), r.Header.Get("X-Signature"))
+
+		if fail {
+			fail = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		topics = append(topics, r.Header.Get("X-Event-Topic"))
+	}))
+	defer s.Close()
+
+	relay := outbox.NewRelay(store, outbox.NewWebhookPublisher(s.URL, "secret"), logrus.New(), outbox.Config{
+		PollInterval:   time.Minute,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Minute,
+		RetryMaxDelay:  time.Minute,
+	})
+
+	assert.NoError(t, relay.Relay(context.Background()))
+	events, _ := store.Outbox().FindUnpublished(context.Background(), 10)
+	if assert.Len(t, events, 2) && assert.NotNil(t, events[0].RetryAt) {
+		assert.Equal(t, 1, events[0].Attempts)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), *events[0].RetryAt, time.Second)
+	}
+
+	assert.NoError(t, relay.Relay(context.Background()))
+	assert.Empty(t, topics, "events wait for the retry of the one before them")
+
+	past := time.Now().Add(-time.Second)
+	events[0].RetryAt = &past
+
+	assert.NoError(t, relay.Relay(context.Background()))
+	assert.Equal(t, []string{"onboarding.submitted", "onboarding.approved"}, topics)
+	events, _ = store.Outbox().FindUnpublished(context.Background(), 10)
+	assert.Len(t, events, 0)
+}
+
+type rejectingPublisher struct {
+	topic     string
+	published []string
+}
+
+func (p *rejectingPublisher) Publish(ctx context.Context, e *model.OutboxEvent) error {
+	if e.Topic == p.topic {
+		return errors.New("rejected")
+	}
+
+	p.published = append(p.published, e.Topic)
+	return nil
+}
+
+func TestRelay_RelayFailed(t *testing.T) {
+	store := teststore.New()
+	o := model.NewOnboarding(1)
+	o.Submit(1)
+	store.Onboarding().Save(context.Background(), o)
+	o.Approve(2)
+	store.Onboarding().Save(context.Background(), o)
+
+	publisher := &rejectingPublisher{topic: "onboarding.submitted"}
+	relay := outbox.NewRelay(store, publisher, logrus.New(), outbox.Config{
+		PollInterval: time.Minute,
+		MaxAttempts:  2,
+		// Retries are due right away
+		RetryBaseDelay: time.Nanosecond,
+		RetryMaxDelay:  time.Nanosecond,
+	})
+
+	assert.NoError(t, relay.Relay(context.Background()))
+	assert.Empty(t, publisher.published)
+
+	assert.NoError(t, relay.Relay(context.Background()))
+	assert.Equal(t, []string{"onboarding.approved"}, publisher.published, "a failed event no longer holds back the others")
+
+	events, _ := store.Outbox().FindAfter(context.Background(), 0, 10)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, 2, events[0].Attempts)
+		assert.Equal(t, "rejected", events[0].LastError)
+		assert.NotNil(t, events[0].FailedAt)
+		assert.Nil(t, events[0].PublishedAt)
+	}
+
+	assert.NoError(t, relay.Relay(context.Background()))
+	assert.Len(t, publisher.published, 1, "failed events aren't retried")
+}
@@ -0,0 +1,37 @@
+// Package ratelimit counts requests per key in fixed time windows, in
+// process for a single instance or in Redis for instances sharing limits.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Result of counting a request
+type Result struct {
+	Allowed bool
+	// Remaining requests in the window
+	Remaining int
+	// Reset is the time left until the window ends
+	Reset time.Duration
+}
+
+// Limiter ...
+type Limiter interface {
+	// Allow counts a request for key, allowing limit requests per window
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// result ...
+func result(count, limit int, reset time.Duration) Result {
+	remaining := limit - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:   count <= limit,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryLimiterSize bounds a MemoryLimiter; when it is full, ended windows
+// are dropped
+const memoryLimiterSize = 100000
+
+// MemoryLimiter counts in process, so each instance applies the limits on
+// its own
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*memoryWindow
+	now     func() time.Time
+}
+
+type memoryWindow struct {
+	count int
+	ends  time.Time
+}
+
+// NewMemoryLimiter ...
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		windows: make(map[string]*memoryWindow),
+		now:     time.Now,
+	}
+}
+
+// Allow ...
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.ends) {
+		if !ok && len(l.windows) >= memoryLimiterSize {
+			l.sweep(now)
+		}
+
+		w = &memoryWindow{ends: now.Add(window)}
+		l.windows[key] = w
+	}
+	w.count++
+
+	return result(w.count, limit, w.ends.Sub(now)), nil
+}
+
+// sweep drops ended windows
+func (l *MemoryLimiter) sweep(now time.Time) {
+	for key, w := range l.windows {
+		if !now.Before(w.ends) {
+			delete(l.windows, key)
+		}
+	}
+}
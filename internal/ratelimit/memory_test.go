@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	now := time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+
+	allow := func(key string) Result {
+		r, err := l.Allow(context.Background(), key, 2, time.Minute)
+		assert.NoError(t, err)
+		return r
+	}
+
+	assert.Equal(t, Result{Allowed: true, Remaining: 1, Reset: time.Minute}, allow("a"))
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, Result{Allowed: true, Remaining: 0, Reset: 50 * time.Second}, allow("a"))
+	assert.Equal(t, Result{Allowed: false, Remaining: 0, Reset: 50 * time.Second}, allow("a"))
+	assert.True(t, allow("b").Allowed, "keys are counted apart")
+
+	now = now.Add(50 * time.Second)
+	assert.Equal(t, Result{Allowed: true, Remaining: 1, Reset: time.Minute}, allow("a"), "a new window starts")
+}
+
+func TestMemoryLimiter_Sweep(t *testing.T) {
+	now := time.Now()
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+
+	l.Allow(context.Background(), "ended", 1, time.Second)
+	l.Allow(context.Background(), "running", 1, time.Hour)
+	now = now.Add(time.Minute)
+	l.sweep(now)
+
+	assert.NotContains(t, l.windows, "ended")
+	assert.Contains(t, l.windows, "running")
+}
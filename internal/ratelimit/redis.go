@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+var errUnexpectedReply = errors.New("unexpected reply from redis")
+
+// countScript increments the count of a window, starting the window on the
+// first request, and returns the count and the milliseconds left
+var countScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return {count, redis.call("PTTL", KEYS[1])}
+`)
+
+// RedisLimiter counts in Redis, so instances sharing it share the limits
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+// NewRedisLimiter connects to a redis://[:password@]host:port/db URL
+func NewRedisLimiter(url string) (*RedisLimiter, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &RedisLimiter{
+		client: client,
+	}, nil
+}
+
+// Allow ...
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	values, err := countScript.Run(l.client.WithContext(ctx), []string{key}, int64(window/time.Millisecond)).Result()
+	if err != nil {
+		return Result{}, err
+	}
+
+	counts, ok := values.([]interface{})
+	if !ok || len(counts) != 2 {
+		return Result{}, errUnexpectedReply
+	}
+
+	count, _ := counts[0].(int64)
+	ttl, _ := counts[1].(int64)
+	if ttl < 0 {
+		ttl = int64(window / time.Millisecond)
+	}
+
+	return result(int(count), limit, time.Duration(ttl)*time.Millisecond), nil
+}
+
+// Close ...
+func (l *RedisLimiter) Close() error {
+	return l.client.Close()
+}
@@ -0,0 +1,79 @@
+// Package requestid carries the ID of the request being served through
+// contexts and on to the services it calls, so that their logs can be
+// correlated.
+package requestid
+
+import (
+	"context"
+	"net/http"
+)
+
+// Header carries request IDs between services
+const Header = "X-Request-ID"
+
+// maxLength bounds inbound IDs, which end up in every log line
+const maxLength = 128
+
+type ctxKey int8
+
+const ctxKeyRequestID ctxKey = iota
+
+// NewContext ...
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKeyRequestID, id)
+}
+
+// FromContext ...
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(ctxKeyRequestID).(string)
+	return id, ok && id != ""
+}
+
+// Valid reports whether id is short and made of characters that are safe
+// in logs and headers, as UUIDs and most other ID formats are
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Transport sends the request ID of the context along with outgoing requests
+type Transport struct {
+	next http.RoundTripper
+}
+
+// NewTransport ...
+func NewTransport(next http.RoundTripper) *Transport {
+	return &Transport{next: next}
+}
+
+// RoundTrip ...
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id, ok := FromContext(req.Context())
+	if !ok || req.Header.Get(Header) != "" {
+		return t.next.RoundTrip(req)
+	}
+
+	// A RoundTripper must not modify the caller's request
+	header := make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		header[k] = v
+	}
+	header.Set(Header, id)
+	r := *req
+	r.Header = header
+
+	return t.next.RoundTrip(&r)
+}
@@ -0,0 +1,53 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		id    string
+		valid bool
+	}{
+		{name: "uuid", id: "3b241101-e2bb-4255-8caf-4136c566a962", valid: true},
+		{name: "base64", id: "c2VydmljZQ==/1+2", valid: true},
+		{name: "empty", id: ""},
+		{name: "too long", id: strings.Repeat("a", maxLength+1)},
+		{name: "line break", id: "abc\nlevel=error"},
+		{name: "space", id: "abc def"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, Valid(tc.id))
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(Header))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport)}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	_, err := client.Do(req.WithContext(NewContext(context.Background(), "abc")))
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Get(Header))
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	_, err = client.Do(req)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"abc", ""}, received)
+}
@@ -0,0 +1,103 @@
+// Package retention keeps the database bounded by purging data once it is
+// older than its retention period: delivered outbox events, entity change
+// history, done jobs and the personal data of deleted users.
+package retention
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Policy is how long each kind of data is kept; zero keeps it forever
+type Policy struct {
+	// OutboxEvents counts from when an event was published
+	OutboxEvents time.Duration
+	// Changes counts from when a change was recorded
+	Changes time.Duration
+	// Jobs counts from when a job was done; dead jobs are kept
+	Jobs time.Duration
+	// DeletedUsers counts from a user's deletion; afterwards the user is
+	// anonymized rather than removed
+	DeletedUsers time.Duration
+}
+
+// Report counts what a purge removed, or would remove in a dry run
+type Report struct {
+	OutboxEvents    int  `json:"outbox_events"`
+	Changes         int  `json:"changes"`
+	Jobs            int  `json:"jobs"`
+	AnonymizedUsers int  `json:"anonymized_users"`
+	DryRun          bool `json:"dry_run"`
+}
+
+// Purger applies a policy, as a scheduled task
+type Purger struct {
+	store  store.Store
+	policy Policy
+	logger *logrus.Logger
+	dryRun bool
+}
+
+// NewPurger ...
+func NewPurger(store store.Store, policy Policy, logger *logrus.Logger, dryRun bool) *Purger {
+	return &Purger{
+		store:  store,
+		policy: policy,
+		logger: logger,
+		dryRun: dryRun,
+	}
+}
+
+// Purge applies the policy as of now and logs what it purged
+func (p *Purger) Purge(ctx context.Context) error {
+	report, err := Purge(ctx, p.store, p.policy, time.Now(), p.dryRun)
+	if err != nil {
+		return err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"outbox_events":    report.OutboxEvents,
+		"changes":          report.Changes,
+		"jobs":             report.Jobs,
+		"anonymized_users": report.AnonymizedUsers,
+		"dry_run":          report.DryRun,
+	}).Info("retention purge")
+
+	return nil
+}
+
+// Purge applies policy once, as of now. Users are anonymized before the
+// change history is purged, so their last entry is the anonymization.
+func Purge(ctx context.Context, s store.Store, policy Policy, now time.Time, dryRun bool) (*Report, error) {
+	report := &Report{DryRun: dryRun}
+
+	var err error
+	if policy.DeletedUsers > 0 {
+		if report.AnonymizedUsers, err = s.User().Anonymize(ctx, now.Add(-policy.DeletedUsers), dryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	if policy.OutboxEvents > 0 {
+		if report.OutboxEvents, err = s.Outbox().Purge(ctx, now.Add(-policy.OutboxEvents), dryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	if policy.Changes > 0 {
+		if report.Changes, err = s.Change().Purge(ctx, now.Add(-policy.Changes), dryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	if policy.Jobs > 0 {
+		if report.Jobs, err = s.Job().Purge(ctx, now.Add(-policy.Jobs), dryRun); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,86 @@
+package retention_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/retention"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurge(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   retention.Policy
+		dryRun   bool
+		expected retention.Report
+		changes  int
+	}{
+		{
+			name:     "keep forever",
+			expected: retention.Report{},
+			changes:  2,
+		},
+		{
+			name:     "recent data is kept",
+			policy:   retention.Policy{OutboxEvents: time.Hour, Changes: time.Hour, DeletedUsers: time.Hour, Jobs: time.Hour},
+			expected: retention.Report{},
+			changes:  2,
+		},
+		{
+			name:     "dry run",
+			policy:   retention.Policy{OutboxEvents: time.Nanosecond, Changes: time.Nanosecond, DeletedUsers: time.Nanosecond, Jobs: time.Nanosecond},
+			dryRun:   true,
+			expected: retention.Report{OutboxEvents: 1, Changes: 3, Jobs: 1, AnonymizedUsers: 1, DryRun: true},
+			changes:  2,
+		},
+		{
+			name:     "anonymized before history is purged",
+			policy:   retention.Policy{OutboxEvents: time.Nanosecond, Changes: time.Nanosecond, DeletedUsers: time.Nanosecond, Jobs: time.Nanosecond},
+			expected: retention.Report{OutboxEvents: 1, Changes: 1, Jobs: 1, AnonymizedUsers: 1},
+			changes:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := teststore.New()
+			u := model.TestUser(t)
+			assert.NoError(t, s.User().Create(ctx, u))
+			assert.NoError(t, s.User().Delete(ctx, u.ID))
+
+			o := model.NewOnboarding(u.ID)
+			assert.NoError(t, o.Submit(1))
+			assert.NoError(t, s.Onboarding().Save(ctx, o))
+			events, _ := s.Outbox().FindUnpublished(ctx, 10)
+			assert.NoError(t, s.Outbox().MarkPublished(ctx, events[0].ID))
+
+			// Only the done job is purged, the dead one is kept for admins
+			for _, reason := range []string{"", "failed"} {
+				j, err := model.NewJob("mail.send", nil, 1, time.Now())
+				assert.NoError(t, err)
+				assert.NoError(t, s.Job().Create(ctx, j))
+				_, err = s.Job().Claim(ctx, []string{j.Kind}, 1, time.Minute)
+				assert.NoError(t, err)
+				if reason == "" {
+					assert.NoError(t, s.Job().Complete(ctx, j.ID, 1))
+				} else {
+					assert.NoError(t, s.Job().Fail(ctx, j.ID, 1, reason, nil))
+				}
+			}
+
+			time.Sleep(time.Millisecond)
+			report, err := retention.Purge(ctx, s, tc.policy, time.Now(), tc.dryRun)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, *report)
+
+			changes, err := s.Change().FindByEntity(ctx, model.EntityUser, u.ID)
+			assert.NoError(t, err)
+			assert.Len(t, changes, tc.changes)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+	"winding-tree-server/internal/scheduler"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2020, time.January, 15, 10, 30, 20, 0, time.UTC)
+
+	testCases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2020, time.January, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, time.January, 15, 10, 45, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2020, time.January, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2020, time.January, 16, 3, 0, 0, 0, time.UTC)},
+		{"0,30 9-17 * * mon-fri", time.Date(2020, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2020, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * fri", time.Date(2020, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 feb *", time.Time{}},
+		{"@hourly", time.Date(2020, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2020, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 20m", time.Date(2020, time.January, 15, 10, 40, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			s, err := scheduler.Parse(tc.spec)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.expected, s.Next(now))
+			}
+		})
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "@often", "@every 1ms", "@every soon"} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := scheduler.Parse(spec)
+			assert.Error(t, err)
+		})
+	}
+}
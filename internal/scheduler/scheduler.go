@@ -0,0 +1,205 @@
+// Package scheduler runs recurring tasks, such as retention purges, on cron
+// schedules. Every instance schedules the tasks, and each run is claimed in
+// the store so that only one of them does it.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+	"winding-tree-server/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// releaseTimeout bounds the release of a run, which doesn't end with the
+// context of the run
+const releaseTimeout = 10 * time.Second
+
+var (
+	// ErrUnknownTask ...
+	ErrUnknownTask = errors.New("unknown task")
+	// ErrTaskExists ...
+	ErrTaskExists = errors.New("task already added")
+)
+
+// Task is the work of a recurring task. A run that fails isn't retried
+// before the next one.
+type Task func(ctx context.Context) error
+
+// Entry is a task as scheduled
+type Entry struct {
+	Name      string    `json:"name"`
+	Spec      string    `json:"spec"`
+	NextRunAt time.Time `json:"next_run_at"`
+}
+
+// entry ...
+type entry struct {
+	name     string
+	spec     string
+	schedule Schedule
+	task     Task
+}
+
+// Scheduler runs tasks at the times of their schedules. Tasks must be added
+// before Run.
+type Scheduler struct {
+	store   store.Store
+	logger  *logrus.Logger
+	lease   time.Duration
+	entries map[string]*entry
+}
+
+// New ...
+func New(store store.Store, logger *logrus.Logger, lease time.Duration) *Scheduler {
+	return &Scheduler{
+		store:   store,
+		logger:  logger,
+		lease:   lease,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Add schedules task as name, such as "retention.purge", with a spec that
+// Parse understands
+func (s *Scheduler) Add(name, spec string, task Task) error {
+	if _, ok := s.entries[name]; ok {
+		return ErrTaskExists
+	}
+
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	s.entries[name] = &entry{name: name, spec: spec, schedule: schedule, task: task}
+
+	return nil
+}
+
+// Entries lists the tasks by name, with their next run after now
+func (s *Scheduler) Entries(now time.Time) []Entry {
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, Entry{Name: e.name, Spec: e.spec, NextRunAt: e.schedule.Next(now)})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+// Run runs the tasks as they come due until ctx is done, then waits for the
+// runs in progress
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	next := map[string]time.Time{}
+	now := time.Now()
+	for name, e := range s.entries {
+		next[name] = e.schedule.Next(now)
+	}
+
+	for {
+		var wake time.Time
+		for _, at := range next {
+			if !at.IsZero() && (wake.IsZero() || at.Before(wake)) {
+				wake = at
+			}
+		}
+		if wake.IsZero() {
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		for name, at := range next {
+			if at.IsZero() || at.After(now) {
+				continue
+			}
+
+			name, at := name, at
+			next[name] = s.entries[name].schedule.Next(now)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := s.Trigger(ctx, name, at); err != nil {
+					s.logger.Errorf("scheduled task %s failed: %v", name, err)
+				}
+			}()
+		}
+	}
+}
+
+// Trigger runs the run of task name scheduled at, unless another instance
+// took it, and reports whether it ran
+func (s *Scheduler) Trigger(ctx context.Context, name string, at time.Time) (bool, error) {
+	e, ok := s.entries[name]
+	if !ok {
+		return false, ErrUnknownTask
+	}
+
+	claimed, err := s.store.ScheduledTask().Claim(ctx, name, at, s.lease)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	start := time.Now()
+	runErr := s.run(ctx, e)
+
+	var reason string
+	if runErr != nil {
+		reason = runErr.Error()
+	}
+	// The run is released even if ctx ended while it ran
+	releaseCtx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	err = s.store.ScheduledTask().Release(releaseCtx, name, at, reason)
+	cancel()
+	if err == store.ErrLeaseLost {
+		s.logger.Warnf("scheduled task %s outlived its lease, another run was claimed since", name)
+	} else if err != nil {
+		s.logger.Warnf("scheduled task %s not released: %v", name, err)
+	}
+
+	if runErr != nil {
+		return true, runErr
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"task":     name,
+		"duration": time.Since(start),
+	}).Info("scheduled task done")
+
+	return true, nil
+}
+
+// run runs the task within the lease, turning a panic into an error
+func (s *Scheduler) run(ctx context.Context, e *entry) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.lease)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.WithField("stack", string(debug.Stack())).Errorf("scheduled task %s panicked: %v", e.name, r)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return e.task(ctx)
+}
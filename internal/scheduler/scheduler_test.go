@@ -0,0 +1,114 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"winding-tree-server/internal/scheduler"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_Trigger(t *testing.T) {
+	ctx := context.Background()
+	store := teststore.New()
+	logger, _ := test.NewNullLogger()
+
+	// Two instances sharing a store
+	runs := 0
+	instances := make([]*scheduler.Scheduler, 2)
+	for i := range instances {
+		instances[i] = scheduler.New(store, logger, time.Minute)
+		assert.NoError(t, instances[i].Add("purge", "@hourly", func(ctx context.Context) error {
+			runs++
+			return errors.New("database unavailable")
+		}))
+		assert.Equal(t, scheduler.ErrTaskExists, instances[i].Add("purge", "@daily", nil))
+	}
+
+	tick := time.Now().Truncate(time.Hour)
+	ran, err := instances[0].Trigger(ctx, "purge", tick)
+	assert.True(t, ran)
+	assert.EqualError(t, err, "database unavailable")
+
+	ran, err = instances[1].Trigger(ctx, "purge", tick)
+	assert.False(t, ran, "the run was taken")
+	assert.NoError(t, err)
+
+	ran, _ = instances[1].Trigger(ctx, "purge", tick.Add(time.Hour))
+	assert.True(t, ran)
+	assert.Equal(t, 2, runs)
+
+	_, err = instances[0].Trigger(ctx, "sync", tick)
+	assert.Equal(t, scheduler.ErrUnknownTask, err)
+
+	tasks, _ := store.ScheduledTask().List(ctx)
+	if assert.Len(t, tasks, 1) {
+		assert.Equal(t, "database unavailable", tasks[0].LastError)
+	}
+}
+
+func TestScheduler_Panic(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	s := scheduler.New(teststore.New(), logger, time.Minute)
+	assert.NoError(t, s.Add("purge", "@hourly", func(ctx context.Context) error {
+		panic("boom")
+	}))
+
+	ran, err := s.Trigger(context.Background(), "purge", time.Now())
+	assert.True(t, ran)
+	assert.EqualError(t, err, "panic: boom")
+}
+
+func TestScheduler_Run(t *testing.T) {
+	store := teststore.New()
+	logger, _ := test.NewNullLogger()
+
+	// Started just after a second, and stopped before the next but one,
+	// so that a single tick falls in between
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(1100 * time.Millisecond)))
+
+	var mu sync.Mutex
+	runs := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		s := scheduler.New(store, logger, time.Minute)
+		assert.NoError(t, s.Add("purge", "@every 1s", func(ctx context.Context) error {
+			mu.Lock()
+			runs++
+			mu.Unlock()
+			return nil
+		}))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Run(ctx)
+		}()
+	}
+
+	time.Sleep(1400 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	assert.Equal(t, 1, runs, "one instance runs each tick")
+}
+
+func TestScheduler_Entries(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	s := scheduler.New(teststore.New(), logger, time.Minute)
+	assert.NoError(t, s.Add("sync", "*/10 * * * *", nil))
+	assert.NoError(t, s.Add("purge", "@daily", nil))
+	assert.Error(t, s.Add("report", "daily", nil))
+
+	now := time.Date(2020, time.January, 15, 10, 30, 0, 0, time.UTC)
+	assert.Equal(t, []scheduler.Entry{
+		{Name: "purge", Spec: "@daily", NextRunAt: time.Date(2020, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{Name: "sync", Spec: "*/10 * * * *", NextRunAt: time.Date(2020, time.January, 15, 10, 40, 0, 0, time.UTC)},
+	}, s.Entries(now))
+}
@@ -0,0 +1,194 @@
+// Package seed fills a store with demo suppliers, flights and fares so the
+// server is usable for demos and frontend development right after migrating.
+// The data only depends on the options, so the same seed always produces the
+// same suppliers, flights and prices.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Password of every seeded user
+const Password = "password"
+
+var (
+	// ErrAlreadySeeded is returned when the demo users already exist
+	ErrAlreadySeeded = errors.New("database is already seeded")
+
+	airports = []string{"ZRH", "VIE", "KBP", "BER", "CDG", "AMS", "LHR", "MAD", "FCO", "WAW"}
+	carriers = []string{"LX", "OS", "PS", "LH", "AF", "KL", "BA", "IB", "AZ", "LO"}
+)
+
+// Options ...
+type Options struct {
+	// Seed makes the generated data reproducible
+	Seed int64
+	// Suppliers are approved airlines; one more supplier is left waiting for review
+	Suppliers int
+	// FlightsPerSupplier are spread over Days days starting at Start
+	FlightsPerSupplier int
+	Days               int
+	Start              time.Time
+}
+
+// Result lists what was created
+type Result struct {
+	Reviewer  *model.User
+	Suppliers []*model.User
+	Pending   *model.User
+	Flights   int
+	Fares     int
+}
+
+// Run seeds s. The reviewer approves the suppliers' onboardings; the store
+// has no way to grant admin rights, which is left to the caller.
+func Run(ctx context.Context, s store.Store, opts Options) (*Result, error) {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	res := &Result{}
+
+	reviewer, err := createUser(ctx, s, rng, "admin@example.test")
+	if err != nil {
+		if err == store.ErrRecordExists {
+			return nil, ErrAlreadySeeded
+		}
+
+		return nil, err
+	}
+	res.Reviewer = reviewer
+
+	for i := 0; i < opts.Suppliers; i++ {
+		carrier := carriers[i%len(carriers)]
+		u, err := createUser(ctx, s, rng, fmt.Sprintf("supplier%d@example.test", i+1))
+		if err != nil {
+			return nil, err
+		}
+
+		if err := onboard(ctx, s, u, reviewer); err != nil {
+			return nil, err
+		}
+
+		for j := 0; j < opts.FlightsPerSupplier; j++ {
+			f := flight(rng, u.ID, carrier, opts)
+			if err := s.ForTenant(u.ID).Flight().Create(ctx, f); err != nil {
+				return nil, err
+			}
+
+			res.Flights++
+			res.Fares += len(f.Fares)
+		}
+
+		res.Suppliers = append(res.Suppliers, u)
+	}
+
+	pending, err := createUser(ctx, s, rng, "pending@example.test")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := onboard(ctx, s, pending, nil); err != nil {
+		return nil, err
+	}
+	res.Pending = pending
+
+	return res, nil
+}
+
+// createUser uses rng for the public id so that it is reproducible too
+func createUser(ctx context.Context, s store.Store, rng *rand.Rand, email string) (*model.User, error) {
+	var id uuid.UUID
+	rng.Read(id[:])
+	id[6] = (id[6] & 0x0f) | 0x40 // version 4
+	id[8] = (id[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	u := &model.User{
+		PublicID: id.String(),
+		Email:    email,
+		Password: Password,
+	}
+	if err := s.User().Create(ctx, u); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// onboard submits a registration document for u, and approves it unless
+// reviewer is nil
+func onboard(ctx context.Context, s store.Store, u *model.User, reviewer *model.User) error {
+	tenant := s.ForTenant(u.ID)
+	if err := tenant.Onboarding().CreateDocument(ctx, &model.OnboardingDocument{
+		UserID:      u.ID,
+		Kind:        model.DocumentRegistration,
+		FileName:    "registration.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("%PDF-1.4\n% Demo company registration of " + u.Email + "\n%%EOF\n"),
+	}); err != nil {
+		return err
+	}
+
+	o := model.NewOnboarding(u.ID)
+	if err := o.Submit(1); err != nil {
+		return err
+	}
+
+	if reviewer != nil {
+		if err := o.Approve(reviewer.ID); err != nil {
+			return err
+		}
+	}
+
+	return tenant.Onboarding().Save(ctx, o)
+}
+
+// flight returns a direct or one-stop flight with an economy fare and, for
+// some flights, a business fare
+func flight(rng *rand.Rand, userID int, carrier string, opts Options) *model.Flight {
+	perm := rng.Perm(len(airports))
+	stops := 1 + rng.Intn(2)
+
+	departure := opts.Start.
+		AddDate(0, 0, rng.Intn(opts.Days)).
+		Add(time.Duration(6+rng.Intn(14)) * time.Hour).
+		Add(time.Duration(rng.Intn(4)*15) * time.Minute)
+
+	f := &model.Flight{UserID: userID}
+	for i := 0; i < stops; i++ {
+		duration := time.Duration(60+rng.Intn(12)*15) * time.Minute
+		f.Segments = append(f.Segments, &model.FlightSegment{
+			Carrier:     carrier,
+			Number:      fmt.Sprintf("%d", 100+rng.Intn(9900)),
+			Origin:      airports[perm[i]],
+			Destination: airports[perm[i+1]],
+			DepartureAt: departure,
+			ArrivalAt:   departure.Add(duration),
+		})
+		departure = departure.Add(duration + time.Duration(45+rng.Intn(8)*15)*time.Minute)
+	}
+
+	economy := int64(5000 + rng.Intn(300)*100)
+	f.Fares = append(f.Fares, &model.Fare{
+		Cabin:          model.CabinEconomy,
+		Amount:         economy,
+		Currency:       "EUR",
+		SeatsAvailable: rng.Intn(60),
+	})
+
+	if rng.Intn(2) == 0 {
+		f.Fares = append(f.Fares, &model.Fare{
+			Cabin:          model.CabinBusiness,
+			Amount:         economy * int64(3+rng.Intn(3)),
+			Currency:       "EUR",
+			SeatsAvailable: rng.Intn(12),
+		})
+	}
+
+	return f
+}
@@ -0,0 +1,82 @@
+package seed_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/seed"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOptions(t *testing.T) seed.Options {
+	return seed.Options{
+		Seed:               42,
+		Suppliers:          2,
+		FlightsPerSupplier: 5,
+		Days:               3,
+		Start:              time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRun(t *testing.T) {
+	s := teststore.New()
+	res, err := seed.Run(context.Background(), s, testOptions(t))
+	assert.NoError(t, err)
+	assert.Len(t, res.Suppliers, 2)
+	assert.Equal(t, 10, res.Flights)
+
+	for _, u := range res.Suppliers {
+		o, err := s.Onboarding().Find(context.Background(), u.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, model.OnboardingApproved, o.State)
+
+		flights, total, err := s.ForTenant(u.ID).Flight().List(context.Background(), &store.ListOptions{Limit: 100})
+		assert.NoError(t, err)
+		assert.Equal(t, 5, total)
+		for _, f := range flights {
+			assert.NoError(t, f.Validate())
+		}
+	}
+
+	o, err := s.Onboarding().Find(context.Background(), res.Pending.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OnboardingSubmitted, o.State)
+
+	u, err := s.User().FindByEmail(context.Background(), res.Reviewer.Email)
+	assert.NoError(t, err)
+	assert.True(t, u.ComparePasswords(seed.Password))
+
+	_, err = seed.Run(context.Background(), s, testOptions(t))
+	assert.Equal(t, seed.ErrAlreadySeeded, err)
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	flights := func(opts seed.Options) ([]*model.Flight, *seed.Result) {
+		s := teststore.New()
+		res, err := seed.Run(context.Background(), s, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		flights, _, err := s.Flight().List(context.Background(), &store.ListOptions{Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return flights, res
+	}
+
+	first, firstRes := flights(testOptions(t))
+	second, secondRes := flights(testOptions(t))
+	assert.Equal(t, first, second)
+	assert.Equal(t, firstRes.Suppliers[0].PublicID, secondRes.Suppliers[0].PublicID)
+
+	opts := testOptions(t)
+	opts.Seed++
+	other, _ := flights(opts)
+	assert.NotEqual(t, first, other)
+}
@@ -0,0 +1,72 @@
+package cachestore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// ErrCacheMiss is returned by Cache.Get when the key is not cached
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache stores serialized records with an expiry
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// RedisCache ...
+type RedisCache struct {
+	client *redis.Client
+}
+
+// NewRedisCache connects to a redis://[:password@]host:port/db URL
+func NewRedisCache(url string) (*RedisCache, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &RedisCache{
+		client: client,
+	}, nil
+}
+
+// Get ...
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := c.client.WithContext(ctx).Get(key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrCacheMiss
+	}
+
+	return b, err
+}
+
+// Set ...
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.WithContext(ctx).Set(key, value, ttl).Err()
+}
+
+// Delete ...
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	return c.client.WithContext(ctx).Del(keys...).Err()
+}
+
+// Ping checks that Redis answers
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.WithContext(ctx).Ping().Err()
+}
+
+// Close ...
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
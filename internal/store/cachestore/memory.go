@@ -0,0 +1,88 @@
+package cachestore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryCacheSize bounds a MemoryCache; when it is full, expired entries are
+// dropped and, if that isn't enough, everything is
+const memoryCacheSize = 10000
+
+// MemoryCache keeps entries in process. Unlike Redis it isn't shared, so
+// instances rely on ListenInvalidations to learn about each other's writes.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// NewMemoryCache ...
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]memoryEntry),
+	}
+}
+
+// Get ...
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, ErrCacheMiss
+	}
+
+	return e.value, nil
+}
+
+// Set ...
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= memoryCacheSize {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+
+		if len(c.entries) >= memoryCacheSize {
+			c.entries = make(map[string]memoryEntry)
+		}
+	}
+
+	c.entries[key] = memoryEntry{value, time.Now().Add(ttl)}
+
+	return nil
+}
+
+// Delete ...
+func (c *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, k := range keys {
+		delete(c.entries, k)
+	}
+
+	return nil
+}
+
+// Flush drops every entry
+func (c *MemoryCache) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]memoryEntry)
+
+	return nil
+}
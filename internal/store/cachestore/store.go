@@ -0,0 +1,67 @@
+// Package cachestore decorates a store.Store with a read-through cache.
+// Repositories that aren't cached are passed through unchanged.
+package cachestore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/store"
+)
+
+// Flusher is implemented by caches that can drop all of their entries
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Store ...
+type Store struct {
+	store.Store
+	cache          Cache
+	ttl            time.Duration
+	userRepository *UserRepository
+}
+
+// New ...
+func New(s store.Store, cache Cache, ttl time.Duration) *Store {
+	return &Store{
+		Store: s,
+		cache: cache,
+		ttl:   ttl,
+	}
+}
+
+// User ...
+func (s *Store) User() store.UserRepository {
+	if s.userRepository != nil {
+		return s.userRepository
+	}
+
+	s.userRepository = &UserRepository{
+		UserRepository: s.Store.User(),
+		store:          s,
+	}
+
+	return s.userRepository
+}
+
+// ForTenant scopes the underlying store, sharing the cache
+func (s *Store) ForTenant(tenantID int) store.Store {
+	return New(s.Store.ForTenant(tenantID), s.cache, s.ttl)
+}
+
+// Invalidate drops the cached copies of a row of table changed by another
+// writer. An empty table drops everything the cache can flush; a shared
+// cache like Redis is instead left to expire, since the instance that wrote
+// to it already invalidated its entries.
+func (s *Store) Invalidate(ctx context.Context, table string, id int) error {
+	switch table {
+	case "users":
+		return s.cache.Delete(ctx, userKey(id))
+	case "":
+		if f, ok := s.cache.(Flusher); ok {
+			return f.Flush(ctx)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package cachestore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/cachestore"
+	"winding-tree-server/internal/store/storetest"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		return cachestore.New(teststore.New(), memoryCache{}, time.Minute), func() {}
+	})
+}
+
+func TestStore_MemoryCache(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		return cachestore.New(teststore.New(), cachestore.NewMemoryCache(), time.Minute), func() {}
+	})
+}
+
+func TestStore_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	inner := teststore.New()
+	cache := cachestore.NewMemoryCache()
+	s := cachestore.New(inner, cache, time.Minute)
+
+	u := model.TestUser(t)
+	assert.NoError(t, s.User().Create(ctx, u))
+	_, err := s.User().Find(ctx, u.ID)
+	assert.NoError(t, err)
+
+	// Deleted by another instance: still cached until the notification arrives
+	assert.NoError(t, inner.User().Delete(ctx, u.ID))
+	_, err = s.User().Find(ctx, u.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Invalidate(ctx, "flights", u.ID))
+	_, err = s.User().Find(ctx, u.ID)
+	assert.NoError(t, err, "other tables don't touch users")
+
+	assert.NoError(t, s.Invalidate(ctx, "users", u.ID))
+	_, err = s.User().Find(ctx, u.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	assert.NoError(t, cache.Set(ctx, "key", []byte("value"), time.Minute))
+	assert.NoError(t, s.Invalidate(ctx, "", 0))
+	_, err = cache.Get(ctx, "key")
+	assert.Equal(t, cachestore.ErrCacheMiss, err, "flushed after a reconnect")
+}
+
+func TestMemoryCache_Expiry(t *testing.T) {
+	ctx := context.Background()
+	cache := cachestore.NewMemoryCache()
+
+	assert.NoError(t, cache.Set(ctx, "fresh", []byte("1"), time.Minute))
+	assert.NoError(t, cache.Set(ctx, "stale", []byte("2"), -time.Second))
+
+	b, err := cache.Get(ctx, "fresh")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), b)
+
+	_, err = cache.Get(ctx, "stale")
+	assert.Equal(t, cachestore.ErrCacheMiss, err)
+
+	assert.NoError(t, cache.Delete(ctx, "fresh"))
+	_, err = cache.Get(ctx, "fresh")
+	assert.Equal(t, cachestore.ErrCacheMiss, err)
+}
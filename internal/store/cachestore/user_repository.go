@@ -0,0 +1,91 @@
+package cachestore
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"strconv"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// UserRepository caches Find, which runs on every authenticated request.
+// Writes that change a user invalidate its entry.
+type UserRepository struct {
+	store.UserRepository
+	store *Store
+}
+
+// Find ...
+func (r *UserRepository) Find(ctx context.Context, id int) (*model.User, error) {
+	key := userKey(id)
+
+	// The cache is an optimisation, so any failure to read it falls through to the store
+	if b, err := r.store.cache.Get(ctx, key); err == nil {
+		u := &model.User{}
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(u); err == nil {
+			return u, nil
+		}
+	}
+
+	u, err := r.UserRepository.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// gob rather than JSON so EncryptedPassword survives the round trip
+	b := &bytes.Buffer{}
+	if err := gob.NewEncoder(b).Encode(u); err == nil {
+		r.store.cache.Set(ctx, key, b.Bytes(), r.store.ttl)
+	}
+
+	return u, nil
+}
+
+// FindByPublicID caches the public id's internal id, which never changes,
+// and goes through Find so that deletes invalidate a single entry
+func (r *UserRepository) FindByPublicID(ctx context.Context, publicID string) (*model.User, error) {
+	key := publicIDKey(publicID)
+
+	if b, err := r.store.cache.Get(ctx, key); err == nil {
+		if id, err := strconv.Atoi(string(b)); err == nil {
+			return r.Find(ctx, id)
+		}
+	}
+
+	u, err := r.UserRepository.FindByPublicID(ctx, publicID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.store.cache.Set(ctx, key, []byte(strconv.Itoa(u.ID)), r.store.ttl)
+
+	return u, nil
+}
+
+// Delete ...
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	if err := r.UserRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	return r.store.cache.Delete(ctx, userKey(id))
+}
+
+// Restore ...
+func (r *UserRepository) Restore(ctx context.Context, id int) error {
+	if err := r.UserRepository.Restore(ctx, id); err != nil {
+		return err
+	}
+
+	return r.store.cache.Delete(ctx, userKey(id))
+}
+
+func userKey(id int) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+func publicIDKey(publicID string) string {
+	return "user:public:" + publicID
+}
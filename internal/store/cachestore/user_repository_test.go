@@ -0,0 +1,89 @@
+package cachestore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/cachestore"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryCache map[string][]byte
+
+func (c memoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	b, ok := c[key]
+	if !ok {
+		return nil, cachestore.ErrCacheMiss
+	}
+
+	return b, nil
+}
+
+func (c memoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c[key] = value
+	return nil
+}
+
+func (c memoryCache) Delete(ctx context.Context, keys ...string) error {
+	for _, k := range keys {
+		delete(c, k)
+	}
+
+	return nil
+}
+
+func TestUserRepository_Find(t *testing.T) {
+	inner := teststore.New()
+	cache := memoryCache{}
+	s := cachestore.New(inner, cache, time.Minute)
+
+	u := model.TestUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+
+	cached, err := s.User().Find(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, u.EncryptedPassword, cached.EncryptedPassword)
+	assert.Len(t, cache, 1)
+
+	// Served from the cache even though the store no longer returns the user
+	inner.User().Delete(context.Background(), u.ID)
+	cached, err = s.User().Find(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, u.Email, cached.Email)
+	assert.Equal(t, u.EncryptedPassword, cached.EncryptedPassword)
+
+	assert.NoError(t, s.User().Restore(context.Background(), u.ID))
+	assert.Len(t, cache, 0)
+
+	assert.NoError(t, s.User().Delete(context.Background(), u.ID))
+	_, err = s.User().Find(context.Background(), u.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+	assert.Len(t, cache, 0)
+}
+
+func TestUserRepository_FindByPublicID(t *testing.T) {
+	inner := teststore.New()
+	cache := memoryCache{}
+	s := cachestore.New(inner, cache, time.Minute)
+
+	u := model.TestUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+
+	found, err := s.User().FindByPublicID(context.Background(), u.PublicID)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, found.ID)
+	assert.Len(t, cache, 1, "only the id mapping is cached on a miss")
+
+	found, err = s.User().FindByPublicID(context.Background(), u.PublicID)
+	assert.NoError(t, err)
+	assert.Equal(t, u.Email, found.Email)
+	assert.Len(t, cache, 2)
+
+	assert.NoError(t, s.User().Delete(context.Background(), u.ID))
+	_, err = s.User().FindByPublicID(context.Background(), u.PublicID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+}
@@ -5,4 +5,17 @@ import "errors"
 var (
 	// ErrRecordNotFound ...
 	ErrRecordNotFound = errors.New("record not found")
+	// ErrRecordExists ...
+	ErrRecordExists = errors.New("record already exists")
+	// ErrConflict is returned when a record was changed since it was read
+	ErrConflict = errors.New("record was modified concurrently")
+	// ErrTenantMismatch is returned when a tenant scoped store is asked to
+	// write a record owned by someone else
+	ErrTenantMismatch = errors.New("record belongs to another tenant")
+	// ErrLeaseLost is returned when a job or a scheduled task run is
+	// finished after its lease ended, as another worker or instance may have
+	// claimed it since
+	ErrLeaseLost = errors.New("lease lost")
+	// ErrInvalidListOptions ...
+	ErrInvalidListOptions = errors.New("invalid list options")
 )
@@ -0,0 +1,76 @@
+package metricstore
+
+import (
+	"time"
+	"winding-tree-server/internal/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Result labels of store_operations_total
+const (
+	resultOK       = "ok"
+	resultNotFound = "not_found"
+	resultError    = "error"
+)
+
+// Metrics are shared by a store and the tenant-scoped stores derived from it
+type Metrics struct {
+	operations    *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	logger        *logrus.Logger
+	slowThreshold time.Duration
+}
+
+// NewMetrics registers the store collectors on reg. Calls taking longer than
+// slowThreshold are logged to logger; zero disables the log.
+func NewMetrics(reg prometheus.Registerer, logger *logrus.Logger, slowThreshold time.Duration) (*Metrics, error) {
+	m := &Metrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "store_operations_total",
+			Help: "Store repository calls by repository, method and result.",
+		}, []string{"repository", "method", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "store_operation_duration_seconds",
+			Help:    "Latency of store repository calls.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"repository", "method"}),
+		logger:        logger,
+		slowThreshold: slowThreshold,
+	}
+
+	for _, c := range []prometheus.Collector{m.operations, m.duration} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Metrics) observe(repository, method string, elapsed time.Duration, err error) {
+	result := resultOK
+	switch {
+	case err == store.ErrRecordNotFound:
+		result = resultNotFound
+	case err != nil:
+		result = resultError
+	}
+
+	m.operations.WithLabelValues(repository, method, result).Inc()
+	m.duration.WithLabelValues(repository, method).Observe(elapsed.Seconds())
+
+	if m.slowThreshold > 0 && elapsed >= m.slowThreshold {
+		entry := m.logger.WithFields(logrus.Fields{
+			"repository": repository,
+			"method":     method,
+			"duration":   elapsed,
+		})
+		if err != nil {
+			entry = entry.WithError(err)
+		}
+
+		entry.Warn("slow store operation")
+	}
+}
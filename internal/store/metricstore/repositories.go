@@ -0,0 +1,567 @@
+package metricstore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// UserRepository ...
+type UserRepository struct {
+	next  store.UserRepository
+	store *Store
+}
+
+// Create ...
+func (r *UserRepository) Create(ctx context.Context, u *model.User) error {
+	return r.store.observe(ctx, "user", "Create", func(ctx context.Context) error {
+		return r.next.Create(ctx, u)
+	})
+}
+
+// Find ...
+func (r *UserRepository) Find(ctx context.Context, id int) (*model.User, error) {
+	var result *model.User
+	err := r.store.observe(ctx, "user", "Find", func(ctx context.Context) (err error) {
+		result, err = r.next.Find(ctx, id)
+		return err
+	})
+
+	return result, err
+}
+
+// FindByEmail ...
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	var result *model.User
+	err := r.store.observe(ctx, "user", "FindByEmail", func(ctx context.Context) (err error) {
+		result, err = r.next.FindByEmail(ctx, email)
+		return err
+	})
+
+	return result, err
+}
+
+// FindByPublicID ...
+func (r *UserRepository) FindByPublicID(ctx context.Context, publicID string) (*model.User, error) {
+	var result *model.User
+	err := r.store.observe(ctx, "user", "FindByPublicID", func(ctx context.Context) (err error) {
+		result, err = r.next.FindByPublicID(ctx, publicID)
+		return err
+	})
+
+	return result, err
+}
+
+// List ...
+func (r *UserRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.User, int, error) {
+	var result []*model.User
+	var total int
+	err := r.store.observe(ctx, "user", "List", func(ctx context.Context) (err error) {
+		result, total, err = r.next.List(ctx, opts)
+		return err
+	})
+
+	return result, total, err
+}
+
+// Search ...
+func (r *UserRepository) Search(ctx context.Context, query string, opts *store.ListOptions) ([]*model.User, int, error) {
+	var result []*model.User
+	var total int
+	err := r.store.observe(ctx, "user", "Search", func(ctx context.Context) (err error) {
+		result, total, err = r.next.Search(ctx, query, opts)
+		return err
+	})
+
+	return result, total, err
+}
+
+// Delete ...
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	return r.store.observe(ctx, "user", "Delete", func(ctx context.Context) error {
+		return r.next.Delete(ctx, id)
+	})
+}
+
+// Restore ...
+func (r *UserRepository) Restore(ctx context.Context, id int) error {
+	return r.store.observe(ctx, "user", "Restore", func(ctx context.Context) error {
+		return r.next.Restore(ctx, id)
+	})
+}
+
+// Anonymize ...
+func (r *UserRepository) Anonymize(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	var result int
+	err := r.store.observe(ctx, "user", "Anonymize", func(ctx context.Context) (err error) {
+		result, err = r.next.Anonymize(ctx, t, dryRun)
+		return err
+	})
+
+	return result, err
+}
+
+// OrgJSONRepository ...
+type OrgJSONRepository struct {
+	next  store.OrgJSONRepository
+	store *Store
+}
+
+// Create ...
+func (r *OrgJSONRepository) Create(ctx context.Context, o *model.OrgJSON) error {
+	return r.store.observe(ctx, "org_json", "Create", func(ctx context.Context) error {
+		return r.next.Create(ctx, o)
+	})
+}
+
+// FindLatest ...
+func (r *OrgJSONRepository) FindLatest(ctx context.Context, userID int) (*model.OrgJSON, error) {
+	var result *model.OrgJSON
+	err := r.store.observe(ctx, "org_json", "FindLatest", func(ctx context.Context) (err error) {
+		result, err = r.next.FindLatest(ctx, userID)
+		return err
+	})
+
+	return result, err
+}
+
+// FindByVersion ...
+func (r *OrgJSONRepository) FindByVersion(ctx context.Context, userID int, version int) (*model.OrgJSON, error) {
+	var result *model.OrgJSON
+	err := r.store.observe(ctx, "org_json", "FindByVersion", func(ctx context.Context) (err error) {
+		result, err = r.next.FindByVersion(ctx, userID, version)
+		return err
+	})
+
+	return result, err
+}
+
+// OrgIDRepository ...
+type OrgIDRepository struct {
+	next  store.OrgIDRepository
+	store *Store
+}
+
+// Save ...
+func (r *OrgIDRepository) Save(ctx context.Context, o *model.OrgID) error {
+	return r.store.observe(ctx, "orgid", "Save", func(ctx context.Context) error {
+		return r.next.Save(ctx, o)
+	})
+}
+
+// FindByUser ...
+func (r *OrgIDRepository) FindByUser(ctx context.Context, userID int) ([]*model.OrgID, error) {
+	var result []*model.OrgID
+	err := r.store.observe(ctx, "orgid", "FindByUser", func(ctx context.Context) (err error) {
+		result, err = r.next.FindByUser(ctx, userID)
+		return err
+	})
+
+	return result, err
+}
+
+// Prune ...
+func (r *OrgIDRepository) Prune(ctx context.Context, directory string, listed []string) (int, error) {
+	var result int
+	err := r.store.observe(ctx, "orgid", "Prune", func(ctx context.Context) (err error) {
+		result, err = r.next.Prune(ctx, directory, listed)
+		return err
+	})
+
+	return result, err
+}
+
+// ContractEventRepository ...
+type ContractEventRepository struct {
+	next  store.ContractEventRepository
+	store *Store
+}
+
+// Create ...
+func (r *ContractEventRepository) Create(ctx context.Context, e *model.ContractEvent) error {
+	return r.store.observe(ctx, "contract_event", "Create", func(ctx context.Context) error {
+		return r.next.Create(ctx, e)
+	})
+}
+
+// FindUnprocessed ...
+func (r *ContractEventRepository) FindUnprocessed(ctx context.Context, limit int) ([]*model.ContractEvent, error) {
+	var result []*model.ContractEvent
+	err := r.store.observe(ctx, "contract_event", "FindUnprocessed", func(ctx context.Context) (err error) {
+		result, err = r.next.FindUnprocessed(ctx, limit)
+		return err
+	})
+
+	return result, err
+}
+
+// MarkProcessed ...
+func (r *ContractEventRepository) MarkProcessed(ctx context.Context, id int) error {
+	return r.store.observe(ctx, "contract_event", "MarkProcessed", func(ctx context.Context) error {
+		return r.next.MarkProcessed(ctx, id)
+	})
+}
+
+// MarkFailed ...
+func (r *ContractEventRepository) MarkFailed(ctx context.Context, id int, handled int, reason string, retryAt *time.Time) error {
+	return r.store.observe(ctx, "contract_event", "MarkFailed", func(ctx context.Context) error {
+		return r.next.MarkFailed(ctx, id, handled, reason, retryAt)
+	})
+}
+
+// Cursor ...
+func (r *ContractEventRepository) Cursor(ctx context.Context, name string) (uint64, error) {
+	var result uint64
+	err := r.store.observe(ctx, "contract_event", "Cursor", func(ctx context.Context) (err error) {
+		result, err = r.next.Cursor(ctx, name)
+		return err
+	})
+
+	return result, err
+}
+
+// SaveCursor ...
+func (r *ContractEventRepository) SaveCursor(ctx context.Context, name string, block uint64) error {
+	return r.store.observe(ctx, "contract_event", "SaveCursor", func(ctx context.Context) error {
+		return r.next.SaveCursor(ctx, name, block)
+	})
+}
+
+// FlightRepository ...
+type FlightRepository struct {
+	next  store.FlightRepository
+	store *Store
+}
+
+// Create ...
+func (r *FlightRepository) Create(ctx context.Context, f *model.Flight) error {
+	return r.store.observe(ctx, "flight", "Create", func(ctx context.Context) error {
+		return r.next.Create(ctx, f)
+	})
+}
+
+// Find ...
+func (r *FlightRepository) Find(ctx context.Context, id int) (*model.Flight, error) {
+	var result *model.Flight
+	err := r.store.observe(ctx, "flight", "Find", func(ctx context.Context) (err error) {
+		result, err = r.next.Find(ctx, id)
+		return err
+	})
+
+	return result, err
+}
+
+// List ...
+func (r *FlightRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Flight, int, error) {
+	var result []*model.Flight
+	var total int
+	err := r.store.observe(ctx, "flight", "List", func(ctx context.Context) (err error) {
+		result, total, err = r.next.List(ctx, opts)
+		return err
+	})
+
+	return result, total, err
+}
+
+// FareRepository ...
+type FareRepository struct {
+	next  store.FareRepository
+	store *Store
+}
+
+// Create ...
+func (r *FareRepository) Create(ctx context.Context, f *model.Fare) error {
+	return r.store.observe(ctx, "fare", "Create", func(ctx context.Context) error {
+		return r.next.Create(ctx, f)
+	})
+}
+
+// Upsert ...
+func (r *FareRepository) Upsert(ctx context.Context, fares []*model.Fare) error {
+	return r.store.observe(ctx, "fare", "Upsert", func(ctx context.Context) error {
+		return r.next.Upsert(ctx, fares)
+	})
+}
+
+// Search ...
+func (r *FareRepository) Search(ctx context.Context, s *model.FlightSearch) ([]*model.FlightOffer, error) {
+	var result []*model.FlightOffer
+	err := r.store.observe(ctx, "fare", "Search", func(ctx context.Context) (err error) {
+		result, err = r.next.Search(ctx, s)
+		return err
+	})
+
+	return result, err
+}
+
+// OnboardingRepository ...
+type OnboardingRepository struct {
+	next  store.OnboardingRepository
+	store *Store
+}
+
+// Find ...
+func (r *OnboardingRepository) Find(ctx context.Context, userID int) (*model.Onboarding, error) {
+	var result *model.Onboarding
+	err := r.store.observe(ctx, "onboarding", "Find", func(ctx context.Context) (err error) {
+		result, err = r.next.Find(ctx, userID)
+		return err
+	})
+
+	return result, err
+}
+
+// List ...
+func (r *OnboardingRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Onboarding, int, error) {
+	var result []*model.Onboarding
+	var total int
+	err := r.store.observe(ctx, "onboarding", "List", func(ctx context.Context) (err error) {
+		result, total, err = r.next.List(ctx, opts)
+		return err
+	})
+
+	return result, total, err
+}
+
+// Save ...
+func (r *OnboardingRepository) Save(ctx context.Context, o *model.Onboarding) error {
+	return r.store.observe(ctx, "onboarding", "Save", func(ctx context.Context) error {
+		return r.next.Save(ctx, o)
+	})
+}
+
+// CreateDocument ...
+func (r *OnboardingRepository) CreateDocument(ctx context.Context, d *model.OnboardingDocument) error {
+	return r.store.observe(ctx, "onboarding", "CreateDocument", func(ctx context.Context) error {
+		return r.next.CreateDocument(ctx, d)
+	})
+}
+
+// FindDocuments ...
+func (r *OnboardingRepository) FindDocuments(ctx context.Context, userID int) ([]*model.OnboardingDocument, error) {
+	var result []*model.OnboardingDocument
+	err := r.store.observe(ctx, "onboarding", "FindDocuments", func(ctx context.Context) (err error) {
+		result, err = r.next.FindDocuments(ctx, userID)
+		return err
+	})
+
+	return result, err
+}
+
+// FindDocument ...
+func (r *OnboardingRepository) FindDocument(ctx context.Context, userID int, id int) (*model.OnboardingDocument, error) {
+	var result *model.OnboardingDocument
+	err := r.store.observe(ctx, "onboarding", "FindDocument", func(ctx context.Context) (err error) {
+		result, err = r.next.FindDocument(ctx, userID, id)
+		return err
+	})
+
+	return result, err
+}
+
+// OutboxRepository ...
+type OutboxRepository struct {
+	next  store.OutboxRepository
+	store *Store
+}
+
+// FindUnpublished ...
+func (r *OutboxRepository) FindUnpublished(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	var result []*model.OutboxEvent
+	err := r.store.observe(ctx, "outbox", "FindUnpublished", func(ctx context.Context) (err error) {
+		result, err = r.next.FindUnpublished(ctx, limit)
+		return err
+	})
+
+	return result, err
+}
+
+// FindAfter ...
+func (r *OutboxRepository) FindAfter(ctx context.Context, id int, limit int) ([]*model.OutboxEvent, error) {
+	var result []*model.OutboxEvent
+	err := r.store.observe(ctx, "outbox", "FindAfter", func(ctx context.Context) (err error) {
+		result, err = r.next.FindAfter(ctx, id, limit)
+		return err
+	})
+
+	return result, err
+}
+
+// LastID ...
+func (r *OutboxRepository) LastID(ctx context.Context) (int, error) {
+	var result int
+	err := r.store.observe(ctx, "outbox", "LastID", func(ctx context.Context) (err error) {
+		result, err = r.next.LastID(ctx)
+		return err
+	})
+
+	return result, err
+}
+
+// MarkPublished ...
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int) error {
+	return r.store.observe(ctx, "outbox", "MarkPublished", func(ctx context.Context) error {
+		return r.next.MarkPublished(ctx, id)
+	})
+}
+
+// MarkFailed ...
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int, reason string, retryAt *time.Time) error {
+	return r.store.observe(ctx, "outbox", "MarkFailed", func(ctx context.Context) error {
+		return r.next.MarkFailed(ctx, id, reason, retryAt)
+	})
+}
+
+// Purge ...
+func (r *OutboxRepository) Purge(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	var result int
+	err := r.store.observe(ctx, "outbox", "Purge", func(ctx context.Context) (err error) {
+		result, err = r.next.Purge(ctx, t, dryRun)
+		return err
+	})
+
+	return result, err
+}
+
+// ChangeRepository ...
+type ChangeRepository struct {
+	next  store.ChangeRepository
+	store *Store
+}
+
+// FindByEntity ...
+func (r *ChangeRepository) FindByEntity(ctx context.Context, entity string, entityID int) ([]*model.Change, error) {
+	var result []*model.Change
+	err := r.store.observe(ctx, "change", "FindByEntity", func(ctx context.Context) (err error) {
+		result, err = r.next.FindByEntity(ctx, entity, entityID)
+		return err
+	})
+
+	return result, err
+}
+
+// Purge ...
+func (r *ChangeRepository) Purge(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	var result int
+	err := r.store.observe(ctx, "change", "Purge", func(ctx context.Context) (err error) {
+		result, err = r.next.Purge(ctx, t, dryRun)
+		return err
+	})
+
+	return result, err
+}
+
+// JobRepository ...
+type JobRepository struct {
+	next  store.JobRepository
+	store *Store
+}
+
+// Create ...
+func (r *JobRepository) Create(ctx context.Context, j *model.Job) error {
+	return r.store.observe(ctx, "job", "Create", func(ctx context.Context) error {
+		return r.next.Create(ctx, j)
+	})
+}
+
+// Find ...
+func (r *JobRepository) Find(ctx context.Context, id int) (*model.Job, error) {
+	var result *model.Job
+	err := r.store.observe(ctx, "job", "Find", func(ctx context.Context) (err error) {
+		result, err = r.next.Find(ctx, id)
+		return err
+	})
+
+	return result, err
+}
+
+// List ...
+func (r *JobRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Job, int, error) {
+	var result []*model.Job
+	var total int
+	err := r.store.observe(ctx, "job", "List", func(ctx context.Context) (err error) {
+		result, total, err = r.next.List(ctx, opts)
+		return err
+	})
+
+	return result, total, err
+}
+
+// Claim ...
+func (r *JobRepository) Claim(ctx context.Context, kinds []string, n int, lease time.Duration) ([]*model.Job, error) {
+	var result []*model.Job
+	err := r.store.observe(ctx, "job", "Claim", func(ctx context.Context) (err error) {
+		result, err = r.next.Claim(ctx, kinds, n, lease)
+		return err
+	})
+
+	return result, err
+}
+
+// Complete ...
+func (r *JobRepository) Complete(ctx context.Context, id int, attempt int) error {
+	return r.store.observe(ctx, "job", "Complete", func(ctx context.Context) error {
+		return r.next.Complete(ctx, id, attempt)
+	})
+}
+
+// Fail ...
+func (r *JobRepository) Fail(ctx context.Context, id int, attempt int, reason string, retryAt *time.Time) error {
+	return r.store.observe(ctx, "job", "Fail", func(ctx context.Context) error {
+		return r.next.Fail(ctx, id, attempt, reason, retryAt)
+	})
+}
+
+// Reschedule ...
+func (r *JobRepository) Reschedule(ctx context.Context, id int, runAt time.Time) error {
+	return r.store.observe(ctx, "job", "Reschedule", func(ctx context.Context) error {
+		return r.next.Reschedule(ctx, id, runAt)
+	})
+}
+
+// Purge ...
+func (r *JobRepository) Purge(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	var result int
+	err := r.store.observe(ctx, "job", "Purge", func(ctx context.Context) (err error) {
+		result, err = r.next.Purge(ctx, t, dryRun)
+		return err
+	})
+
+	return result, err
+}
+
+// ScheduledTaskRepository ...
+type ScheduledTaskRepository struct {
+	next  store.ScheduledTaskRepository
+	store *Store
+}
+
+// Claim ...
+func (r *ScheduledTaskRepository) Claim(ctx context.Context, task string, runAt time.Time, lease time.Duration) (bool, error) {
+	var result bool
+	err := r.store.observe(ctx, "scheduled_task", "Claim", func(ctx context.Context) (err error) {
+		result, err = r.next.Claim(ctx, task, runAt, lease)
+		return err
+	})
+
+	return result, err
+}
+
+// Release ...
+func (r *ScheduledTaskRepository) Release(ctx context.Context, task string, runAt time.Time, runErr string) error {
+	return r.store.observe(ctx, "scheduled_task", "Release", func(ctx context.Context) error {
+		return r.next.Release(ctx, task, runAt, runErr)
+	})
+}
+
+// List ...
+func (r *ScheduledTaskRepository) List(ctx context.Context) ([]*model.ScheduledTask, error) {
+	var result []*model.ScheduledTask
+	err := r.store.observe(ctx, "scheduled_task", "List", func(ctx context.Context) (err error) {
+		result, err = r.next.List(ctx)
+		return err
+	})
+
+	return result, err
+}
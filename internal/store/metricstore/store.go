@@ -0,0 +1,229 @@
+// Package metricstore decorates a store.Store so that every repository call
+// is counted and timed per repository and method, and calls slower than a
+// threshold are logged. Calls are also traced when a tracer is set.
+package metricstore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/tracing"
+)
+
+// Store ...
+type Store struct {
+	store.Store
+	metrics                 *Metrics
+	tracer                  *tracing.Tracer
+	dbSystem                string
+	userRepository          *UserRepository
+	orgJSONRepository       *OrgJSONRepository
+	orgIDRepository         *OrgIDRepository
+	contractEventRepository *ContractEventRepository
+	flightRepository        *FlightRepository
+	fareRepository          *FareRepository
+	onboardingRepository    *OnboardingRepository
+	outboxRepository        *OutboxRepository
+	changeRepository        *ChangeRepository
+	jobRepository           *JobRepository
+	scheduledTaskRepository *ScheduledTaskRepository
+}
+
+// New ...
+func New(s store.Store, m *Metrics) *Store {
+	return &Store{
+		Store:   s,
+		metrics: m,
+	}
+}
+
+// Trace records a span per call, attributed to the database dbSystem
+func (s *Store) Trace(t *tracing.Tracer, dbSystem string) {
+	s.tracer = t
+	s.dbSystem = dbSystem
+}
+
+// observe records one call of a repository method. fn runs with the
+// context of its span.
+func (s *Store) observe(ctx context.Context, repository, method string, fn func(ctx context.Context) error) error {
+	ctx, span := s.tracer.Start(ctx, repository+"."+method, tracing.KindClient)
+	span.SetAttribute("db.system", s.dbSystem)
+	span.SetAttribute("db.operation", method)
+	span.SetAttribute("store.repository", repository)
+
+	start := time.Now()
+	err := fn(ctx)
+	s.metrics.observe(repository, method, time.Since(start), err)
+
+	if err != nil && err != store.ErrRecordNotFound {
+		span.SetError(err)
+	}
+	span.End()
+
+	return err
+}
+
+// User ...
+func (s *Store) User() store.UserRepository {
+	if s.userRepository != nil {
+		return s.userRepository
+	}
+
+	s.userRepository = &UserRepository{
+		next:  s.Store.User(),
+		store: s,
+	}
+
+	return s.userRepository
+}
+
+// OrgJSON ...
+func (s *Store) OrgJSON() store.OrgJSONRepository {
+	if s.orgJSONRepository != nil {
+		return s.orgJSONRepository
+	}
+
+	s.orgJSONRepository = &OrgJSONRepository{
+		next:  s.Store.OrgJSON(),
+		store: s,
+	}
+
+	return s.orgJSONRepository
+}
+
+// OrgID ...
+func (s *Store) OrgID() store.OrgIDRepository {
+	if s.orgIDRepository != nil {
+		return s.orgIDRepository
+	}
+
+	s.orgIDRepository = &OrgIDRepository{
+		next:  s.Store.OrgID(),
+		store: s,
+	}
+
+	return s.orgIDRepository
+}
+
+// ContractEvent ...
+func (s *Store) ContractEvent() store.ContractEventRepository {
+	if s.contractEventRepository != nil {
+		return s.contractEventRepository
+	}
+
+	s.contractEventRepository = &ContractEventRepository{
+		next:  s.Store.ContractEvent(),
+		store: s,
+	}
+
+	return s.contractEventRepository
+}
+
+// Flight ...
+func (s *Store) Flight() store.FlightRepository {
+	if s.flightRepository != nil {
+		return s.flightRepository
+	}
+
+	s.flightRepository = &FlightRepository{
+		next:  s.Store.Flight(),
+		store: s,
+	}
+
+	return s.flightRepository
+}
+
+// Fare ...
+func (s *Store) Fare() store.FareRepository {
+	if s.fareRepository != nil {
+		return s.fareRepository
+	}
+
+	s.fareRepository = &FareRepository{
+		next:  s.Store.Fare(),
+		store: s,
+	}
+
+	return s.fareRepository
+}
+
+// Onboarding ...
+func (s *Store) Onboarding() store.OnboardingRepository {
+	if s.onboardingRepository != nil {
+		return s.onboardingRepository
+	}
+
+	s.onboardingRepository = &OnboardingRepository{
+		next:  s.Store.Onboarding(),
+		store: s,
+	}
+
+	return s.onboardingRepository
+}
+
+// Outbox ...
+func (s *Store) Outbox() store.OutboxRepository {
+	if s.outboxRepository != nil {
+		return s.outboxRepository
+	}
+
+	s.outboxRepository = &OutboxRepository{
+		next:  s.Store.Outbox(),
+		store: s,
+	}
+
+	return s.outboxRepository
+}
+
+// Change ...
+func (s *Store) Change() store.ChangeRepository {
+	if s.changeRepository != nil {
+		return s.changeRepository
+	}
+
+	s.changeRepository = &ChangeRepository{
+		next:  s.Store.Change(),
+		store: s,
+	}
+
+	return s.changeRepository
+}
+
+// Job ...
+func (s *Store) Job() store.JobRepository {
+	if s.jobRepository != nil {
+		return s.jobRepository
+	}
+
+	s.jobRepository = &JobRepository{
+		next:  s.Store.Job(),
+		store: s,
+	}
+
+	return s.jobRepository
+}
+
+// ScheduledTask ...
+func (s *Store) ScheduledTask() store.ScheduledTaskRepository {
+	if s.scheduledTaskRepository != nil {
+		return s.scheduledTaskRepository
+	}
+
+	s.scheduledTaskRepository = &ScheduledTaskRepository{
+		next:  s.Store.ScheduledTask(),
+		store: s,
+	}
+
+	return s.scheduledTaskRepository
+}
+
+// ForTenant scopes the underlying store, recording into the same metrics
+// and tracer
+func (s *Store) ForTenant(tenantID int) store.Store {
+	return &Store{
+		Store:    s.Store.ForTenant(tenantID),
+		metrics:  s.metrics,
+		tracer:   s.tracer,
+		dbSystem: s.dbSystem,
+	}
+}
@@ -0,0 +1,156 @@
+package metricstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/metricstore"
+	"winding-tree-server/internal/store/storetest"
+	"winding-tree-server/internal/store/teststore"
+	"winding-tree-server/internal/tracing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// countOperations returns store_operations_total for one label combination
+func countOperations(t *testing.T, reg *prometheus.Registry, repository, method, result string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range families {
+		if f.GetName() != "store_operations_total" {
+			continue
+		}
+
+		for _, m := range f.GetMetric() {
+			values := map[string]string{}
+			for _, l := range m.GetLabel() {
+				values[l.GetName()] = l.GetValue()
+			}
+
+			if values["repository"] == repository && values["method"] == method && values["result"] == result {
+				return m.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return 0
+}
+
+func TestStore_CountsOperations(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := metricstore.NewMetrics(reg, logrus.New(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := metricstore.New(teststore.New(), m)
+	u := model.TestUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+	_, err = s.User().Find(context.Background(), u.ID)
+	assert.NoError(t, err)
+	_, err = s.User().Find(context.Background(), u.ID+1)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+	assert.Error(t, s.User().Create(context.Background(), &model.User{}))
+
+	testCases := []struct {
+		method   string
+		result   string
+		expected float64
+	}{
+		{"Create", "ok", 1},
+		{"Create", "error", 1},
+		{"Find", "ok", 1},
+		{"Find", "not_found", 1},
+		{"Find", "error", 0},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, countOperations(t, reg, "user", tc.method, tc.result), tc.method+" "+tc.result)
+	}
+
+	_, _, err = s.ForTenant(u.ID).Flight().List(context.Background(), &store.ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), countOperations(t, reg, "flight", "List", "ok"), "tenant stores share the metrics")
+}
+
+func TestStore_LogsSlowOperations(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	m, err := metricstore.NewMetrics(prometheus.NewRegistry(), logger, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := metricstore.New(teststore.New(), m)
+	_, err = s.User().Find(context.Background(), 1)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	if assert.Len(t, hook.Entries, 1) {
+		entry := hook.LastEntry()
+		assert.Equal(t, logrus.WarnLevel, entry.Level)
+		assert.Equal(t, "user", entry.Data["repository"])
+		assert.Equal(t, "Find", entry.Data["method"])
+		assert.Equal(t, store.ErrRecordNotFound, entry.Data[logrus.ErrorKey])
+	}
+}
+
+type spanRecorder []*tracing.Span
+
+func (r *spanRecorder) Export(s *tracing.Span) {
+	*r = append(*r, s)
+}
+
+func TestStore_TracesOperations(t *testing.T) {
+	m, err := metricstore.NewMetrics(prometheus.NewRegistry(), logrus.New(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := &spanRecorder{}
+	tracer := tracing.NewTracer(spans, 1)
+	s := metricstore.New(teststore.New(), m)
+	s.Trace(tracer, "postgresql")
+
+	ctx, parent := tracer.Start(context.Background(), "request", tracing.KindServer)
+	_, err = s.User().Find(ctx, 1)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+	assert.Error(t, s.ForTenant(1).User().Create(ctx, &model.User{}))
+
+	if assert.Len(t, *spans, 2) {
+		find, create := (*spans)[0], (*spans)[1]
+		assert.Equal(t, "user.Find", find.Name)
+		assert.Equal(t, parent.Context.SpanID, find.Parent)
+		assert.Equal(t, "postgresql", find.Attributes["db.system"])
+		assert.NoError(t, find.Err, "missing records aren't failures")
+		assert.Equal(t, "user.Create", create.Name)
+		assert.Error(t, create.Err, "tenant stores share the tracer")
+	}
+}
+
+func TestNewMetrics_AlreadyRegistered(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	_, err := metricstore.NewMetrics(reg, logrus.New(), 0)
+	assert.NoError(t, err)
+	_, err = metricstore.NewMetrics(reg, logrus.New(), 0)
+	assert.Error(t, err)
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		m, err := metricstore.NewMetrics(prometheus.NewRegistry(), logrus.New(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return metricstore.New(teststore.New(), m), func() {}
+	})
+}
@@ -0,0 +1,53 @@
+package store
+
+import "strings"
+
+const (
+	// DefaultLimit ...
+	DefaultLimit = 20
+	// MaxLimit ...
+	MaxLimit = 100
+)
+
+// ListOptions controls paging, ordering and filtering of List methods
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Sort is a field name, prefixed with "-" for descending order
+	Sort string
+	// Filters match fields by exact value
+	Filters map[string]string
+}
+
+// SortField returns the sort field without its direction prefix and whether it is descending
+func (o *ListOptions) SortField() (string, bool) {
+	if strings.HasPrefix(o.Sort, "-") {
+		return o.Sort[1:], true
+	}
+
+	return o.Sort, false
+}
+
+// Validate checks paging bounds and that sorting and filtering only use fields
+func (o *ListOptions) Validate(fields ...string) error {
+	if o.Limit < 1 || o.Limit > MaxLimit || o.Offset < 0 {
+		return ErrInvalidListOptions
+	}
+
+	allowed := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		allowed[f] = true
+	}
+
+	if field, _ := o.SortField(); field != "" && !allowed[field] {
+		return ErrInvalidListOptions
+	}
+
+	for f := range o.Filters {
+		if !allowed[f] {
+			return ErrInvalidListOptions
+		}
+	}
+
+	return nil
+}
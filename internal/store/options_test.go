@@ -0,0 +1,57 @@
+package store_test
+
+import (
+	"testing"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListOptions_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		opts    *store.ListOptions
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			opts:    &store.ListOptions{Limit: 10, Offset: 20, Sort: "-email", Filters: map[string]string{"id": "1"}},
+			isValid: true,
+		},
+		{
+			name:    "zero limit",
+			opts:    &store.ListOptions{Limit: 0},
+			isValid: false,
+		},
+		{
+			name:    "limit too large",
+			opts:    &store.ListOptions{Limit: store.MaxLimit + 1},
+			isValid: false,
+		},
+		{
+			name:    "negative offset",
+			opts:    &store.ListOptions{Limit: 10, Offset: -1},
+			isValid: false,
+		},
+		{
+			name:    "unknown sort field",
+			opts:    &store.ListOptions{Limit: 10, Sort: "password"},
+			isValid: false,
+		},
+		{
+			name:    "unknown filter field",
+			opts:    &store.ListOptions{Limit: 10, Filters: map[string]string{"password": "secret"}},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.opts.Validate("id", "email"))
+			} else {
+				assert.Equal(t, store.ErrInvalidListOptions, tc.opts.Validate("id", "email"))
+			}
+		})
+	}
+}
@@ -1,10 +1,135 @@
 package store
 
-import "winding-tree-server/internal/model"
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/model"
+)
 
 // UserRepository interface
 type UserRepository interface {
-	Create(*model.User) error
-	Find(int) (*model.User, error)
-	FindByEmail(string) (*model.User, error)
+	Create(context.Context, *model.User) error
+	Find(context.Context, int) (*model.User, error)
+	FindByEmail(context.Context, string) (*model.User, error)
+	FindByPublicID(context.Context, string) (*model.User, error)
+	List(context.Context, *ListOptions) ([]*model.User, int, error)
+	Search(context.Context, string, *ListOptions) ([]*model.User, int, error)
+	Delete(context.Context, int) error
+	Restore(context.Context, int) error
+	// Anonymize scrubs the personal data of users deleted before the given
+	// time and returns how many there were; with dryRun it only counts them
+	Anonymize(ctx context.Context, deletedBefore time.Time, dryRun bool) (int, error)
+}
+
+// OrgJSONRepository interface
+type OrgJSONRepository interface {
+	Create(context.Context, *model.OrgJSON) error
+	FindLatest(context.Context, int) (*model.OrgJSON, error)
+	FindByVersion(context.Context, int, int) (*model.OrgJSON, error)
+}
+
+// OrgIDRepository interface
+type OrgIDRepository interface {
+	Save(context.Context, *model.OrgID) error
+	FindByUser(context.Context, int) ([]*model.OrgID, error)
+	// Prune deletes the organizations mirrored from directory that aren't
+	// among listed, the ids it lists now, and returns how many it deleted
+	Prune(ctx context.Context, directory string, listed []string) (int, error)
+}
+
+// ContractEventRepository interface
+type ContractEventRepository interface {
+	Create(context.Context, *model.ContractEvent) error
+	FindUnprocessed(context.Context, int) ([]*model.ContractEvent, error)
+	MarkProcessed(context.Context, int) error
+	// MarkFailed records a failed attempt, after the first handled handlers
+	// of the event succeeded. It is retried at retryAt, or never without it.
+	MarkFailed(ctx context.Context, id int, handled int, reason string, retryAt *time.Time) error
+	Cursor(context.Context, string) (uint64, error)
+	SaveCursor(context.Context, string, uint64) error
+}
+
+// FlightRepository interface
+type FlightRepository interface {
+	Create(context.Context, *model.Flight) error
+	Find(context.Context, int) (*model.Flight, error)
+	List(context.Context, *ListOptions) ([]*model.Flight, int, error)
+}
+
+// FareRepository interface
+type FareRepository interface {
+	Create(context.Context, *model.Fare) error
+	Upsert(context.Context, []*model.Fare) error
+	Search(context.Context, *model.FlightSearch) ([]*model.FlightOffer, error)
+}
+
+// OnboardingRepository interface
+type OnboardingRepository interface {
+	Find(context.Context, int) (*model.Onboarding, error)
+	List(context.Context, *ListOptions) ([]*model.Onboarding, int, error)
+	Save(context.Context, *model.Onboarding) error
+	CreateDocument(context.Context, *model.OnboardingDocument) error
+	FindDocuments(context.Context, int) ([]*model.OnboardingDocument, error)
+	FindDocument(context.Context, int, int) (*model.OnboardingDocument, error)
+}
+
+// OutboxRepository interface. Events are written by the repositories whose
+// changes they describe, in the same transaction.
+type OutboxRepository interface {
+	FindUnpublished(context.Context, int) ([]*model.OutboxEvent, error)
+	// FindAfter returns up to limit events written after the one with the
+	// given id, published or not, oldest first
+	FindAfter(ctx context.Context, id int, limit int) ([]*model.OutboxEvent, error)
+	// LastID is the id of the last event written, 0 without events
+	LastID(context.Context) (int, error)
+	MarkPublished(context.Context, int) error
+	// MarkFailed records a failed delivery. The event is retried at retryAt,
+	// or never without it.
+	MarkFailed(ctx context.Context, id int, reason string, retryAt *time.Time) error
+	// Purge deletes events published before the given time
+	Purge(ctx context.Context, publishedBefore time.Time, dryRun bool) (int, error)
+}
+
+// ChangeRepository interface. Changes are recorded by the repositories of
+// the entities they describe, in the same transaction.
+type ChangeRepository interface {
+	FindByEntity(context.Context, string, int) ([]*model.Change, error)
+	// Purge deletes changes recorded before the given time
+	Purge(ctx context.Context, before time.Time, dryRun bool) (int, error)
+}
+
+// JobRepository interface. A job is claimed by one worker at a time, for a
+// lease after which a job whose worker stopped is claimed again.
+type JobRepository interface {
+	Create(context.Context, *model.Job) error
+	Find(context.Context, int) (*model.Job, error)
+	List(context.Context, *ListOptions) ([]*model.Job, int, error)
+	// Claim marks up to n due jobs of the given kinds running for lease,
+	// counting an attempt, and returns them, oldest first
+	Claim(ctx context.Context, kinds []string, n int, lease time.Duration) ([]*model.Job, error)
+	// Complete marks a job done, if it is still running the given claimed
+	// attempt; otherwise it returns ErrLeaseLost
+	Complete(ctx context.Context, id int, attempt int) error
+	// Fail records a failed attempt of a claimed job, or returns ErrLeaseLost
+	// as Complete. It is run again at retryAt, or is dead when retryAt is nil.
+	Fail(ctx context.Context, id int, attempt int, reason string, retryAt *time.Time) error
+	// Reschedule runs a pending or dead job again at runAt, with all its
+	// attempts, returning ErrConflict if it started running in between
+	Reschedule(ctx context.Context, id int, runAt time.Time) error
+	// Purge deletes jobs done before the given time
+	Purge(ctx context.Context, doneBefore time.Time, dryRun bool) (int, error)
+}
+
+// ScheduledTaskRepository interface. Instances running the same recurring
+// tasks claim each run so that only one of them does it.
+type ScheduledTaskRepository interface {
+	// Claim takes the run of task scheduled at runAt for lease, and reports
+	// whether it did. It doesn't once another instance took this run or a
+	// later one, or while an instance holds an earlier one.
+	Claim(ctx context.Context, task string, runAt time.Time, lease time.Duration) (bool, error)
+	// Release ends the run of task scheduled at runAt, recording runErr if
+	// it failed. It returns ErrLeaseLost once another run was claimed.
+	Release(ctx context.Context, task string, runAt time.Time, runErr string) error
+	// List returns the tasks that have run, by name
+	List(context.Context) ([]*model.ScheduledTask, error)
 }
@@ -0,0 +1,42 @@
+package retrystore
+
+import (
+	"context"
+	"math/rand"
+	"time"
+	"winding-tree-server/internal/backoff"
+)
+
+// Policy bounds the retries of one class of operations
+type Policy struct {
+	// Attempts is the total number of tries; 1 or less disables retries
+	Attempts int
+	// BaseDelay doubles after each failed try, up to MaxDelay. The actual
+	// wait is a random duration below it so that retries don't line up.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Retryable reports whether a failed try may succeed if repeated
+	Retryable func(error) bool
+}
+
+// do runs fn until it succeeds, fails with an error that isn't retryable,
+// runs out of attempts or ctx is done
+func (p Policy) do(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || p.Retryable == nil || !p.Retryable(err) {
+			return err
+		}
+
+		var wait time.Duration
+		if delay := backoff.Delay(p.BaseDelay, p.MaxDelay, attempt); delay > 0 {
+			wait = time.Duration(rand.Int63n(int64(delay)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package retrystore decorates a store.Store so that operations failing with
+// transient database errors are retried with backoff instead of surfacing as
+// errors. Reads and writes have separate policies, since a write can only be
+// repeated when the failure guarantees it didn't commit.
+package retrystore
+
+import "winding-tree-server/internal/store"
+
+// Store ...
+type Store struct {
+	store.Store
+	reads                   Policy
+	writes                  Policy
+	userRepository          *UserRepository
+	orgJSONRepository       *OrgJSONRepository
+	orgIDRepository         *OrgIDRepository
+	contractEventRepository *ContractEventRepository
+	flightRepository        *FlightRepository
+	fareRepository          *FareRepository
+	onboardingRepository    *OnboardingRepository
+	outboxRepository        *OutboxRepository
+	changeRepository        *ChangeRepository
+	jobRepository           *JobRepository
+	scheduledTaskRepository *ScheduledTaskRepository
+}
+
+// New ...
+func New(s store.Store, reads, writes Policy) *Store {
+	return &Store{
+		Store:  s,
+		reads:  reads,
+		writes: writes,
+	}
+}
+
+// User ...
+func (s *Store) User() store.UserRepository {
+	if s.userRepository != nil {
+		return s.userRepository
+	}
+
+	s.userRepository = &UserRepository{
+		next:  s.Store.User(),
+		store: s,
+	}
+
+	return s.userRepository
+}
+
+// OrgJSON ...
+func (s *Store) OrgJSON() store.OrgJSONRepository {
+	if s.orgJSONRepository != nil {
+		return s.orgJSONRepository
+	}
+
+	s.orgJSONRepository = &OrgJSONRepository{
+		next:  s.Store.OrgJSON(),
+		store: s,
+	}
+
+	return s.orgJSONRepository
+}
+
+// OrgID ...
+func (s *Store) OrgID() store.OrgIDRepository {
+	if s.orgIDRepository != nil {
+		return s.orgIDRepository
+	}
+
+	s.orgIDRepository = &OrgIDRepository{
+		next:  s.Store.OrgID(),
+		store: s,
+	}
+
+	return s.orgIDRepository
+}
+
+// ContractEvent ...
+func (s *Store) ContractEvent() store.ContractEventRepository {
+	if s.contractEventRepository != nil {
+		return s.contractEventRepository
+	}
+
+	s.contractEventRepository = &ContractEventRepository{
+		next:  s.Store.ContractEvent(),
+		store: s,
+	}
+
+	return s.contractEventRepository
+}
+
+// Flight ...
+func (s *Store) Flight() store.FlightRepository {
+	if s.flightRepository != nil {
+		return s.flightRepository
+	}
+
+	s.flightRepository = &FlightRepository{
+		next:  s.Store.Flight(),
+		store: s,
+	}
+
+	return s.flightRepository
+}
+
+// Fare ...
+func (s *Store) Fare() store.FareRepository {
+	if s.fareRepository != nil {
+		return s.fareRepository
+	}
+
+	s.fareRepository = &FareRepository{
+		next:  s.Store.Fare(),
+		store: s,
+	}
+
+	return s.fareRepository
+}
+
+// Onboarding ...
+func (s *Store) Onboarding() store.OnboardingRepository {
+	if s.onboardingRepository != nil {
+		return s.onboardingRepository
+	}
+
+	s.onboardingRepository = &OnboardingRepository{
+		next:  s.Store.Onboarding(),
+		store: s,
+	}
+
+	return s.onboardingRepository
+}
+
+// Outbox ...
+func (s *Store) Outbox() store.OutboxRepository {
+	if s.outboxRepository != nil {
+		return s.outboxRepository
+	}
+
+	s.outboxRepository = &OutboxRepository{
+		next:  s.Store.Outbox(),
+		store: s,
+	}
+
+	return s.outboxRepository
+}
+
+// Change ...
+func (s *Store) Change() store.ChangeRepository {
+	if s.changeRepository != nil {
+		return s.changeRepository
+	}
+
+	s.changeRepository = &ChangeRepository{
+		next:  s.Store.Change(),
+		store: s,
+	}
+
+	return s.changeRepository
+}
+
+// Job ...
+func (s *Store) Job() store.JobRepository {
+	if s.jobRepository != nil {
+		return s.jobRepository
+	}
+
+	s.jobRepository = &JobRepository{
+		next:  s.Store.Job(),
+		store: s,
+	}
+
+	return s.jobRepository
+}
+
+// ScheduledTask ...
+func (s *Store) ScheduledTask() store.ScheduledTaskRepository {
+	if s.scheduledTaskRepository != nil {
+		return s.scheduledTaskRepository
+	}
+
+	s.scheduledTaskRepository = &ScheduledTaskRepository{
+		next:  s.Store.ScheduledTask(),
+		store: s,
+	}
+
+	return s.scheduledTaskRepository
+}
+
+// ForTenant scopes the underlying store, keeping the retry policies
+func (s *Store) ForTenant(tenantID int) store.Store {
+	return New(s.Store.ForTenant(tenantID), s.reads, s.writes)
+}
@@ -0,0 +1,172 @@
+package retrystore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/retrystore"
+	"winding-tree-server/internal/store/storetest"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errTransient  = errors.New("transient")
+	errDisconnect = errors.New("disconnect")
+)
+
+// flakyUserRepository fails the given number of calls before passing through
+type flakyUserRepository struct {
+	store.UserRepository
+	failures int
+	err      error
+	calls    int
+}
+
+func (r *flakyUserRepository) Find(ctx context.Context, id int) (*model.User, error) {
+	r.calls++
+	if r.calls <= r.failures {
+		return nil, r.err
+	}
+
+	return r.UserRepository.Find(ctx, id)
+}
+
+func (r *flakyUserRepository) Delete(ctx context.Context, id int) error {
+	r.calls++
+	if r.calls <= r.failures {
+		return r.err
+	}
+
+	return r.UserRepository.Delete(ctx, id)
+}
+
+type flakyStore struct {
+	*teststore.Store
+	users *flakyUserRepository
+}
+
+func (s *flakyStore) User() store.UserRepository {
+	return s.users
+}
+
+func TestStore_Retries(t *testing.T) {
+	reads := retrystore.Policy{
+		Attempts:  3,
+		BaseDelay: time.Millisecond,
+		MaxDelay:  2 * time.Millisecond,
+		Retryable: func(err error) bool {
+			return err == errTransient || err == errDisconnect
+		},
+	}
+	writes := reads
+	writes.Retryable = func(err error) bool {
+		return err == errTransient
+	}
+
+	testCases := []struct {
+		name          string
+		write         bool
+		failures      int
+		err           error
+		expectedErr   error
+		expectedCalls int
+	}{
+		{
+			name:          "read recovers",
+			failures:      2,
+			err:           errDisconnect,
+			expectedCalls: 3,
+		},
+		{
+			name:          "read gives up",
+			failures:      3,
+			err:           errTransient,
+			expectedErr:   errTransient,
+			expectedCalls: 3,
+		},
+		{
+			name:          "write recovers",
+			write:         true,
+			failures:      1,
+			err:           errTransient,
+			expectedCalls: 2,
+		},
+		{
+			name:          "write not retried after disconnect",
+			write:         true,
+			failures:      1,
+			err:           errDisconnect,
+			expectedErr:   errDisconnect,
+			expectedCalls: 1,
+		},
+		{
+			name:          "not found is not retried",
+			failures:      0,
+			expectedErr:   store.ErrRecordNotFound,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := teststore.New()
+			u := model.TestUser(t)
+			if tc.expectedErr != store.ErrRecordNotFound {
+				ts.User().Create(context.Background(), u)
+			}
+
+			users := &flakyUserRepository{
+				UserRepository: ts.User(),
+				failures:       tc.failures,
+				err:            tc.err,
+			}
+			s := retrystore.New(&flakyStore{Store: ts, users: users}, reads, writes)
+
+			var err error
+			if tc.write {
+				err = s.User().Delete(context.Background(), u.ID)
+			} else {
+				_, err = s.User().Find(context.Background(), u.ID)
+			}
+
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedCalls, users.calls)
+		})
+	}
+}
+
+func TestStore_StopsWhenContextDone(t *testing.T) {
+	ts := teststore.New()
+	users := &flakyUserRepository{
+		UserRepository: ts.User(),
+		failures:       10,
+		err:            errTransient,
+	}
+	policy := retrystore.Policy{
+		Attempts:  10,
+		BaseDelay: time.Hour,
+		Retryable: func(err error) bool {
+			return true
+		},
+	}
+	s := retrystore.New(&flakyStore{Store: ts, users: users}, policy, policy)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := s.User().Find(ctx, 1)
+	assert.Equal(t, errTransient, err)
+	assert.Equal(t, 1, users.calls)
+}
+
+func TestStore(t *testing.T) {
+	policy := retrystore.Policy{Attempts: 1}
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		return retrystore.New(teststore.New(), policy, policy), func() {}
+	})
+}
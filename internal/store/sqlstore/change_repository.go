@@ -0,0 +1,114 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"winding-tree-server/internal/model"
+)
+
+// ChangeRepository ...
+type ChangeRepository struct {
+	store *Store
+}
+
+// FindByEntity returns an entity's changes, oldest first
+func (r *ChangeRepository) FindByEntity(ctx context.Context, entity string, entityID int) ([]*model.Change, error) {
+	rows, err := r.store.db.QueryContext(
+		ctx,
+		`SELECT id, entity, entity_id, action, before, after, created_at
+		FROM entity_changes WHERE entity = $1 AND entity_id = $2 ORDER BY id`,
+		entity,
+		entityID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*model.Change{}
+	for rows.Next() {
+		c := &model.Change{}
+		var before, after sql.NullString
+		if err := rows.Scan(
+			&c.ID,
+			&c.Entity,
+			&c.EntityID,
+			&c.Action,
+			&before,
+			&after,
+			&c.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		if before.Valid {
+			c.Before = []byte(before.String)
+		}
+		if after.Valid {
+			c.After = []byte(after.String)
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes, rows.Err()
+}
+
+// Purge deletes changes recorded before t
+func (r *ChangeRepository) Purge(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	return r.store.purgeBefore(ctx, "entity_changes", "created_at", t, dryRun)
+}
+
+// insertChange is called by other repositories inside the transaction that
+// makes the change. Pass a nil before for creates.
+func insertChange(ctx context.Context, q queryRower, entity string, entityID int, action string, before, after interface{}) error {
+	c, err := model.NewChange(entity, entityID, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	return q.QueryRowContext(
+		ctx,
+		"INSERT INTO entity_changes (entity, entity_id, action, before, after) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		c.Entity,
+		c.EntityID,
+		c.Action,
+		nullJSON(c.Before),
+		nullJSON(c.After),
+	).Scan(&c.ID)
+}
+
+// insertChanges is the multi-row form of insertChange for bulk writes
+func insertChanges(ctx context.Context, tx *sql.Tx, changes []*model.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(changes))
+	args := make([]interface{}, 0, len(changes)*5)
+	for _, c := range changes {
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5))
+		args = append(args, c.Entity, c.EntityID, c.Action, nullJSON(c.Before), nullJSON(c.After))
+	}
+
+	_, err := tx.ExecContext(
+		ctx,
+		"INSERT INTO entity_changes (entity, entity_id, action, before, after) VALUES "+strings.Join(values, ", "),
+		args...,
+	)
+
+	return err
+}
+
+// nullJSON ...
+func nullJSON(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+
+	return string(b)
+}
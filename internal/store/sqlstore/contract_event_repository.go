@@ -0,0 +1,157 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// ContractEventRepository ...
+type ContractEventRepository struct {
+	store *Store
+}
+
+// Create stores an event once; a log seen again returns store.ErrRecordExists
+func (r *ContractEventRepository) Create(ctx context.Context, e *model.ContractEvent) error {
+	if err := r.store.db.QueryRowContext(
+		ctx,
+		`INSERT INTO contract_events (address, topic, topics, data, block_number, block_hash, tx_hash, log_index)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (tx_hash, log_index) DO NOTHING
+		RETURNING id, created_at`,
+		e.Address,
+		e.Topic,
+		r.store.stringArray(&e.Topics),
+		e.Data,
+		e.BlockNumber,
+		e.BlockHash,
+		e.TxHash,
+		e.LogIndex,
+	).Scan(&e.ID, timestamp{&e.CreatedAt}); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrRecordExists
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// FindUnprocessed returns events not yet handled successfully, oldest first.
+// Failed events are left out, and so are those waiting to be retried.
+func (r *ContractEventRepository) FindUnprocessed(ctx context.Context, limit int) ([]*model.ContractEvent, error) {
+	rows, err := r.store.db.QueryContext(
+		ctx,
+		`SELECT id, address, topic, topics, data, block_number, block_hash, tx_hash, log_index,
+			handled, attempts, last_error, retry_at, failed_at, processed_at, created_at
+		FROM contract_events
+		WHERE processed_at IS NULL AND failed_at IS NULL AND (retry_at IS NULL OR retry_at <= $1)
+		ORDER BY block_number, log_index LIMIT $2`,
+		time.Now().UTC(),
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*model.ContractEvent{}
+	for rows.Next() {
+		e := &model.ContractEvent{}
+		if err := rows.Scan(
+			&e.ID,
+			&e.Address,
+			&e.Topic,
+			r.store.stringArray(&e.Topics),
+			&e.Data,
+			&e.BlockNumber,
+			&e.BlockHash,
+			&e.TxHash,
+			&e.LogIndex,
+			&e.Handled,
+			&e.Attempts,
+			&e.LastError,
+			&e.RetryAt,
+			&e.FailedAt,
+			&e.ProcessedAt,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkProcessed ...
+func (r *ContractEventRepository) MarkProcessed(ctx context.Context, id int) error {
+	_, err := r.store.db.ExecContext(ctx, "UPDATE contract_events SET processed_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+	return err
+}
+
+// MarkFailed records a failed attempt, after which the first handled
+// handlers succeeded. The event is retried at retryAt, or has failed for
+// good without it.
+func (r *ContractEventRepository) MarkFailed(ctx context.Context, id int, handled int, reason string, retryAt *time.Time) error {
+	if retryAt == nil {
+		_, err := r.store.db.ExecContext(
+			ctx,
+			`UPDATE contract_events SET handled = $1, attempts = attempts + 1, last_error = $2, retry_at = NULL, failed_at = $3
+			WHERE id = $4`,
+			handled,
+			reason,
+			time.Now().UTC(),
+			id,
+		)
+
+		return err
+	}
+
+	_, err := r.store.db.ExecContext(
+		ctx,
+		`UPDATE contract_events SET handled = $1, attempts = attempts + 1, last_error = $2, retry_at = $3
+		WHERE id = $4`,
+		handled,
+		reason,
+		retryAt.UTC(),
+		id,
+	)
+
+	return err
+}
+
+// Cursor returns the last block scanned by the named listener, 0 if it never ran
+func (r *ContractEventRepository) Cursor(ctx context.Context, name string) (uint64, error) {
+	var block uint64
+	if err := r.store.db.QueryRowContext(
+		ctx,
+		"SELECT block_number FROM contract_event_cursors WHERE name = $1",
+		name,
+	).Scan(&block); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	return block, nil
+}
+
+// SaveCursor ...
+func (r *ContractEventRepository) SaveCursor(ctx context.Context, name string, block uint64) error {
+	_, err := r.store.db.ExecContext(
+		ctx,
+		`INSERT INTO contract_event_cursors (name, block_number) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET block_number = EXCLUDED.block_number`,
+		name,
+		block,
+	)
+
+	return err
+}
@@ -0,0 +1,46 @@
+package sqlstore_test
+
+import (
+	"context"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContractEventRepository_Create(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("contract_events", "contract_event_cursors")
+
+	s := sqlstore.New(db)
+	e := &model.ContractEvent{
+		Address:     "0x0000000000000000000000000000000000000001",
+		Topic:       "0x47b688936cae1ca5de00ac709e05309381fb9f18b4c5adb358a5b542ce67caea",
+		Topics:      []string{"0x47b688936cae1ca5de00ac709e05309381fb9f18b4c5adb358a5b542ce67caea"},
+		Data:        []byte{1},
+		BlockNumber: 100,
+		BlockHash:   "0x01",
+		TxHash:      "0x02",
+	}
+	assert.NoError(t, s.ContractEvent().Create(context.Background(), e))
+	assert.Equal(t, store.ErrRecordExists, s.ContractEvent().Create(context.Background(), e))
+
+	events, err := s.ContractEvent().FindUnprocessed(context.Background(), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, e.Topics, events[0].Topics)
+	}
+
+	assert.NoError(t, s.ContractEvent().MarkProcessed(context.Background(), e.ID))
+	events, err = s.ContractEvent().FindUnprocessed(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 0)
+
+	assert.NoError(t, s.ContractEvent().SaveCursor(context.Background(), "test", 100))
+	assert.NoError(t, s.ContractEvent().SaveCursor(context.Background(), "test", 101))
+	block, err := s.ContractEvent().Cursor(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(101), block)
+}
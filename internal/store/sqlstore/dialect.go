@@ -0,0 +1,87 @@
+package sqlstore
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+	"winding-tree-server/migrations"
+
+	"github.com/lib/pq"
+)
+
+// Queries are written in the subset of SQL shared by Postgres and SQLite:
+// $N placeholders, RETURNING, ON CONFLICT and CURRENT_TIMESTAMP. SQLite
+// numbers $N parameters in order of first appearance, so placeholders must
+// first appear in ascending order. The few column types SQLite lacks are
+// mapped here.
+
+// sqliteTimeFormats are the layouts of CURRENT_TIMESTAMP and of times
+// written by the SQLite driver
+var sqliteTimeFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05",
+}
+
+// stringArray wraps a to read and write a text[] column, which is stored as
+// a JSON array under SQLite
+func (s *Store) stringArray(a *[]string) interface{} {
+	if dialect(s.db) == migrations.SQLite {
+		return &jsonStringArray{a}
+	}
+
+	return pq.Array(a)
+}
+
+// jsonStringArray ...
+type jsonStringArray struct {
+	a *[]string
+}
+
+// Value ...
+func (j *jsonStringArray) Value() (driver.Value, error) {
+	b, err := json.Marshal(*j.a)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Scan ...
+func (j *jsonStringArray) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(src), j.a)
+	case []byte:
+		return json.Unmarshal(src, j.a)
+	default:
+		return errors.New("sqlstore: cannot scan string array")
+	}
+}
+
+// timestamp scans a time that SQLite returns as text, which it does for
+// RETURNING columns since they carry no declared type
+type timestamp struct {
+	t *time.Time
+}
+
+// Scan ...
+func (ts timestamp) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case time.Time:
+		*ts.t = src
+		return nil
+	case []byte:
+		return ts.Scan(string(src))
+	case string:
+		for _, layout := range sqliteTimeFormats {
+			if t, err := time.Parse(layout, src); err == nil {
+				*ts.t = t
+				return nil
+			}
+		}
+	}
+
+	return errors.New("sqlstore: cannot scan timestamp")
+}
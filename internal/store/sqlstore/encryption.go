@@ -0,0 +1,64 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"winding-tree-server/internal/envelope"
+	"winding-tree-server/internal/model"
+)
+
+var errNoSealer = errors.New("document is encrypted but no encryption keys are configured")
+
+// EncryptDocuments makes the store seal onboarding document contents before
+// writing them. Documents written in plaintext before are still read as is.
+func (s *Store) EncryptDocuments(sealer *envelope.Sealer) {
+	s.sealer = sealer
+}
+
+// documentAAD binds a document's ciphertext to its owner, so it can't be
+// opened after being copied to another user's row
+func documentAAD(userID int) []byte {
+	return []byte(fmt.Sprintf("onboarding_documents:%d", userID))
+}
+
+// sealDocument returns the content to store along with its wrapped key,
+// which are nil when encryption is off
+func (s *Store) sealDocument(ctx context.Context, d *model.OnboardingDocument) ([]byte, *string, []byte, error) {
+	if s.sealer == nil {
+		return d.Content, nil, nil, nil
+	}
+
+	sealed, err := s.sealer.Seal(ctx, d.Content, documentAAD(d.UserID))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return sealed.Ciphertext, &sealed.KeyID, sealed.Key, nil
+}
+
+// openDocument sets the plaintext content of d from a stored row
+func (s *Store) openDocument(ctx context.Context, d *model.OnboardingDocument, content []byte, keyID sql.NullString, key []byte) error {
+	if !keyID.Valid {
+		d.Content = content
+		return nil
+	}
+
+	if s.sealer == nil {
+		return errNoSealer
+	}
+
+	plaintext, err := s.sealer.Open(ctx, &envelope.Sealed{
+		KeyID:      keyID.String,
+		Key:        key,
+		Ciphertext: content,
+	}, documentAAD(d.UserID))
+	if err != nil {
+		return err
+	}
+
+	d.Content = plaintext
+
+	return nil
+}
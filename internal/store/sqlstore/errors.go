@@ -0,0 +1,46 @@
+package sqlstore
+
+import (
+	"database/sql/driver"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// IsTransient reports whether err means the statement or transaction was
+// rolled back and may succeed if repeated: serialization failures, deadlocks
+// and SQLite's busy and locked errors. Writes can be retried safely on these.
+func IsTransient(err error) bool {
+	switch e := err.(type) {
+	case *pq.Error:
+		return e.Code == "40001" || e.Code == "40P01"
+	case sqlite3.Error:
+		return e.Code == sqlite3.ErrBusy || e.Code == sqlite3.ErrLocked
+	}
+
+	return false
+}
+
+// IsDisconnect reports whether err means the connection to the database was
+// lost. The statement may or may not have run, so only reads should be retried.
+func IsDisconnect(err error) bool {
+	if err == driver.ErrBadConn || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+
+	if e, ok := err.(*pq.Error); ok {
+		// Class 08 is connection exceptions, 57P0x are server shutdowns and restarts
+		return strings.HasPrefix(string(e.Code), "08") || strings.HasPrefix(string(e.Code), "57P0")
+	}
+
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// IsRetryableRead ...
+func IsRetryableRead(err error) bool {
+	return IsTransient(err) || IsDisconnect(err)
+}
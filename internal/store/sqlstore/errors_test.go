@@ -0,0 +1,80 @@
+package sqlstore_test
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"winding-tree-server/internal/store/sqlstore"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		transient  bool
+		disconnect bool
+	}{
+		{
+			name:      "serialization failure",
+			err:       &pq.Error{Code: "40001"},
+			transient: true,
+		},
+		{
+			name:      "deadlock",
+			err:       &pq.Error{Code: "40P01"},
+			transient: true,
+		},
+		{
+			name:      "sqlite busy",
+			err:       sqlite3.Error{Code: sqlite3.ErrBusy},
+			transient: true,
+		},
+		{
+			name:       "connection failure",
+			err:        &pq.Error{Code: "08006"},
+			disconnect: true,
+		},
+		{
+			name:       "admin shutdown",
+			err:        &pq.Error{Code: "57P01"},
+			disconnect: true,
+		},
+		{
+			name:       "bad connection",
+			err:        driver.ErrBadConn,
+			disconnect: true,
+		},
+		{
+			name:       "unexpected eof",
+			err:        io.ErrUnexpectedEOF,
+			disconnect: true,
+		},
+		{
+			name:       "connection reset",
+			err:        &net.OpError{Op: "read", Err: errors.New("connection reset by peer")},
+			disconnect: true,
+		},
+		{
+			name: "unique violation",
+			err:  &pq.Error{Code: "23505"},
+		},
+		{
+			name: "sqlite constraint",
+			err:  sqlite3.Error{Code: sqlite3.ErrConstraint},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.transient, sqlstore.IsTransient(tc.err))
+			assert.Equal(t, tc.disconnect, sqlstore.IsDisconnect(tc.err))
+			assert.Equal(t, tc.transient || tc.disconnect, sqlstore.IsRetryableRead(tc.err))
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/migrations"
+)
+
+// exportFormat identifies the files written by Export
+const exportFormat = "winding-tree-export"
+
+// exportTables are written in this order, parents before children, so an
+// export can be loaded back table by table. userColumn selects a user's rows;
+// tables without one only appear in whole-database exports.
+var exportTables = []struct {
+	name       string
+	userColumn string
+}{
+	{"users", "id"},
+	{"supplier_onboardings", "user_id"},
+	{"onboarding_documents", "user_id"},
+	{"org_jsons", "user_id"},
+	{"orgids", "user_id"},
+	{"flights", "user_id"},
+	{"flight_segments", "user_id"},
+	{"fares", "user_id"},
+	{"contract_events", ""},
+	{"contract_event_cursors", ""},
+	{"outbox_events", ""},
+	{"entity_changes", ""},
+}
+
+var (
+	// exportJSONColumns hold JSON documents, which are embedded as is.
+	// org_jsons.document stays a string since its exact bytes are hashed.
+	exportJSONColumns = map[string]bool{
+		"outbox_events.payload": true,
+		"entity_changes.before": true,
+		"entity_changes.after":  true,
+	}
+	// exportBinaryColumns are base64 encoded; other byte values are text
+	exportBinaryColumns = map[string]bool{
+		"onboarding_documents.content":     true,
+		"onboarding_documents.content_key": true,
+		"contract_events.data":             true,
+	}
+	// exportSkippedColumns are derived data
+	exportSkippedColumns = map[string]bool{
+		"users.search": true,
+	}
+	// exportPrivateColumns are left out of per-user exports, which are handed
+	// to the users themselves; users are known there by their public id
+	exportPrivateColumns = map[string]bool{
+		"users.id":                            true,
+		"users.encrypted_password":            true,
+		"supplier_onboardings.user_id":        true,
+		"supplier_onboardings.reviewer_id":    true,
+		"onboarding_documents.user_id":        true,
+		"onboarding_documents.content_key_id": true,
+		"onboarding_documents.content_key":    true,
+		"org_jsons.user_id":                   true,
+		"orgids.user_id":                      true,
+		"flights.user_id":                     true,
+		"flight_segments.user_id":             true,
+		"fares.user_id":                       true,
+	}
+)
+
+// ExportHeader is the first line of an export
+type ExportHeader struct {
+	Format        string    `json:"format"`
+	SchemaVersion uint      `json:"schema_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+	// Users are the public ids of the users exported, or empty for the
+	// whole database
+	Users []string `json:"users,omitempty"`
+}
+
+// ExportRow is every following line
+type ExportRow struct {
+	Table string                 `json:"table"`
+	Row   map[string]interface{} `json:"row"`
+}
+
+// ExportProgress is called after each table with the number of rows written
+type ExportProgress func(table string, rows int)
+
+// Export writes a logical export as JSON lines: a header followed by one line
+// per row. With userIDs it holds only the rows those users own, with
+// onboarding documents decrypted and credentials left out; otherwise it is
+// a backup of every table. All rows are read in one read-only transaction,
+// so the export is consistent even while the server keeps writing.
+func (s *Store) Export(ctx context.Context, w io.Writer, userIDs []int, progress ExportProgress) error {
+	version, _, _, err := MigrationStatus(s.db)
+	if err != nil {
+		return err
+	}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	if dialect(s.db) == migrations.SQLite {
+		// SQLite transactions are always serializable
+		opts = nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	header := &ExportHeader{
+		Format:        exportFormat,
+		SchemaVersion: version,
+		ExportedAt:    time.Now().UTC(),
+	}
+	for _, id := range userIDs {
+		publicID, err := publicUserID(ctx, tx, &id)
+		if err != nil {
+			return err
+		}
+
+		header.Users = append(header.Users, publicID)
+	}
+
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+
+	for _, t := range exportTables {
+		if userIDs != nil && t.userColumn == "" {
+			continue
+		}
+
+		n, err := s.exportTable(ctx, tx, enc, t.name, t.userColumn, userIDs)
+		if err != nil {
+			return fmt.Errorf("exporting %s: %v", t.name, err)
+		}
+
+		if progress != nil {
+			progress(t.name, n)
+		}
+	}
+
+	return nil
+}
+
+// exportTable writes the rows of table, restricted to userIDs unless nil
+func (s *Store) exportTable(ctx context.Context, tx *sql.Tx, enc *json.Encoder, table, userColumn string, userIDs []int) (int, error) {
+	query := "SELECT * FROM " + table
+	args := []interface{}{}
+	if userIDs != nil {
+		if len(userIDs) == 0 {
+			return 0, nil
+		}
+
+		placeholders := make([]string, 0, len(userIDs))
+		for _, id := range userIDs {
+			args = append(args, id)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		query += " WHERE " + userColumn + " IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	rows, err := tx.QueryContext(ctx, query+" ORDER BY 1", args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if err := rows.Scan(pointers...); err != nil {
+			return 0, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, c := range columns {
+			key := table + "." + c
+			if exportSkippedColumns[key] || (userIDs != nil && exportPrivateColumns[key]) {
+				continue
+			}
+
+			row[c] = exportValue(key, values[i])
+		}
+
+		if table == "onboarding_documents" && userIDs != nil {
+			if err := s.openExportedDocument(ctx, columns, values, row); err != nil {
+				return 0, err
+			}
+		}
+
+		if err := enc.Encode(&ExportRow{Table: table, Row: row}); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, rows.Err()
+}
+
+// exportValue converts a scanned value to what it should look like in JSON.
+// Drivers return text, JSON and binary columns alike as []byte.
+func exportValue(column string, v interface{}) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		if str, isString := v.(string); isString && exportJSONColumns[column] && json.Valid([]byte(str)) {
+			return json.RawMessage(str)
+		}
+
+		return v
+	}
+
+	switch {
+	case exportBinaryColumns[column]:
+		return b
+	case exportJSONColumns[column] && json.Valid(b):
+		return json.RawMessage(b)
+	default:
+		return string(b)
+	}
+}
+
+// openExportedDocument replaces the stored content of a document row with
+// its plaintext
+func (s *Store) openExportedDocument(ctx context.Context, columns []string, values []interface{}, row map[string]interface{}) error {
+	d := &model.OnboardingDocument{}
+	var content, key []byte
+	var keyID sql.NullString
+	for i, c := range columns {
+		switch c {
+		case "user_id":
+			if id, ok := values[i].(int64); ok {
+				d.UserID = int(id)
+			}
+		case "content":
+			content, _ = values[i].([]byte)
+		case "content_key":
+			key, _ = values[i].([]byte)
+		case "content_key_id":
+			if err := keyID.Scan(values[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.openDocument(ctx, d, content, keyID, key); err != nil {
+		return err
+	}
+
+	row["content"] = d.Content
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package sqlstore_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"winding-tree-server/internal/envelope"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_Export(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "outbox_events", "onboarding_documents", "users")
+
+	keyring, err := envelope.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	s := sqlstore.New(db)
+	s.EncryptDocuments(envelope.NewSealer(keyring))
+
+	ctx := context.Background()
+	u := model.TestUser(t)
+	assert.NoError(t, s.User().Create(ctx, u))
+	other := model.TestUser(t)
+	other.Email = "other@example.org"
+	assert.NoError(t, s.User().Create(ctx, other))
+	assert.NoError(t, s.Onboarding().CreateDocument(ctx, &model.OnboardingDocument{
+		UserID:      u.ID,
+		Kind:        model.DocumentRegistration,
+		FileName:    "registration.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("%PDF-1.4"),
+	}))
+
+	testCases := []struct {
+		name      string
+		userIDs   []int
+		publicIDs []string
+		users     int
+		changes   int
+		private   bool
+		plaintext bool
+	}{
+		{
+			name:      "user",
+			userIDs:   []int{u.ID},
+			publicIDs: []string{u.PublicID},
+			users:     1,
+			plaintext: true,
+		},
+		{
+			name:    "whole database",
+			users:   2,
+			changes: 2,
+			private: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			progress := map[string]int{}
+			assert.NoError(t, s.Export(ctx, &buf, tc.userIDs, func(table string, rows int) {
+				progress[table] = rows
+			}))
+
+			scanner := bufio.NewScanner(&buf)
+			if !assert.True(t, scanner.Scan()) {
+				return
+			}
+
+			header := &sqlstore.ExportHeader{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), header))
+			assert.Equal(t, "winding-tree-export", header.Format)
+			assert.NotZero(t, header.SchemaVersion)
+			assert.Equal(t, tc.publicIDs, header.Users)
+
+			rows := map[string][]map[string]interface{}{}
+			for scanner.Scan() {
+				row := &sqlstore.ExportRow{}
+				assert.NoError(t, json.Unmarshal(scanner.Bytes(), row))
+				rows[row.Table] = append(rows[row.Table], row.Row)
+			}
+
+			assert.Len(t, rows["users"], tc.users)
+			assert.Equal(t, tc.users, progress["users"])
+			assert.Len(t, rows["entity_changes"], tc.changes)
+			for _, user := range rows["users"] {
+				_, ok := user["encrypted_password"]
+				assert.Equal(t, tc.private, ok)
+				_, ok = user["id"]
+				assert.Equal(t, tc.private, ok, "internal ids are only in backups")
+			}
+
+			if assert.Len(t, rows["onboarding_documents"], 1) {
+				content, _ := base64.StdEncoding.DecodeString(rows["onboarding_documents"][0]["content"].(string))
+				assert.Equal(t, tc.plaintext, string(content) == "%PDF-1.4")
+				_, ok := rows["onboarding_documents"][0]["user_id"]
+				assert.Equal(t, tc.private, ok)
+			}
+		})
+	}
+}
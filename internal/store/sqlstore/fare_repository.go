@@ -0,0 +1,385 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// FareRepository ...
+type FareRepository struct {
+	store *Store
+}
+
+// Create ...
+func (r *FareRepository) Create(ctx context.Context, f *model.Fare) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.store.insertFare(ctx, tx, f); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// fareBatchSize keeps multi-row statements well under the bind parameter
+// limits of both Postgres and SQLite
+const fareBatchSize = 1000
+
+// fareKey identifies a fare for upserts
+type fareKey struct {
+	flightID int
+	cabin    string
+}
+
+// Upsert inserts fares, or updates the amount, currency and seats of the
+// stored fare for the same flight and cabin, using multi-row statements in
+// one transaction. IDs are set on all fares; when the same key appears more
+// than once the last one wins.
+func (r *FareRepository) Upsert(ctx context.Context, fares []*model.Fare) error {
+	for _, f := range fares {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(fares); start += fareBatchSize {
+		end := start + fareBatchSize
+		if end > len(fares) {
+			end = len(fares)
+		}
+
+		if err := r.store.upsertFares(ctx, tx, fares[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// upsertFares writes one batch along with its change history
+func (s *Store) upsertFares(ctx context.Context, tx *sql.Tx, fares []*model.Fare) error {
+	keys := []fareKey{}
+	flightIDs := []int{}
+	latest := map[fareKey]*model.Fare{}
+	for _, f := range fares {
+		k := fareKey{f.FlightID, f.Cabin}
+		if _, ok := latest[k]; !ok {
+			keys = append(keys, k)
+			flightIDs = append(flightIDs, f.FlightID)
+		}
+		latest[k] = f
+	}
+
+	owners, err := s.flightOwners(ctx, tx, flightIDs)
+	if err != nil {
+		return err
+	}
+
+	before, err := s.findFaresByKey(ctx, tx, keys)
+	if err != nil {
+		return err
+	}
+
+	values := make([]string, 0, len(keys))
+	args := make([]interface{}, 0, len(keys)*6)
+	for _, k := range keys {
+		f := latest[k]
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6))
+		args = append(args, f.FlightID, owners[f.FlightID], f.Cabin, f.Amount, f.Currency, f.SeatsAvailable)
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`INSERT INTO fares (flight_id, user_id, cabin, amount, currency, seats_available)
+		VALUES `+strings.Join(values, ", ")+`
+		ON CONFLICT (flight_id, cabin) DO UPDATE SET
+			amount = excluded.amount,
+			currency = excluded.currency,
+			seats_available = excluded.seats_available
+		RETURNING id, flight_id, cabin`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	ids := map[fareKey]int{}
+	for rows.Next() {
+		var id int
+		var k fareKey
+		if err := rows.Scan(&id, &k.flightID, &k.cabin); err != nil {
+			return err
+		}
+
+		ids[k] = id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, f := range fares {
+		f.ID = ids[fareKey{f.FlightID, f.Cabin}]
+	}
+
+	changes := make([]*model.Change, 0, len(keys))
+	for _, k := range keys {
+		var c *model.Change
+		if b, ok := before[k]; ok {
+			c, err = model.NewChange(model.EntityFare, b.ID, model.ChangeUpdate, b, latest[k])
+		} else {
+			c, err = model.NewChange(model.EntityFare, ids[k], model.ChangeCreate, nil, latest[k])
+		}
+		if err != nil {
+			return err
+		}
+
+		changes = append(changes, c)
+	}
+
+	return insertChanges(ctx, tx, changes)
+}
+
+// flightOwners returns the user owning each of the flights, or
+// store.ErrRecordNotFound if one of them does not exist or belongs to
+// another tenant
+func (s *Store) flightOwners(ctx context.Context, tx *sql.Tx, flightIDs []int) (map[int]int, error) {
+	owners := map[int]int{}
+	placeholders := []string{}
+	args := []interface{}{}
+	for _, id := range flightIDs {
+		if _, ok := owners[id]; !ok {
+			owners[id] = 0
+			args = append(args, id)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+	}
+
+	cond, args := s.tenantCondition("user_id", args)
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT id, user_id FROM flights WHERE id IN ("+strings.Join(placeholders, ", ")+")"+cond,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	found := 0
+	for rows.Next() {
+		var id, userID int
+		if err := rows.Scan(&id, &userID); err != nil {
+			return nil, err
+		}
+
+		owners[id] = userID
+		found++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if found != len(owners) {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return owners, nil
+}
+
+// findFaresByKey loads the stored fares among keys
+func (s *Store) findFaresByKey(ctx context.Context, tx *sql.Tx, keys []fareKey) (map[fareKey]*model.Fare, error) {
+	placeholders := []string{}
+	args := []interface{}{}
+	seen := map[int]bool{}
+	for _, k := range keys {
+		if !seen[k.flightID] {
+			seen[k.flightID] = true
+			args = append(args, k.flightID)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+	}
+
+	cond, args := s.tenantCondition("user_id", args)
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT id, flight_id, cabin, amount, currency, seats_available
+		FROM fares WHERE flight_id IN (`+strings.Join(placeholders, ", ")+`)`+cond,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wanted := map[fareKey]bool{}
+	for _, k := range keys {
+		wanted[k] = true
+	}
+
+	fares := map[fareKey]*model.Fare{}
+	for rows.Next() {
+		f := &model.Fare{}
+		if err := rows.Scan(
+			&f.ID,
+			&f.FlightID,
+			&f.Cabin,
+			&f.Amount,
+			&f.Currency,
+			&f.SeatsAvailable,
+		); err != nil {
+			return nil, err
+		}
+
+		if k := (fareKey{f.FlightID, f.Cabin}); wanted[k] {
+			fares[k] = f
+		}
+	}
+
+	return fares, rows.Err()
+}
+
+// Search returns offers of approved, not deleted, suppliers for flights from origin to
+// destination departing on the requested (UTC) date with enough seats, cheapest first
+func (r *FareRepository) Search(ctx context.Context, s *model.FlightSearch) ([]*model.FlightOffer, error) {
+	day, err := time.Parse("2006-01-02", s.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	db := r.store.reader()
+
+	cond, args := r.store.tenantCondition("fl.user_id", []interface{}{
+		s.Origin,
+		s.Destination,
+		day,
+		day.AddDate(0, 0, 1),
+		s.Passengers,
+		s.Cabin,
+	})
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT fl.id, fl.user_id, fa.id, fa.cabin, fa.amount, fa.currency, fa.seats_available
+		FROM flights fl
+		JOIN supplier_onboardings so ON so.user_id = fl.user_id AND so.state = 'approved'
+		JOIN users u ON u.id = fl.user_id AND u.deleted_at IS NULL
+		JOIN fares fa ON fa.flight_id = fl.id
+		JOIN flight_segments dep ON dep.flight_id = fl.id AND dep.position = 0
+		JOIN flight_segments arr ON arr.flight_id = fl.id
+			AND arr.position = (SELECT MAX(position) FROM flight_segments WHERE flight_id = fl.id)
+		WHERE dep.origin = $1
+			AND arr.destination = $2
+			AND dep.departure_at >= $3
+			AND dep.departure_at < $4
+			AND fa.seats_available >= $5
+			AND ($6 = '' OR fa.cabin = $6)`+cond+`
+		ORDER BY fa.amount, dep.departure_at`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offers := []*model.FlightOffer{}
+	flights := make(map[int]*model.Flight)
+	for rows.Next() {
+		fl := &model.Flight{}
+		fa := &model.Fare{}
+		if err := rows.Scan(
+			&fl.ID,
+			&fl.UserID,
+			&fa.ID,
+			&fa.Cabin,
+			&fa.Amount,
+			&fa.Currency,
+			&fa.SeatsAvailable,
+		); err != nil {
+			return nil, err
+		}
+
+		if f, ok := flights[fl.ID]; ok {
+			fl = f
+		} else {
+			flights[fl.ID] = fl
+		}
+		fa.FlightID = fl.ID
+
+		offers = append(offers, &model.FlightOffer{Flight: fl, Fare: fa})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(flights) == 0 {
+		return offers, nil
+	}
+
+	ids := make([]int, 0, len(flights))
+	for id := range flights {
+		ids = append(ids, id)
+	}
+
+	segments, err := r.store.findFlightSegments(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for id, f := range flights {
+		f.Segments = segments[id]
+	}
+
+	return offers, nil
+}
+
+// insertFare also records the fare in its change history. A flight has one
+// fare per cabin; adding another returns store.ErrRecordExists.
+func (s *Store) insertFare(ctx context.Context, tx *sql.Tx, f *model.Fare) error {
+	owners, err := s.flightOwners(ctx, tx, []int{f.FlightID})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO fares (flight_id, user_id, cabin, amount, currency, seats_available)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (flight_id, cabin) DO NOTHING
+		RETURNING id`,
+		f.FlightID,
+		owners[f.FlightID],
+		f.Cabin,
+		f.Amount,
+		f.Currency,
+		f.SeatsAvailable,
+	).Scan(&f.ID); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrRecordExists
+		}
+
+		return err
+	}
+
+	return insertChange(ctx, tx, model.EntityFare, f.ID, model.ChangeCreate, nil, f)
+}
@@ -0,0 +1,106 @@
+package sqlstore_test
+
+import (
+	"context"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFareRepository_Search(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "outbox_events", "fares", "flight_segments", "flights", "supplier_onboardings", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+	f := model.TestFlight(t)
+	f.UserID = u.ID
+	assert.NoError(t, s.Flight().Create(context.Background(), f))
+
+	search := &model.FlightSearch{
+		Origin:      "ZRH",
+		Destination: "KBP",
+		Date:        "2019-12-20",
+		Passengers:  1,
+	}
+
+	offers, err := s.Fare().Search(context.Background(), search)
+	assert.NoError(t, err)
+	assert.Len(t, offers, 0, "unapproved suppliers are hidden")
+
+	o := model.NewOnboarding(u.ID)
+	o.Submit(1)
+	o.Approve(u.ID)
+	s.Onboarding().Save(context.Background(), o)
+
+	offers, err = s.Fare().Search(context.Background(), search)
+	assert.NoError(t, err)
+	if assert.Len(t, offers, 1) {
+		assert.Len(t, offers[0].Flight.Segments, 2)
+	}
+
+	search.Date = "2019-12-21"
+	offers, err = s.Fare().Search(context.Background(), search)
+	assert.NoError(t, err)
+	assert.Len(t, offers, 0)
+
+	flights, total, err := s.Flight().List(context.Background(), &store.ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, flights, 1) {
+		assert.Len(t, flights[0].Segments, 2)
+	}
+}
+
+func TestFareRepository_Upsert(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "outbox_events", "fares", "flight_segments", "flights", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+	f := model.TestFlight(t)
+	f.UserID = u.ID
+	assert.NoError(t, s.Flight().Create(context.Background(), f))
+
+	economy := *f.Fares[0]
+	assert.Equal(t, store.ErrRecordExists, s.Fare().Create(context.Background(), &economy))
+
+	economy.ID = 0
+	economy.SeatsAvailable = 3
+	business := &model.Fare{
+		FlightID:       f.ID,
+		Cabin:          model.CabinBusiness,
+		Amount:         90000,
+		Currency:       "EUR",
+		SeatsAvailable: 2,
+	}
+	assert.NoError(t, s.Fare().Upsert(context.Background(), []*model.Fare{&economy, business}))
+	assert.Equal(t, f.Fares[0].ID, economy.ID)
+	assert.NotZero(t, business.ID)
+
+	found, err := s.Flight().Find(context.Background(), f.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, found.Fares, 2) {
+		for _, fare := range found.Fares {
+			if fare.Cabin == model.CabinEconomy {
+				assert.Equal(t, 3, fare.SeatsAvailable)
+			}
+		}
+	}
+
+	changes, err := s.Change().FindByEntity(context.Background(), model.EntityFare, economy.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, model.ChangeUpdate, changes[1].Action)
+		assert.Contains(t, string(changes[1].Before), `"seats_available":9`)
+		assert.Contains(t, string(changes[1].After), `"seats_available":3`)
+	}
+
+	business.FlightID = f.ID + 1
+	assert.Error(t, s.Fare().Upsert(context.Background(), []*model.Fare{business}))
+}
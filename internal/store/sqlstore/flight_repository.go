@@ -0,0 +1,244 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// FlightRepository ...
+type FlightRepository struct {
+	store *Store
+}
+
+// Create inserts the flight with its segments and fares, and its outbox event, in one transaction
+func (r *FlightRepository) Create(ctx context.Context, f *model.Flight) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.store.checkTenant(f.UserID); err != nil {
+		return err
+	}
+
+	f.BeforeCreate()
+
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(
+		ctx,
+		"INSERT INTO flights (user_id) VALUES ($1) RETURNING id",
+		f.UserID,
+	).Scan(&f.ID); err != nil {
+		return err
+	}
+
+	for _, s := range f.Segments {
+		s.FlightID = f.ID
+		if err := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO flight_segments (flight_id, user_id, position, carrier, number, origin, destination, departure_at, arrival_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+			s.FlightID,
+			f.UserID,
+			s.Position,
+			s.Carrier,
+			s.Number,
+			s.Origin,
+			s.Destination,
+			s.DepartureAt.UTC(),
+			s.ArrivalAt.UTC(),
+		).Scan(&s.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, fare := range f.Fares {
+		fare.FlightID = f.ID
+		if err := r.store.insertFare(ctx, tx, fare); err != nil {
+			return err
+		}
+	}
+
+	supplierID, err := publicUserID(ctx, tx, &f.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := insertOutboxEvent(ctx, tx, model.TopicFlightCreated, &model.FlightPayload{Flight: f, SupplierID: supplierID}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Find returns the flight with its segments and fares
+func (r *FlightRepository) Find(ctx context.Context, id int) (*model.Flight, error) {
+	cond, args := r.store.tenantCondition("user_id", []interface{}{id})
+
+	f := &model.Flight{}
+	if err := r.store.db.QueryRowContext(
+		ctx,
+		"SELECT id, user_id FROM flights WHERE id = $1"+cond,
+		args...,
+	).Scan(
+		&f.ID,
+		&f.UserID,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+
+		return nil, err
+	}
+
+	segments, err := r.store.findFlightSegments(ctx, r.store.db, []int{f.ID})
+	if err != nil {
+		return nil, err
+	}
+	f.Segments = segments[f.ID]
+
+	cond, args = r.store.tenantCondition("user_id", []interface{}{f.ID})
+	rows, err := r.store.db.QueryContext(
+		ctx,
+		"SELECT id, flight_id, cabin, amount, currency, seats_available FROM fares WHERE flight_id = $1"+cond+" ORDER BY amount",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		fare := &model.Fare{}
+		if err := rows.Scan(
+			&fare.ID,
+			&fare.FlightID,
+			&fare.Cabin,
+			&fare.Amount,
+			&fare.Currency,
+			&fare.SeatsAvailable,
+		); err != nil {
+			return nil, err
+		}
+
+		f.Fares = append(f.Fares, fare)
+	}
+
+	return f, rows.Err()
+}
+
+// findFlightSegments returns the ordered segments of each flight keyed by flight id
+func (s *Store) findFlightSegments(ctx context.Context, db *sqlx.DB, flightIDs []int) (map[int][]*model.FlightSegment, error) {
+	segments := make(map[int][]*model.FlightSegment)
+	if len(flightIDs) == 0 {
+		return segments, nil
+	}
+
+	placeholders := make([]string, len(flightIDs))
+	args := make([]interface{}, len(flightIDs))
+	for i, id := range flightIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	cond, args := s.tenantCondition("user_id", args)
+	rows, err := db.QueryContext(
+		ctx,
+		`SELECT id, flight_id, position, carrier, number, origin, destination, departure_at, arrival_at
+		FROM flight_segments WHERE flight_id IN (`+strings.Join(placeholders, ", ")+`)`+cond+` ORDER BY flight_id, position`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		segment := &model.FlightSegment{}
+		if err := rows.Scan(
+			&segment.ID,
+			&segment.FlightID,
+			&segment.Position,
+			&segment.Carrier,
+			&segment.Number,
+			&segment.Origin,
+			&segment.Destination,
+			&segment.DepartureAt,
+			&segment.ArrivalAt,
+		); err != nil {
+			return nil, err
+		}
+
+		segments[segment.FlightID] = append(segments[segment.FlightID], segment)
+	}
+
+	return segments, rows.Err()
+}
+
+// flightListColumns ...
+var flightListColumns = map[string]string{
+	"id":      "id",
+	"user_id": "user_id",
+}
+
+// List returns a page of flights with their segments and the total number matching the filters
+func (r *FlightRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Flight, int, error) {
+	where, args, tail, err := listQuery(opts, flightListColumns, "id")
+	if err != nil {
+		return nil, 0, err
+	}
+	where, args = r.store.scopeList(where, args, "user_id")
+
+	db := r.store.reader()
+
+	var total int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM flights"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, user_id FROM flights"+where+tail, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	flights := []*model.Flight{}
+	ids := []int{}
+	for rows.Next() {
+		f := &model.Flight{}
+		if err := rows.Scan(
+			&f.ID,
+			&f.UserID,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		flights = append(flights, f)
+		ids = append(ids, f.ID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	segments, err := r.store.findFlightSegments(ctx, db, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, f := range flights {
+		f.Segments = segments[f.ID]
+	}
+
+	return flights, total, nil
+}
@@ -0,0 +1,289 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+const jobColumns = "id, kind, payload, state, attempts, max_attempts, last_error, run_at, locked_until, created_at, finished_at"
+
+// jobDue matches jobs to claim as of $N: pending ones whose time has come
+// and running ones whose worker's lease ended, while attempts remain
+const jobDue = "((state = 'pending' AND run_at <= %[1]s) OR (state = 'running' AND locked_until < %[1]s AND attempts < max_attempts))"
+
+// jobExpired matches running jobs as of $N whose lease ended on their last
+// attempt
+const jobExpired = "state = 'running' AND locked_until < %[1]s AND attempts >= max_attempts"
+
+// jobClaimed selects a job while it runs an attempt; each claim counts an
+// attempt, so a worker whose job was claimed again no longer matches
+const jobClaimed = "id = %s AND state = 'running' AND attempts = %s"
+
+// jobListColumns are the fields jobs can be filtered and sorted by
+var jobListColumns = map[string]string{
+	"id":         "id",
+	"kind":       "kind",
+	"state":      "state",
+	"run_at":     "run_at",
+	"created_at": "created_at",
+}
+
+// JobRepository ...
+type JobRepository struct {
+	store *Store
+}
+
+// Create ...
+func (r *JobRepository) Create(ctx context.Context, j *model.Job) error {
+	return r.store.db.QueryRowContext(
+		ctx,
+		"INSERT INTO jobs (kind, payload, state, max_attempts, run_at) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		j.Kind,
+		string(j.Payload),
+		j.State,
+		j.MaxAttempts,
+		j.RunAt.UTC(),
+	).Scan(&j.ID)
+}
+
+// Find ...
+func (r *JobRepository) Find(ctx context.Context, id int) (*model.Job, error) {
+	j, err := scanJob(r.store.db.QueryRowContext(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return j, err
+}
+
+// List ...
+func (r *JobRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Job, int, error) {
+	where, args, tail, err := listQuery(opts, jobListColumns, "id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	db := r.store.reader()
+
+	var total int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM jobs"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+jobColumns+" FROM jobs"+where+tail, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs := []*model.Job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, total, rows.Err()
+}
+
+// Claim picks due jobs, then claims them one by one with an update that
+// checks they are still due, so that workers racing for a job don't both
+// get it. Jobs another worker claimed in between are skipped. Jobs whose
+// lease ended on their last attempt are dead first.
+func (r *JobRepository) Claim(ctx context.Context, kinds []string, n int, lease time.Duration) ([]*model.Job, error) {
+	if len(kinds) == 0 || n < 1 {
+		return []*model.Job{}, nil
+	}
+
+	now := time.Now().UTC()
+	if _, err := r.store.db.ExecContext(
+		ctx,
+		"UPDATE jobs SET state = 'dead', last_error = $1, locked_until = NULL, finished_at = $2 WHERE "+fmt.Sprintf(jobExpired, "$2"),
+		model.JobLeaseExpired,
+		now,
+	); err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{now}
+	placeholders := make([]string, len(kinds))
+	for i, kind := range kinds {
+		args = append(args, kind)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+
+	rows, err := r.store.db.QueryContext(
+		ctx,
+		"SELECT id FROM jobs WHERE "+fmt.Sprintf(jobDue, "$1")+" AND kind IN ("+strings.Join(placeholders, ", ")+
+			fmt.Sprintf(") ORDER BY run_at, id LIMIT %d", n),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	jobs := []*model.Job{}
+	for _, id := range ids {
+		res, err := r.store.db.ExecContext(
+			ctx,
+			"UPDATE jobs SET state = 'running', attempts = attempts + 1, locked_until = $1 WHERE id = $2 AND "+fmt.Sprintf(jobDue, "$3"),
+			now.Add(lease),
+			id,
+			now,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if claimed, err := res.RowsAffected(); err != nil || claimed == 0 {
+			continue
+		}
+
+		j, err := r.Find(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+
+	return jobs, nil
+}
+
+// Complete ...
+func (r *JobRepository) Complete(ctx context.Context, id int, attempt int) error {
+	res, err := r.store.db.ExecContext(
+		ctx,
+		"UPDATE jobs SET state = 'done', locked_until = NULL, finished_at = $1 WHERE "+fmt.Sprintf(jobClaimed, "$2", "$3"),
+		time.Now().UTC(),
+		id,
+		attempt,
+	)
+
+	return leaseResult(res, err)
+}
+
+// Fail ...
+func (r *JobRepository) Fail(ctx context.Context, id int, attempt int, reason string, retryAt *time.Time) error {
+	if retryAt == nil {
+		res, err := r.store.db.ExecContext(
+			ctx,
+			"UPDATE jobs SET state = 'dead', last_error = $1, locked_until = NULL, finished_at = $2 WHERE "+fmt.Sprintf(jobClaimed, "$3", "$4"),
+			reason,
+			time.Now().UTC(),
+			id,
+			attempt,
+		)
+
+		return leaseResult(res, err)
+	}
+
+	res, err := r.store.db.ExecContext(
+		ctx,
+		"UPDATE jobs SET state = 'pending', last_error = $1, locked_until = NULL, run_at = $2 WHERE "+fmt.Sprintf(jobClaimed, "$3", "$4"),
+		reason,
+		retryAt.UTC(),
+		id,
+		attempt,
+	)
+
+	return leaseResult(res, err)
+}
+
+// leaseResult returns ErrLeaseLost when an update conditioned on a lease,
+// such as jobClaimed, changed no row
+func leaseResult(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrLeaseLost
+	}
+
+	return nil
+}
+
+// Reschedule ...
+func (r *JobRepository) Reschedule(ctx context.Context, id int, runAt time.Time) error {
+	res, err := r.store.db.ExecContext(
+		ctx,
+		`UPDATE jobs SET state = 'pending', attempts = 0, run_at = $1, finished_at = NULL
+		WHERE id = $2 AND state IN ('pending', 'dead')`,
+		runAt.UTC(),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		if _, err := r.Find(ctx, id); err != nil {
+			return err
+		}
+
+		return store.ErrConflict
+	}
+
+	return nil
+}
+
+// Purge deletes jobs done before t; dead jobs are kept for admins
+func (r *JobRepository) Purge(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	return r.store.purgeBefore(ctx, "jobs", "finished_at", t, dryRun, "state = 'done'")
+}
+
+// scanJob ...
+func scanJob(row interface{ Scan(...interface{}) error }) (*model.Job, error) {
+	j := &model.Job{}
+	var payload string
+	if err := row.Scan(
+		&j.ID,
+		&j.Kind,
+		&payload,
+		&j.State,
+		&j.Attempts,
+		&j.MaxAttempts,
+		&j.LastError,
+		&j.RunAt,
+		&j.LockedUntil,
+		&j.CreatedAt,
+		&j.FinishedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	j.Payload = []byte(payload)
+
+	return j, nil
+}
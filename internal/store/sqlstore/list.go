@@ -0,0 +1,105 @@
+package sqlstore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"winding-tree-server/internal/store"
+)
+
+// listQuery validates opts against columns, which maps the field names
+// clients may filter and sort by to SQL columns, and returns the WHERE
+// clause with its arguments and the ORDER BY / LIMIT / OFFSET tail.
+// defaultSort is used when opts.Sort is empty.
+func listQuery(opts *store.ListOptions, columns map[string]string, defaultSort string) (string, []interface{}, string, error) {
+	fields := make([]string, 0, len(columns))
+	for f := range columns {
+		fields = append(fields, f)
+	}
+
+	if err := opts.Validate(fields...); err != nil {
+		return "", nil, "", err
+	}
+
+	filters := make([]string, 0, len(opts.Filters))
+	for f := range opts.Filters {
+		filters = append(filters, f)
+	}
+	sort.Strings(filters)
+
+	conditions := make([]string, 0, len(filters))
+	args := make([]interface{}, 0, len(filters))
+	for _, f := range filters {
+		args = append(args, opts.Filters[f])
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", columns[f], len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	sortField, desc := opts.SortField()
+	if sortField == "" {
+		sortField, desc = defaultSort, false
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+
+	tail := fmt.Sprintf(
+		" ORDER BY %s %s, %s LIMIT %d OFFSET %d",
+		columns[sortField],
+		direction,
+		columns[defaultSort],
+		opts.Limit,
+		opts.Offset,
+	)
+
+	return where, args, tail, nil
+}
+
+// boolColumn makes a boolean expression filterable by "true" and "false" on
+// both databases; SQLite stores booleans as integers that never equal those strings
+func boolColumn(expr string) string {
+	return fmt.Sprintf("(CASE WHEN %s THEN 'true' ELSE 'false' END)", expr)
+}
+
+// andWhere adds a condition to a WHERE clause built by listQuery; the %s in
+// condition is replaced by the placeholder of arg
+func andWhere(where string, args []interface{}, condition string, arg interface{}) (string, []interface{}) {
+	args = append(args, arg)
+	condition = fmt.Sprintf(condition, fmt.Sprintf("$%d", len(args)))
+
+	if where == "" {
+		return " WHERE " + condition, args
+	}
+
+	return where + " AND " + condition, args
+}
+
+// searchWords splits a search query into lower-case words of letters and digits
+func searchWords(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// likePattern escapes the wildcards of s for a LIKE pattern with ESCAPE '\'
+func likePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// prefixTSQuery matches documents containing every word as a prefix. Words
+// come from searchWords, so they can't carry tsquery operators.
+func prefixTSQuery(words []string) string {
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = w + ":*"
+	}
+
+	return strings.Join(terms, " & ")
+}
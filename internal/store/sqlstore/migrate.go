@@ -0,0 +1,109 @@
+package sqlstore
+
+import (
+	"winding-tree-server/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	bindata "github.com/golang-migrate/migrate/v4/source/go_bindata"
+	"github.com/jmoiron/sqlx"
+)
+
+// Migration is an embedded schema migration and whether it has been applied
+type Migration struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+// MigrateUp applies all pending migrations
+func MigrateUp(db *sqlx.DB) error {
+	m, err := newMigrate(db)
+	if err != nil {
+		return err
+	}
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+
+	return nil
+}
+
+// MigrateDown rolls back the last n applied migrations
+func MigrateDown(db *sqlx.DB, n int) error {
+	m, err := newMigrate(db)
+	if err != nil {
+		return err
+	}
+
+	return m.Steps(-n)
+}
+
+// MigrationStatus returns the current schema version, whether the last
+// migration failed halfway (dirty), and every embedded migration
+func MigrationStatus(db *sqlx.DB) (uint, bool, []*Migration, error) {
+	m, err := newMigrate(db)
+	if err != nil {
+		return 0, false, nil, err
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return 0, false, nil, err
+	}
+
+	list := []*Migration{}
+	for _, name := range migrations.DialectNames(dialect(db)) {
+		sm, err := source.DefaultParse(name)
+		if err != nil || sm.Direction != source.Up {
+			continue
+		}
+
+		list = append(list, &Migration{
+			Version: sm.Version,
+			Name:    sm.Identifier,
+			Applied: sm.Version <= version,
+		})
+	}
+
+	return version, dirty, list, nil
+}
+
+// newMigrate ...
+func newMigrate(db *sqlx.DB) (*migrate.Migrate, error) {
+	d := dialect(db)
+	src, err := bindata.WithInstance(bindata.Resource(migrations.DialectNames(d), func(name string) ([]byte, error) {
+		return migrations.DialectAsset(d, name)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	if d == migrations.SQLite {
+		driver, err := sqlite3.WithInstance(db.DB, &sqlite3.Config{})
+		if err != nil {
+			return nil, err
+		}
+
+		return migrate.NewWithInstance("go-bindata", src, "sqlite3", driver)
+	}
+
+	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("go-bindata", src, "postgres", driver)
+}
+
+// dialect returns which set of embedded migrations applies to db
+func dialect(db *sqlx.DB) string {
+	if db.DriverName() == "sqlite3" {
+		return migrations.SQLite
+	}
+
+	return migrations.Postgres
+}
@@ -0,0 +1,67 @@
+package sqlstore
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// invalidationChannel is notified by triggers with "<table>:<id>" of the rows
+// updated or deleted, see the add_cache_invalidation_trigger migration
+const invalidationChannel = "cache_invalidation"
+
+// Invalidator drops cached copies of a row. An empty table means
+// notifications may have been missed and everything should be dropped.
+type Invalidator func(ctx context.Context, table string, id int)
+
+// ListenInvalidations calls invalidate for each row change committed by any
+// writer of the Postgres database at url, until ctx is done. Lost connections
+// are re-established in the background.
+func ListenInvalidations(ctx context.Context, url string, invalidate Invalidator) error {
+	listener := pq.NewListener(url, time.Second, time.Minute, nil)
+	defer listener.Close()
+
+	if err := listener.Listen(invalidationChannel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case n := <-listener.Notify:
+			// A nil notification follows a reconnect
+			if n == nil {
+				invalidate(ctx, "", 0)
+				continue
+			}
+
+			if table, id, ok := parseInvalidation(n.Extra); ok {
+				invalidate(ctx, table, id)
+			}
+
+		case <-time.After(90 * time.Second):
+			// Detects dead connections that would otherwise go unnoticed
+			go listener.Ping()
+		}
+	}
+}
+
+// parseInvalidation splits a "<table>:<id>" payload
+func parseInvalidation(payload string) (string, int, bool) {
+	i := strings.LastIndex(payload, ":")
+	if i < 1 {
+		return "", 0, false
+	}
+
+	id, err := strconv.Atoi(payload[i+1:])
+	if err != nil {
+		return "", 0, false
+	}
+
+	return payload[:i], id, true
+}
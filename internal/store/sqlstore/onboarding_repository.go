@@ -0,0 +1,324 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// OnboardingRepository ...
+type OnboardingRepository struct {
+	store *Store
+}
+
+// Find ...
+func (r *OnboardingRepository) Find(ctx context.Context, userID int) (*model.Onboarding, error) {
+	return r.store.findOnboarding(ctx, r.store.db, userID)
+}
+
+// findOnboarding ...
+func (s *Store) findOnboarding(ctx context.Context, q queryRower, userID int) (*model.Onboarding, error) {
+	cond, args := s.tenantCondition("user_id", []interface{}{userID})
+
+	o := &model.Onboarding{}
+	if err := q.QueryRowContext(
+		ctx,
+		`SELECT user_id, state, rejection_reason, reviewer_id, submitted_at, reviewed_at, version
+		FROM supplier_onboardings WHERE user_id = $1`+cond,
+		args...,
+	).Scan(
+		&o.UserID,
+		&o.State,
+		&o.RejectionReason,
+		&o.ReviewerID,
+		&o.SubmittedAt,
+		&o.ReviewedAt,
+		&o.Version,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// onboardingListColumns ...
+var onboardingListColumns = map[string]string{
+	"user_id":      "user_id",
+	"state":        "state",
+	"submitted_at": "submitted_at",
+	"reviewed_at":  "reviewed_at",
+}
+
+// List returns a page of onboardings and the total number matching the filters
+func (r *OnboardingRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Onboarding, int, error) {
+	where, args, tail, err := listQuery(opts, onboardingListColumns, "user_id")
+	if err != nil {
+		return nil, 0, err
+	}
+	where, args = r.store.scopeList(where, args, "user_id")
+
+	db := r.store.reader()
+
+	var total int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM supplier_onboardings"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(
+		ctx,
+		"SELECT user_id, state, rejection_reason, reviewer_id, submitted_at, reviewed_at, version FROM supplier_onboardings"+where+tail,
+		args...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	onboardings := []*model.Onboarding{}
+	for rows.Next() {
+		o := &model.Onboarding{}
+		if err := rows.Scan(
+			&o.UserID,
+			&o.State,
+			&o.RejectionReason,
+			&o.ReviewerID,
+			&o.SubmittedAt,
+			&o.ReviewedAt,
+			&o.Version,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		onboardings = append(onboardings, o)
+	}
+
+	return onboardings, total, rows.Err()
+}
+
+// Save inserts a new onboarding or updates one whose version still matches
+// the stored row, returning store.ErrConflict if it was changed in between.
+// Pending transition events and the change history are written in the same transaction.
+func (r *OnboardingRepository) Save(ctx context.Context, o *model.Onboarding) error {
+	if err := r.store.checkTenant(o.UserID); err != nil {
+		return err
+	}
+
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// before stays an untyped nil for inserts so the change records no snapshot
+	var before interface{}
+	var row *sql.Row
+	if o.Version == 0 {
+		row = tx.QueryRowContext(
+			ctx,
+			`INSERT INTO supplier_onboardings (user_id, state, rejection_reason, reviewer_id, submitted_at, reviewed_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (user_id) DO NOTHING
+			RETURNING version`,
+			o.UserID,
+			o.State,
+			o.RejectionReason,
+			o.ReviewerID,
+			o.SubmittedAt,
+			o.ReviewedAt,
+		)
+	} else {
+		current, err := r.store.findOnboarding(ctx, tx, o.UserID)
+		if err == store.ErrRecordNotFound {
+			return store.ErrConflict
+		} else if err != nil {
+			return err
+		}
+		before = current
+
+		cond, args := r.store.tenantCondition("user_id", []interface{}{
+			o.State,
+			o.RejectionReason,
+			o.ReviewerID,
+			o.SubmittedAt,
+			o.ReviewedAt,
+			o.UserID,
+			o.Version,
+		})
+		row = tx.QueryRowContext(
+			ctx,
+			`UPDATE supplier_onboardings SET
+				state = $1,
+				rejection_reason = $2,
+				reviewer_id = $3,
+				submitted_at = $4,
+				reviewed_at = $5,
+				version = version + 1
+			WHERE user_id = $6 AND version = $7`+cond+`
+			RETURNING version`,
+			args...,
+		)
+	}
+
+	version := o.Version
+	if err := row.Scan(&o.Version); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrConflict
+		}
+
+		return err
+	}
+
+	action := model.ChangeUpdate
+	if before == nil {
+		action = model.ChangeCreate
+	}
+
+	if err := insertChange(ctx, tx, model.EntityOnboarding, o.UserID, action, before, o); err != nil {
+		o.Version = version
+		return err
+	}
+
+	if err := insertOnboardingEvents(ctx, tx, o); err != nil {
+		o.Version = version
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		o.Version = version
+		return err
+	}
+
+	o.ClearEvents()
+
+	return nil
+}
+
+// insertOnboardingEvents writes the outbox events of the transitions made
+// to o since it was last saved
+func insertOnboardingEvents(ctx context.Context, tx *sql.Tx, o *model.Onboarding) error {
+	if len(o.Events()) == 0 {
+		return nil
+	}
+
+	payload := &model.OnboardingPayload{Onboarding: o}
+	var err error
+	if payload.SupplierID, err = publicUserID(ctx, tx, &o.UserID); err != nil {
+		return err
+	}
+	if payload.ReviewerID, err = publicUserID(ctx, tx, o.ReviewerID); err != nil {
+		return err
+	}
+
+	for _, topic := range o.Events() {
+		if err := insertOutboxEvent(ctx, tx, topic, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateDocument ...
+func (r *OnboardingRepository) CreateDocument(ctx context.Context, d *model.OnboardingDocument) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.store.checkTenant(d.UserID); err != nil {
+		return err
+	}
+
+	content, keyID, key, err := r.store.sealDocument(ctx, d)
+	if err != nil {
+		return err
+	}
+
+	return r.store.db.QueryRowContext(
+		ctx,
+		`INSERT INTO onboarding_documents (user_id, kind, file_name, content_type, content, content_key_id, content_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		d.UserID,
+		d.Kind,
+		d.FileName,
+		d.ContentType,
+		content,
+		keyID,
+		key,
+	).Scan(&d.ID, timestamp{&d.CreatedAt})
+}
+
+// FindDocuments lists a user's documents without their content
+func (r *OnboardingRepository) FindDocuments(ctx context.Context, userID int) ([]*model.OnboardingDocument, error) {
+	cond, args := r.store.tenantCondition("user_id", []interface{}{userID})
+	rows, err := r.store.db.QueryContext(
+		ctx,
+		`SELECT id, user_id, kind, file_name, content_type, created_at
+		FROM onboarding_documents WHERE user_id = $1`+cond+` ORDER BY id`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []*model.OnboardingDocument{}
+	for rows.Next() {
+		d := &model.OnboardingDocument{}
+		if err := rows.Scan(
+			&d.ID,
+			&d.UserID,
+			&d.Kind,
+			&d.FileName,
+			&d.ContentType,
+			&d.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, d)
+	}
+
+	return documents, rows.Err()
+}
+
+// FindDocument returns a single document including its decrypted content
+func (r *OnboardingRepository) FindDocument(ctx context.Context, userID int, id int) (*model.OnboardingDocument, error) {
+	cond, args := r.store.tenantCondition("user_id", []interface{}{userID, id})
+
+	d := &model.OnboardingDocument{}
+	var content, key []byte
+	var keyID sql.NullString
+	if err := r.store.db.QueryRowContext(
+		ctx,
+		`SELECT id, user_id, kind, file_name, content_type, content, content_key_id, content_key, created_at
+		FROM onboarding_documents WHERE user_id = $1 AND id = $2`+cond,
+		args...,
+	).Scan(
+		&d.ID,
+		&d.UserID,
+		&d.Kind,
+		&d.FileName,
+		&d.ContentType,
+		&content,
+		&keyID,
+		&key,
+		&d.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+
+		return nil, err
+	}
+
+	if err := r.store.openDocument(ctx, d, content, keyID, key); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
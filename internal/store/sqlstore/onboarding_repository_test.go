@@ -0,0 +1,153 @@
+package sqlstore_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"winding-tree-server/internal/envelope"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnboardingRepository_Save(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "outbox_events", "supplier_onboardings", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+
+	o := model.NewOnboarding(u.ID)
+	assert.NoError(t, s.Onboarding().Save(context.Background(), o))
+	assert.Equal(t, 1, o.Version)
+	assert.Equal(t, store.ErrConflict, s.Onboarding().Save(context.Background(), model.NewOnboarding(u.ID)))
+
+	first, err := s.Onboarding().Find(context.Background(), u.ID)
+	assert.NoError(t, err)
+	second, _ := s.Onboarding().Find(context.Background(), u.ID)
+
+	assert.NoError(t, first.Submit(1))
+	assert.NoError(t, s.Onboarding().Save(context.Background(), first))
+	assert.Equal(t, 2, first.Version)
+
+	assert.NoError(t, second.Submit(1))
+	assert.Equal(t, store.ErrConflict, s.Onboarding().Save(context.Background(), second))
+
+	onboardings, total, err := s.Onboarding().List(context.Background(), &store.ListOptions{
+		Limit:   10,
+		Sort:    "submitted_at",
+		Filters: map[string]string{"state": model.OnboardingSubmitted},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, onboardings, 1) {
+		assert.NotNil(t, onboardings[0].SubmittedAt)
+	}
+}
+
+func TestOnboardingRepository_CreateDocument(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "onboarding_documents", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+
+	d := &model.OnboardingDocument{
+		UserID:      u.ID,
+		Kind:        model.DocumentRegistration,
+		FileName:    "registration.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("%PDF-1.4"),
+	}
+	assert.NoError(t, s.Onboarding().CreateDocument(context.Background(), d))
+	assert.NotZero(t, d.ID)
+	assert.False(t, d.CreatedAt.IsZero())
+
+	documents, err := s.Onboarding().FindDocuments(context.Background(), u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, documents, 1) {
+		assert.Nil(t, documents[0].Content)
+	}
+
+	found, err := s.Onboarding().FindDocument(context.Background(), u.ID, d.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, d.Content, found.Content)
+}
+
+func TestOnboardingRepository_EncryptedDocument(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "onboarding_documents", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+
+	plain := &model.OnboardingDocument{
+		UserID:      u.ID,
+		Kind:        model.DocumentRegistration,
+		FileName:    "registration.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("%PDF-1.4 plaintext"),
+	}
+	assert.NoError(t, s.Onboarding().CreateDocument(context.Background(), plain))
+
+	keyring, err := envelope.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.EncryptDocuments(envelope.NewSealer(keyring))
+
+	d := *plain
+	d.Content = []byte("%PDF-1.4 sealed")
+	assert.NoError(t, s.Onboarding().CreateDocument(context.Background(), &d))
+
+	var stored []byte
+	assert.NoError(t, db.QueryRow("SELECT content FROM onboarding_documents WHERE id = $1", d.ID).Scan(&stored))
+	assert.NotContains(t, string(stored), "sealed")
+
+	for _, want := range []*model.OnboardingDocument{plain, &d} {
+		found, err := s.Onboarding().FindDocument(context.Background(), u.ID, want.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, want.Content, found.Content)
+		}
+	}
+
+	_, err = sqlstore.New(db).Onboarding().FindDocument(context.Background(), u.ID, d.ID)
+	assert.Error(t, err, "encrypted documents can't be read without keys")
+}
+
+func TestOnboardingRepository_SaveWritesOutbox(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "outbox_events", "supplier_onboardings", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+
+	o := model.NewOnboarding(u.ID)
+	assert.NoError(t, s.Onboarding().Save(context.Background(), o))
+	assert.NoError(t, o.Submit(1))
+	assert.NoError(t, s.Onboarding().Save(context.Background(), o))
+	assert.Len(t, o.Events(), 0)
+
+	stale := model.NewOnboarding(u.ID)
+	stale.Submit(1)
+	assert.Equal(t, store.ErrConflict, s.Onboarding().Save(context.Background(), stale))
+
+	events, err := s.Outbox().FindUnpublished(context.Background(), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "onboarding.submitted", events[0].Topic)
+		assert.Contains(t, string(events[0].Payload), `"state":"submitted"`)
+
+		assert.NoError(t, s.Outbox().MarkPublished(context.Background(), events[0].ID))
+	}
+
+	events, err = s.Outbox().FindUnpublished(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 0)
+}
@@ -0,0 +1,82 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// OrgJSONRepository ...
+type OrgJSONRepository struct {
+	store *Store
+}
+
+// Create stores the document as the next version for its user
+func (r *OrgJSONRepository) Create(ctx context.Context, o *model.OrgJSON) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.store.checkTenant(o.UserID); err != nil {
+		return err
+	}
+
+	if err := o.BeforeCreate(); err != nil {
+		return err
+	}
+
+	return r.store.db.QueryRowContext(
+		ctx,
+		`INSERT INTO org_jsons (user_id, version, document, hash)
+		VALUES ($1, COALESCE((SELECT MAX(version) FROM org_jsons WHERE user_id = $1), 0) + 1, $2, $3)
+		RETURNING id, version, created_at`,
+		o.UserID,
+		string(o.Document),
+		o.Hash,
+	).Scan(&o.ID, &o.Version, timestamp{&o.CreatedAt})
+}
+
+// FindLatest ...
+func (r *OrgJSONRepository) FindLatest(ctx context.Context, userID int) (*model.OrgJSON, error) {
+	cond, args := r.store.tenantCondition("user_id", []interface{}{userID})
+	return r.find(
+		ctx,
+		"SELECT id, user_id, version, document, hash, created_at FROM org_jsons WHERE user_id = $1"+cond+" ORDER BY version DESC LIMIT 1",
+		args...,
+	)
+}
+
+// FindByVersion ...
+func (r *OrgJSONRepository) FindByVersion(ctx context.Context, userID int, version int) (*model.OrgJSON, error) {
+	cond, args := r.store.tenantCondition("user_id", []interface{}{userID, version})
+	return r.find(
+		ctx,
+		"SELECT id, user_id, version, document, hash, created_at FROM org_jsons WHERE user_id = $1 AND version = $2"+cond,
+		args...,
+	)
+}
+
+// find ...
+func (r *OrgJSONRepository) find(ctx context.Context, query string, args ...interface{}) (*model.OrgJSON, error) {
+	o := &model.OrgJSON{}
+	var document string
+	if err := r.store.db.QueryRowContext(ctx, query, args...).Scan(
+		&o.ID,
+		&o.UserID,
+		&o.Version,
+		&document,
+		&o.Hash,
+		&o.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+
+		return nil, err
+	}
+
+	o.Document = []byte(document)
+
+	return o, nil
+}
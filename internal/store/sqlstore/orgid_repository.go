@@ -0,0 +1,144 @@
+package sqlstore
+
+import (
+	"context"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// OrgIDRepository ...
+type OrgIDRepository struct {
+	store *Store
+}
+
+// Save inserts or refreshes a mirrored organization, linked to o.UserID, the
+// supplier its ORG.JSON URI points at, as long as that supplier uploaded an
+// ORG.JSON matching the on-chain hash. Anyone may upload a copy of a public
+// document, so uploads alone never link an organization. The link is derived
+// from chain data rather than written on behalf of a tenant, so a scoped store
+// may only refresh organizations that end up linked to its tenant.
+func (r *OrgIDRepository) Save(ctx context.Context, o *model.OrgID) error {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(
+		ctx,
+		`INSERT INTO orgids (id, directory, orgjson_uri, orgjson_hash, owner, is_active, lif_deposit, user_id, synced_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, (SELECT user_id FROM org_jsons WHERE user_id = $8 AND hash = $4 LIMIT 1), CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE SET
+			directory = EXCLUDED.directory,
+			orgjson_uri = EXCLUDED.orgjson_uri,
+			orgjson_hash = EXCLUDED.orgjson_hash,
+			owner = EXCLUDED.owner,
+			is_active = EXCLUDED.is_active,
+			lif_deposit = EXCLUDED.lif_deposit,
+			user_id = EXCLUDED.user_id,
+			synced_at = EXCLUDED.synced_at
+		RETURNING user_id, synced_at`,
+		o.ID,
+		o.Directory,
+		o.OrgJSONURI,
+		o.OrgJSONHash,
+		o.Owner,
+		o.IsActive,
+		o.LifDeposit,
+		o.UserID,
+	).Scan(&o.UserID, timestamp{&o.SyncedAt}); err != nil {
+		return err
+	}
+
+	if r.store.tenantID != 0 && (o.UserID == nil || *o.UserID != r.store.tenantID) {
+		return store.ErrTenantMismatch
+	}
+
+	return tx.Commit()
+}
+
+// FindByUser ...
+func (r *OrgIDRepository) FindByUser(ctx context.Context, userID int) ([]*model.OrgID, error) {
+	cond, args := r.store.tenantCondition("user_id", []interface{}{userID})
+	rows, err := r.store.reader().QueryContext(
+		ctx,
+		"SELECT id, directory, orgjson_uri, orgjson_hash, owner, is_active, lif_deposit, user_id, synced_at FROM orgids WHERE user_id = $1"+cond+" ORDER BY id",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []*model.OrgID{}
+	for rows.Next() {
+		o := &model.OrgID{}
+		if err := rows.Scan(
+			&o.ID,
+			&o.Directory,
+			&o.OrgJSONURI,
+			&o.OrgJSONHash,
+			&o.Owner,
+			&o.IsActive,
+			&o.LifDeposit,
+			&o.UserID,
+			&o.SyncedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		orgs = append(orgs, o)
+	}
+
+	return orgs, rows.Err()
+}
+
+// Prune unlinks the suppliers of the deleted organizations. A scoped store
+// only deletes organizations linked to its tenant.
+func (r *OrgIDRepository) Prune(ctx context.Context, directory string, listed []string) (int, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	cond, args := r.store.tenantCondition("user_id", []interface{}{directory})
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM orgids WHERE directory = $1"+cond, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	keep := make(map[string]bool, len(listed))
+	for _, id := range listed {
+		keep[id] = true
+	}
+
+	n := 0
+	for _, id := range ids {
+		if keep[id] {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM orgids WHERE id = $1", id); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, tx.Commit()
+}
@@ -0,0 +1,93 @@
+package sqlstore_test
+
+import (
+	"context"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgIDRepository_Save(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "orgids", "org_jsons", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+	oj := model.TestOrgJSON(t)
+	oj.UserID = u.ID
+	assert.NoError(t, s.OrgJSON().Create(context.Background(), oj))
+	assert.Equal(t, 1, oj.Version)
+
+	o := model.TestOrgID(t)
+	o.OrgJSONHash = oj.Hash
+	o.UserID = &u.ID
+	assert.NoError(t, s.OrgID().Save(context.Background(), o))
+	if assert.NotNil(t, o.UserID) {
+		assert.Equal(t, u.ID, *o.UserID)
+	}
+
+	o.IsActive = false
+	assert.NoError(t, s.OrgID().Save(context.Background(), o))
+
+	orgs, err := s.OrgID().FindByUser(context.Background(), u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, orgs, 1) {
+		assert.False(t, orgs[0].IsActive)
+		assert.Equal(t, o.LifDeposit, orgs[0].LifDeposit)
+	}
+}
+
+func TestOrgIDRepository_SaveCopiedOrgJSON(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "orgids", "org_jsons", "users")
+
+	ctx := context.Background()
+	s := sqlstore.New(db)
+	owner := model.TestUser(t)
+	s.User().Create(ctx, owner)
+	copycat := model.TestUser(t)
+	copycat.Email = "copycat@example.test"
+	s.User().Create(ctx, copycat)
+
+	// The owner uploads the document, then someone else uploads a copy
+	for _, userID := range []int{owner.ID, copycat.ID} {
+		oj := model.TestOrgJSON(t)
+		oj.UserID = userID
+		assert.NoError(t, s.OrgJSON().Create(ctx, oj))
+	}
+	latest, _ := s.OrgJSON().FindLatest(ctx, copycat.ID)
+
+	testCases := []struct {
+		name   string
+		userID *int
+		linked *int
+	}{
+		{
+			name:   "uri of the owner",
+			userID: &owner.ID,
+			linked: &owner.ID,
+		},
+		{
+			name:   "uri of no supplier",
+			userID: nil,
+			linked: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := model.TestOrgID(t)
+			o.OrgJSONHash = latest.Hash
+			o.UserID = tc.userID
+			assert.NoError(t, s.OrgID().Save(ctx, o))
+			assert.Equal(t, tc.linked, o.UserID)
+
+			orgs, err := s.OrgID().FindByUser(ctx, copycat.ID)
+			assert.NoError(t, err)
+			assert.Len(t, orgs, 0, "the most recent uploader isn't linked")
+		})
+	}
+}
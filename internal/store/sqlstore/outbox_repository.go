@@ -0,0 +1,121 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/model"
+)
+
+// OutboxRepository ...
+type OutboxRepository struct {
+	store *Store
+}
+
+// FindUnpublished returns events not yet relayed, oldest first. Failed events
+// are left out, but not those waiting to be retried, which hold back the
+// events after them.
+func (r *OutboxRepository) FindUnpublished(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	return r.find(ctx, "published_at IS NULL AND failed_at IS NULL ORDER BY id LIMIT $1", limit)
+}
+
+// FindAfter reads from the primary, as replicas may not have the events yet
+func (r *OutboxRepository) FindAfter(ctx context.Context, id int, limit int) ([]*model.OutboxEvent, error) {
+	return r.find(ctx, "id > $1 ORDER BY id LIMIT $2", id, limit)
+}
+
+// LastID ...
+func (r *OutboxRepository) LastID(ctx context.Context) (int, error) {
+	var id int
+	err := r.store.db.QueryRowContext(ctx, "SELECT COALESCE(max(id), 0) FROM outbox_events").Scan(&id)
+
+	return id, err
+}
+
+// find returns the events matching where, which is never user input
+func (r *OutboxRepository) find(ctx context.Context, where string, args ...interface{}) ([]*model.OutboxEvent, error) {
+	rows, err := r.store.db.QueryContext(
+		ctx,
+		"SELECT id, topic, payload, attempts, last_error, retry_at, failed_at, created_at FROM outbox_events WHERE "+where,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*model.OutboxEvent{}
+	for rows.Next() {
+		e := &model.OutboxEvent{}
+		var payload string
+		if err := rows.Scan(
+			&e.ID,
+			&e.Topic,
+			&payload,
+			&e.Attempts,
+			&e.LastError,
+			&e.RetryAt,
+			&e.FailedAt,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		e.Payload = []byte(payload)
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// MarkPublished ...
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int) error {
+	_, err := r.store.db.ExecContext(ctx, "UPDATE outbox_events SET published_at = CURRENT_TIMESTAMP WHERE id = $1", id)
+	return err
+}
+
+// MarkFailed records a failed delivery attempt. The event is retried at
+// retryAt, or has failed for good without it; it stays unpublished either way.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int, reason string, retryAt *time.Time) error {
+	if retryAt == nil {
+		_, err := r.store.db.ExecContext(
+			ctx,
+			"UPDATE outbox_events SET attempts = attempts + 1, last_error = $1, retry_at = NULL, failed_at = $2 WHERE id = $3",
+			reason,
+			time.Now().UTC(),
+			id,
+		)
+
+		return err
+	}
+
+	_, err := r.store.db.ExecContext(
+		ctx,
+		"UPDATE outbox_events SET attempts = attempts + 1, last_error = $1, retry_at = $2 WHERE id = $3",
+		reason,
+		retryAt.UTC(),
+		id,
+	)
+
+	return err
+}
+
+// Purge deletes events published before t; unpublished events are kept
+func (r *OutboxRepository) Purge(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	return r.store.purgeBefore(ctx, "outbox_events", "published_at", t, dryRun)
+}
+
+// insertOutboxEvent is called by other repositories inside the transaction
+// that makes the change the event describes
+func insertOutboxEvent(ctx context.Context, q queryRower, topic string, payload interface{}) error {
+	e, err := model.NewOutboxEvent(topic, payload)
+	if err != nil {
+		return err
+	}
+
+	return q.QueryRowContext(
+		ctx,
+		"INSERT INTO outbox_events (topic, payload) VALUES ($1, $2) RETURNING id",
+		e.Topic,
+		string(e.Payload),
+	).Scan(&e.ID)
+}
@@ -0,0 +1,120 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"winding-tree-server/migrations"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// minPostgresVersion is 9.5, the first with ON CONFLICT
+	minPostgresVersion = 90500
+	// minSQLiteVersion is 3.35.0, the first with RETURNING
+	minSQLiteVersion = "3.35.0"
+)
+
+// requiredExtensions are Postgres extensions the migrations depend on
+var requiredExtensions = []string{
+	// users_search_update() is written in PL/pgSQL
+	"plpgsql",
+}
+
+// CheckRequirements verifies the database server is recent enough and has
+// the extensions the schema needs, so a misconfigured database fails at
+// startup rather than on the first request that hits the missing feature
+func CheckRequirements(ctx context.Context, db *sqlx.DB) error {
+	if dialect(db) == migrations.SQLite {
+		var version string
+		if err := db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&version); err != nil {
+			return err
+		}
+
+		if compareVersions(version, minSQLiteVersion) < 0 {
+			return fmt.Errorf("sqlite %s is too old, %s or newer is required; rebuild against a newer go-sqlite3", version, minSQLiteVersion)
+		}
+
+		return nil
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::integer").Scan(&version); err != nil {
+		return err
+	}
+
+	if version < minPostgresVersion {
+		return fmt.Errorf("postgres server version %d is too old, 9.5 or newer is required", version)
+	}
+
+	for _, name := range requiredExtensions {
+		var installed bool
+		if err := db.QueryRowContext(
+			ctx,
+			"SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = $1)",
+			name,
+		).Scan(&installed); err != nil {
+			return err
+		}
+
+		if !installed {
+			return fmt.Errorf("postgres extension %s is missing; run CREATE EXTENSION %s as a superuser", name, name)
+		}
+	}
+
+	return nil
+}
+
+// CheckSchema verifies every embedded migration has been applied and the
+// last one didn't fail halfway. A schema newer than this build is accepted
+// so that a rollback of the server doesn't require one of the database.
+func CheckSchema(db *sqlx.DB) error {
+	version, dirty, list, err := MigrationStatus(db)
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("database schema version %d is dirty: a migration failed halfway; fix the schema by hand, then force the version with the migrate tool", version)
+	}
+
+	if len(list) > 0 && version < list[len(list)-1].Version {
+		return fmt.Errorf(
+			"database schema is at version %d but this build needs %d; run \"migrate up\" or set auto_migrate",
+			version,
+			list[len(list)-1].Version,
+		)
+	}
+
+	return nil
+}
+
+// Ping checks the primary database is reachable
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// compareVersions compares dotted version numbers such as "3.35.4"
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
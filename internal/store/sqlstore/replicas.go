@@ -0,0 +1,67 @@
+package sqlstore
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// replicaPingTimeout bounds each health check so a hung replica can't stall the others
+const replicaPingTimeout = 2 * time.Second
+
+// replica is a read-only database and whether its last health check passed
+type replica struct {
+	db      *sqlx.DB
+	healthy int32
+}
+
+// reader returns a healthy replica in round-robin order, or the primary if
+// there are none. Only reads that tolerate replication lag should use it;
+// read-modify-write paths and lookups right after a write stay on s.db.
+func (s *Store) reader() *sqlx.DB {
+	n := len(s.replicas)
+	for i := 0; i < n; i++ {
+		r := s.replicas[int(atomic.AddUint32(&s.nextReplica, 1))%n]
+		if atomic.LoadInt32(&r.healthy) == 1 {
+			return r.db
+		}
+	}
+
+	return s.db
+}
+
+// CheckReplicas pings every replica and takes failing ones out of rotation
+func (s *Store) CheckReplicas(ctx context.Context) {
+	for _, r := range s.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+		var healthy int32
+		if err := r.db.PingContext(pingCtx); err == nil {
+			healthy = 1
+		}
+		cancel()
+
+		atomic.StoreInt32(&r.healthy, healthy)
+	}
+}
+
+// MonitorReplicas runs CheckReplicas every interval until ctx is cancelled
+func (s *Store) MonitorReplicas(ctx context.Context, interval time.Duration) {
+	if len(s.replicas) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.CheckReplicas(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package sqlstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore_Reader(t *testing.T) {
+	// Nothing listens on port 1, so pings fail without a database
+	open := func() *sqlx.DB {
+		db, err := sqlx.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+		assert.NoError(t, err)
+		return db
+	}
+
+	primary, first, second := open(), open(), open()
+	defer primary.Close()
+	defer first.Close()
+	defer second.Close()
+
+	assert.Equal(t, primary, New(primary).reader())
+
+	s := New(primary, first, second)
+	readers := map[*sqlx.DB]bool{
+		s.reader(): true,
+		s.reader(): true,
+	}
+	assert.Equal(t, map[*sqlx.DB]bool{first: true, second: true}, readers)
+
+	s.CheckReplicas(context.Background())
+	assert.Equal(t, primary, s.reader())
+}
@@ -0,0 +1,29 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+)
+
+// purgeBefore deletes the rows of table whose column is before t and that
+// match conditions, or with dryRun counts them. table, column and
+// conditions are never user input.
+func (s *Store) purgeBefore(ctx context.Context, table, column string, t time.Time, dryRun bool, conditions ...string) (int, error) {
+	where := " WHERE " + column + " < $1"
+	for _, condition := range conditions {
+		where += " AND " + condition
+	}
+	if dryRun {
+		var n int
+		err := s.db.QueryRowContext(ctx, "SELECT count(*) FROM "+table+where, t.UTC()).Scan(&n)
+		return n, err
+	}
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM "+table+where, t.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,87 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/model"
+)
+
+// ScheduledTaskRepository ...
+type ScheduledTaskRepository struct {
+	store *Store
+}
+
+// Claim updates the task's row only if the run is newer than its last one
+// and no lease is held, so that of instances racing for a run one gets it
+func (r *ScheduledTaskRepository) Claim(ctx context.Context, task string, runAt time.Time, lease time.Duration) (bool, error) {
+	if _, err := r.store.db.ExecContext(
+		ctx,
+		"INSERT INTO scheduled_tasks (name) VALUES ($1) ON CONFLICT (name) DO NOTHING",
+		task,
+	); err != nil {
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	res, err := r.store.db.ExecContext(
+		ctx,
+		`UPDATE scheduled_tasks SET last_run_at = $1, locked_until = $2
+		WHERE name = $3 AND (last_run_at IS NULL OR last_run_at < $1) AND (locked_until IS NULL OR locked_until < $4)`,
+		taskRun(runAt),
+		now.Add(lease),
+		task,
+		now,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	claimed, err := res.RowsAffected()
+
+	return claimed == 1, err
+}
+
+// Release only updates the row while it still holds the run, so that a run
+// outliving its lease doesn't end the one claimed after it
+func (r *ScheduledTaskRepository) Release(ctx context.Context, task string, runAt time.Time, runErr string) error {
+	res, err := r.store.db.ExecContext(
+		ctx,
+		"UPDATE scheduled_tasks SET locked_until = NULL, finished_at = $1, last_error = $2 WHERE name = $3 AND last_run_at = $4",
+		time.Now().UTC(),
+		runErr,
+		task,
+		taskRun(runAt),
+	)
+
+	return leaseResult(res, err)
+}
+
+// taskRun is the time of a run as stored, which Postgres keeps to the
+// microsecond, so that Release finds the run Claim stored
+func taskRun(runAt time.Time) time.Time {
+	return runAt.UTC().Truncate(time.Microsecond)
+}
+
+// List ...
+func (r *ScheduledTaskRepository) List(ctx context.Context) ([]*model.ScheduledTask, error) {
+	rows, err := r.store.reader().QueryContext(
+		ctx,
+		"SELECT name, last_run_at, locked_until, finished_at, last_error FROM scheduled_tasks ORDER BY name",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []*model.ScheduledTask{}
+	for rows.Next() {
+		t := &model.ScheduledTask{}
+		if err := rows.Scan(&t.Name, &t.LastRunAt, &t.LockedUntil, &t.FinishedAt, &t.LastError); err != nil {
+			return nil, err
+		}
+
+		tasks = append(tasks, t)
+	}
+
+	return tasks, rows.Err()
+}
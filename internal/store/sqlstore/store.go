@@ -1,6 +1,9 @@
 package sqlstore
 
 import (
+	"context"
+	"database/sql"
+	"winding-tree-server/internal/envelope"
 	"winding-tree-server/internal/store"
 
 	"github.com/jmoiron/sqlx"
@@ -8,15 +11,46 @@ import (
 
 // Store ..
 type Store struct {
-	db             *sqlx.DB
-	userRepository *UserRepository
+	db                      *sqlx.DB
+	replicas                []*replica
+	nextReplica             uint32
+	userRepository          *UserRepository
+	orgJSONRepository       *OrgJSONRepository
+	orgIDRepository         *OrgIDRepository
+	contractEventRepository *ContractEventRepository
+	flightRepository        *FlightRepository
+	fareRepository          *FareRepository
+	onboardingRepository    *OnboardingRepository
+	outboxRepository        *OutboxRepository
+	changeRepository        *ChangeRepository
+	jobRepository           *JobRepository
+	scheduledTaskRepository *ScheduledTaskRepository
+	// tenantID scopes supplier-owned queries to one user; 0 for unscoped stores
+	tenantID int
+	// sealer encrypts onboarding document contents; nil stores them in plaintext
+	sealer *envelope.Sealer
 }
 
-// New ...
-func New(db *sqlx.DB) *Store {
-	return &Store{
+// queryRower is satisfied by both *sqlx.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// New returns a store writing to db. Reads that can be served from
+// replicas are spread across them, falling back to db.
+func New(db *sqlx.DB, replicas ...*sqlx.DB) *Store {
+	s := &Store{
 		db: db,
 	}
+
+	for _, r := range replicas {
+		s.replicas = append(s.replicas, &replica{
+			db:      r,
+			healthy: 1,
+		})
+	}
+
+	return s
 }
 
 // User ...
@@ -31,3 +65,133 @@ func (s *Store) User() store.UserRepository {
 
 	return s.userRepository
 }
+
+// OrgJSON ...
+func (s *Store) OrgJSON() store.OrgJSONRepository {
+	if s.orgJSONRepository != nil {
+		return s.orgJSONRepository
+	}
+
+	s.orgJSONRepository = &OrgJSONRepository{
+		store: s,
+	}
+
+	return s.orgJSONRepository
+}
+
+// OrgID ...
+func (s *Store) OrgID() store.OrgIDRepository {
+	if s.orgIDRepository != nil {
+		return s.orgIDRepository
+	}
+
+	s.orgIDRepository = &OrgIDRepository{
+		store: s,
+	}
+
+	return s.orgIDRepository
+}
+
+// ContractEvent ...
+func (s *Store) ContractEvent() store.ContractEventRepository {
+	if s.contractEventRepository != nil {
+		return s.contractEventRepository
+	}
+
+	s.contractEventRepository = &ContractEventRepository{
+		store: s,
+	}
+
+	return s.contractEventRepository
+}
+
+// Flight ...
+func (s *Store) Flight() store.FlightRepository {
+	if s.flightRepository != nil {
+		return s.flightRepository
+	}
+
+	s.flightRepository = &FlightRepository{
+		store: s,
+	}
+
+	return s.flightRepository
+}
+
+// Fare ...
+func (s *Store) Fare() store.FareRepository {
+	if s.fareRepository != nil {
+		return s.fareRepository
+	}
+
+	s.fareRepository = &FareRepository{
+		store: s,
+	}
+
+	return s.fareRepository
+}
+
+// Onboarding ...
+func (s *Store) Onboarding() store.OnboardingRepository {
+	if s.onboardingRepository != nil {
+		return s.onboardingRepository
+	}
+
+	s.onboardingRepository = &OnboardingRepository{
+		store: s,
+	}
+
+	return s.onboardingRepository
+}
+
+// Outbox ...
+func (s *Store) Outbox() store.OutboxRepository {
+	if s.outboxRepository != nil {
+		return s.outboxRepository
+	}
+
+	s.outboxRepository = &OutboxRepository{
+		store: s,
+	}
+
+	return s.outboxRepository
+}
+
+// Change ...
+func (s *Store) Change() store.ChangeRepository {
+	if s.changeRepository != nil {
+		return s.changeRepository
+	}
+
+	s.changeRepository = &ChangeRepository{
+		store: s,
+	}
+
+	return s.changeRepository
+}
+
+// Job ...
+func (s *Store) Job() store.JobRepository {
+	if s.jobRepository != nil {
+		return s.jobRepository
+	}
+
+	s.jobRepository = &JobRepository{
+		store: s,
+	}
+
+	return s.jobRepository
+}
+
+// ScheduledTask ...
+func (s *Store) ScheduledTask() store.ScheduledTaskRepository {
+	if s.scheduledTaskRepository != nil {
+		return s.scheduledTaskRepository
+	}
+
+	s.scheduledTaskRepository = &ScheduledTaskRepository{
+		store: s,
+	}
+
+	return s.scheduledTaskRepository
+}
@@ -1,20 +1,83 @@
 package sqlstore_test
 
 import (
+	"context"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/sqlstore"
+	"winding-tree-server/internal/store/storetest"
+
+	"github.com/stretchr/testify/assert"
 )
 
 var (
-	databaseURL string
+	databaseDriver string
+	databaseURL    string
 )
 
-// TestMain ...
+// TestMain runs the repository tests against DATABASE_URL when it is set,
+// and against a throwaway SQLite database otherwise
 func TestMain(m *testing.M) {
+	databaseDriver = "postgres"
 	databaseURL = os.Getenv("DATABASE_URL")
 	if databaseURL == "" {
-		databaseURL = "host=localhost db=starlix_db_test sslmode=disable"
+		dir, err := ioutil.TempDir("", "sqlstore")
+		if err != nil {
+			panic(err)
+		}
+
+		databaseDriver = "sqlite3"
+		databaseURL = "file:" + filepath.Join(dir, "test.db") + "?_foreign_keys=on"
+
+		code := m.Run()
+		os.RemoveAll(dir)
+		os.Exit(code)
 	}
 
 	os.Exit(m.Run())
 }
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+
+		return sqlstore.New(db), func() {
+			teardown(
+				"scheduled_tasks",
+				"jobs",
+				"entity_changes",
+				"outbox_events",
+				"contract_events",
+				"contract_event_cursors",
+				"orgids",
+				"org_jsons",
+				"onboarding_documents",
+				"supplier_onboardings",
+				"fares",
+				"flight_segments",
+				"flights",
+				"users",
+			)
+		}
+	})
+}
+
+func TestCheckReadiness(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown()
+
+	assert.NoError(t, sqlstore.CheckRequirements(context.Background(), db))
+	assert.NoError(t, sqlstore.CheckSchema(db))
+	assert.NoError(t, sqlstore.New(db).Ping(context.Background()))
+
+	assert.NoError(t, sqlstore.MigrateDown(db, 1))
+	defer sqlstore.MigrateUp(db)
+
+	err := sqlstore.CheckSchema(db)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "migrate up")
+	}
+}
@@ -0,0 +1,49 @@
+package sqlstore
+
+import (
+	"fmt"
+	"winding-tree-server/internal/store"
+)
+
+// ForTenant returns a store sharing s's connections whose queries on
+// flights, flight segments, fares, onboardings, onboarding documents,
+// ORG.JSON documents and orgids carry the tenant as a condition. Reads of
+// other suppliers' records find nothing and writes of them return
+// store.ErrTenantMismatch.
+func (s *Store) ForTenant(tenantID int) store.Store {
+	return &Store{
+		db:       s.db,
+		replicas: s.replicas,
+		tenantID: tenantID,
+		sealer:   s.sealer,
+	}
+}
+
+// tenantCondition returns " AND column = $n" binding the tenant as the
+// next of args, or nothing for an unscoped store
+func (s *Store) tenantCondition(column string, args []interface{}) (string, []interface{}) {
+	if s.tenantID == 0 {
+		return "", args
+	}
+
+	args = append(args, s.tenantID)
+	return fmt.Sprintf(" AND %s = $%d", column, len(args)), args
+}
+
+// scopeList adds the tenant condition to a WHERE clause built by listQuery
+func (s *Store) scopeList(where string, args []interface{}, column string) (string, []interface{}) {
+	if s.tenantID == 0 {
+		return where, args
+	}
+
+	return andWhere(where, args, column+" = %s", s.tenantID)
+}
+
+// checkTenant guards writes of records owned by userID
+func (s *Store) checkTenant(userID int) error {
+	if s.tenantID != 0 && s.tenantID != userID {
+		return store.ErrTenantMismatch
+	}
+
+	return nil
+}
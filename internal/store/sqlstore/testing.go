@@ -0,0 +1,38 @@
+package sqlstore
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TestDB opens and migrates a database for repository tests. The returned
+// teardown empties the given tables and closes the connection.
+func TestDB(t *testing.T, driverName, databaseURL string) (*sqlx.DB, func(...string)) {
+	t.Helper()
+
+	db, err := sqlx.Connect(driverName, databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return db, func(tables ...string) {
+		if len(tables) > 0 {
+			if driverName == "sqlite3" {
+				for _, table := range tables {
+					db.Exec(fmt.Sprintf("DELETE FROM %s", table))
+				}
+			} else {
+				db.Exec(fmt.Sprintf("TRUNCATE %s CASCADE", strings.Join(tables, ", ")))
+			}
+		}
+
+		db.Close()
+	}
+}
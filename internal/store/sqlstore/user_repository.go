@@ -1,9 +1,12 @@
 package sqlstore
 
 import (
+	"context"
 	"database/sql"
+	"time"
 	"winding-tree-server/internal/model"
 	"winding-tree-server/internal/store"
+	"winding-tree-server/migrations"
 )
 
 // UserRepository ...
@@ -12,7 +15,7 @@ type UserRepository struct {
 }
 
 // Create ...
-func (r *UserRepository) Create(u *model.User) error {
+func (r *UserRepository) Create(ctx context.Context, u *model.User) error {
 	if err := u.Validate(); err != nil {
 		return err
 	}
@@ -21,23 +24,49 @@ func (r *UserRepository) Create(u *model.User) error {
 		return err
 	}
 
-	return r.store.db.QueryRow(
-		"INSERT INTO users (email, encrypted_password) VALUES ($1, $2) RETURNING id",
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(
+		ctx,
+		"INSERT INTO users (public_id, email, encrypted_password) VALUES ($1, $2, $3) ON CONFLICT (email) DO NOTHING RETURNING id",
+		u.PublicID,
 		u.Email,
 		u.EncryptedPassword,
-	).Scan(&u.ID)
+	).Scan(&u.ID); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrRecordExists
+		}
+
+		return err
+	}
+
+	// The plain password is still set on u and must not reach the history
+	snapshot := *u
+	snapshot.Sanitize()
+	if err := insertChange(ctx, tx, model.EntityUser, u.ID, model.ChangeCreate, nil, &snapshot); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // FindByEmail ...
-func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
 	u := &model.User{}
-	if err := r.store.db.QueryRow(
-		"SELECT id, email, encrypted_password FROM users WHERE email = $1",
+	if err := r.store.db.QueryRowContext(
+		ctx,
+		"SELECT id, public_id, email, encrypted_password, is_admin FROM users WHERE email = $1 AND deleted_at IS NULL",
 		email,
 	).Scan(
 		&u.ID,
+		&u.PublicID,
 		&u.Email,
 		&u.EncryptedPassword,
+		&u.IsAdmin,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrRecordNotFound
@@ -50,15 +79,18 @@ func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
 }
 
 // Find ...
-func (r *UserRepository) Find(id int) (*model.User, error) {
+func (r *UserRepository) Find(ctx context.Context, id int) (*model.User, error) {
 	u := &model.User{}
-	if err := r.store.db.QueryRow(
-		"SELECT id, email, encrypted_password FROM users WHERE id = $1",
+	if err := r.store.db.QueryRowContext(
+		ctx,
+		"SELECT id, public_id, email, encrypted_password, is_admin FROM users WHERE id = $1 AND deleted_at IS NULL",
 		id,
 	).Scan(
 		&u.ID,
+		&u.PublicID,
 		&u.Email,
 		&u.EncryptedPassword,
+		&u.IsAdmin,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrRecordNotFound
@@ -69,3 +101,275 @@ func (r *UserRepository) Find(id int) (*model.User, error) {
 
 	return u, nil
 }
+
+// FindByPublicID ...
+func (r *UserRepository) FindByPublicID(ctx context.Context, publicID string) (*model.User, error) {
+	u := &model.User{}
+	if err := r.store.db.QueryRowContext(
+		ctx,
+		"SELECT id, public_id, email, encrypted_password, is_admin FROM users WHERE public_id = $1 AND deleted_at IS NULL",
+		publicID,
+	).Scan(
+		&u.ID,
+		&u.PublicID,
+		&u.Email,
+		&u.EncryptedPassword,
+		&u.IsAdmin,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// userListColumns ...
+var userListColumns = map[string]string{
+	"id":        "id",
+	"public_id": "public_id",
+	"email":     "email",
+	"is_admin":  boolColumn("is_admin"),
+	"deleted":   boolColumn("deleted_at IS NOT NULL"),
+}
+
+// List returns a page of users and the total number matching the filters.
+// Unlike Find, it includes deleted users unless filtered by deleted=false.
+func (r *UserRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.User, int, error) {
+	where, args, tail, err := listQuery(opts, userListColumns, "id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return r.list(ctx, where, args, tail)
+}
+
+// sqliteUserSearch is the lower-case email with a space before each part,
+// as the search column of Postgres splits it
+const sqliteUserSearch = `(' ' || replace(replace(replace(replace(replace(lower(email), '@', ' '), '.', ' '), '-', ' '), '_', ' '), '+', ' '))`
+
+// Search is List restricted to users whose email contains every word of
+// query as a prefix of one of its parts, e.g. "exam" matches user@example.org
+func (r *UserRepository) Search(ctx context.Context, query string, opts *store.ListOptions) ([]*model.User, int, error) {
+	where, args, tail, err := listQuery(opts, userListColumns, "id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	words := searchWords(query)
+	if len(words) == 0 {
+		return []*model.User{}, 0, nil
+	}
+
+	if dialect(r.store.db) == migrations.SQLite {
+		// Like the search column of Postgres, each word is a prefix of a
+		// part of the email, after a space standing for a separator
+		for _, w := range words {
+			where, args = andWhere(where, args, sqliteUserSearch+` LIKE %s ESCAPE '\'`, "% "+likePattern(w)+"%")
+		}
+	} else {
+		where, args = andWhere(where, args, "search @@ to_tsquery('simple', %s)", prefixTSQuery(words))
+	}
+
+	return r.list(ctx, where, args, tail)
+}
+
+// list ...
+func (r *UserRepository) list(ctx context.Context, where string, args []interface{}, tail string) ([]*model.User, int, error) {
+	db := r.store.reader()
+
+	var total int
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, public_id, email, encrypted_password, is_admin, deleted_at, anonymized_at FROM users"+where+tail, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []*model.User{}
+	for rows.Next() {
+		u := &model.User{}
+		if err := rows.Scan(
+			&u.ID,
+			&u.PublicID,
+			&u.Email,
+			&u.EncryptedPassword,
+			&u.IsAdmin,
+			&u.DeletedAt,
+			&u.AnonymizedAt,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		users = append(users, u)
+	}
+
+	return users, total, rows.Err()
+}
+
+// Delete deactivates a user, keeping the record so it can be restored
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	return r.setDeletedAt(ctx, "UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL", id, model.ChangeDelete)
+}
+
+// Restore reactivates a deleted user that hasn't been anonymized
+func (r *UserRepository) Restore(ctx context.Context, id int) error {
+	return r.setDeletedAt(ctx, "UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL AND anonymized_at IS NULL", id, model.ChangeRestore)
+}
+
+// Anonymize replaces the email and password of users deleted before t and
+// removes their onboarding documents and earlier history, which holds the
+// same data. The public id is kept so references elsewhere stay valid; the
+// only history left is the anonymization itself.
+func (r *UserRepository) Anonymize(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(
+		ctx,
+		"SELECT id FROM users WHERE deleted_at < $1 AND anonymized_at IS NULL ORDER BY id",
+		t.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if dryRun || len(ids) == 0 {
+		return len(ids), nil
+	}
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE users SET email = 'anonymized-' || public_id || '@example.invalid',
+				encrypted_password = '', anonymized_at = CURRENT_TIMESTAMP
+			WHERE id = $1`,
+			id,
+		); err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM onboarding_documents WHERE user_id = $1", id); err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM entity_changes WHERE entity = $1 AND entity_id = $2", model.EntityUser, id); err != nil {
+			return 0, err
+		}
+
+		after, err := findUser(ctx, tx, id)
+		if err != nil {
+			return 0, err
+		}
+
+		if err := insertChange(ctx, tx, model.EntityUser, id, model.ChangeAnonymize, nil, after); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+func (r *UserRepository) setDeletedAt(ctx context.Context, query string, id int, action string) error {
+	tx, err := r.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := findUser(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return store.ErrRecordNotFound
+	}
+
+	after, err := findUser(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := insertChange(ctx, tx, model.EntityUser, id, action, before, after); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// findUser loads a user whether deleted or not, for change snapshots
+func findUser(ctx context.Context, q queryRower, id int) (*model.User, error) {
+	u := &model.User{}
+	if err := q.QueryRowContext(
+		ctx,
+		"SELECT id, public_id, email, encrypted_password, is_admin, deleted_at, anonymized_at FROM users WHERE id = $1",
+		id,
+	).Scan(
+		&u.ID,
+		&u.PublicID,
+		&u.Email,
+		&u.EncryptedPassword,
+		&u.IsAdmin,
+		&u.DeletedAt,
+		&u.AnonymizedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrRecordNotFound
+		}
+
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// publicUserID returns the public id of a user whether deleted or not, for
+// outbox payloads; it is empty for no user
+func publicUserID(ctx context.Context, q queryRower, id *int) (string, error) {
+	if id == nil {
+		return "", nil
+	}
+
+	var publicID string
+	err := q.QueryRowContext(ctx, "SELECT public_id FROM users WHERE id = $1", *id).Scan(&publicID)
+
+	return publicID, err
+}
@@ -0,0 +1,192 @@
+package sqlstore_test
+
+import (
+	"context"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/sqlstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepository_Create(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+	assert.NotZero(t, u.ID)
+}
+
+func TestUserRepository_Find(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "users")
+
+	s := sqlstore.New(db)
+	_, err := s.User().Find(context.Background(), 1)
+	assert.EqualError(t, err, store.ErrRecordNotFound.Error())
+
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+	found, err := s.User().Find(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, u.Email, found.Email)
+
+	found, err = s.User().FindByEmail(context.Background(), u.Email)
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, found.ID)
+}
+
+func TestUserRepository_Delete(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+
+	assert.NoError(t, s.User().Delete(context.Background(), u.ID))
+	assert.Equal(t, store.ErrRecordNotFound, s.User().Delete(context.Background(), u.ID))
+	_, err := s.User().FindByEmail(context.Background(), u.Email)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	users, total, err := s.User().List(context.Background(), &store.ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, users, 1) {
+		assert.NotNil(t, users[0].DeletedAt)
+	}
+
+	assert.NoError(t, s.User().Restore(context.Background(), u.ID))
+	_, err = s.User().Find(context.Background(), u.ID)
+	assert.NoError(t, err)
+}
+
+func TestUserRepository_List(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "users")
+
+	s := sqlstore.New(db)
+	for _, email := range []string{"c@example.org", "a@example.org", "b@example.org"} {
+		u := model.TestUser(t)
+		u.Email = email
+		s.User().Create(context.Background(), u)
+	}
+
+	users, total, err := s.User().List(context.Background(), &store.ListOptions{Limit: 2, Offset: 1, Sort: "-email"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, "b@example.org", users[0].Email)
+		assert.Equal(t, "a@example.org", users[1].Email)
+	}
+
+	users, total, err = s.User().List(context.Background(), &store.ListOptions{Limit: 10, Filters: map[string]string{"email": "a@example.org"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, users, 1)
+}
+
+func TestUserRepository_Search(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "users")
+
+	s := sqlstore.New(db)
+	for _, email := range []string{"anna@hotels.example", "bob@airline.example", "carl@hotels.test", "d_n@hotels.test", "dxn@hotels.test"} {
+		u := model.TestUser(t)
+		u.Email = email
+		s.User().Create(context.Background(), u)
+	}
+
+	testCases := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			name:     "domain",
+			query:    "hotels",
+			expected: []string{"anna@hotels.example", "carl@hotels.test", "d_n@hotels.test", "dxn@hotels.test"},
+		},
+		{
+			name:     "prefix",
+			query:    "air",
+			expected: []string{"bob@airline.example"},
+		},
+		{
+			name:     "all words",
+			query:    "Hotels example",
+			expected: []string{"anna@hotels.example"},
+		},
+		{
+			name:     "operators are ignored",
+			query:    "bob & !",
+			expected: []string{"bob@airline.example"},
+		},
+		{
+			name:     "words start parts",
+			query:    "otels",
+			expected: []string{},
+		},
+		{
+			name:     "underscore",
+			query:    "d_n",
+			expected: []string{"d_n@hotels.test"},
+		},
+		{
+			name:     "percent",
+			query:    "d%",
+			expected: []string{"d_n@hotels.test", "dxn@hotels.test"},
+		},
+		{
+			name:     "empty",
+			query:    " ",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			users, total, err := s.User().Search(context.Background(), tc.query, &store.ListOptions{Limit: 10, Sort: "email"})
+			assert.NoError(t, err)
+			assert.Equal(t, len(tc.expected), total)
+
+			emails := []string{}
+			for _, u := range users {
+				emails = append(emails, u.Email)
+			}
+			assert.Equal(t, tc.expected, emails)
+		})
+	}
+}
+
+func TestUserRepository_History(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseDriver, databaseURL)
+	defer teardown("entity_changes", "users")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+	assert.NoError(t, s.User().Delete(context.Background(), u.ID))
+	assert.Equal(t, store.ErrRecordNotFound, s.User().Delete(context.Background(), u.ID))
+	assert.NoError(t, s.User().Restore(context.Background(), u.ID))
+
+	changes, err := s.Change().FindByEntity(context.Background(), model.EntityUser, u.ID)
+	assert.NoError(t, err)
+	if !assert.Len(t, changes, 3) {
+		return
+	}
+
+	assert.Equal(t, model.ChangeCreate, changes[0].Action)
+	assert.Nil(t, changes[0].Before)
+	assert.NotContains(t, string(changes[0].After), "password")
+
+	assert.Equal(t, model.ChangeDelete, changes[1].Action)
+	assert.NotContains(t, string(changes[1].Before), "deleted_at")
+	assert.Contains(t, string(changes[1].After), "deleted_at")
+
+	assert.Equal(t, model.ChangeRestore, changes[2].Action)
+	assert.Equal(t, string(changes[1].After), string(changes[2].Before))
+}
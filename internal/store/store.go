@@ -3,4 +3,18 @@ package store
 // Store interface
 type Store interface {
 	User() UserRepository
+	OrgJSON() OrgJSONRepository
+	OrgID() OrgIDRepository
+	ContractEvent() ContractEventRepository
+	Flight() FlightRepository
+	Fare() FareRepository
+	Onboarding() OnboardingRepository
+	Outbox() OutboxRepository
+	Change() ChangeRepository
+	Job() JobRepository
+	ScheduledTask() ScheduledTaskRepository
+	// ForTenant returns a view of the store whose supplier-owned records,
+	// flights, fares, onboardings and ORG.JSON documents, are limited to
+	// those of one supplier
+	ForTenant(tenantID int) Store
 }
@@ -0,0 +1,118 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOutbox(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "supplier@example.org")
+
+	o := model.NewOnboarding(u.ID)
+	assert.NoError(t, s.Onboarding().Save(ctx, o))
+	assert.NoError(t, o.Submit(1))
+	assert.NoError(t, s.Onboarding().Save(ctx, o))
+	assert.Len(t, o.Events(), 0, "events are cleared once saved")
+	createFlight(t, s, u.ID)
+
+	events, err := s.Outbox().FindUnpublished(ctx, 10)
+	assert.NoError(t, err)
+	if !assert.Len(t, events, 2) {
+		return
+	}
+
+	assert.Equal(t, "onboarding.submitted", events[0].Topic)
+	assert.Contains(t, string(events[0].Payload), `"state":"submitted"`)
+	assert.Equal(t, model.TopicFlightCreated, events[1].Topic)
+	for _, e := range events {
+		assert.Contains(t, string(e.Payload), `"supplier_id":"`+u.PublicID+`"`)
+		assert.NotContains(t, string(e.Payload), `"user_id"`, "internal ids stay in the server")
+	}
+
+	retryAt := time.Now().Add(time.Minute)
+	assert.NoError(t, s.Outbox().MarkFailed(ctx, events[0].ID, "timeout", &retryAt))
+	events, err = s.Outbox().FindUnpublished(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1, "events waiting to be retried are found") {
+		assert.Equal(t, 1, events[0].Attempts)
+		assert.Equal(t, "timeout", events[0].LastError)
+		if assert.NotNil(t, events[0].RetryAt) {
+			assert.WithinDuration(t, retryAt, *events[0].RetryAt, time.Second)
+		}
+	}
+
+	assert.NoError(t, s.Outbox().MarkPublished(ctx, events[0].ID))
+	events, err = s.Outbox().FindUnpublished(ctx, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, model.TopicFlightCreated, events[0].Topic)
+	}
+
+	lastID, err := s.Outbox().LastID(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, events[0].ID, lastID)
+
+	events, err = s.Outbox().FindAfter(ctx, 0, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1, "published events are found too") {
+		assert.Equal(t, "onboarding.submitted", events[0].Topic)
+	}
+
+	events, err = s.Outbox().FindAfter(ctx, events[0].ID, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, lastID, events[0].ID)
+	}
+
+	createFlight(t, s, u.ID)
+	events, err = s.Outbox().FindUnpublished(ctx, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.NoError(t, s.Outbox().MarkFailed(ctx, events[1].ID, "rejected", nil))
+	}
+
+	events, err = s.Outbox().FindUnpublished(ctx, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1, "failed events are left out") {
+		assert.Equal(t, lastID, events[0].ID)
+	}
+}
+
+func testChange(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "supplier@example.org")
+	assert.NoError(t, s.User().Delete(ctx, u.ID))
+
+	changes, err := s.Change().FindByEntity(ctx, model.EntityUser, u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, model.ChangeCreate, changes[0].Action)
+		assert.Nil(t, changes[0].Before)
+		assert.NotContains(t, string(changes[0].After), "password")
+		assert.Equal(t, model.ChangeDelete, changes[1].Action)
+		assert.Contains(t, string(changes[1].After), "deleted_at")
+	}
+
+	o := model.NewOnboarding(u.ID)
+	assert.NoError(t, s.Onboarding().Save(ctx, o))
+	assert.NoError(t, o.Submit(1))
+	assert.NoError(t, s.Onboarding().Save(ctx, o))
+
+	changes, err = s.Change().FindByEntity(ctx, model.EntityOnboarding, u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, model.ChangeUpdate, changes[1].Action)
+		assert.Contains(t, string(changes[1].Before), `"state":"draft"`)
+		assert.Contains(t, string(changes[1].After), `"state":"submitted"`)
+	}
+
+	changes, err = s.Change().FindByEntity(ctx, model.EntityFare, 0)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 0)
+}
@@ -0,0 +1,136 @@
+package storetest
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createFlight ...
+func createFlight(t *testing.T, s store.Store, userID int) *model.Flight {
+	t.Helper()
+
+	f := model.TestFlight(t)
+	f.UserID = userID
+	if err := s.Flight().Create(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func testFlight(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "airline@example.org")
+	other := createUser(t, s, "other@example.org")
+
+	_, err := s.Flight().Find(ctx, 1)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	f := createFlight(t, s, u.ID)
+	assert.NotZero(t, f.ID)
+	for i, segment := range f.Segments {
+		assert.NotZero(t, segment.ID)
+		assert.Equal(t, i, segment.Position)
+	}
+	assert.NotZero(t, f.Fares[0].ID)
+	assert.Equal(t, f.ID, f.Fares[0].FlightID)
+
+	found, err := s.Flight().Find(ctx, f.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, u.ID, found.UserID)
+		if assert.Len(t, found.Segments, 2) {
+			assert.Equal(t, "ZRH", found.Segments[0].Origin)
+			assert.Equal(t, "KBP", found.Segments[1].Destination)
+			assert.True(t, f.Segments[0].DepartureAt.Equal(found.Segments[0].DepartureAt))
+		}
+		if assert.Len(t, found.Fares, 1) {
+			assert.Equal(t, f.Fares[0].Amount, found.Fares[0].Amount)
+		}
+	}
+
+	createFlight(t, s, other.ID)
+	flights, total, err := s.Flight().List(ctx, &store.ListOptions{
+		Limit:   10,
+		Filters: map[string]string{"user_id": strconv.Itoa(u.ID)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, flights, 1) {
+		assert.Equal(t, f.ID, flights[0].ID)
+		assert.Len(t, flights[0].Segments, 2)
+	}
+}
+
+func testFare(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "airline@example.org")
+	f := createFlight(t, s, u.ID)
+
+	economy := *f.Fares[0]
+	economy.ID = 0
+	assert.Equal(t, store.ErrRecordExists, s.Fare().Create(ctx, &economy), "one fare per cabin")
+
+	business := &model.Fare{
+		FlightID:       f.ID,
+		Cabin:          model.CabinBusiness,
+		Amount:         90000,
+		Currency:       "EUR",
+		SeatsAvailable: 1,
+	}
+	assert.NoError(t, s.Fare().Create(ctx, business))
+	assert.NotZero(t, business.ID)
+
+	economy.SeatsAvailable = 3
+	business.Amount = 80000
+	assert.NoError(t, s.Fare().Upsert(ctx, []*model.Fare{&economy, business}))
+	assert.Equal(t, f.Fares[0].ID, economy.ID)
+
+	search := &model.FlightSearch{
+		Origin:      "ZRH",
+		Destination: "KBP",
+		Date:        "2019-12-20",
+		Passengers:  1,
+	}
+
+	offers, err := s.Fare().Search(ctx, search)
+	assert.NoError(t, err)
+	assert.Len(t, offers, 0, "unapproved suppliers are hidden")
+
+	o := model.NewOnboarding(u.ID)
+	o.Submit(1)
+	o.Approve(u.ID)
+	assert.NoError(t, s.Onboarding().Save(ctx, o))
+
+	offers, err = s.Fare().Search(ctx, search)
+	assert.NoError(t, err)
+	if assert.Len(t, offers, 2) {
+		assert.Equal(t, economy.ID, offers[0].Fare.ID, "cheapest first")
+		assert.Equal(t, 3, offers[0].Fare.SeatsAvailable)
+		assert.Equal(t, int64(80000), offers[1].Fare.Amount)
+		assert.Len(t, offers[0].Flight.Segments, 2)
+	}
+
+	search.Passengers = 2
+	search.Cabin = model.CabinBusiness
+	offers, err = s.Fare().Search(ctx, search)
+	assert.NoError(t, err)
+	assert.Len(t, offers, 0)
+
+	search.Passengers = 1
+	search.Date = "2019-12-21"
+	offers, err = s.Fare().Search(ctx, search)
+	assert.NoError(t, err)
+	assert.Len(t, offers, 0)
+
+	search.Cabin = ""
+	search.Date = "2019-12-20"
+	assert.NoError(t, s.User().Delete(ctx, u.ID))
+	offers, err = s.Fare().Search(ctx, search)
+	assert.NoError(t, err)
+	assert.Len(t, offers, 0, "deleted suppliers are hidden")
+}
@@ -0,0 +1,116 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testJob(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	now := time.Now()
+
+	newJob := func(kind string, runAt time.Time) *model.Job {
+		j, err := model.NewJob(kind, map[string]string{"to": "user@example.org"}, 3, runAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.NoError(t, s.Job().Create(ctx, j))
+		return j
+	}
+
+	first := newJob("mail", now.Add(-time.Minute))
+	second := newJob("mail", now.Add(-time.Second))
+	newJob("mail", now.Add(time.Hour))
+	newJob("sync", now.Add(-time.Minute))
+
+	jobs, err := s.Job().Claim(ctx, []string{"mail"}, 10, time.Minute)
+	assert.NoError(t, err)
+	if !assert.Len(t, jobs, 2, "future jobs and other kinds wait") {
+		return
+	}
+	assert.Equal(t, first.ID, jobs[0].ID)
+	assert.Equal(t, model.JobRunning, jobs[0].State)
+	assert.Equal(t, 1, jobs[0].Attempts)
+	assert.JSONEq(t, `{"to":"user@example.org"}`, string(jobs[0].Payload))
+	assert.NotNil(t, jobs[0].LockedUntil)
+
+	jobs, err = s.Job().Claim(ctx, []string{"mail"}, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 0, "claimed jobs aren't claimed again during their lease")
+
+	assert.NoError(t, s.Job().Complete(ctx, first.ID, 1))
+	assert.Equal(t, store.ErrLeaseLost, s.Job().Complete(ctx, first.ID, 1), "finished jobs can't be finished again")
+	retryAt := now.Add(-time.Millisecond)
+	assert.NoError(t, s.Job().Fail(ctx, second.ID, 1, "timeout", &retryAt))
+
+	jobs, err = s.Job().Claim(ctx, []string{"mail"}, 10, -time.Second)
+	assert.NoError(t, err)
+	if assert.Len(t, jobs, 1, "failed jobs are retried") {
+		assert.Equal(t, second.ID, jobs[0].ID)
+		assert.Equal(t, 2, jobs[0].Attempts)
+		assert.Equal(t, "timeout", jobs[0].LastError)
+	}
+
+	jobs, err = s.Job().Claim(ctx, []string{"mail"}, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1, "jobs whose lease ended are claimed again")
+	assert.Equal(t, store.ErrConflict, s.Job().Reschedule(ctx, second.ID, now))
+
+	assert.Equal(t, store.ErrLeaseLost, s.Job().Fail(ctx, second.ID, 2, "late", nil), "the worker of an ended lease lost the job")
+	assert.Equal(t, store.ErrLeaseLost, s.Job().Complete(ctx, second.ID, 2))
+	assert.NoError(t, s.Job().Fail(ctx, second.ID, 3, "bounced", nil))
+	dead, err := s.Job().Find(ctx, second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobDead, dead.State)
+	assert.NotNil(t, dead.FinishedAt)
+
+	assert.NoError(t, s.Job().Reschedule(ctx, second.ID, now.Add(-time.Second)))
+	rescheduled, err := s.Job().Find(ctx, second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobPending, rescheduled.State)
+	assert.Equal(t, 0, rescheduled.Attempts)
+
+	_, err = s.Job().Find(ctx, 0)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+	assert.Equal(t, store.ErrRecordNotFound, s.Job().Reschedule(ctx, 0, now))
+
+	jobs, total, err := s.Job().List(ctx, &store.ListOptions{Limit: 10, Sort: "-id", Filters: map[string]string{"kind": "mail"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	if assert.Len(t, jobs, 3) {
+		assert.Equal(t, first.ID, jobs[2].ID)
+		assert.Equal(t, model.JobDone, jobs[2].State)
+	}
+
+	n, err := s.Job().Purge(ctx, time.Now().Add(time.Minute), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "only done jobs are purged")
+
+	// A job whose worker never finishes is dead after its last attempt
+	hung, err := model.NewJob("hang", nil, 2, now.Add(-time.Second))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Job().Create(ctx, hung))
+	for attempt := 1; attempt <= 2; attempt++ {
+		jobs, err = s.Job().Claim(ctx, []string{"hang"}, 10, -time.Second)
+		assert.NoError(t, err)
+		if assert.Len(t, jobs, 1) {
+			assert.Equal(t, attempt, jobs[0].Attempts)
+		}
+	}
+
+	jobs, err = s.Job().Claim(ctx, []string{"hang"}, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 0, "jobs out of attempts aren't claimed again")
+	hung, err = s.Job().Find(ctx, hung.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.JobDead, hung.State)
+	assert.Equal(t, model.JobLeaseExpired, hung.LastError)
+	assert.Nil(t, hung.LockedUntil)
+	assert.NotNil(t, hung.FinishedAt)
+}
@@ -0,0 +1,95 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOnboarding(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "supplier@example.org")
+	other := createUser(t, s, "other@example.org")
+
+	_, err := s.Onboarding().Find(ctx, u.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	o := model.NewOnboarding(u.ID)
+	assert.NoError(t, s.Onboarding().Save(ctx, o))
+	assert.Equal(t, 1, o.Version)
+	assert.Equal(t, store.ErrConflict, s.Onboarding().Save(ctx, model.NewOnboarding(u.ID)), "already created")
+
+	first, err := s.Onboarding().Find(ctx, u.ID)
+	assert.NoError(t, err)
+	second, err := s.Onboarding().Find(ctx, u.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, first.Submit(1))
+	assert.NoError(t, s.Onboarding().Save(ctx, first))
+	assert.Equal(t, 2, first.Version)
+
+	assert.NoError(t, second.Submit(1))
+	assert.Equal(t, store.ErrConflict, s.Onboarding().Save(ctx, second), "saved in between")
+
+	found, err := s.Onboarding().Find(ctx, u.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, model.OnboardingSubmitted, found.State)
+		assert.Equal(t, 2, found.Version)
+		assert.NotNil(t, found.SubmittedAt)
+	}
+
+	assert.NoError(t, s.Onboarding().Save(ctx, model.NewOnboarding(other.ID)))
+
+	onboardings, total, err := s.Onboarding().List(ctx, &store.ListOptions{
+		Limit:   10,
+		Filters: map[string]string{"state": model.OnboardingSubmitted},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, onboardings, 1) {
+		assert.Equal(t, u.ID, onboardings[0].UserID)
+	}
+
+	onboardings, total, err = s.Onboarding().List(ctx, &store.ListOptions{Limit: 10, Sort: "-user_id"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, total)
+	if assert.Len(t, onboardings, 2) {
+		assert.Equal(t, other.ID, onboardings[0].UserID)
+	}
+}
+
+func testOnboardingDocument(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "supplier@example.org")
+
+	d := &model.OnboardingDocument{
+		UserID:      u.ID,
+		Kind:        model.DocumentRegistration,
+		FileName:    "registration.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("%PDF-1.4"),
+	}
+	assert.NoError(t, s.Onboarding().CreateDocument(ctx, d))
+	assert.NotZero(t, d.ID)
+	assert.False(t, d.CreatedAt.IsZero())
+
+	assert.Error(t, s.Onboarding().CreateDocument(ctx, &model.OnboardingDocument{UserID: u.ID}))
+
+	documents, err := s.Onboarding().FindDocuments(ctx, u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, documents, 1) {
+		assert.Equal(t, d.FileName, documents[0].FileName)
+		assert.Nil(t, documents[0].Content, "listing doesn't load content")
+	}
+
+	found, err := s.Onboarding().FindDocument(ctx, u.ID, d.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, d.Content, found.Content)
+	}
+
+	_, err = s.Onboarding().FindDocument(ctx, u.ID+1, d.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err, "documents are scoped to their user")
+}
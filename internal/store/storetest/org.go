@@ -0,0 +1,191 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOrgJSON(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "supplier@example.org")
+
+	_, err := s.OrgJSON().FindLatest(ctx, u.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	first := model.TestOrgJSON(t)
+	first.UserID = u.ID
+	assert.NoError(t, s.OrgJSON().Create(ctx, first))
+	assert.Equal(t, 1, first.Version)
+	assert.NotEmpty(t, first.Hash)
+
+	second := model.TestOrgJSON(t)
+	second.UserID = u.ID
+	assert.NoError(t, s.OrgJSON().Create(ctx, second))
+	assert.Equal(t, 2, second.Version)
+
+	latest, err := s.OrgJSON().FindLatest(ctx, u.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, second.ID, latest.ID)
+		assert.JSONEq(t, string(second.Document), string(latest.Document))
+	}
+
+	found, err := s.OrgJSON().FindByVersion(ctx, u.ID, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, first.ID, found.ID)
+		assert.Equal(t, first.Hash, found.Hash)
+	}
+
+	_, err = s.OrgJSON().FindByVersion(ctx, u.ID, 3)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+}
+
+func testOrgID(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "supplier@example.org")
+
+	orgs, err := s.OrgID().FindByUser(ctx, u.ID)
+	assert.NoError(t, err)
+	assert.Len(t, orgs, 0)
+
+	unlinked := model.TestOrgID(t)
+	unlinked.UserID = &u.ID
+	assert.NoError(t, s.OrgID().Save(ctx, unlinked))
+	assert.Nil(t, unlinked.UserID, "no org.json with this hash was uploaded")
+
+	doc := model.TestOrgJSON(t)
+	doc.UserID = u.ID
+	assert.NoError(t, s.OrgJSON().Create(ctx, doc))
+
+	o := model.TestOrgID(t)
+	o.OrgJSONHash = doc.Hash
+	assert.NoError(t, s.OrgID().Save(ctx, o))
+	assert.Nil(t, o.UserID, "an upload alone doesn't link the organization")
+
+	o.UserID = &u.ID
+	assert.NoError(t, s.OrgID().Save(ctx, o))
+	if assert.NotNil(t, o.UserID) {
+		assert.Equal(t, u.ID, *o.UserID)
+	}
+
+	// Another user uploading a copy of the public document gets nothing
+	other := createUser(t, s, "copycat@example.org")
+	copied := model.TestOrgJSON(t)
+	copied.UserID = other.ID
+	assert.NoError(t, s.OrgJSON().Create(ctx, copied))
+	assert.Equal(t, doc.Hash, copied.Hash)
+	o.UserID = &u.ID
+	assert.NoError(t, s.OrgID().Save(ctx, o))
+	if assert.NotNil(t, o.UserID) {
+		assert.Equal(t, u.ID, *o.UserID)
+	}
+	orgs, err = s.OrgID().FindByUser(ctx, other.ID)
+	assert.NoError(t, err)
+	assert.Len(t, orgs, 0)
+
+	o.IsActive = false
+	assert.NoError(t, s.OrgID().Save(ctx, o))
+
+	orgs, err = s.OrgID().FindByUser(ctx, u.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, orgs, 1) {
+		assert.Equal(t, o.ID, orgs[0].ID)
+		assert.False(t, orgs[0].IsActive)
+		assert.Equal(t, o.LifDeposit, orgs[0].LifDeposit)
+	}
+
+	n, err := s.OrgID().Prune(ctx, "0x0000000000000000000000000000000000000003", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "other directories are kept")
+
+	n, err = s.OrgID().Prune(ctx, o.Directory, []string{o.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "listed organizations are kept")
+
+	n, err = s.OrgID().Prune(ctx, o.Directory, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	orgs, err = s.OrgID().FindByUser(ctx, u.ID)
+	assert.NoError(t, err)
+	assert.Len(t, orgs, 0, "the supplier is unlinked")
+}
+
+func testContractEvent(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	block, err := s.ContractEvent().Cursor(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), block)
+
+	newEvent := func(logIndex uint64) *model.ContractEvent {
+		return &model.ContractEvent{
+			Address:     "0x0000000000000000000000000000000000000001",
+			Topic:       "0x47b688936cae1ca5de00ac709e05309381fb9f18b4c5adb358a5b542ce67caea",
+			Topics:      []string{"0x47b688936cae1ca5de00ac709e05309381fb9f18b4c5adb358a5b542ce67caea"},
+			Data:        []byte{1},
+			BlockNumber: 100,
+			BlockHash:   "0x01",
+			TxHash:      "0x02",
+			LogIndex:    logIndex,
+		}
+	}
+
+	first, second := newEvent(0), newEvent(1)
+	assert.NoError(t, s.ContractEvent().Create(ctx, first))
+	assert.NoError(t, s.ContractEvent().Create(ctx, second))
+	assert.Equal(t, store.ErrRecordExists, s.ContractEvent().Create(ctx, newEvent(0)))
+
+	events, err := s.ContractEvent().FindUnprocessed(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, first.ID, events[0].ID)
+		assert.Equal(t, first.Topics, events[0].Topics)
+		assert.Equal(t, first.Data, events[0].Data)
+	}
+
+	assert.NoError(t, s.ContractEvent().MarkProcessed(ctx, first.ID))
+	events, err = s.ContractEvent().FindUnprocessed(ctx, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, second.ID, events[0].ID)
+	}
+
+	// Failed events wait for their retry, without holding back later ones
+	third := newEvent(2)
+	assert.NoError(t, s.ContractEvent().Create(ctx, third))
+	later := time.Now().Add(time.Hour)
+	assert.NoError(t, s.ContractEvent().MarkFailed(ctx, second.ID, 1, "failed", &later))
+	events, err = s.ContractEvent().FindUnprocessed(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, third.ID, events[0].ID)
+	}
+
+	due := time.Now().Add(-time.Second)
+	assert.NoError(t, s.ContractEvent().MarkFailed(ctx, second.ID, 1, "failed again", &due))
+	events, err = s.ContractEvent().FindUnprocessed(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, second.ID, events[0].ID)
+		assert.Equal(t, 1, events[0].Handled)
+		assert.Equal(t, 2, events[0].Attempts)
+		assert.Equal(t, "failed again", events[0].LastError)
+	}
+
+	assert.NoError(t, s.ContractEvent().MarkFailed(ctx, second.ID, 1, "failed for good", nil))
+	events, err = s.ContractEvent().FindUnprocessed(ctx, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, third.ID, events[0].ID)
+	}
+
+	assert.NoError(t, s.ContractEvent().SaveCursor(ctx, "test", 100))
+	assert.NoError(t, s.ContractEvent().SaveCursor(ctx, "test", 101))
+	block, err = s.ContractEvent().Cursor(ctx, "test")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(101), block)
+}
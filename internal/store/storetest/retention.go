@@ -0,0 +1,131 @@
+package storetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAnonymize(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	kept := createUser(t, s, "kept@example.org")
+	deleted := createUser(t, s, "deleted@example.org")
+	assert.NoError(t, s.Onboarding().CreateDocument(ctx, &model.OnboardingDocument{
+		UserID:      deleted.ID,
+		Kind:        model.DocumentIdentity,
+		FileName:    "passport.png",
+		ContentType: "image/png",
+		Content:     []byte("png"),
+	}))
+	assert.NoError(t, s.User().Delete(ctx, deleted.ID))
+
+	earlier := time.Now().Add(-time.Hour)
+	later := time.Now().Add(time.Minute)
+
+	n, err := s.User().Anonymize(ctx, earlier, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "deleted too recently")
+
+	n, err = s.User().Anonymize(ctx, later, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	users, _, err := s.User().List(ctx, &store.ListOptions{Limit: 10, Filters: map[string]string{"deleted": "true"}})
+	assert.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, "deleted@example.org", users[0].Email, "dry run changes nothing")
+		assert.Nil(t, users[0].AnonymizedAt)
+	}
+
+	n, err = s.User().Anonymize(ctx, later, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	users, _, err = s.User().List(ctx, &store.ListOptions{Limit: 10, Filters: map[string]string{"deleted": "true"}})
+	assert.NoError(t, err)
+	if assert.Len(t, users, 1) {
+		assert.True(t, strings.HasPrefix(users[0].Email, "anonymized-"))
+		assert.Equal(t, deleted.PublicID, users[0].PublicID)
+		assert.Empty(t, users[0].EncryptedPassword)
+		assert.NotNil(t, users[0].AnonymizedAt)
+	}
+
+	documents, err := s.Onboarding().FindDocuments(ctx, deleted.ID)
+	assert.NoError(t, err)
+	assert.Len(t, documents, 0)
+
+	changes, err := s.Change().FindByEntity(ctx, model.EntityUser, deleted.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, model.ChangeAnonymize, changes[0].Action)
+		assert.NotContains(t, string(changes[0].After), "deleted@example.org")
+	}
+
+	assert.Equal(t, store.ErrRecordNotFound, s.User().Restore(ctx, deleted.ID))
+
+	n, err = s.User().Anonymize(ctx, later, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "already anonymized")
+
+	u, err := s.User().Find(ctx, kept.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "kept@example.org", u.Email)
+}
+
+func testPurge(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "supplier@example.org")
+	createFlight(t, s, u.ID)
+	createFlight(t, s, u.ID)
+
+	events, err := s.Outbox().FindUnpublished(ctx, 10)
+	assert.NoError(t, err)
+	if !assert.Len(t, events, 2) {
+		return
+	}
+	assert.NoError(t, s.Outbox().MarkPublished(ctx, events[0].ID))
+
+	earlier := time.Now().Add(-time.Hour)
+	later := time.Now().Add(time.Minute)
+
+	n, err := s.Outbox().Purge(ctx, earlier, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = s.Outbox().Purge(ctx, later, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "only published events")
+
+	n, err = s.Outbox().Purge(ctx, later, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	events, err = s.Outbox().FindUnpublished(ctx, 10)
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	n, err = s.Change().Purge(ctx, earlier, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = s.Change().Purge(ctx, later, true)
+	assert.NoError(t, err)
+	assert.NotZero(t, n)
+
+	changes, err := s.Change().FindByEntity(ctx, model.EntityUser, u.ID)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1, "dry run changes nothing")
+
+	purged, err := s.Change().Purge(ctx, later, false)
+	assert.NoError(t, err)
+	assert.Equal(t, n, purged)
+
+	changes, err = s.Change().FindByEntity(ctx, model.EntityUser, u.ID)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 0)
+}
@@ -0,0 +1,75 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testScheduledTask(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	tick := time.Now().Truncate(time.Minute)
+
+	claimed, err := s.ScheduledTask().Claim(ctx, "purge", tick, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = s.ScheduledTask().Claim(ctx, "purge", tick, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, claimed, "a run is claimed once")
+
+	claimed, err = s.ScheduledTask().Claim(ctx, "purge", tick.Add(time.Minute), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, claimed, "the next run waits for the lease")
+
+	claimed, err = s.ScheduledTask().Claim(ctx, "sync", tick, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed, "tasks are claimed apart")
+
+	assert.NoError(t, s.ScheduledTask().Release(ctx, "purge", tick, "failed"))
+	claimed, err = s.ScheduledTask().Claim(ctx, "purge", tick.Add(-time.Minute), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, claimed, "earlier runs are over")
+
+	tasks, err := s.ScheduledTask().List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, tasks, 2) {
+		assert.Equal(t, "purge", tasks[0].Name)
+		assert.Equal(t, "failed", tasks[0].LastError)
+		assert.Nil(t, tasks[0].LockedUntil)
+		assert.NotNil(t, tasks[0].FinishedAt)
+		if assert.NotNil(t, tasks[0].LastRunAt) {
+			assert.True(t, tick.Equal(*tasks[0].LastRunAt))
+		}
+		assert.Equal(t, "sync", tasks[1].Name)
+		assert.NotNil(t, tasks[1].LockedUntil)
+	}
+
+	claimed, err = s.ScheduledTask().Claim(ctx, "purge", tick.Add(time.Minute), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	// A run that outlives its lease doesn't release the next one
+	first := tick.Add(1500 * time.Nanosecond)
+	claimed, err = s.ScheduledTask().Claim(ctx, "report", first, time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	time.Sleep(10 * time.Millisecond)
+	second := first.Add(time.Minute)
+	claimed, err = s.ScheduledTask().Claim(ctx, "report", second, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	assert.Equal(t, store.ErrLeaseLost, s.ScheduledTask().Release(ctx, "report", first, "late"))
+	assert.NoError(t, s.ScheduledTask().Release(ctx, "report", second, ""))
+	tasks, err = s.ScheduledTask().List(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, tasks, 3) {
+		assert.Equal(t, "report", tasks[1].Name)
+		assert.Empty(t, tasks[1].LastError)
+		assert.Nil(t, tasks[1].LockedUntil)
+	}
+}
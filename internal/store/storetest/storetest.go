@@ -0,0 +1,53 @@
+// Package storetest is a conformance suite for store.Store implementations.
+// Backends run it from their own tests to show they honour the same
+// repository contracts as the others:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+//			return teststore.New(), func() {}
+//		})
+//	}
+package storetest
+
+import (
+	"testing"
+	"winding-tree-server/internal/store"
+)
+
+// NewStoreFunc returns an empty store and a function that releases it.
+// It is called once per test.
+type NewStoreFunc func(t *testing.T) (store.Store, func())
+
+// Run exercises every repository of the stores returned by newStore
+func Run(t *testing.T, newStore NewStoreFunc) {
+	tests := []struct {
+		name string
+		test func(*testing.T, store.Store)
+	}{
+		{"User", testUser},
+		{"UserList", testUserList},
+		{"OrgJSON", testOrgJSON},
+		{"OrgID", testOrgID},
+		{"ContractEvent", testContractEvent},
+		{"Flight", testFlight},
+		{"Fare", testFare},
+		{"Onboarding", testOnboarding},
+		{"OnboardingDocument", testOnboardingDocument},
+		{"Outbox", testOutbox},
+		{"Change", testChange},
+		{"Job", testJob},
+		{"ScheduledTask", testScheduledTask},
+		{"Tenant", testTenant},
+		{"Anonymize", testAnonymize},
+		{"Purge", testPurge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, teardown := newStore(t)
+			defer teardown()
+
+			tt.test(t, s)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package storetest
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTenant(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	u := createUser(t, s, "airline@example.org")
+	other := createUser(t, s, "other@example.org")
+	f := createFlight(t, s, u.ID)
+	otherFlight := createFlight(t, s, other.ID)
+	scoped := s.ForTenant(u.ID)
+
+	found, err := scoped.Flight().Find(ctx, f.ID)
+	if assert.NoError(t, err) {
+		assert.Len(t, found.Segments, 2)
+		assert.Len(t, found.Fares, 1)
+	}
+	_, err = scoped.Flight().Find(ctx, otherFlight.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	flights, total, err := scoped.Flight().List(ctx, &store.ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, flights, 1) {
+		assert.Equal(t, f.ID, flights[0].ID)
+	}
+	flights, total, err = scoped.Flight().List(ctx, &store.ListOptions{
+		Limit:   10,
+		Filters: map[string]string{"user_id": strconv.Itoa(other.ID)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Len(t, flights, 0)
+
+	foreign := model.TestFlight(t)
+	foreign.UserID = other.ID
+	assert.Equal(t, store.ErrTenantMismatch, scoped.Flight().Create(ctx, foreign))
+
+	business := &model.Fare{
+		FlightID:       otherFlight.ID,
+		Cabin:          model.CabinBusiness,
+		Amount:         90000,
+		Currency:       "EUR",
+		SeatsAvailable: 2,
+	}
+	assert.Equal(t, store.ErrRecordNotFound, scoped.Fare().Create(ctx, business))
+	assert.Equal(t, store.ErrRecordNotFound, scoped.Fare().Upsert(ctx, []*model.Fare{business}))
+	business.FlightID = f.ID
+	assert.NoError(t, scoped.Fare().Upsert(ctx, []*model.Fare{business}))
+	assert.NotZero(t, business.ID)
+
+	for _, id := range []int{u.ID, other.ID} {
+		o := model.NewOnboarding(id)
+		o.Submit(1)
+		o.Approve(id)
+		assert.NoError(t, s.Onboarding().Save(ctx, o))
+	}
+
+	search := &model.FlightSearch{
+		Origin:      "ZRH",
+		Destination: "KBP",
+		Date:        "2019-12-20",
+		Passengers:  1,
+		Cabin:       model.CabinEconomy,
+	}
+	offers, err := s.Fare().Search(ctx, search)
+	assert.NoError(t, err)
+	assert.Len(t, offers, 2)
+	offers, err = scoped.Fare().Search(ctx, search)
+	assert.NoError(t, err)
+	if assert.Len(t, offers, 1) {
+		assert.Equal(t, f.ID, offers[0].Flight.ID)
+		assert.Len(t, offers[0].Flight.Segments, 2)
+	}
+
+	_, err = scoped.Onboarding().Find(ctx, other.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+	assert.Equal(t, store.ErrTenantMismatch, scoped.Onboarding().Save(ctx, model.NewOnboarding(other.ID)))
+	onboardings, total, err := scoped.Onboarding().List(ctx, &store.ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, onboardings, 1) {
+		assert.Equal(t, u.ID, onboardings[0].UserID)
+	}
+
+	d := &model.OnboardingDocument{
+		UserID:      other.ID,
+		Kind:        model.DocumentRegistration,
+		FileName:    "registration.pdf",
+		ContentType: "application/pdf",
+		Content:     []byte("%PDF-1.4"),
+	}
+	assert.Equal(t, store.ErrTenantMismatch, scoped.Onboarding().CreateDocument(ctx, d))
+	assert.NoError(t, s.Onboarding().CreateDocument(ctx, d))
+	documents, err := scoped.Onboarding().FindDocuments(ctx, other.ID)
+	assert.NoError(t, err)
+	assert.Len(t, documents, 0)
+	_, err = scoped.Onboarding().FindDocument(ctx, other.ID, d.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	doc := model.TestOrgJSON(t)
+	doc.UserID = other.ID
+	assert.Equal(t, store.ErrTenantMismatch, scoped.OrgJSON().Create(ctx, doc))
+	assert.NoError(t, s.OrgJSON().Create(ctx, doc))
+	_, err = scoped.OrgJSON().FindLatest(ctx, other.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+	_, err = scoped.OrgJSON().FindByVersion(ctx, other.ID, doc.Version)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	org := model.TestOrgID(t)
+	org.OrgJSONHash = doc.Hash
+	org.UserID = &other.ID
+	assert.Equal(t, store.ErrTenantMismatch, scoped.OrgID().Save(ctx, org))
+	assert.NoError(t, s.OrgID().Save(ctx, org))
+	orgs, err := scoped.OrgID().FindByUser(ctx, other.ID)
+	assert.NoError(t, err)
+	assert.Len(t, orgs, 0)
+}
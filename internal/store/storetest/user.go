@@ -0,0 +1,150 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createUser ...
+func createUser(t *testing.T, s store.Store, email string) *model.User {
+	t.Helper()
+
+	u := model.TestUser(t)
+	u.Email = email
+	if err := s.User().Create(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+
+	return u
+}
+
+func testUser(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	_, err := s.User().Find(ctx, 1)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	u := createUser(t, s, "user@example.org")
+	assert.NotZero(t, u.ID)
+	assert.NotEmpty(t, u.EncryptedPassword)
+	assert.Len(t, u.PublicID, 36)
+
+	duplicate := model.TestUser(t)
+	duplicate.Email = u.Email
+	assert.Equal(t, store.ErrRecordExists, s.User().Create(ctx, duplicate))
+
+	found, err := s.User().Find(ctx, u.ID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, u.Email, found.Email)
+		assert.True(t, found.ComparePasswords("password"))
+	}
+
+	found, err = s.User().FindByEmail(ctx, u.Email)
+	if assert.NoError(t, err) {
+		assert.Equal(t, u.ID, found.ID)
+	}
+
+	_, err = s.User().FindByEmail(ctx, "nobody@example.org")
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	found, err = s.User().FindByPublicID(ctx, u.PublicID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, u.ID, found.ID)
+		assert.Equal(t, u.PublicID, found.PublicID)
+	}
+
+	_, err = s.User().FindByPublicID(ctx, "00000000-0000-0000-0000-000000000000")
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	assert.NoError(t, s.User().Delete(ctx, u.ID))
+	assert.Equal(t, store.ErrRecordNotFound, s.User().Delete(ctx, u.ID))
+	_, err = s.User().Find(ctx, u.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+	_, err = s.User().FindByEmail(ctx, u.Email)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+	_, err = s.User().FindByPublicID(ctx, u.PublicID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	assert.NoError(t, s.User().Restore(ctx, u.ID))
+	assert.Equal(t, store.ErrRecordNotFound, s.User().Restore(ctx, u.ID))
+	_, err = s.User().Find(ctx, u.ID)
+	assert.NoError(t, err)
+}
+
+func testUserList(t *testing.T, s store.Store) {
+	ctx := context.Background()
+
+	first := createUser(t, s, "first@example.org")
+	second := createUser(t, s, "second@example.com")
+	third := createUser(t, s, "third@example.org")
+	assert.NoError(t, s.User().Delete(ctx, third.ID))
+
+	ids := func(users []*model.User) []int {
+		ids := []int{}
+		for _, u := range users {
+			ids = append(ids, u.ID)
+		}
+		return ids
+	}
+
+	users, total, err := s.User().List(ctx, &store.ListOptions{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []int{first.ID, second.ID}, ids(users))
+
+	users, total, err = s.User().List(ctx, &store.ListOptions{Limit: 2, Offset: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []int{third.ID}, ids(users))
+
+	users, _, err = s.User().List(ctx, &store.ListOptions{Limit: 10, Sort: "-email"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{third.ID, second.ID, first.ID}, ids(users))
+
+	users, total, err = s.User().List(ctx, &store.ListOptions{Limit: 10, Filters: map[string]string{"deleted": "true"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []int{third.ID}, ids(users))
+
+	users, _, err = s.User().List(ctx, &store.ListOptions{Limit: 10, Filters: map[string]string{"is_admin": "false", "deleted": "false"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{first.ID, second.ID}, ids(users))
+
+	_, _, err = s.User().List(ctx, &store.ListOptions{Limit: 10, Sort: "encrypted_password"})
+	assert.Equal(t, store.ErrInvalidListOptions, err)
+	_, _, err = s.User().List(ctx, &store.ListOptions{})
+	assert.Equal(t, store.ErrInvalidListOptions, err)
+
+	users, total, err = s.User().Search(ctx, "sec", &store.ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []int{second.ID}, ids(users))
+
+	users, total, err = s.User().Search(ctx, "  ", &store.ListOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Len(t, users, 0)
+
+	// Words are prefixes of the parts of emails, wildcards are not special
+	fourth := createUser(t, s, "under_score@example.net")
+	testCases := []struct {
+		query string
+		ids   []int
+	}{
+		{query: "exam", ids: []int{first.ID, second.ID, third.ID, fourth.ID}},
+		{query: "ample", ids: []int{}},
+		{query: "under_sc", ids: []int{fourth.ID}},
+		{query: "r_s", ids: []int{}},
+		{query: "%ample", ids: []int{}},
+		{query: "o_g", ids: []int{}},
+	}
+	for _, tc := range testCases {
+		users, _, err = s.User().Search(ctx, tc.query, &store.ListOptions{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, tc.ids, ids(users), tc.query)
+	}
+}
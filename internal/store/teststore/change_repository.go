@@ -0,0 +1,76 @@
+package teststore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/model"
+)
+
+// ChangeRepository ...
+type ChangeRepository struct {
+	store   *Store
+	changes []*model.Change
+	lastID  int
+}
+
+// FindByEntity ...
+func (r *ChangeRepository) FindByEntity(ctx context.Context, entity string, entityID int) ([]*model.Change, error) {
+	changes := []*model.Change{}
+	for _, c := range r.changes {
+		if c.Entity == entity && c.EntityID == entityID {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes, nil
+}
+
+// Purge ...
+func (r *ChangeRepository) Purge(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	match := func(c *model.Change) bool {
+		return c.CreatedAt.Before(t)
+	}
+
+	if dryRun {
+		n := 0
+		for _, c := range r.changes {
+			if match(c) {
+				n++
+			}
+		}
+
+		return n, nil
+	}
+
+	return r.remove(match), nil
+}
+
+// remove drops the changes matching and returns how many there were
+func (r *ChangeRepository) remove(match func(*model.Change) bool) int {
+	kept := r.changes[:0]
+	for _, c := range r.changes {
+		if !match(c) {
+			kept = append(kept, c)
+		}
+	}
+
+	n := len(r.changes) - len(kept)
+	r.changes = kept
+
+	return n
+}
+
+// add is the in-memory counterpart of sqlstore's insertChange
+func (r *ChangeRepository) add(entity string, entityID int, action string, before, after interface{}) error {
+	c, err := model.NewChange(entity, entityID, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	r.lastID++
+	c.ID = r.lastID
+	c.CreatedAt = time.Now()
+	r.changes = append(r.changes, c)
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package teststore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// ContractEventRepository ...
+type ContractEventRepository struct {
+	store   *Store
+	events  []*model.ContractEvent
+	cursors map[string]uint64
+}
+
+// Create ...
+func (r *ContractEventRepository) Create(ctx context.Context, e *model.ContractEvent) error {
+	for _, existing := range r.events {
+		if existing.TxHash == e.TxHash && existing.LogIndex == e.LogIndex {
+			return store.ErrRecordExists
+		}
+	}
+
+	e.ID = len(r.events) + 1
+	e.CreatedAt = time.Now()
+	r.events = append(r.events, e)
+
+	return nil
+}
+
+// FindUnprocessed ...
+func (r *ContractEventRepository) FindUnprocessed(ctx context.Context, limit int) ([]*model.ContractEvent, error) {
+	events := []*model.ContractEvent{}
+	for _, e := range r.events {
+		if len(events) == limit {
+			break
+		}
+
+		if e.ProcessedAt == nil && e.FailedAt == nil && (e.RetryAt == nil || !e.RetryAt.After(time.Now())) {
+			events = append(events, e)
+		}
+	}
+
+	return events, nil
+}
+
+// MarkProcessed ...
+func (r *ContractEventRepository) MarkProcessed(ctx context.Context, id int) error {
+	for _, e := range r.events {
+		if e.ID == id {
+			now := time.Now()
+			e.ProcessedAt = &now
+		}
+	}
+
+	return nil
+}
+
+// MarkFailed ...
+func (r *ContractEventRepository) MarkFailed(ctx context.Context, id int, handled int, reason string, retryAt *time.Time) error {
+	for _, e := range r.events {
+		if e.ID != id {
+			continue
+		}
+
+		e.Handled = handled
+		e.Attempts++
+		e.LastError = reason
+		e.RetryAt = retryAt
+		if retryAt == nil {
+			now := time.Now()
+			e.FailedAt = &now
+		}
+	}
+
+	return nil
+}
+
+// Cursor ...
+func (r *ContractEventRepository) Cursor(ctx context.Context, name string) (uint64, error) {
+	return r.cursors[name], nil
+}
+
+// SaveCursor ...
+func (r *ContractEventRepository) SaveCursor(ctx context.Context, name string, block uint64) error {
+	r.cursors[name] = block
+	return nil
+}
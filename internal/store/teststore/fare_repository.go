@@ -0,0 +1,141 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// FareRepository ...
+type FareRepository struct {
+	store *Store
+	fares map[int]*model.Fare
+}
+
+// Create ...
+func (r *FareRepository) Create(ctx context.Context, f *model.Fare) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	flight, ok := r.store.Flight().(*FlightRepository).flights[f.FlightID]
+	if !ok {
+		return store.ErrRecordNotFound
+	}
+
+	if r.find(f.FlightID, f.Cabin) != nil {
+		return store.ErrRecordExists
+	}
+
+	f.ID = len(r.fares) + 1
+	r.fares[f.ID] = f
+
+	if !containsFare(flight.Fares, f) {
+		flight.Fares = append(flight.Fares, f)
+	}
+
+	return r.store.Change().(*ChangeRepository).add(model.EntityFare, f.ID, model.ChangeCreate, nil, f)
+}
+
+// Upsert ...
+func (r *FareRepository) Upsert(ctx context.Context, fares []*model.Fare) error {
+	for _, f := range fares {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fares {
+		existing := r.find(f.FlightID, f.Cabin)
+		if existing == nil {
+			if err := r.Create(ctx, f); err != nil {
+				return err
+			}
+			continue
+		}
+
+		before := *existing
+		existing.Amount = f.Amount
+		existing.Currency = f.Currency
+		existing.SeatsAvailable = f.SeatsAvailable
+		f.ID = existing.ID
+
+		if err := r.store.Change().(*ChangeRepository).add(model.EntityFare, f.ID, model.ChangeUpdate, &before, existing); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// find ...
+func (r *FareRepository) find(flightID int, cabin string) *model.Fare {
+	for _, f := range r.fares {
+		if f.FlightID == flightID && f.Cabin == cabin {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// Search ...
+func (r *FareRepository) Search(ctx context.Context, s *model.FlightSearch) ([]*model.FlightOffer, error) {
+	date, err := time.Parse("2006-01-02", s.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	offers := []*model.FlightOffer{}
+	for _, fare := range r.fares {
+		f, ok := r.store.Flight().(*FlightRepository).flights[fare.FlightID]
+		if !ok {
+			continue
+		}
+
+		if _, err := r.store.User().Find(ctx, f.UserID); err != nil {
+			continue
+		}
+
+		o, err := r.store.Onboarding().Find(ctx, f.UserID)
+		if err != nil || !o.IsApproved() {
+			continue
+		}
+
+		first, last := f.Segments[0], f.Segments[len(f.Segments)-1]
+		departure := first.DepartureAt.UTC()
+		if first.Origin != s.Origin ||
+			last.Destination != s.Destination ||
+			departure.Before(date) ||
+			!departure.Before(date.AddDate(0, 0, 1)) ||
+			fare.SeatsAvailable < s.Passengers ||
+			(s.Cabin != "" && fare.Cabin != s.Cabin) {
+			continue
+		}
+
+		offers = append(offers, &model.FlightOffer{Flight: f, Fare: fare})
+	}
+
+	sort.Slice(offers, func(i, j int) bool {
+		if offers[i].Fare.Amount != offers[j].Fare.Amount {
+			return offers[i].Fare.Amount < offers[j].Fare.Amount
+		}
+
+		return offers[i].Flight.Segments[0].DepartureAt.Before(offers[j].Flight.Segments[0].DepartureAt)
+	})
+
+	return offers, nil
+}
+
+// containsFare ...
+func containsFare(fares []*model.Fare, f *model.Fare) bool {
+	for _, existing := range fares {
+		if existing == f {
+			return true
+		}
+	}
+
+	return false
+}
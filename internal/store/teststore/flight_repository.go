@@ -0,0 +1,82 @@
+package teststore
+
+import (
+	"context"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// FlightRepository ...
+type FlightRepository struct {
+	store    *Store
+	flights  map[int]*model.Flight
+	segments int
+}
+
+// Create ...
+func (r *FlightRepository) Create(ctx context.Context, f *model.Flight) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	f.BeforeCreate()
+
+	f.ID = len(r.flights) + 1
+	for _, s := range f.Segments {
+		r.segments++
+		s.ID = r.segments
+		s.FlightID = f.ID
+	}
+
+	r.flights[f.ID] = f
+
+	for _, fare := range f.Fares {
+		fare.FlightID = f.ID
+		if err := r.store.Fare().Create(ctx, fare); err != nil {
+			return err
+		}
+	}
+
+	supplierID := r.store.User().(*UserRepository).publicID(&f.UserID)
+
+	return r.store.Outbox().(*OutboxRepository).add(model.TopicFlightCreated, &model.FlightPayload{Flight: f, SupplierID: supplierID})
+}
+
+// Find ...
+func (r *FlightRepository) Find(ctx context.Context, id int) (*model.Flight, error) {
+	f, ok := r.flights[id]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return f, nil
+}
+
+// List ...
+func (r *FlightRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Flight, int, error) {
+	all := make([]*model.Flight, 0, len(r.flights))
+	for _, f := range r.flights {
+		all = append(all, f)
+	}
+
+	indexes, total, err := list(opts, len(all), func(i int, field string) interface{} {
+		switch field {
+		case "id":
+			return all[i].ID
+		case "user_id":
+			return all[i].UserID
+		}
+
+		return nil
+	}, "id", "id", "user_id")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	flights := make([]*model.Flight, 0, len(indexes))
+	for _, i := range indexes {
+		flights = append(flights, all[i])
+	}
+
+	return flights, total, nil
+}
@@ -0,0 +1,245 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// JobRepository is safe for concurrent use, as job queue workers share it
+type JobRepository struct {
+	mu     sync.Mutex
+	jobs   []*model.Job
+	lastID int
+}
+
+// Create ...
+func (r *JobRepository) Create(ctx context.Context, j *model.Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	j.ID = r.lastID
+	j.CreatedAt = time.Now()
+	clone := *j
+	r.jobs = append(r.jobs, &clone)
+
+	return nil
+}
+
+// Find ...
+func (r *JobRepository) Find(ctx context.Context, id int) (*model.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j, err := r.find(id)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := *j
+
+	return &clone, nil
+}
+
+// List ...
+func (r *JobRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Job, int, error) {
+	r.mu.Lock()
+	all := make([]*model.Job, 0, len(r.jobs))
+	for _, j := range r.jobs {
+		clone := *j
+		all = append(all, &clone)
+	}
+	r.mu.Unlock()
+
+	indexes, total, err := list(opts, len(all), func(i int, field string) interface{} {
+		switch field {
+		case "id":
+			return all[i].ID
+		case "kind":
+			return all[i].Kind
+		case "state":
+			return all[i].State
+		case "run_at":
+			return &all[i].RunAt
+		case "created_at":
+			return &all[i].CreatedAt
+		}
+
+		return nil
+	}, "id", "id", "kind", "state", "run_at", "created_at")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	jobs := make([]*model.Job, 0, len(indexes))
+	for _, i := range indexes {
+		jobs = append(jobs, all[i])
+	}
+
+	return jobs, total, nil
+}
+
+// Claim ...
+func (r *JobRepository) Claim(ctx context.Context, kinds []string, n int, lease time.Duration) ([]*model.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wanted := map[string]bool{}
+	for _, kind := range kinds {
+		wanted[kind] = true
+	}
+
+	now := time.Now()
+	due := []*model.Job{}
+	for _, j := range r.jobs {
+		pending := j.State == model.JobPending && !j.RunAt.After(now)
+		abandoned := j.State == model.JobRunning && j.LockedUntil != nil && j.LockedUntil.Before(now)
+		if abandoned && j.Attempts >= j.MaxAttempts {
+			j.State = model.JobDead
+			j.LastError = model.JobLeaseExpired
+			j.LockedUntil = nil
+			j.FinishedAt = &now
+			continue
+		}
+
+		if wanted[j.Kind] && (pending || abandoned) {
+			due = append(due, j)
+		}
+	}
+
+	sort.SliceStable(due, func(a, b int) bool {
+		return due[a].RunAt.Before(due[b].RunAt)
+	})
+	if len(due) > n {
+		due = due[:n]
+	}
+
+	claimed := make([]*model.Job, 0, len(due))
+	lockedUntil := now.Add(lease)
+	for _, j := range due {
+		j.State = model.JobRunning
+		j.Attempts++
+		j.LockedUntil = &lockedUntil
+
+		clone := *j
+		claimed = append(claimed, &clone)
+	}
+
+	return claimed, nil
+}
+
+// Complete ...
+func (r *JobRepository) Complete(ctx context.Context, id int, attempt int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j, err := r.claimed(id, attempt)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	j.State = model.JobDone
+	j.LockedUntil = nil
+	j.FinishedAt = &now
+
+	return nil
+}
+
+// Fail ...
+func (r *JobRepository) Fail(ctx context.Context, id int, attempt int, reason string, retryAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j, err := r.claimed(id, attempt)
+	if err != nil {
+		return err
+	}
+
+	j.LastError = reason
+	j.LockedUntil = nil
+	if retryAt == nil {
+		now := time.Now()
+		j.State = model.JobDead
+		j.FinishedAt = &now
+		return nil
+	}
+
+	j.State = model.JobPending
+	j.RunAt = *retryAt
+
+	return nil
+}
+
+// Reschedule ...
+func (r *JobRepository) Reschedule(ctx context.Context, id int, runAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	j, err := r.find(id)
+	if err != nil {
+		return err
+	}
+
+	if !j.Retryable() {
+		return store.ErrConflict
+	}
+
+	j.State = model.JobPending
+	j.Attempts = 0
+	j.RunAt = runAt
+	j.FinishedAt = nil
+
+	return nil
+}
+
+// Purge ...
+func (r *JobRepository) Purge(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := []*model.Job{}
+	for _, j := range r.jobs {
+		if j.State != model.JobDone || !j.FinishedAt.Before(t) {
+			kept = append(kept, j)
+		}
+	}
+
+	n := len(r.jobs) - len(kept)
+	if !dryRun {
+		r.jobs = kept
+	}
+
+	return n, nil
+}
+
+func (r *JobRepository) find(id int) (*model.Job, error) {
+	for _, j := range r.jobs {
+		if j.ID == id {
+			return j, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+// claimed finds a job running the given attempt
+func (r *JobRepository) claimed(id int, attempt int) (*model.Job, error) {
+	j, err := r.find(id)
+	if err == store.ErrRecordNotFound {
+		return nil, store.ErrLeaseLost
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if j.State != model.JobRunning || j.Attempts != attempt {
+		return nil, store.ErrLeaseLost
+	}
+
+	return j, nil
+}
@@ -0,0 +1,85 @@
+package teststore
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"winding-tree-server/internal/store"
+)
+
+// fieldFunc returns the value of a named field of the i-th record
+type fieldFunc func(i int, field string) interface{}
+
+// list applies opts to n records the same way sqlstore does and returns
+// the indexes of the requested page along with the total number of matches.
+func list(opts *store.ListOptions, n int, value fieldFunc, defaultSort string, fields ...string) ([]int, int, error) {
+	if err := opts.Validate(fields...); err != nil {
+		return nil, 0, err
+	}
+
+	matches := []int{}
+	for i := 0; i < n; i++ {
+		match := true
+		for f, v := range opts.Filters {
+			if fmt.Sprint(value(i, f)) != v {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			matches = append(matches, i)
+		}
+	}
+
+	sortField, desc := opts.SortField()
+	if sortField == "" {
+		sortField, desc = defaultSort, false
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		va, vb := value(matches[a], sortField), value(matches[b], sortField)
+		if !less(va, vb) && !less(vb, va) {
+			return less(value(matches[a], defaultSort), value(matches[b], defaultSort))
+		}
+
+		if desc {
+			return less(vb, va)
+		}
+
+		return less(va, vb)
+	})
+
+	total := len(matches)
+	if opts.Offset >= total {
+		return []int{}, total, nil
+	}
+
+	end := opts.Offset + opts.Limit
+	if end > total {
+		end = total
+	}
+
+	return matches[opts.Offset:end], total, nil
+}
+
+// less orders field values, placing nil times last like Postgres does
+func less(a, b interface{}) bool {
+	switch a := a.(type) {
+	case int:
+		return a < b.(int)
+	case string:
+		return a < b.(string)
+	case bool:
+		return !a && b.(bool)
+	case *time.Time:
+		b := b.(*time.Time)
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+
+		return a.Before(*b)
+	}
+
+	return false
+}
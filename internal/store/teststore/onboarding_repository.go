@@ -0,0 +1,148 @@
+package teststore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// OnboardingRepository ...
+type OnboardingRepository struct {
+	store       *Store
+	onboardings map[int]*model.Onboarding
+	documents   []*model.OnboardingDocument
+}
+
+// Find ...
+func (r *OnboardingRepository) Find(ctx context.Context, userID int) (*model.Onboarding, error) {
+	o, ok := r.onboardings[userID]
+	if !ok {
+		return nil, store.ErrRecordNotFound
+	}
+
+	clone := *o
+	return &clone, nil
+}
+
+// List ...
+func (r *OnboardingRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Onboarding, int, error) {
+	all := make([]*model.Onboarding, 0, len(r.onboardings))
+	for _, o := range r.onboardings {
+		clone := *o
+		all = append(all, &clone)
+	}
+
+	indexes, total, err := list(opts, len(all), func(i int, field string) interface{} {
+		switch field {
+		case "user_id":
+			return all[i].UserID
+		case "state":
+			return all[i].State
+		case "submitted_at":
+			return all[i].SubmittedAt
+		case "reviewed_at":
+			return all[i].ReviewedAt
+		}
+
+		return nil
+	}, "user_id", "user_id", "state", "submitted_at", "reviewed_at")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	onboardings := make([]*model.Onboarding, 0, len(indexes))
+	for _, i := range indexes {
+		onboardings = append(onboardings, all[i])
+	}
+
+	return onboardings, total, nil
+}
+
+// Save ...
+func (r *OnboardingRepository) Save(ctx context.Context, o *model.Onboarding) error {
+	current, ok := r.onboardings[o.UserID]
+	if ok != (o.Version != 0) || (ok && current.Version != o.Version) {
+		return store.ErrConflict
+	}
+
+	var before interface{}
+	action := model.ChangeCreate
+	if ok {
+		before = current
+		action = model.ChangeUpdate
+	}
+
+	o.Version++
+	if err := r.store.Change().(*ChangeRepository).add(model.EntityOnboarding, o.UserID, action, before, o); err != nil {
+		return err
+	}
+
+	users := r.store.User().(*UserRepository)
+	payload := &model.OnboardingPayload{
+		Onboarding: o,
+		SupplierID: users.publicID(&o.UserID),
+		ReviewerID: users.publicID(o.ReviewerID),
+	}
+	for _, topic := range o.Events() {
+		if err := r.store.Outbox().(*OutboxRepository).add(topic, payload); err != nil {
+			return err
+		}
+	}
+	o.ClearEvents()
+
+	clone := *o
+	r.onboardings[o.UserID] = &clone
+
+	return nil
+}
+
+// CreateDocument ...
+func (r *OnboardingRepository) CreateDocument(ctx context.Context, d *model.OnboardingDocument) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	d.ID = len(r.documents) + 1
+	d.CreatedAt = time.Now()
+	r.documents = append(r.documents, d)
+
+	return nil
+}
+
+// FindDocuments ...
+func (r *OnboardingRepository) FindDocuments(ctx context.Context, userID int) ([]*model.OnboardingDocument, error) {
+	documents := []*model.OnboardingDocument{}
+	for _, d := range r.documents {
+		if d.UserID == userID {
+			clone := *d
+			clone.Content = nil
+			documents = append(documents, &clone)
+		}
+	}
+
+	return documents, nil
+}
+
+// FindDocument ...
+func (r *OnboardingRepository) FindDocument(ctx context.Context, userID int, id int) (*model.OnboardingDocument, error) {
+	for _, d := range r.documents {
+		if d.UserID == userID && d.ID == id {
+			return d, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+// removeDocuments drops a user's documents when they are anonymized
+func (r *OnboardingRepository) removeDocuments(userID int) {
+	kept := r.documents[:0]
+	for _, d := range r.documents {
+		if d.UserID != userID {
+			kept = append(kept, d)
+		}
+	}
+
+	r.documents = kept
+}
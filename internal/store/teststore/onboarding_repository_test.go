@@ -0,0 +1,28 @@
+package teststore_test
+
+import (
+	"context"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnboardingRepository_Save(t *testing.T) {
+	s := teststore.New()
+	o := model.NewOnboarding(1)
+	assert.NoError(t, s.Onboarding().Save(context.Background(), o))
+	assert.Equal(t, 1, o.Version)
+	assert.Equal(t, store.ErrConflict, s.Onboarding().Save(context.Background(), model.NewOnboarding(1)))
+
+	first, _ := s.Onboarding().Find(context.Background(), 1)
+	second, _ := s.Onboarding().Find(context.Background(), 1)
+	assert.NoError(t, first.Submit(1))
+	assert.NoError(t, s.Onboarding().Save(context.Background(), first))
+	assert.Equal(t, 2, first.Version)
+
+	assert.NoError(t, second.Submit(1))
+	assert.Equal(t, store.ErrConflict, s.Onboarding().Save(context.Background(), second))
+}
@@ -0,0 +1,74 @@
+package teststore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// OrgJSONRepository ...
+type OrgJSONRepository struct {
+	store    *Store
+	orgJSONs []*model.OrgJSON
+}
+
+// Create ...
+func (r *OrgJSONRepository) Create(ctx context.Context, o *model.OrgJSON) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.BeforeCreate(); err != nil {
+		return err
+	}
+
+	o.Version = 1
+	if latest, err := r.FindLatest(ctx, o.UserID); err == nil {
+		o.Version = latest.Version + 1
+	}
+
+	o.ID = len(r.orgJSONs) + 1
+	o.CreatedAt = time.Now()
+	r.orgJSONs = append(r.orgJSONs, o)
+
+	return nil
+}
+
+// FindLatest ...
+func (r *OrgJSONRepository) FindLatest(ctx context.Context, userID int) (*model.OrgJSON, error) {
+	var latest *model.OrgJSON
+	for _, o := range r.orgJSONs {
+		if o.UserID == userID && (latest == nil || o.Version > latest.Version) {
+			latest = o
+		}
+	}
+
+	if latest == nil {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return latest, nil
+}
+
+// FindByVersion ...
+func (r *OrgJSONRepository) FindByVersion(ctx context.Context, userID int, version int) (*model.OrgJSON, error) {
+	for _, o := range r.orgJSONs {
+		if o.UserID == userID && o.Version == version {
+			return o, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+// uploaded reports whether the user uploaded a document with the given hash
+func (r *OrgJSONRepository) uploaded(userID int, hash string) bool {
+	for _, o := range r.orgJSONs {
+		if o.UserID == userID && o.Hash == hash {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"time"
+	"winding-tree-server/internal/model"
+)
+
+// OrgIDRepository ...
+type OrgIDRepository struct {
+	store *Store
+	orgs  map[string]*model.OrgID
+}
+
+// Save ...
+func (r *OrgIDRepository) Save(ctx context.Context, o *model.OrgID) error {
+	if o.UserID != nil && !r.store.OrgJSON().(*OrgJSONRepository).uploaded(*o.UserID, o.OrgJSONHash) {
+		o.UserID = nil
+	}
+
+	o.SyncedAt = time.Now()
+	r.orgs[o.ID] = o
+
+	return nil
+}
+
+// FindByUser ...
+func (r *OrgIDRepository) FindByUser(ctx context.Context, userID int) ([]*model.OrgID, error) {
+	orgs := []*model.OrgID{}
+	for _, o := range r.orgs {
+		if o.UserID != nil && *o.UserID == userID {
+			orgs = append(orgs, o)
+		}
+	}
+
+	sort.Slice(orgs, func(i, j int) bool {
+		return orgs[i].ID < orgs[j].ID
+	})
+
+	return orgs, nil
+}
+
+// Prune ...
+func (r *OrgIDRepository) Prune(ctx context.Context, directory string, listed []string) (int, error) {
+	return r.prune(directory, listed, 0), nil
+}
+
+// prune deletes the organizations of directory that aren't listed, only
+// those linked to tenantID unless it is 0
+func (r *OrgIDRepository) prune(directory string, listed []string, tenantID int) int {
+	keep := make(map[string]bool, len(listed))
+	for _, id := range listed {
+		keep[id] = true
+	}
+
+	n := 0
+	for id, o := range r.orgs {
+		if o.Directory != directory || keep[id] {
+			continue
+		}
+		if tenantID != 0 && (o.UserID == nil || *o.UserID != tenantID) {
+			continue
+		}
+
+		delete(r.orgs, id)
+		n++
+	}
+
+	return n
+}
@@ -0,0 +1,121 @@
+package teststore
+
+import (
+	"context"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// OutboxRepository ...
+type OutboxRepository struct {
+	store  *Store
+	events []*model.OutboxEvent
+	lastID int
+}
+
+// FindUnpublished ...
+func (r *OutboxRepository) FindUnpublished(ctx context.Context, limit int) ([]*model.OutboxEvent, error) {
+	events := []*model.OutboxEvent{}
+	for _, e := range r.events {
+		if e.PublishedAt == nil && e.FailedAt == nil && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+
+	return events, nil
+}
+
+// FindAfter ...
+func (r *OutboxRepository) FindAfter(ctx context.Context, id int, limit int) ([]*model.OutboxEvent, error) {
+	events := []*model.OutboxEvent{}
+	for _, e := range r.events {
+		if e.ID > id && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+
+	return events, nil
+}
+
+// LastID ...
+func (r *OutboxRepository) LastID(ctx context.Context) (int, error) {
+	if len(r.events) == 0 {
+		return 0, nil
+	}
+
+	return r.events[len(r.events)-1].ID, nil
+}
+
+// MarkPublished ...
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int) error {
+	e, err := r.find(id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e.PublishedAt = &now
+
+	return nil
+}
+
+// MarkFailed ...
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int, reason string, retryAt *time.Time) error {
+	e, err := r.find(id)
+	if err != nil {
+		return err
+	}
+
+	e.Attempts++
+	e.LastError = reason
+	e.RetryAt = retryAt
+	if retryAt == nil {
+		now := time.Now()
+		e.FailedAt = &now
+	}
+
+	return nil
+}
+
+// Purge ...
+func (r *OutboxRepository) Purge(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	kept := []*model.OutboxEvent{}
+	for _, e := range r.events {
+		if e.PublishedAt == nil || !e.PublishedAt.Before(t) {
+			kept = append(kept, e)
+		}
+	}
+
+	n := len(r.events) - len(kept)
+	if !dryRun {
+		r.events = kept
+	}
+
+	return n, nil
+}
+
+// add is the in-memory counterpart of sqlstore's insertOutboxEvent
+func (r *OutboxRepository) add(topic string, payload interface{}) error {
+	e, err := model.NewOutboxEvent(topic, payload)
+	if err != nil {
+		return err
+	}
+
+	r.lastID++
+	e.ID = r.lastID
+	e.CreatedAt = time.Now()
+	r.events = append(r.events, e)
+
+	return nil
+}
+
+func (r *OutboxRepository) find(id int) (*model.OutboxEvent, error) {
+	for _, e := range r.events {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
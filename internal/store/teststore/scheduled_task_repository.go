@@ -0,0 +1,75 @@
+package teststore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// ScheduledTaskRepository is safe for concurrent use, as schedulers share it
+type ScheduledTaskRepository struct {
+	mu    sync.Mutex
+	tasks map[string]*model.ScheduledTask
+}
+
+// Claim ...
+func (r *ScheduledTaskRepository) Claim(ctx context.Context, task string, runAt time.Time, lease time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tasks[task]
+	if !ok {
+		t = &model.ScheduledTask{Name: task}
+		r.tasks[task] = t
+	}
+
+	now := time.Now()
+	if (t.LastRunAt != nil && !t.LastRunAt.Before(runAt)) || (t.LockedUntil != nil && !t.LockedUntil.Before(now)) {
+		return false, nil
+	}
+
+	lockedUntil := now.Add(lease)
+	t.LastRunAt = &runAt
+	t.LockedUntil = &lockedUntil
+
+	return true, nil
+}
+
+// Release ...
+func (r *ScheduledTaskRepository) Release(ctx context.Context, task string, runAt time.Time, runErr string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tasks[task]
+	if !ok || t.LastRunAt == nil || !t.LastRunAt.Equal(runAt) {
+		return store.ErrLeaseLost
+	}
+
+	now := time.Now()
+	t.LockedUntil = nil
+	t.FinishedAt = &now
+	t.LastError = runErr
+
+	return nil
+}
+
+// List ...
+func (r *ScheduledTaskRepository) List(ctx context.Context) ([]*model.ScheduledTask, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tasks := make([]*model.ScheduledTask, 0, len(r.tasks))
+	for _, t := range r.tasks {
+		clone := *t
+		tasks = append(tasks, &clone)
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Name < tasks[j].Name
+	})
+
+	return tasks, nil
+}
@@ -0,0 +1,173 @@
+package teststore
+
+import (
+	"sync"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// Store is an in-memory store.Store for tests
+type Store struct {
+	userRepository          *UserRepository
+	orgJSONRepository       *OrgJSONRepository
+	orgIDRepository         *OrgIDRepository
+	contractEventRepository *ContractEventRepository
+	flightRepository        *FlightRepository
+	fareRepository          *FareRepository
+	onboardingRepository    *OnboardingRepository
+	outboxRepository        *OutboxRepository
+	changeRepository        *ChangeRepository
+	jobRepository           *JobRepository
+	scheduledTaskRepository *ScheduledTaskRepository
+	// jobOnce and scheduledTaskOnce create the repositories used concurrently
+	jobOnce           sync.Once
+	scheduledTaskOnce sync.Once
+}
+
+// New ...
+func New() *Store {
+	return &Store{}
+}
+
+// User ...
+func (s *Store) User() store.UserRepository {
+	if s.userRepository != nil {
+		return s.userRepository
+	}
+
+	s.userRepository = &UserRepository{
+		store: s,
+		users: make(map[int]*model.User),
+	}
+
+	return s.userRepository
+}
+
+// OrgJSON ...
+func (s *Store) OrgJSON() store.OrgJSONRepository {
+	if s.orgJSONRepository != nil {
+		return s.orgJSONRepository
+	}
+
+	s.orgJSONRepository = &OrgJSONRepository{
+		store: s,
+	}
+
+	return s.orgJSONRepository
+}
+
+// OrgID ...
+func (s *Store) OrgID() store.OrgIDRepository {
+	if s.orgIDRepository != nil {
+		return s.orgIDRepository
+	}
+
+	s.orgIDRepository = &OrgIDRepository{
+		store: s,
+		orgs:  make(map[string]*model.OrgID),
+	}
+
+	return s.orgIDRepository
+}
+
+// ContractEvent ...
+func (s *Store) ContractEvent() store.ContractEventRepository {
+	if s.contractEventRepository != nil {
+		return s.contractEventRepository
+	}
+
+	s.contractEventRepository = &ContractEventRepository{
+		store:   s,
+		cursors: make(map[string]uint64),
+	}
+
+	return s.contractEventRepository
+}
+
+// Flight ...
+func (s *Store) Flight() store.FlightRepository {
+	if s.flightRepository != nil {
+		return s.flightRepository
+	}
+
+	s.flightRepository = &FlightRepository{
+		store:   s,
+		flights: make(map[int]*model.Flight),
+	}
+
+	return s.flightRepository
+}
+
+// Fare ...
+func (s *Store) Fare() store.FareRepository {
+	if s.fareRepository != nil {
+		return s.fareRepository
+	}
+
+	s.fareRepository = &FareRepository{
+		store: s,
+		fares: make(map[int]*model.Fare),
+	}
+
+	return s.fareRepository
+}
+
+// Onboarding ...
+func (s *Store) Onboarding() store.OnboardingRepository {
+	if s.onboardingRepository != nil {
+		return s.onboardingRepository
+	}
+
+	s.onboardingRepository = &OnboardingRepository{
+		store:       s,
+		onboardings: make(map[int]*model.Onboarding),
+	}
+
+	return s.onboardingRepository
+}
+
+// Outbox ...
+func (s *Store) Outbox() store.OutboxRepository {
+	if s.outboxRepository != nil {
+		return s.outboxRepository
+	}
+
+	s.outboxRepository = &OutboxRepository{
+		store: s,
+	}
+
+	return s.outboxRepository
+}
+
+// Change ...
+func (s *Store) Change() store.ChangeRepository {
+	if s.changeRepository != nil {
+		return s.changeRepository
+	}
+
+	s.changeRepository = &ChangeRepository{
+		store: s,
+	}
+
+	return s.changeRepository
+}
+
+// Job ...
+func (s *Store) Job() store.JobRepository {
+	s.jobOnce.Do(func() {
+		s.jobRepository = &JobRepository{}
+	})
+
+	return s.jobRepository
+}
+
+// ScheduledTask ...
+func (s *Store) ScheduledTask() store.ScheduledTaskRepository {
+	s.scheduledTaskOnce.Do(func() {
+		s.scheduledTaskRepository = &ScheduledTaskRepository{
+			tasks: make(map[string]*model.ScheduledTask),
+		}
+	})
+
+	return s.scheduledTaskRepository
+}
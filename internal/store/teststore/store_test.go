@@ -0,0 +1,14 @@
+package teststore_test
+
+import (
+	"testing"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/storetest"
+	"winding-tree-server/internal/store/teststore"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		return teststore.New(), func() {}
+	})
+}
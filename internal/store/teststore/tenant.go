@@ -0,0 +1,312 @@
+package teststore
+
+import (
+	"context"
+	"strconv"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// tenantStore limits the supplier-owned repositories of a Store to one
+// user, mirroring the scoping sqlstore applies in its queries
+type tenantStore struct {
+	*Store
+	tenantID int
+}
+
+// ForTenant ...
+func (s *Store) ForTenant(tenantID int) store.Store {
+	return &tenantStore{
+		Store:    s,
+		tenantID: tenantID,
+	}
+}
+
+// Flight ...
+func (s *tenantStore) Flight() store.FlightRepository {
+	return &tenantFlightRepository{s.Store.Flight(), s.tenantID}
+}
+
+// Fare ...
+func (s *tenantStore) Fare() store.FareRepository {
+	return &tenantFareRepository{s.Store.Fare(), s.Store.Flight(), s.tenantID}
+}
+
+// Onboarding ...
+func (s *tenantStore) Onboarding() store.OnboardingRepository {
+	return &tenantOnboardingRepository{s.Store.Onboarding(), s.tenantID}
+}
+
+// OrgJSON ...
+func (s *tenantStore) OrgJSON() store.OrgJSONRepository {
+	return &tenantOrgJSONRepository{s.Store.OrgJSON(), s.tenantID}
+}
+
+// OrgID ...
+func (s *tenantStore) OrgID() store.OrgIDRepository {
+	return &tenantOrgIDRepository{s.Store.OrgID(), s.tenantID}
+}
+
+// scopeList returns opts filtered by the tenant, or false when opts already
+// filter by another user and nothing can match
+func scopeList(opts *store.ListOptions, tenantID int) (*store.ListOptions, bool) {
+	tenant := strconv.Itoa(tenantID)
+	filters := map[string]string{"user_id": tenant}
+	for f, v := range opts.Filters {
+		if f == "user_id" && v != tenant {
+			return opts, false
+		}
+
+		filters[f] = v
+	}
+
+	scoped := *opts
+	scoped.Filters = filters
+
+	return &scoped, true
+}
+
+// tenantFlightRepository ...
+type tenantFlightRepository struct {
+	store.FlightRepository
+	tenantID int
+}
+
+// Create ...
+func (r *tenantFlightRepository) Create(ctx context.Context, f *model.Flight) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	if f.UserID != r.tenantID {
+		return store.ErrTenantMismatch
+	}
+
+	return r.FlightRepository.Create(ctx, f)
+}
+
+// Find ...
+func (r *tenantFlightRepository) Find(ctx context.Context, id int) (*model.Flight, error) {
+	f, err := r.FlightRepository.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.UserID != r.tenantID {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return f, nil
+}
+
+// List ...
+func (r *tenantFlightRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Flight, int, error) {
+	scoped, ok := scopeList(opts, r.tenantID)
+	flights, total, err := r.FlightRepository.List(ctx, scoped)
+	if err != nil || !ok {
+		return []*model.Flight{}, 0, err
+	}
+
+	return flights, total, nil
+}
+
+// tenantFareRepository ...
+type tenantFareRepository struct {
+	store.FareRepository
+	flights  store.FlightRepository
+	tenantID int
+}
+
+// checkFlight ...
+func (r *tenantFareRepository) checkFlight(ctx context.Context, flightID int) error {
+	f, err := r.flights.Find(ctx, flightID)
+	if err != nil {
+		return err
+	}
+
+	if f.UserID != r.tenantID {
+		return store.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Create ...
+func (r *tenantFareRepository) Create(ctx context.Context, f *model.Fare) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.checkFlight(ctx, f.FlightID); err != nil {
+		return err
+	}
+
+	return r.FareRepository.Create(ctx, f)
+}
+
+// Upsert ...
+func (r *tenantFareRepository) Upsert(ctx context.Context, fares []*model.Fare) error {
+	for _, f := range fares {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fares {
+		if err := r.checkFlight(ctx, f.FlightID); err != nil {
+			return err
+		}
+	}
+
+	return r.FareRepository.Upsert(ctx, fares)
+}
+
+// Search ...
+func (r *tenantFareRepository) Search(ctx context.Context, s *model.FlightSearch) ([]*model.FlightOffer, error) {
+	offers, err := r.FareRepository.Search(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	scoped := []*model.FlightOffer{}
+	for _, o := range offers {
+		if o.Flight.UserID == r.tenantID {
+			scoped = append(scoped, o)
+		}
+	}
+
+	return scoped, nil
+}
+
+// tenantOnboardingRepository ...
+type tenantOnboardingRepository struct {
+	store.OnboardingRepository
+	tenantID int
+}
+
+// Find ...
+func (r *tenantOnboardingRepository) Find(ctx context.Context, userID int) (*model.Onboarding, error) {
+	if userID != r.tenantID {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return r.OnboardingRepository.Find(ctx, userID)
+}
+
+// List ...
+func (r *tenantOnboardingRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.Onboarding, int, error) {
+	scoped, ok := scopeList(opts, r.tenantID)
+	onboardings, total, err := r.OnboardingRepository.List(ctx, scoped)
+	if err != nil || !ok {
+		return []*model.Onboarding{}, 0, err
+	}
+
+	return onboardings, total, nil
+}
+
+// Save ...
+func (r *tenantOnboardingRepository) Save(ctx context.Context, o *model.Onboarding) error {
+	if o.UserID != r.tenantID {
+		return store.ErrTenantMismatch
+	}
+
+	return r.OnboardingRepository.Save(ctx, o)
+}
+
+// CreateDocument ...
+func (r *tenantOnboardingRepository) CreateDocument(ctx context.Context, d *model.OnboardingDocument) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+
+	if d.UserID != r.tenantID {
+		return store.ErrTenantMismatch
+	}
+
+	return r.OnboardingRepository.CreateDocument(ctx, d)
+}
+
+// FindDocuments ...
+func (r *tenantOnboardingRepository) FindDocuments(ctx context.Context, userID int) ([]*model.OnboardingDocument, error) {
+	if userID != r.tenantID {
+		return []*model.OnboardingDocument{}, nil
+	}
+
+	return r.OnboardingRepository.FindDocuments(ctx, userID)
+}
+
+// FindDocument ...
+func (r *tenantOnboardingRepository) FindDocument(ctx context.Context, userID int, id int) (*model.OnboardingDocument, error) {
+	if userID != r.tenantID {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return r.OnboardingRepository.FindDocument(ctx, userID, id)
+}
+
+// tenantOrgJSONRepository ...
+type tenantOrgJSONRepository struct {
+	store.OrgJSONRepository
+	tenantID int
+}
+
+// Create ...
+func (r *tenantOrgJSONRepository) Create(ctx context.Context, o *model.OrgJSON) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	if o.UserID != r.tenantID {
+		return store.ErrTenantMismatch
+	}
+
+	return r.OrgJSONRepository.Create(ctx, o)
+}
+
+// FindLatest ...
+func (r *tenantOrgJSONRepository) FindLatest(ctx context.Context, userID int) (*model.OrgJSON, error) {
+	if userID != r.tenantID {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return r.OrgJSONRepository.FindLatest(ctx, userID)
+}
+
+// FindByVersion ...
+func (r *tenantOrgJSONRepository) FindByVersion(ctx context.Context, userID int, version int) (*model.OrgJSON, error) {
+	if userID != r.tenantID {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return r.OrgJSONRepository.FindByVersion(ctx, userID, version)
+}
+
+// tenantOrgIDRepository ...
+type tenantOrgIDRepository struct {
+	store.OrgIDRepository
+	tenantID int
+}
+
+// Save only refreshes organizations that are linked to the tenant
+func (r *tenantOrgIDRepository) Save(ctx context.Context, o *model.OrgID) error {
+	s := r.OrgIDRepository.(*OrgIDRepository)
+	if o.UserID == nil || *o.UserID != r.tenantID || !s.store.OrgJSON().(*OrgJSONRepository).uploaded(r.tenantID, o.OrgJSONHash) {
+		return store.ErrTenantMismatch
+	}
+
+	return s.Save(ctx, o)
+}
+
+// FindByUser ...
+func (r *tenantOrgIDRepository) FindByUser(ctx context.Context, userID int) ([]*model.OrgID, error) {
+	if userID != r.tenantID {
+		return []*model.OrgID{}, nil
+	}
+
+	return r.OrgIDRepository.FindByUser(ctx, userID)
+}
+
+// Prune only deletes organizations linked to the tenant
+func (r *tenantOrgIDRepository) Prune(ctx context.Context, directory string, listed []string) (int, error) {
+	return r.OrgIDRepository.(*OrgIDRepository).prune(directory, listed, r.tenantID), nil
+}
@@ -0,0 +1,224 @@
+package teststore
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+)
+
+// UserRepository ...
+type UserRepository struct {
+	store *Store
+	users map[int]*model.User
+}
+
+// Create ...
+func (r *UserRepository) Create(ctx context.Context, u *model.User) error {
+	if err := u.Validate(); err != nil {
+		return err
+	}
+
+	if err := u.BeforeCreate(); err != nil {
+		return err
+	}
+
+	for _, existing := range r.users {
+		if existing.Email == u.Email {
+			return store.ErrRecordExists
+		}
+	}
+
+	u.ID = len(r.users) + 1
+	r.users[u.ID] = u
+
+	return r.store.Change().(*ChangeRepository).add(model.EntityUser, u.ID, model.ChangeCreate, nil, snapshot(u))
+}
+
+// Find ...
+func (r *UserRepository) Find(ctx context.Context, id int) (*model.User, error) {
+	u, ok := r.users[id]
+	if !ok || u.DeletedAt != nil {
+		return nil, store.ErrRecordNotFound
+	}
+
+	return u, nil
+}
+
+// FindByEmail ...
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	for _, u := range r.users {
+		if u.Email == email && u.DeletedAt == nil {
+			return u, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+// FindByPublicID ...
+func (r *UserRepository) FindByPublicID(ctx context.Context, publicID string) (*model.User, error) {
+	for _, u := range r.users {
+		if u.PublicID == publicID && u.DeletedAt == nil {
+			return u, nil
+		}
+	}
+
+	return nil, store.ErrRecordNotFound
+}
+
+// List ...
+func (r *UserRepository) List(ctx context.Context, opts *store.ListOptions) ([]*model.User, int, error) {
+	return r.list(opts, func(u *model.User) bool {
+		return true
+	})
+}
+
+// Search matches words anywhere in the email, which is looser than sqlstore's prefix match
+func (r *UserRepository) Search(ctx context.Context, query string, opts *store.ListOptions) ([]*model.User, int, error) {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	return r.list(opts, func(u *model.User) bool {
+		if len(words) == 0 {
+			return false
+		}
+
+		parts := strings.FieldsFunc(strings.ToLower(u.Email), func(r rune) bool {
+			return strings.ContainsRune("@.-_+", r)
+		})
+		for _, w := range words {
+			if !hasPrefix(parts, w) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+func (r *UserRepository) list(opts *store.ListOptions, match func(*model.User) bool) ([]*model.User, int, error) {
+	all := make([]*model.User, 0, len(r.users))
+	for _, u := range r.users {
+		if match(u) {
+			all = append(all, u)
+		}
+	}
+
+	indexes, total, err := list(opts, len(all), func(i int, field string) interface{} {
+		switch field {
+		case "id":
+			return all[i].ID
+		case "public_id":
+			return all[i].PublicID
+		case "email":
+			return all[i].Email
+		case "is_admin":
+			return all[i].IsAdmin
+		case "deleted":
+			return all[i].DeletedAt != nil
+		}
+
+		return nil
+	}, "id", "id", "public_id", "email", "is_admin", "deleted")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users := make([]*model.User, 0, len(indexes))
+	for _, i := range indexes {
+		users = append(users, all[i])
+	}
+
+	return users, total, nil
+}
+
+// Delete ...
+func (r *UserRepository) Delete(ctx context.Context, id int) error {
+	u, ok := r.users[id]
+	if !ok || u.DeletedAt != nil {
+		return store.ErrRecordNotFound
+	}
+
+	before := snapshot(u)
+	now := time.Now()
+	u.DeletedAt = &now
+
+	return r.store.Change().(*ChangeRepository).add(model.EntityUser, id, model.ChangeDelete, before, snapshot(u))
+}
+
+// Restore ...
+func (r *UserRepository) Restore(ctx context.Context, id int) error {
+	u, ok := r.users[id]
+	if !ok || u.DeletedAt == nil || u.AnonymizedAt != nil {
+		return store.ErrRecordNotFound
+	}
+
+	before := snapshot(u)
+	u.DeletedAt = nil
+
+	return r.store.Change().(*ChangeRepository).add(model.EntityUser, id, model.ChangeRestore, before, snapshot(u))
+}
+
+// Anonymize ...
+func (r *UserRepository) Anonymize(ctx context.Context, t time.Time, dryRun bool) (int, error) {
+	n := 0
+	for id, u := range r.users {
+		if u.DeletedAt == nil || !u.DeletedAt.Before(t) || u.AnonymizedAt != nil {
+			continue
+		}
+
+		n++
+		if dryRun {
+			continue
+		}
+
+		now := time.Now()
+		u.Email = "anonymized-" + u.PublicID + "@example.invalid"
+		u.Password = ""
+		u.EncryptedPassword = ""
+		u.AnonymizedAt = &now
+
+		r.store.Onboarding().(*OnboardingRepository).removeDocuments(id)
+		changes := r.store.Change().(*ChangeRepository)
+		changes.remove(func(c *model.Change) bool {
+			return c.Entity == model.EntityUser && c.EntityID == id
+		})
+		if err := changes.add(model.EntityUser, id, model.ChangeAnonymize, nil, snapshot(u)); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// snapshot copies u without the plain password, which stored users keep here
+func snapshot(u *model.User) *model.User {
+	clone := *u
+	clone.Sanitize()
+	return &clone
+}
+
+// hasPrefix reports whether one of parts starts with prefix
+func hasPrefix(parts []string, prefix string) bool {
+	for _, p := range parts {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// publicID is the in-memory counterpart of sqlstore's publicUserID; unknown
+// users have none, as nothing enforces them here
+func (r *UserRepository) publicID(id *int) string {
+	if id == nil || r.users[*id] == nil {
+		return ""
+	}
+
+	return r.users[*id].PublicID
+}
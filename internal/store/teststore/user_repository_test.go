@@ -0,0 +1,94 @@
+package teststore_test
+
+import (
+	"context"
+	"testing"
+	"winding-tree-server/internal/model"
+	"winding-tree-server/internal/store"
+	"winding-tree-server/internal/store/teststore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepository_Create(t *testing.T) {
+	s := teststore.New()
+	u := model.TestUser(t)
+	assert.NoError(t, s.User().Create(context.Background(), u))
+	assert.NotNil(t, u)
+	assert.Equal(t, store.ErrRecordExists, s.User().Create(context.Background(), model.TestUser(t)))
+}
+
+func TestUserRepository_Find(t *testing.T) {
+	s := teststore.New()
+	_, err := s.User().Find(context.Background(), 1)
+	assert.EqualError(t, err, store.ErrRecordNotFound.Error())
+
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+	u, err = s.User().Find(context.Background(), u.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, u)
+}
+
+func TestUserRepository_FindByEmail(t *testing.T) {
+	s := teststore.New()
+	email := "user@example.org"
+	_, err := s.User().FindByEmail(context.Background(), email)
+	assert.EqualError(t, err, store.ErrRecordNotFound.Error())
+
+	u := model.TestUser(t)
+	u.Email = email
+	s.User().Create(context.Background(), u)
+	u, err = s.User().FindByEmail(context.Background(), email)
+	assert.NoError(t, err)
+	assert.NotNil(t, u)
+}
+
+func TestUserRepository_List(t *testing.T) {
+	s := teststore.New()
+	for _, email := range []string{"c@example.org", "a@example.org", "b@example.org"} {
+		u := model.TestUser(t)
+		u.Email = email
+		s.User().Create(context.Background(), u)
+	}
+
+	users, total, err := s.User().List(context.Background(), &store.ListOptions{Limit: 2, Offset: 1, Sort: "-email"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, "b@example.org", users[0].Email)
+		assert.Equal(t, "a@example.org", users[1].Email)
+	}
+
+	users, total, err = s.User().List(context.Background(), &store.ListOptions{Limit: 10, Filters: map[string]string{"email": "a@example.org"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, users, 1)
+
+	_, _, err = s.User().List(context.Background(), &store.ListOptions{Limit: 10, Sort: "password"})
+	assert.Equal(t, store.ErrInvalidListOptions, err)
+}
+
+func TestUserRepository_Delete(t *testing.T) {
+	s := teststore.New()
+	u := model.TestUser(t)
+	s.User().Create(context.Background(), u)
+
+	assert.NoError(t, s.User().Delete(context.Background(), u.ID))
+	assert.Equal(t, store.ErrRecordNotFound, s.User().Delete(context.Background(), u.ID))
+
+	_, err := s.User().Find(context.Background(), u.ID)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+	_, err = s.User().FindByEmail(context.Background(), u.Email)
+	assert.Equal(t, store.ErrRecordNotFound, err)
+
+	users, total, err := s.User().List(context.Background(), &store.ListOptions{Limit: 10, Filters: map[string]string{"deleted": "true"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	assert.Len(t, users, 1)
+
+	assert.NoError(t, s.User().Restore(context.Background(), u.ID))
+	assert.Equal(t, store.ErrRecordNotFound, s.User().Restore(context.Background(), u.ID))
+	_, err = s.User().Find(context.Background(), u.ID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,242 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// otlpTracesPath is appended to the collector's base URL
+	otlpTracesPath = "/v1/traces"
+	otlpTimeout    = 10 * time.Second
+
+	// OTLP status codes
+	statusOK    = 1
+	statusError = 2
+)
+
+// OTLPExporter sends spans in batches to an OpenTelemetry collector over
+// OTLP/HTTP, in its JSON encoding. Spans are dropped when the collector
+// can't keep up rather than slowing requests down.
+type OTLPExporter struct {
+	url       string
+	service   string
+	logger    *logrus.Logger
+	spans     chan *Span
+	batchSize int
+	interval  time.Duration
+	// client doesn't use http.DefaultTransport, which may be traced itself
+	client *http.Client
+}
+
+// NewOTLPExporter exports to the collector at endpoint, such as
+// http://localhost:4318, on behalf of service
+func NewOTLPExporter(endpoint, service string, logger *logrus.Logger) *OTLPExporter {
+	return &OTLPExporter{
+		url:       strings.TrimRight(endpoint, "/") + otlpTracesPath,
+		service:   service,
+		logger:    logger,
+		spans:     make(chan *Span, 2048),
+		batchSize: 512,
+		interval:  5 * time.Second,
+		client: &http.Client{
+			Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+			Timeout:   otlpTimeout,
+		},
+	}
+}
+
+// Export never blocks
+func (e *OTLPExporter) Export(s *Span) {
+	select {
+	case e.spans <- s:
+	default:
+		e.logger.Debugf("span %s dropped, export queue is full", s.Name)
+	}
+}
+
+// Run sends spans until ctx is done, then sends those still queued
+func (e *OTLPExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, e.batchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := e.send(ctx, batch); err != nil {
+			e.logger.Warnf("exporting %d spans failed: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+		drain:
+			for {
+				select {
+				case s := <-e.spans:
+					batch = append(batch, s)
+				default:
+					break drain
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+			flush(ctx)
+			cancel()
+			return
+		case s := <-e.spans:
+			batch = append(batch, s)
+			if len(batch) >= e.batchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+// send ...
+func (e *OTLPExporter) send(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("collector responded %s", res.Status)
+	}
+
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              Kind            `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// encode ...
+func (e *OTLPExporter) encode(spans []*Span) *otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           s.Context.TraceID.String(),
+			SpanID:            s.Context.SpanID.String(),
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+			Attributes:        attributes(s.Attributes),
+			Status:            otlpStatus{Code: statusOK},
+		}
+		if s.Parent != (SpanID{}) {
+			span.ParentSpanID = s.Parent.String()
+		}
+		if s.Err != nil {
+			span.Status = otlpStatus{Code: statusError, Message: s.Err.Error()}
+		}
+		s.mu.Unlock()
+
+		encoded = append(encoded, span)
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: attributes(map[string]interface{}{"service.name": e.service}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "winding-tree-server/internal/tracing"},
+				Spans: encoded,
+			}},
+		}},
+	}
+}
+
+// attributes encodes values by type; 64-bit integers are strings in OTLP JSON
+func attributes(values map[string]interface{}) []otlpAttribute {
+	encoded := make([]otlpAttribute, 0, len(values))
+	for key, value := range values {
+		var v map[string]interface{}
+		switch value := value.(type) {
+		case bool:
+			v = map[string]interface{}{"boolValue": value}
+		case int:
+			v = map[string]interface{}{"intValue": strconv.Itoa(value)}
+		case int64:
+			v = map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}
+		case float64:
+			v = map[string]interface{}{"doubleValue": value}
+		default:
+			v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+		}
+
+		encoded = append(encoded, otlpAttribute{Key: key, Value: v})
+	}
+
+	return encoded
+}
@@ -0,0 +1,206 @@
+// Package tracing records spans in the OpenTelemetry model, propagates them
+// with W3C trace context headers and exports them over OTLP/HTTP, so that
+// traces can be followed across services in any OpenTelemetry backend.
+//
+// A nil *Tracer and the nil spans it starts do nothing, so code can be
+// instrumented whether or not tracing is configured.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Kind is the role of a span in a trace
+type Kind int
+
+// Kinds, numbered as in OTLP
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// TraceID ...
+type TraceID [16]byte
+
+// String ...
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID ...
+type SpanID [8]byte
+
+// String ...
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext identifies a span, possibly one in another service
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid ...
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+type ctxKey int8
+
+const ctxKeySpanContext ctxKey = iota
+
+// ContextWithSpanContext makes sc the parent of spans started from the
+// returned context
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, ctxKeySpanContext, sc)
+}
+
+// SpanContextFromContext ...
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(ctxKeySpanContext).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// Exporter sends finished, sampled spans to a backend
+type Exporter interface {
+	Export(s *Span)
+}
+
+// Tracer starts spans and hands them to its exporter when they end
+type Tracer struct {
+	exporter Exporter
+	// sampleRatio of new traces are recorded; traces started elsewhere
+	// follow the sampling decision of the caller
+	sampleRatio float64
+}
+
+// NewTracer ...
+func NewTracer(exporter Exporter, sampleRatio float64) *Tracer {
+	return &Tracer{
+		exporter:    exporter,
+		sampleRatio: sampleRatio,
+	}
+}
+
+// Start starts a span, a child of the span in ctx if there is one. The
+// returned context carries the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	s := &Span{
+		tracer:     t,
+		Name:       name,
+		Kind:       kind,
+		StartTime:  time.Now(),
+		Attributes: map[string]interface{}{},
+	}
+
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		s.Context.TraceID = parent.TraceID
+		s.Context.Sampled = parent.Sampled
+		s.Parent = parent.SpanID
+	} else {
+		s.Context.TraceID = newTraceID()
+		s.Context.Sampled = t.sample(s.Context.TraceID)
+	}
+	s.Context.SpanID = newSpanID()
+
+	return ContextWithSpanContext(ctx, s.Context), s
+}
+
+// sample decides from the trace ID alone, so every service sampling at the
+// same ratio makes the same decision
+func (t *Tracer) sample(id TraceID) bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+
+	return binary.BigEndian.Uint64(id[8:])>>1 < uint64(t.sampleRatio*(1<<63))
+}
+
+// Span is a timed operation
+type Span struct {
+	tracer     *Tracer
+	mu         sync.Mutex
+	ended      bool
+	Name       string
+	Kind       Kind
+	Context    SpanContext
+	Parent     SpanID
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]interface{}
+	// Err marks the span as failed
+	Err error
+}
+
+// SetAttribute records a string, bool, int, int64 or float64 value
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+// SetError ...
+func (s *Span) SetError(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.Err = err
+	s.mu.Unlock()
+}
+
+// End finishes the span and exports it if it is sampled. Only the first
+// call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+
+	if s.Context.Sampled && s.tracer.exporter != nil {
+		s.tracer.exporter.Export(s)
+	}
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for id == (TraceID{}) {
+		rand.Read(id[:])
+	}
+
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for id == (SpanID{}) {
+		rand.Read(id[:])
+	}
+
+	return id
+}
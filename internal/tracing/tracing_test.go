@@ -0,0 +1,208 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"winding-tree-server/internal/tracing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder keeps exported spans
+type recorder struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+func (r *recorder) Export(s *tracing.Span) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+func TestTracer_Start(t *testing.T) {
+	r := &recorder{}
+	tracer := tracing.NewTracer(r, 1)
+
+	ctx, root := tracer.Start(context.Background(), "root", tracing.KindServer)
+	_, child := tracer.Start(ctx, "child", tracing.KindClient)
+	child.SetAttribute("n", 1)
+	child.End()
+	child.End()
+	root.End()
+
+	if assert.Len(t, r.spans, 2) {
+		assert.Equal(t, "child", r.spans[0].Name)
+		assert.Equal(t, root.Context.TraceID, child.Context.TraceID)
+		assert.Equal(t, root.Context.SpanID, child.Parent)
+		assert.Equal(t, tracing.SpanID{}, root.Parent)
+		assert.Equal(t, 1, child.Attributes["n"])
+	}
+
+	var disabled *tracing.Tracer
+	ctx, span := disabled.Start(context.Background(), "nothing", tracing.KindInternal)
+	span.SetAttribute("n", 1)
+	span.End()
+	_, ok := tracing.SpanContextFromContext(ctx)
+	assert.False(t, ok)
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	r := &recorder{}
+	tracer := tracing.NewTracer(r, 0)
+
+	_, span := tracer.Start(context.Background(), "unsampled", tracing.KindServer)
+	span.End()
+	assert.Empty(t, r.spans)
+
+	// The caller's decision wins over the ratio
+	ctx := tracing.ContextWithSpanContext(context.Background(), tracing.SpanContext{
+		TraceID: tracing.TraceID{1},
+		SpanID:  tracing.SpanID{1},
+		Sampled: true,
+	})
+	_, span = tracer.Start(ctx, "sampled", tracing.KindServer)
+	span.End()
+	assert.Len(t, r.spans, 1)
+}
+
+func TestExtract(t *testing.T) {
+	testCases := []struct {
+		name        string
+		traceparent string
+		isValid     bool
+		sampled     bool
+	}{
+		{
+			name:        "sampled",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			isValid:     true,
+			sampled:     true,
+		},
+		{
+			name:        "not sampled",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			isValid:     true,
+		},
+		{
+			name:        "future version with more fields",
+			traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+			isValid:     true,
+			sampled:     true,
+		},
+		{
+			name:        "missing",
+			traceparent: "",
+		},
+		{
+			name:        "invalid version",
+			traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:        "uppercase",
+			traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:        "zero trace id",
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+		{
+			name:        "short span id",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := http.Header{}
+			h.Set(tracing.TraceparentHeader, tc.traceparent)
+			sc, ok := tracing.Extract(h)
+			assert.Equal(t, tc.isValid, ok)
+			if ok {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+				assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+				assert.Equal(t, tc.sampled, sc.Sampled)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(tracing.TraceparentHeader)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	r := &recorder{}
+	tracer := tracing.NewTracer(r, 1)
+	client := &http.Client{Transport: tracing.NewTransport(http.DefaultTransport, tracer)}
+
+	ctx, parent := tracer.Start(context.Background(), "parent", tracing.KindInternal)
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/?key=secret", nil)
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	assert.Empty(t, req.Header.Get(tracing.TraceparentHeader), "the caller's request is unchanged")
+	if assert.Len(t, r.spans, 1) {
+		span := r.spans[0]
+		assert.Equal(t, parent.Context.SpanID, span.Parent)
+		assert.Equal(t, "00-"+span.Context.TraceID.String()+"-"+span.Context.SpanID.String()+"-01", traceparent)
+		assert.Equal(t, http.StatusBadGateway, span.Attributes["http.status_code"])
+		assert.Error(t, span.Err)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		body := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		received <- body
+	}))
+	defer ts.Close()
+
+	exporter := tracing.NewOTLPExporter(ts.URL, "test-service", logrus.New())
+	tracer := tracing.NewTracer(exporter, 1)
+	_, span := tracer.Start(context.Background(), "GET /suppliers/:id", tracing.KindServer)
+	span.SetAttribute("http.status_code", 200)
+	span.End()
+
+	// Queued spans are sent when the exporter stops
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	exporter.Run(ctx)
+
+	select {
+	case body := <-received:
+		resourceSpans := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+		resource := resourceSpans["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "service.name", resource["key"])
+		assert.Equal(t, map[string]interface{}{"stringValue": "test-service"}, resource["value"])
+
+		spans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+		if assert.Len(t, spans, 1) {
+			s := spans[0].(map[string]interface{})
+			assert.Equal(t, span.Context.TraceID.String(), s["traceId"])
+			assert.Equal(t, "GET /suppliers/:id", s["name"])
+			assert.Equal(t, float64(tracing.KindServer), s["kind"])
+			assert.Equal(t, []interface{}{map[string]interface{}{
+				"key":   "http.status_code",
+				"value": map[string]interface{}{"intValue": "200"},
+			}}, s["attributes"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no spans exported")
+	}
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"net/http"
+)
+
+// Transport traces outgoing requests and passes the trace context on to
+// the services they reach
+type Transport struct {
+	next   http.RoundTripper
+	tracer *Tracer
+}
+
+// NewTransport ...
+func NewTransport(next http.RoundTripper, tracer *Tracer) *Transport {
+	return &Transport{
+		next:   next,
+		tracer: tracer,
+	}
+}
+
+// RoundTrip ...
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), "HTTP "+req.Method, KindClient)
+	// URLs may hold credentials, such as API keys in RPC paths, so only the
+	// host is recorded
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.host", req.URL.Host)
+
+	// A RoundTripper must not modify the caller's request
+	req = req.WithContext(ctx)
+	header := make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		header[k] = v
+	}
+	req.Header = header
+	Inject(ctx, req.Header)
+
+	res, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.SetError(err)
+	} else {
+		span.SetAttribute("http.status_code", res.StatusCode)
+		if res.StatusCode >= 500 {
+			span.SetError(errServerError(res.Status))
+		}
+	}
+	span.End()
+
+	return res, err
+}
+
+// errServerError is a 5xx response, which fails a client span
+type errServerError string
+
+func (e errServerError) Error() string {
+	return string(e)
+}
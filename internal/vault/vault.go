@@ -0,0 +1,171 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RefPrefix marks config values that are references to Vault secrets
+const RefPrefix = "vault:"
+
+var (
+	// ErrInvalidRef ...
+	ErrInvalidRef = errors.New("vault references look like vault:secret/path#key")
+	// ErrKeyNotFound ...
+	ErrKeyNotFound = errors.New("key not found in vault secret")
+)
+
+// Client is a minimal client for the Vault HTTP API
+type Client struct {
+	address    string
+	token      string
+	httpClient *http.Client
+}
+
+// Secret is a secret read from Vault
+type Secret struct {
+	Data map[string]interface{}
+	// LeaseID is set for dynamic secrets, such as database credentials,
+	// which expire unless renewed
+	LeaseID       string
+	LeaseDuration time.Duration
+	Renewable     bool
+}
+
+type secretResponse struct {
+	Data          map[string]interface{} `json:"data"`
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Errors        []string               `json:"errors"`
+}
+
+// NewClient ...
+func NewClient(address, token string) *Client {
+	return &Client{
+		address: strings.TrimRight(address, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+// Read reads the secret at path. Key/value version 2 secrets are unwrapped;
+// their paths include data/, as in secret/data/winding-tree.
+func (c *Client) Read(ctx context.Context, path string) (*Secret, error) {
+	return c.do(ctx, http.MethodGet, "/v1/"+strings.TrimLeft(path, "/"), nil)
+}
+
+// Renew extends the lease of a dynamic secret by increment
+func (c *Client) Renew(ctx context.Context, leaseID string, increment time.Duration) (*Secret, error) {
+	return c.do(ctx, http.MethodPut, "/v1/sys/leases/renew", map[string]interface{}{
+		"lease_id":  leaseID,
+		"increment": int(increment.Seconds()),
+	})
+}
+
+// KeepRenewed renews the lease of a dynamic secret halfway through its
+// duration until ctx is done. Failures are retried until the lease runs out.
+func (c *Client) KeepRenewed(ctx context.Context, s *Secret, logger *logrus.Logger) {
+	if !s.Renewable || s.LeaseID == "" {
+		return
+	}
+
+	duration := s.LeaseDuration
+	expires := time.Now().Add(duration)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(duration / 2):
+		}
+
+		renewed, err := c.Renew(ctx, s.LeaseID, s.LeaseDuration)
+		if err != nil {
+			logger.Warnf("renewing vault lease %s failed: %v", s.LeaseID, err)
+			duration = time.Until(expires)
+			if duration < time.Second {
+				logger.Errorf("vault lease %s expired", s.LeaseID)
+				return
+			}
+			continue
+		}
+
+		duration = renewed.LeaseDuration
+		expires = time.Now().Add(duration)
+		if !renewed.Renewable || duration <= 0 {
+			return
+		}
+	}
+}
+
+// do ...
+func (c *Client) do(ctx context.Context, method, path string, body interface{}) (*Secret, error) {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.address+path, &buf)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Vault-Token", c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resp := &secretResponse{}
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil && res.StatusCode < 300 {
+		return nil, err
+	}
+
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("vault %s: %s %v", path, res.Status, resp.Errors)
+	}
+
+	data := resp.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	return &Secret{
+		Data:          data,
+		LeaseID:       resp.LeaseID,
+		LeaseDuration: time.Duration(resp.LeaseDuration) * time.Second,
+		Renewable:     resp.Renewable,
+	}, nil
+}
+
+// ParseRef splits a vault:path#key reference
+func ParseRef(ref string) (string, string, error) {
+	if !strings.HasPrefix(ref, RefPrefix) {
+		return "", "", ErrInvalidRef
+	}
+
+	ref = strings.TrimPrefix(ref, RefPrefix)
+	i := strings.LastIndex(ref, "#")
+	if i < 1 || i == len(ref)-1 {
+		return "", "", ErrInvalidRef
+	}
+
+	return ref[:i], ref[i+1:], nil
+}
@@ -0,0 +1,144 @@
+package vault_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"winding-tree-server/internal/vault"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Read(t *testing.T) {
+	s := testVaultServer(t, map[string]string{
+		"/v1/secret/data/wt": `{"data": {"data": {"session_key": "s3cret"}, "metadata": {"version": 2}}}`,
+		"/v1/kv/wt":          `{"data": {"session_key": "s3cret"}}`,
+		"/v1/database/creds/wt": `{"lease_id": "database/creds/wt/1", "lease_duration": 3600, "renewable": true,
+			"data": {"username": "wt-1", "password": "p"}}`,
+	})
+	defer s.Close()
+
+	c := vault.NewClient(s.URL+"/", "token")
+
+	testCases := []struct {
+		name    string
+		path    string
+		data    map[string]interface{}
+		lease   time.Duration
+		isValid bool
+	}{
+		{
+			name:    "kv version 2",
+			path:    "secret/data/wt",
+			data:    map[string]interface{}{"session_key": "s3cret"},
+			isValid: true,
+		},
+		{
+			name:    "kv version 1",
+			path:    "/kv/wt",
+			data:    map[string]interface{}{"session_key": "s3cret"},
+			isValid: true,
+		},
+		{
+			name:    "dynamic",
+			path:    "database/creds/wt",
+			data:    map[string]interface{}{"username": "wt-1", "password": "p"},
+			lease:   time.Hour,
+			isValid: true,
+		},
+		{
+			name:    "not found",
+			path:    "secret/data/missing",
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secret, err := c.Read(context.Background(), tc.path)
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.data, secret.Data)
+			assert.Equal(t, tc.lease, secret.LeaseDuration)
+		})
+	}
+}
+
+func TestClient_KeepRenewed(t *testing.T) {
+	renewals := make(chan string, 10)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&body)
+		renewals <- body["lease_id"].(string)
+
+		// The lease can't be extended further
+		w.Write([]byte(`{"lease_id": "l1", "lease_duration": 1, "renewable": false}`))
+	}))
+	defer s.Close()
+
+	c := vault.NewClient(s.URL, "token")
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+
+	c.KeepRenewed(context.Background(), &vault.Secret{LeaseID: "l1", LeaseDuration: time.Hour}, logger)
+	assert.Len(t, renewals, 0, "not renewable")
+
+	c.KeepRenewed(context.Background(), &vault.Secret{LeaseID: "l1", LeaseDuration: 20 * time.Millisecond, Renewable: true}, logger)
+	assert.Len(t, renewals, 1)
+	assert.Equal(t, "l1", <-renewals)
+}
+
+func TestParseRef(t *testing.T) {
+	testCases := []struct {
+		ref     string
+		path    string
+		key     string
+		isValid bool
+	}{
+		{ref: "vault:secret/data/wt#session_key", path: "secret/data/wt", key: "session_key", isValid: true},
+		{ref: "vault:secret/data/wt#a#b", path: "secret/data/wt#a", key: "b", isValid: true},
+		{ref: "vault:secret/data/wt", isValid: false},
+		{ref: "vault:#key", isValid: false},
+		{ref: "vault:secret/data/wt#", isValid: false},
+		{ref: "secret/data/wt#key", isValid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.ref, func(t *testing.T) {
+			path, key, err := vault.ParseRef(tc.ref)
+			if !tc.isValid {
+				assert.Equal(t, vault.ErrInvalidRef, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.path, path)
+			assert.Equal(t, tc.key, key)
+		})
+	}
+}
+
+// testVaultServer answers GET requests for paths with canned responses
+func testVaultServer(t *testing.T, responses map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+			return
+		}
+
+		w.Write([]byte(response))
+	}))
+}
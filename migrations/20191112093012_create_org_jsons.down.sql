@@ -0,0 +1 @@
+DROP TABLE org_jsons;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE org_jsons(
+    id bigserial not null primary key,
+    user_id bigint not null references users (id),
+    version integer not null,
+    -- stored as text, not jsonb, so the served bytes match the registered hash
+    document text not null,
+    hash varchar(66) not null,
+    created_at timestamptz not null default now(),
+    unique (user_id, version)
+)
@@ -0,0 +1 @@
+DROP TABLE orgids;
\ No newline at end of file
@@ -0,0 +1,12 @@
+CREATE TABLE orgids(
+    id varchar(66) not null primary key,
+    directory varchar(42) not null,
+    orgjson_uri varchar not null,
+    orgjson_hash varchar(66) not null,
+    owner varchar(42) not null,
+    is_active boolean not null,
+    user_id bigint references users (id),
+    synced_at timestamptz not null
+);
+
+CREATE INDEX orgids_user_id_idx ON orgids (user_id);
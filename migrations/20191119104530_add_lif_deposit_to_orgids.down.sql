@@ -0,0 +1 @@
+ALTER TABLE orgids DROP COLUMN lif_deposit;
\ No newline at end of file
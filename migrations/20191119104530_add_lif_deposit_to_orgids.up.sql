@@ -0,0 +1 @@
+ALTER TABLE orgids ADD COLUMN lif_deposit numeric(78, 0) not null default 0;
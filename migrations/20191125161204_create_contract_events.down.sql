@@ -0,0 +1,2 @@
+DROP TABLE contract_event_cursors;
+DROP TABLE contract_events;
\ No newline at end of file
@@ -0,0 +1,21 @@
+CREATE TABLE contract_events(
+    id bigserial not null primary key,
+    address varchar(42) not null,
+    topic varchar(66) not null,
+    topics varchar(66)[] not null,
+    data bytea not null,
+    block_number bigint not null,
+    block_hash varchar(66) not null,
+    tx_hash varchar(66) not null,
+    log_index integer not null,
+    processed_at timestamptz,
+    created_at timestamptz not null default now(),
+    unique (tx_hash, log_index)
+);
+
+CREATE INDEX contract_events_unprocessed_idx ON contract_events (block_number, log_index) WHERE processed_at IS NULL;
+
+CREATE TABLE contract_event_cursors(
+    name varchar not null primary key,
+    block_number bigint not null
+);
@@ -0,0 +1,28 @@
+CREATE TABLE flights(
+    id bigserial not null primary key,
+    user_id bigint not null references users (id)
+);
+
+CREATE TABLE flight_segments(
+    id bigserial not null primary key,
+    flight_id bigint not null references flights (id) on delete cascade,
+    position integer not null,
+    carrier char(2) not null,
+    number varchar(4) not null,
+    origin char(3) not null,
+    destination char(3) not null,
+    departure_at timestamptz not null,
+    arrival_at timestamptz not null,
+    unique (flight_id, position)
+);
+
+CREATE INDEX flight_segments_route_idx ON flight_segments (origin, departure_at);
+
+CREATE TABLE fares(
+    id bigserial not null primary key,
+    flight_id bigint not null references flights (id) on delete cascade,
+    cabin varchar not null,
+    amount bigint not null,
+    currency char(3) not null,
+    seats_available integer not null
+);
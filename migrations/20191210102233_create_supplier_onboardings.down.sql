@@ -0,0 +1,3 @@
+DROP TABLE onboarding_documents;
+DROP TABLE supplier_onboardings;
+ALTER TABLE users DROP COLUMN is_admin;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE supplier_onboardings DROP COLUMN version;
\ No newline at end of file
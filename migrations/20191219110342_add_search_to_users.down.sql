@@ -0,0 +1,4 @@
+DROP INDEX users_search_idx;
+DROP TRIGGER users_search_update ON users;
+DROP FUNCTION users_search_update();
+ALTER TABLE users DROP COLUMN search;
@@ -0,0 +1,16 @@
+ALTER TABLE users ADD COLUMN search tsvector;
+
+-- email separators become spaces so each part of an address is a searchable word
+CREATE FUNCTION users_search_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search := to_tsvector('simple', translate(NEW.email, '@.-_+', '     '));
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_search_update BEFORE INSERT OR UPDATE OF email ON users
+    FOR EACH ROW EXECUTE PROCEDURE users_search_update();
+
+UPDATE users SET search = to_tsvector('simple', translate(email, '@.-_+', '     '));
+
+CREATE INDEX users_search_idx ON users USING gin (search);
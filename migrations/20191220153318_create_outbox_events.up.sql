@@ -0,0 +1,11 @@
+CREATE TABLE outbox_events(
+    id bigserial not null primary key,
+    topic varchar not null,
+    payload jsonb not null,
+    attempts integer not null default 0,
+    last_error varchar not null default '',
+    created_at timestamptz not null default now(),
+    published_at timestamptz
+);
+
+CREATE INDEX outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL;
@@ -0,0 +1 @@
+DROP TABLE entity_changes;
@@ -0,0 +1,11 @@
+CREATE TABLE entity_changes(
+    id bigserial not null primary key,
+    entity varchar not null,
+    entity_id bigint not null,
+    action varchar not null,
+    before jsonb,
+    after jsonb,
+    created_at timestamptz not null default now()
+);
+
+CREATE INDEX entity_changes_entity_idx ON entity_changes (entity, entity_id, id);
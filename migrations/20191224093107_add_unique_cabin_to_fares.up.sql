@@ -0,0 +1 @@
+CREATE UNIQUE INDEX fares_flight_cabin_idx ON fares (flight_id, cabin);
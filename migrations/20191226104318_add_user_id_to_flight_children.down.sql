@@ -0,0 +1,4 @@
+ALTER TABLE fares DROP COLUMN user_id;
+ALTER TABLE flight_segments DROP COLUMN user_id;
+ALTER TABLE flights DROP CONSTRAINT flights_id_user_id_key;
+DROP INDEX flights_user_id_idx;
@@ -0,0 +1,14 @@
+CREATE INDEX flights_user_id_idx ON flights (user_id);
+ALTER TABLE flights ADD CONSTRAINT flights_id_user_id_key UNIQUE (id, user_id);
+
+ALTER TABLE flight_segments ADD COLUMN user_id bigint;
+UPDATE flight_segments SET user_id = flights.user_id FROM flights WHERE flights.id = flight_segments.flight_id;
+ALTER TABLE flight_segments
+    ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT flight_segments_flight_user_fkey FOREIGN KEY (flight_id, user_id) REFERENCES flights (id, user_id) ON DELETE CASCADE;
+
+ALTER TABLE fares ADD COLUMN user_id bigint;
+UPDATE fares SET user_id = flights.user_id FROM flights WHERE flights.id = fares.flight_id;
+ALTER TABLE fares
+    ALTER COLUMN user_id SET NOT NULL,
+    ADD CONSTRAINT fares_flight_user_fkey FOREIGN KEY (flight_id, user_id) REFERENCES flights (id, user_id) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+ALTER TABLE onboarding_documents
+    DROP COLUMN content_key,
+    DROP COLUMN content_key_id;
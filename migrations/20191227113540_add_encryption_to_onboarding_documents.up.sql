@@ -0,0 +1,3 @@
+ALTER TABLE onboarding_documents
+    ADD COLUMN content_key_id varchar,
+    ADD COLUMN content_key bytea;
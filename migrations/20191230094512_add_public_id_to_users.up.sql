@@ -0,0 +1,6 @@
+-- gen_random_uuid() is built in from PostgreSQL 13, and in pgcrypto before
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+ALTER TABLE users ADD COLUMN public_id uuid;
+UPDATE users SET public_id = gen_random_uuid();
+ALTER TABLE users ALTER COLUMN public_id SET NOT NULL;
+CREATE UNIQUE INDEX users_public_id_idx ON users (public_id);
@@ -0,0 +1,2 @@
+DROP TRIGGER users_cache_invalidation ON users;
+DROP FUNCTION notify_cache_invalidation();
@@ -0,0 +1,11 @@
+-- Instances caching rows listen on this channel; the payload is "<table>:<id>".
+-- Notifications are delivered on commit, so listeners never see rolled back writes.
+CREATE FUNCTION notify_cache_invalidation() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('cache_invalidation', TG_TABLE_NAME || ':' || OLD.id);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_cache_invalidation AFTER UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE PROCEDURE notify_cache_invalidation();
@@ -0,0 +1,15 @@
+CREATE TABLE jobs(
+    id bigserial not null primary key,
+    kind varchar not null,
+    payload jsonb not null,
+    state varchar not null default 'pending',
+    attempts integer not null default 0,
+    max_attempts integer not null,
+    last_error varchar not null default '',
+    run_at timestamptz not null,
+    locked_until timestamptz,
+    created_at timestamptz not null default now(),
+    finished_at timestamptz
+);
+
+CREATE INDEX jobs_due_idx ON jobs (run_at) WHERE state IN ('pending', 'running');
@@ -0,0 +1,7 @@
+CREATE TABLE scheduled_tasks(
+    name varchar not null primary key,
+    last_run_at timestamptz,
+    locked_until timestamptz,
+    finished_at timestamptz,
+    last_error varchar not null default ''
+);
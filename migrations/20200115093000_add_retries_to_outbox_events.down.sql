@@ -0,0 +1,4 @@
+DROP INDEX outbox_events_unpublished_idx;
+CREATE INDEX outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL;
+ALTER TABLE outbox_events DROP COLUMN failed_at;
+ALTER TABLE outbox_events DROP COLUMN retry_at;
\ No newline at end of file
@@ -0,0 +1,6 @@
+ALTER TABLE outbox_events ADD COLUMN retry_at timestamptz;
+ALTER TABLE outbox_events ADD COLUMN failed_at timestamptz;
+
+-- failed events are left for admins and no longer hold back the others
+DROP INDEX outbox_events_unpublished_idx;
+CREATE INDEX outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL AND failed_at IS NULL;
@@ -0,0 +1,51 @@
+//go:build ignore
+// +build ignore
+
+// gen embeds the SQL migrations of this directory and of its dialect
+// subdirectories into files.go.
+// Run it through `go generate ./migrations` after adding a migration.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+)
+
+func main() {
+	var names []string
+	for _, pattern := range []string{"*.sql", "*/*.sql"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		names = append(names, matches...)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen.go; DO NOT EDIT.\n\npackage migrations\n\nvar files = map[string]string{\n")
+	for _, name := range names {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fmt.Fprintf(&buf, "\t%q: %q,\n", filepath.ToSlash(name), b)
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("files.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
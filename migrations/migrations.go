@@ -0,0 +1,59 @@
+// Package migrations embeds the SQL schema migrations into the binary.
+// Postgres migrations live in this directory; the SQLite schema used for
+// local development is kept in sqlite/ and must be changed alongside them.
+package migrations
+
+//go:generate go run gen.go
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// Postgres ...
+	Postgres = ""
+	// SQLite ...
+	SQLite = "sqlite"
+)
+
+var (
+	// ErrNotFound ...
+	ErrNotFound = errors.New("migration not found")
+)
+
+// Names returns the embedded Postgres migration file names in order
+func Names() []string {
+	return DialectNames(Postgres)
+}
+
+// Asset returns the contents of an embedded Postgres migration file
+func Asset(name string) ([]byte, error) {
+	return DialectAsset(Postgres, name)
+}
+
+// DialectNames returns the file names of a dialect's migrations in order
+func DialectNames(dialect string) []string {
+	names := []string{}
+	for name := range files {
+		dir, file := path.Split(name)
+		if strings.TrimSuffix(dir, "/") == dialect {
+			names = append(names, file)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// DialectAsset returns the contents of one of a dialect's migration files
+func DialectAsset(dialect string, name string) ([]byte, error) {
+	s, ok := files[path.Join(dialect, name)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return []byte(s), nil
+}
@@ -0,0 +1,43 @@
+package migrations_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"winding-tree-server/migrations"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEmbedded fails when files.go wasn't regenerated after changing a migration
+func TestEmbedded(t *testing.T) {
+	for _, dialect := range []string{migrations.Postgres, migrations.SQLite} {
+		paths, err := filepath.Glob(filepath.Join(dialect, "*.sql"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := []string{}
+		for _, p := range paths {
+			names = append(names, filepath.Base(p))
+		}
+
+		assert.Equal(t, names, migrations.DialectNames(dialect), "run go generate ./migrations")
+
+		for i, name := range names {
+			b, err := ioutil.ReadFile(paths[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			embedded, err := migrations.DialectAsset(dialect, name)
+			assert.NoError(t, err)
+			assert.Equal(t, string(b), string(embedded), "run go generate ./migrations")
+		}
+	}
+}
+
+// TestSQLiteMirrorsPostgres fails when a migration was added for one dialect only
+func TestSQLiteMirrorsPostgres(t *testing.T) {
+	assert.Equal(t, migrations.Names(), migrations.DialectNames(migrations.SQLite))
+}
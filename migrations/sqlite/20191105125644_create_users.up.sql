@@ -0,0 +1,5 @@
+CREATE TABLE users(
+    id integer not null primary key,
+    email varchar not null unique,
+    encrypted_password varchar not null
+);
@@ -0,0 +1 @@
+DROP TABLE org_jsons;
@@ -0,0 +1,9 @@
+CREATE TABLE org_jsons(
+    id integer not null primary key,
+    user_id bigint not null references users (id),
+    version integer not null,
+    document text not null,
+    hash varchar(66) not null,
+    created_at timestamp not null default CURRENT_TIMESTAMP,
+    unique (user_id, version)
+);
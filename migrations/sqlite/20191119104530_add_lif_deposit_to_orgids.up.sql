@@ -0,0 +1,2 @@
+-- text keeps all 78 digits of a uint256 amount
+ALTER TABLE orgids ADD COLUMN lif_deposit text not null default '0';
@@ -0,0 +1,3 @@
+DROP TABLE fares;
+DROP TABLE flight_segments;
+DROP TABLE flights;
@@ -0,0 +1,24 @@
+ALTER TABLE users ADD COLUMN is_admin boolean not null default false;
+
+CREATE TABLE supplier_onboardings(
+    user_id bigint not null primary key references users (id),
+    state varchar not null,
+    rejection_reason varchar not null default '',
+    reviewer_id bigint references users (id),
+    submitted_at timestamp,
+    reviewed_at timestamp
+);
+
+CREATE INDEX supplier_onboardings_state_idx ON supplier_onboardings (state, submitted_at);
+
+CREATE TABLE onboarding_documents(
+    id integer not null primary key,
+    user_id bigint not null references users (id),
+    kind varchar not null,
+    file_name varchar not null,
+    content_type varchar not null,
+    content blob not null,
+    created_at timestamp not null default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX onboarding_documents_user_id_idx ON onboarding_documents (user_id);
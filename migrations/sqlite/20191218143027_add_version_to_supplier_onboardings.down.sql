@@ -0,0 +1 @@
+ALTER TABLE supplier_onboardings DROP COLUMN version;
@@ -0,0 +1 @@
+ALTER TABLE supplier_onboardings ADD COLUMN version integer not null default 1;
@@ -0,0 +1,2 @@
+-- SQLite has no tsvector; user search falls back to LIKE on email
+SELECT 1;
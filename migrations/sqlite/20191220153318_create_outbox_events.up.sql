@@ -0,0 +1,11 @@
+CREATE TABLE outbox_events(
+    id integer not null primary key,
+    topic varchar not null,
+    payload text not null,
+    attempts integer not null default 0,
+    last_error varchar not null default '',
+    created_at timestamp not null default CURRENT_TIMESTAMP,
+    published_at timestamp
+);
+
+CREATE INDEX outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE entity_changes(
+    id integer not null primary key,
+    entity varchar not null,
+    entity_id bigint not null,
+    action varchar not null,
+    before text,
+    after text,
+    created_at timestamp not null default CURRENT_TIMESTAMP
+);
+
+CREATE INDEX entity_changes_entity_idx ON entity_changes (entity, entity_id, id);
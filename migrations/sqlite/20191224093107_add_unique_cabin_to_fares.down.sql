@@ -0,0 +1 @@
+DROP INDEX fares_flight_cabin_idx;
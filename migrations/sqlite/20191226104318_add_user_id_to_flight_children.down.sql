@@ -0,0 +1,3 @@
+ALTER TABLE fares DROP COLUMN user_id;
+ALTER TABLE flight_segments DROP COLUMN user_id;
+DROP INDEX flights_user_id_idx;
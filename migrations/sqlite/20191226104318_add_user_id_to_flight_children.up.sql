@@ -0,0 +1,7 @@
+CREATE INDEX flights_user_id_idx ON flights (user_id);
+
+ALTER TABLE flight_segments ADD COLUMN user_id bigint not null default 0;
+UPDATE flight_segments SET user_id = (SELECT user_id FROM flights WHERE flights.id = flight_segments.flight_id);
+
+ALTER TABLE fares ADD COLUMN user_id bigint not null default 0;
+UPDATE fares SET user_id = (SELECT user_id FROM flights WHERE flights.id = fares.flight_id);
@@ -0,0 +1,2 @@
+ALTER TABLE onboarding_documents DROP COLUMN content_key;
+ALTER TABLE onboarding_documents DROP COLUMN content_key_id;
@@ -0,0 +1,2 @@
+ALTER TABLE onboarding_documents ADD COLUMN content_key_id varchar;
+ALTER TABLE onboarding_documents ADD COLUMN content_key blob;
@@ -0,0 +1,2 @@
+DROP INDEX users_public_id_idx;
+ALTER TABLE users DROP COLUMN public_id;
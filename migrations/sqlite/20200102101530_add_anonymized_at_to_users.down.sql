@@ -0,0 +1,3 @@
+DROP INDEX entity_changes_created_at_idx;
+DROP INDEX outbox_events_published_at_idx;
+ALTER TABLE users DROP COLUMN anonymized_at;
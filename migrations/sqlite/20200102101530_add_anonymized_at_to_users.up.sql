@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN anonymized_at timestamp;
+
+-- retention purges select on these
+CREATE INDEX outbox_events_published_at_idx ON outbox_events (published_at) WHERE published_at IS NOT NULL;
+CREATE INDEX entity_changes_created_at_idx ON entity_changes (created_at);
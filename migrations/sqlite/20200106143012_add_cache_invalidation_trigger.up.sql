@@ -0,0 +1,2 @@
+-- SQLite has no LISTEN/NOTIFY; dev mode runs a single instance
+SELECT 1;
@@ -0,0 +1,15 @@
+CREATE TABLE jobs(
+    id integer not null primary key,
+    kind varchar not null,
+    payload text not null,
+    state varchar not null default 'pending',
+    attempts integer not null default 0,
+    max_attempts integer not null,
+    last_error varchar not null default '',
+    run_at timestamp not null,
+    locked_until timestamp,
+    created_at timestamp not null default CURRENT_TIMESTAMP,
+    finished_at timestamp
+);
+
+CREATE INDEX jobs_due_idx ON jobs (run_at) WHERE state IN ('pending', 'running');
@@ -0,0 +1,7 @@
+CREATE TABLE scheduled_tasks(
+    name varchar not null primary key,
+    last_run_at timestamp,
+    locked_until timestamp,
+    finished_at timestamp,
+    last_error varchar not null default ''
+);
@@ -0,0 +1,7 @@
+DROP INDEX contract_events_unprocessed_idx;
+CREATE INDEX contract_events_unprocessed_idx ON contract_events (block_number, log_index) WHERE processed_at IS NULL;
+ALTER TABLE contract_events DROP COLUMN failed_at;
+ALTER TABLE contract_events DROP COLUMN retry_at;
+ALTER TABLE contract_events DROP COLUMN last_error;
+ALTER TABLE contract_events DROP COLUMN attempts;
+ALTER TABLE contract_events DROP COLUMN handled;
\ No newline at end of file
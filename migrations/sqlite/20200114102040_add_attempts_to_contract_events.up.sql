@@ -0,0 +1,9 @@
+ALTER TABLE contract_events ADD COLUMN handled integer not null default 0;
+ALTER TABLE contract_events ADD COLUMN attempts integer not null default 0;
+ALTER TABLE contract_events ADD COLUMN last_error text not null default '';
+ALTER TABLE contract_events ADD COLUMN retry_at timestamp;
+ALTER TABLE contract_events ADD COLUMN failed_at timestamp;
+
+-- failed events are left for admins and no longer hold back the others
+DROP INDEX contract_events_unprocessed_idx;
+CREATE INDEX contract_events_unprocessed_idx ON contract_events (block_number, log_index) WHERE processed_at IS NULL AND failed_at IS NULL;